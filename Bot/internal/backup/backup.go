@@ -0,0 +1,238 @@
+// Package backup выгружает данные всех сервисов в архив и загружает их обратно - для резервного копирования
+// и переноса между хранилищами (memory, postgres, sqlite, redis для напоминаний).
+//
+// Архив - JSON lines: первая строка - заголовок с версией формата, затем по строке на запись
+// ({"type":"ticket","data":{...}}), последняя строка - итог с числом записей, по которому обнаруживается
+// обрезанный файл. Записи сохраняют исходные ID и даты, поэтому ссылки между ними (выдача -> книга,
+// обращение -> пользователь) остаются верными после переноса.
+//
+// Сервисы работают с архивом через необязательные интерфейсы Archiver своих пакетов
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/news"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/support"
+	"first-max-bot/internal/services/user"
+)
+
+// Version - версия формата архива. Архивы более новой версии не загружаются
+const Version = 1
+
+// Типы записей архива
+const (
+	TypeUser     = "user"
+	TypeBook     = "book"
+	TypeLoan     = "loan"
+	TypeTicket   = "ticket"
+	TypeDocument = "document"
+	TypeTrip     = "trip"
+	TypeNews     = "news"
+	TypeReminder = "reminder"
+
+	typeHeader = "header"
+	typeFooter = "footer"
+)
+
+// Services - сервисы, данные которых попадают в архив. Каждый должен реализовывать Archiver своего пакета
+type Services struct {
+	Users     user.Service
+	Support   support.Service
+	Deanery   deanery.Service
+	Library   library.Service
+	Trips     businesstrip.Service
+	News      news.Service
+	Reminders reminder.Service
+}
+
+// line - строка архива: заголовок, запись или итог
+type line struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version,omitempty"`
+	CreatedAt *time.Time      `json:"created_at,omitempty"`
+	Records   int             `json:"records,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Report - итог выгрузки или загрузки
+type Report struct {
+	Records  map[string]int // выгружено или загружено записей по типам
+	Skipped  map[string]int // пропущено при загрузке из-за совпадения ID (режим skip)
+	Warnings []string       // некритичные проблемы: ссылки на отсутствующих пользователей и т.п.
+}
+
+func newReport() Report {
+	return Report{Records: make(map[string]int), Skipped: make(map[string]int)}
+}
+
+// Total возвращает общее число выгруженных или загруженных записей
+func (r Report) Total() int {
+	total := 0
+	for _, n := range r.Records {
+		total += n
+	}
+	return total
+}
+
+// archivers - Archiver'ы всех сервисов; ошибка, если какой-то сервис их не поддерживает
+type archivers struct {
+	users     user.Archiver
+	support   support.Archiver
+	deanery   deanery.Archiver
+	library   library.Archiver
+	trips     businesstrip.Archiver
+	news      news.Archiver
+	reminders reminder.Archiver
+}
+
+func (s Services) archivers() (*archivers, error) {
+	var (
+		a  archivers
+		ok bool
+	)
+	if a.users, ok = s.Users.(user.Archiver); !ok {
+		return nil, fmt.Errorf("user service does not support backup")
+	}
+	if a.support, ok = s.Support.(support.Archiver); !ok {
+		return nil, fmt.Errorf("support service does not support backup")
+	}
+	if a.deanery, ok = s.Deanery.(deanery.Archiver); !ok {
+		return nil, fmt.Errorf("deanery service does not support backup")
+	}
+	if a.library, ok = s.Library.(library.Archiver); !ok {
+		return nil, fmt.Errorf("library service does not support backup")
+	}
+	if a.trips, ok = s.Trips.(businesstrip.Archiver); !ok {
+		return nil, fmt.Errorf("business trip service does not support backup")
+	}
+	if a.news, ok = s.News.(news.Archiver); !ok {
+		return nil, fmt.Errorf("news service does not support backup")
+	}
+	if a.reminders, ok = s.Reminders.(reminder.Archiver); !ok {
+		return nil, fmt.Errorf("reminder service does not support backup")
+	}
+	return &a, nil
+}
+
+// Export записывает в w архив со всеми данными сервисов.
+// Порядок записей - пользователи, книги, выдачи, затем остальное: при загрузке ссылки указывают на уже прочитанное
+func Export(ctx context.Context, w io.Writer, services Services) (Report, error) {
+	report := newReport()
+	a, err := services.archivers()
+	if err != nil {
+		return report, err
+	}
+
+	enc := json.NewEncoder(w)
+	now := time.Now()
+	if err := enc.Encode(line{Type: typeHeader, Version: Version, CreatedAt: &now}); err != nil {
+		return report, fmt.Errorf("write header: %w", err)
+	}
+
+	write := func(kind string, data any) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", kind, err)
+		}
+		if err := enc.Encode(line{Type: kind, Data: raw}); err != nil {
+			return fmt.Errorf("write %s: %w", kind, err)
+		}
+		report.Records[kind]++
+		return nil
+	}
+
+	users, err := a.users.ExportUsers(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, u := range users {
+		if err := write(TypeUser, u); err != nil {
+			return report, err
+		}
+	}
+
+	books, err := a.library.ExportBooks(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, b := range books {
+		if err := write(TypeBook, b); err != nil {
+			return report, err
+		}
+	}
+
+	loans, err := a.library.ExportLoans(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, loan := range loans {
+		loan.Book = nil // книга выгружается отдельной записью, в выдаче достаточно book_id
+		if err := write(TypeLoan, loan); err != nil {
+			return report, err
+		}
+	}
+
+	tickets, err := a.support.ExportTickets(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, t := range tickets {
+		if err := write(TypeTicket, t); err != nil {
+			return report, err
+		}
+	}
+
+	documents, err := a.deanery.ExportDocuments(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, d := range documents {
+		if err := write(TypeDocument, d); err != nil {
+			return report, err
+		}
+	}
+
+	trips, err := a.trips.ExportTrips(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, t := range trips {
+		if err := write(TypeTrip, t); err != nil {
+			return report, err
+		}
+	}
+
+	newsList, err := a.news.ExportNews(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, n := range newsList {
+		if err := write(TypeNews, n); err != nil {
+			return report, err
+		}
+	}
+
+	reminders, err := a.reminders.ExportReminders(ctx)
+	if err != nil {
+		return report, err
+	}
+	for _, r := range reminders {
+		if err := write(TypeReminder, r); err != nil {
+			return report, err
+		}
+	}
+
+	if err := enc.Encode(line{Type: typeFooter, Records: report.Total()}); err != nil {
+		return report, fmt.Errorf("write footer: %w", err)
+	}
+	return report, nil
+}
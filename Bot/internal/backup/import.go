@@ -0,0 +1,370 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/news"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/support"
+	"first-max-bot/internal/services/user"
+)
+
+// ConflictMode - что делать с записью архива, ID которой уже есть в хранилище
+type ConflictMode string
+
+const (
+	ConflictFail      ConflictMode = "fail"      // ничего не загружать (по умолчанию)
+	ConflictSkip      ConflictMode = "skip"      // оставить запись хранилища
+	ConflictOverwrite ConflictMode = "overwrite" // заменить запись хранилища записью архива
+)
+
+// ParseConflictMode разбирает режим из флага командной строки; пустая строка - ConflictFail
+func ParseConflictMode(value string) (ConflictMode, error) {
+	switch mode := ConflictMode(value); mode {
+	case "":
+		return ConflictFail, nil
+	case ConflictFail, ConflictSkip, ConflictOverwrite:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown conflict mode %q (fail, skip, overwrite)", value)
+	}
+}
+
+// maxLineSize - предельный размер строки архива
+const maxLineSize = 16 << 20
+
+// conflictSamples - сколько совпавших ID показывать в ошибке
+const conflictSamples = 5
+
+// archive - прочитанное содержимое архива
+type archive struct {
+	users     []user.User
+	books     []library.Book
+	loans     []library.UserBook
+	tickets   []support.Ticket
+	documents []deanery.Document
+	trips     []businesstrip.Trip
+	news      []news.News
+	reminders []reminder.Reminder
+}
+
+// Import загружает архив из r в сервисы. Архив сначала читается и проверяется целиком
+// (версия, итоговое число записей, повторяющиеся ID, ссылки выдач на книги), затем проверяются совпадения ID
+// с хранилищем - и только после этого начинается запись, поэтому ошибка проверки ничего не меняет.
+// Захват напоминаний обработчиком не переносится
+func Import(ctx context.Context, r io.Reader, services Services, mode ConflictMode) (Report, error) {
+	report := newReport()
+	a, err := services.archivers()
+	if err != nil {
+		return report, err
+	}
+
+	data, err := readArchive(r)
+	if err != nil {
+		return report, err
+	}
+	if err := data.validate(); err != nil {
+		return report, err
+	}
+
+	steps := make([]*step, 0, 8)
+	add := func(s *step, err error) error {
+		if err != nil {
+			return err
+		}
+		steps = append(steps, s)
+		return nil
+	}
+	// Порядок шагов - порядок записи: книги до выдач, пользователи до всего остального
+	err = firstError(
+		add(newStep(ctx, TypeUser, data.users, func(u user.User) string { return u.UserID }, a.users.ExportUsers, a.users.RestoreUser)),
+		add(newStep(ctx, TypeBook, data.books, func(b library.Book) string { return b.ID }, a.library.ExportBooks, a.library.RestoreBook)),
+		add(newStep(ctx, TypeLoan, data.loans, func(l library.UserBook) string { return l.ID }, a.library.ExportLoans, a.library.RestoreLoan)),
+		add(newStep(ctx, TypeTicket, data.tickets, func(t support.Ticket) string { return t.ID }, a.support.ExportTickets, a.support.RestoreTicket)),
+		add(newStep(ctx, TypeDocument, data.documents, func(d deanery.Document) string { return d.ID }, a.deanery.ExportDocuments, a.deanery.RestoreDocument)),
+		add(newStep(ctx, TypeTrip, data.trips, func(t businesstrip.Trip) string { return t.ID }, a.trips.ExportTrips, a.trips.RestoreTrip)),
+		add(newStep(ctx, TypeNews, data.news, func(n news.News) string { return n.ID }, a.news.ExportNews, a.news.RestoreNews)),
+		add(newStep(ctx, TypeReminder, data.reminders, func(r reminder.Reminder) string { return r.ID }, a.reminders.ExportReminders, a.reminders.RestoreReminder)),
+	)
+	if err != nil {
+		return report, err
+	}
+	users, books := steps[0], steps[1]
+
+	// Выдача должна ссылаться на книгу из архива или из хранилища
+	archiveBooks := make(map[string]bool, len(data.books))
+	for _, b := range data.books {
+		archiveBooks[b.ID] = true
+	}
+	for _, loan := range data.loans {
+		if !archiveBooks[loan.BookID] && !books.existing[loan.BookID] {
+			return report, fmt.Errorf("loan %s references unknown book %s", loan.ID, loan.BookID)
+		}
+	}
+
+	// Ссылки на неизвестных пользователей не мешают загрузке (пользователь мог не пройти регистрацию),
+	// но о них сообщается
+	archiveUsers := make(map[string]bool, len(data.users))
+	for _, u := range data.users {
+		archiveUsers[u.UserID] = true
+	}
+	knownUser := func(id string) bool {
+		return archiveUsers[id] || users.existing[id]
+	}
+	report.Warnings = append(report.Warnings, unknownUsers(TypeLoan, data.loans, func(l library.UserBook) string { return l.UserID }, knownUser)...)
+	report.Warnings = append(report.Warnings, unknownUsers(TypeTicket, data.tickets, func(t support.Ticket) string { return t.UserID }, knownUser)...)
+	report.Warnings = append(report.Warnings, unknownUsers(TypeDocument, data.documents, func(d deanery.Document) string { return d.UserID }, knownUser)...)
+	report.Warnings = append(report.Warnings, unknownUsers(TypeTrip, data.trips, func(t businesstrip.Trip) string { return t.UserID }, knownUser)...)
+	report.Warnings = append(report.Warnings, unknownUsers(TypeReminder, data.reminders, func(r reminder.Reminder) string { return r.UserID }, knownUser)...)
+
+	if mode == ConflictFail {
+		var conflicts []string
+		total := 0
+		for _, s := range steps {
+			total += len(s.conflicts)
+			for _, id := range s.conflicts {
+				if len(conflicts) < conflictSamples {
+					conflicts = append(conflicts, s.kind+" "+id)
+				}
+			}
+		}
+		if total > 0 {
+			return report, fmt.Errorf("%d records already exist in storage (%s); use skip or overwrite conflict mode",
+				total, strings.Join(conflicts, ", "))
+		}
+	}
+
+	for _, s := range steps {
+		if err := s.apply(ctx, mode, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// step - загрузка записей одного типа
+type step struct {
+	kind      string
+	existing  map[string]bool // ID, которые уже есть в хранилище
+	conflicts []string        // ID архива, которые уже есть в хранилище
+	apply     func(ctx context.Context, mode ConflictMode, report *Report) error
+}
+
+func newStep[T any](ctx context.Context, kind string, records []T, id func(T) string,
+	export func(context.Context) ([]T, error), restore func(context.Context, T) error) (*step, error) {
+	current, err := export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read existing %s records: %w", kind, err)
+	}
+
+	s := &step{kind: kind, existing: make(map[string]bool, len(current))}
+	for _, record := range current {
+		s.existing[id(record)] = true
+	}
+	for _, record := range records {
+		if s.existing[id(record)] {
+			s.conflicts = append(s.conflicts, id(record))
+		}
+	}
+
+	s.apply = func(ctx context.Context, mode ConflictMode, report *Report) error {
+		for _, record := range records {
+			if mode == ConflictSkip && s.existing[id(record)] {
+				report.Skipped[kind]++
+				continue
+			}
+			if err := restore(ctx, record); err != nil {
+				return err
+			}
+			report.Records[kind]++
+		}
+		return nil
+	}
+	return s, nil
+}
+
+// unknownUsers возвращает предупреждение о записях, ссылающихся на неизвестных пользователей
+func unknownUsers[T any](kind string, records []T, userID func(T) string, known func(string) bool) []string {
+	var missing []string
+	for _, record := range records {
+		if id := userID(record); !known(id) {
+			missing = append(missing, id)
+		}
+	}
+	count := len(missing)
+	if count == 0 {
+		return nil
+	}
+	if count > conflictSamples {
+		missing = append(missing[:conflictSamples], "...")
+	}
+	return []string{fmt.Sprintf("%d %s records reference unknown users (%s)", count, kind, strings.Join(missing, ", "))}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readArchive читает архив целиком: заголовок, записи и итог
+func readArchive(r io.Reader) (*archive, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	var (
+		data      archive
+		lineNo    int
+		records   int
+		header    bool
+		footer    bool
+		footerCnt int
+	)
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Bytes()
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		if footer {
+			return nil, fmt.Errorf("line %d: data after footer", lineNo)
+		}
+
+		var l line
+		if err := json.Unmarshal(raw, &l); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		if !header {
+			if l.Type != typeHeader {
+				return nil, fmt.Errorf("line %d: archive header expected", lineNo)
+			}
+			if l.Version < 1 || l.Version > Version {
+				return nil, fmt.Errorf("unsupported archive version %d (supported up to %d)", l.Version, Version)
+			}
+			header = true
+			continue
+		}
+
+		var err error
+		switch l.Type {
+		case typeFooter:
+			footer, footerCnt = true, l.Records
+			continue
+		case TypeUser:
+			err = appendRecord(&data.users, l.Data)
+		case TypeBook:
+			err = appendRecord(&data.books, l.Data)
+		case TypeLoan:
+			err = appendRecord(&data.loans, l.Data)
+		case TypeTicket:
+			err = appendRecord(&data.tickets, l.Data)
+		case TypeDocument:
+			err = appendRecord(&data.documents, l.Data)
+		case TypeTrip:
+			err = appendRecord(&data.trips, l.Data)
+		case TypeNews:
+			err = appendRecord(&data.news, l.Data)
+		case TypeReminder:
+			err = appendRecord(&data.reminders, l.Data)
+		default:
+			err = fmt.Errorf("unknown record type %q", l.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		records++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read archive: %w", err)
+	}
+
+	if !header {
+		return nil, fmt.Errorf("archive is empty")
+	}
+	if !footer {
+		return nil, fmt.Errorf("archive is truncated: footer not found")
+	}
+	if footerCnt != records {
+		return nil, fmt.Errorf("archive is damaged: footer counts %d records, found %d", footerCnt, records)
+	}
+	return &data, nil
+}
+
+func appendRecord[T any](records *[]T, raw json.RawMessage) error {
+	var record T
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return err
+	}
+	*records = append(*records, record)
+	return nil
+}
+
+// validate проверяет архив без обращения к хранилищу: ID заданы и не повторяются,
+// одна книга не выдана по двум открытым записям. Приводит записи к виду для загрузки
+func (a *archive) validate() error {
+	for i := range a.users {
+		a.users[i].ID = a.users[i].UserID
+	}
+	for i := range a.loans {
+		a.loans[i].Book = nil
+	}
+	for i := range a.reminders {
+		a.reminders[i].ClaimedBy = ""
+		a.reminders[i].ClaimedUntil = time.Time{}
+	}
+
+	err := firstError(
+		uniqueIDs(TypeUser, a.users, func(u user.User) string { return u.UserID }),
+		uniqueIDs(TypeBook, a.books, func(b library.Book) string { return b.ID }),
+		uniqueIDs(TypeLoan, a.loans, func(l library.UserBook) string { return l.ID }),
+		uniqueIDs(TypeTicket, a.tickets, func(t support.Ticket) string { return t.ID }),
+		uniqueIDs(TypeDocument, a.documents, func(d deanery.Document) string { return d.ID }),
+		uniqueIDs(TypeTrip, a.trips, func(t businesstrip.Trip) string { return t.ID }),
+		uniqueIDs(TypeNews, a.news, func(n news.News) string { return n.ID }),
+		uniqueIDs(TypeReminder, a.reminders, func(r reminder.Reminder) string { return r.ID }),
+	)
+	if err != nil {
+		return err
+	}
+
+	onLoan := make(map[string]string)
+	for _, loan := range a.loans {
+		if loan.Returned {
+			continue
+		}
+		if other, ok := onLoan[loan.BookID]; ok {
+			return fmt.Errorf("book %s is on loan twice (%s and %s)", loan.BookID, other, loan.ID)
+		}
+		onLoan[loan.BookID] = loan.ID
+	}
+	return nil
+}
+
+func uniqueIDs[T any](kind string, records []T, id func(T) string) error {
+	seen := make(map[string]bool, len(records))
+	for _, record := range records {
+		value := id(record)
+		if value == "" {
+			return fmt.Errorf("%s record without id", kind)
+		}
+		if seen[value] {
+			return fmt.Errorf("duplicate %s id %s", kind, value)
+		}
+		seen[value] = true
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -10,26 +14,64 @@ import (
 	maxbot "github.com/max-messenger/max-bot-api-client-go"
 	"github.com/max-messenger/max-bot-api-client-go/schemes"
 
+	"first-max-bot/internal/cluster"
+	"first-max-bot/internal/secret"
 	"first-max-bot/internal/state"
 )
 
+// DefaultFlowTimeout - через сколько бездействия сценарий ввода (ответ на обращение, создание напоминания и т.п.) отменяется
+const DefaultFlowTimeout = 30 * time.Minute
+
 type Bot struct {
-	api    *maxbot.Api
-	router *Router
-	state  state.Repository
-	logger zerolog.Logger
+	api         *maxbot.Api
+	router      *Router
+	state       state.Repository
+	dedup       cluster.Deduplicator
+	flowTimeout time.Duration
+	username    string // username бота, нужен для распознавания адресованных ему команд в группах
+	token       string // токен бота для загрузки файлов, см. upload.go
+	logger      zerolog.Logger
+}
+
+type Option func(*Bot)
+
+// WithDeduplicator включает дедупликацию обновлений: при нескольких экземплярах бота
+// или повторной доставке каждое обновление обрабатывается один раз
+func WithDeduplicator(dedup cluster.Deduplicator) Option {
+	return func(b *Bot) {
+		b.dedup = dedup
+	}
+}
+
+// WithFlowTimeout задает, через сколько бездействия отменяется сценарий ввода; 0 отключает таймаут
+func WithFlowTimeout(timeout time.Duration) Option {
+	return func(b *Bot) {
+		b.flowTimeout = timeout
+	}
 }
 
-func New(api *maxbot.Api, router *Router, stateRepo state.Repository, logger zerolog.Logger) *Bot {
-	return &Bot{
-		api:    api,
-		router: router,
-		state:  stateRepo,
-		logger: logger,
+func New(api *maxbot.Api, router *Router, stateRepo state.Repository, logger zerolog.Logger, opts ...Option) *Bot {
+	b := &Bot{
+		api:         api,
+		router:      router,
+		state:       stateRepo,
+		dedup:       cluster.NewLocal(),
+		flowTimeout: DefaultFlowTimeout,
+		logger:      logger,
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
+	return b
 }
 
 func (b *Bot) Run(ctx context.Context) error {
+	if info, err := b.api.Bots.GetBot(ctx); err != nil {
+		b.logger.Warn().Err(err).Msg("failed to get bot info, commands with @mention will be ignored in group chats")
+	} else {
+		b.username = info.Username
+	}
+
 	updates := b.api.GetUpdates(ctx)
 	for {
 		select {
@@ -45,13 +87,34 @@ func (b *Bot) Run(ctx context.Context) error {
 }
 
 func (b *Bot) handleUpdate(ctx context.Context, update schemes.UpdateInterface) {
+	key := updateKey(update)
+	claimed, err := b.dedup.ClaimUpdate(ctx, key)
+	if err != nil {
+		// Лучше обработать обновление дважды, чем потерять его из-за недоступности Redis
+		b.logger.Warn().Err(err).Str("update_key", key).Msg("failed to claim update, processing anyway")
+	} else if !claimed {
+		b.logger.Debug().Str("update_key", key).Msg("duplicate update skipped")
+		return
+	}
+
 	switch upd := update.(type) {
 	case *schemes.MessageCreatedUpdate:
 		b.handleMessage(ctx, upd)
 	case *schemes.MessageCallbackUpdate:
 		b.handleCallback(ctx, upd)
 	default:
-		b.logger.Debug().Str("type", fmt.Sprintf("%T", update)).Msg("update ignored")
+		b.handleEvent(ctx, update)
+	}
+
+	// Обработка могла быть прервана остановкой бота: тогда захват истечет сам, и обновление
+	// обработает другой экземпляр. Иначе помечаем его обработанным, даже если ctx отменили только что
+	if ctx.Err() != nil {
+		return
+	}
+	completeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := b.dedup.CompleteUpdate(completeCtx, key); err != nil {
+		b.logger.Warn().Err(err).Str("update_key", key).Msg("failed to mark update processed")
 	}
 }
 
@@ -59,9 +122,14 @@ func (b *Bot) handleMessage(ctx context.Context, upd *schemes.MessageCreatedUpda
 	logger := b.logger.With().
 		Int64("chat_id", upd.Message.Recipient.ChatId).
 		Int64("user_id", upd.Message.Sender.UserId).
-		Str("text", upd.Message.Body.Text).
 		Logger()
 
+	// В групповых чатах бот отвечает только на адресованные ему команды
+	if isGroupRecipient(upd.Message.Recipient) {
+		b.handleGroupMessage(ctx, upd, logger.With().Str("text", upd.Message.Body.Text).Logger())
+		return
+	}
+
 	// Сначала загружаем состояние, чтобы проверить, не в процессе ли регистрации
 	var (
 		userID    = extractUserID(upd)
@@ -84,6 +152,19 @@ func (b *Bot) handleMessage(ctx context.Context, upd *schemes.MessageCreatedUpda
 		userState.UserRegistrationData = make(map[string]string)
 	}
 
+	// Секреты, которые пользователь вводит в сценарии (токен Moodle), в лог не попадают
+	text := upd.Message.Body.Text
+	if userState.SensitiveInput() && !strings.HasPrefix(strings.TrimSpace(text), "/") {
+		text = secret.Redacted
+	}
+	logger = logger.With().Str("text", text).Logger()
+
+	// Просроченный сценарий отменяем и сообщаем об этом, а не считаем сообщение вводом для него.
+	// Команда после этого выполняется как обычно
+	if b.expireFlow(ctx, userID, userState, upd.Message.Recipient, logger) && !strings.HasPrefix(strings.TrimSpace(upd.Message.Body.Text), "/") {
+		return
+	}
+
 	// Разрешаем handler с учетом состояния
 	handler, command, args := b.router.ResolveByState(upd.Message.Body.Text, userState)
 	if handler == nil {
@@ -99,6 +180,10 @@ func (b *Bot) handleMessage(ctx context.Context, upd *schemes.MessageCreatedUpda
 		UserState: userState,
 	}
 
+	if !b.router.Allow(ctx, req, b, command) {
+		return
+	}
+
 	if err := handler.Handle(ctx, req, b); err != nil {
 		logger.Error().Err(err).Msg("handler failed")
 	}
@@ -121,6 +206,271 @@ func (b *Bot) handleMessage(ctx context.Context, upd *schemes.MessageCreatedUpda
 	}
 }
 
+// updateKey возвращает идентификатор обновления для дедупликации: id сообщения или callback'а,
+// а для прочих событий - тип, чат, пользователя и время
+func updateKey(update schemes.UpdateInterface) string {
+	switch upd := update.(type) {
+	case *schemes.MessageCreatedUpdate:
+		return "message:" + upd.Message.Body.Mid
+	case *schemes.MessageCallbackUpdate:
+		return "callback:" + upd.Callback.CallbackID
+	case *schemes.MessageEditedUpdate:
+		return fmt.Sprintf("edited:%s:%d", upd.Message.Body.Mid, upd.Timestamp)
+	case *schemes.MessageRemovedUpdate:
+		return "removed:" + upd.MessageId
+	}
+	return fmt.Sprintf("%s:%d:%d:%d", update.GetUpdateType(), update.GetChatID(), update.GetUserID(), update.GetUpdateTime().Unix())
+}
+
+// expireFlow отменяет сценарий ввода, если пользователь не отвечал дольше flowTimeout,
+// сохраняет состояние и уведомляет пользователя. Возвращает true, если сценарий был отменен
+func (b *Bot) expireFlow(ctx context.Context, userID string, userState *state.UserState, recipient schemes.Recipient, logger zerolog.Logger) bool {
+	if b.flowTimeout <= 0 || userID == "" || !userState.InFlow() || userState.LastUpdated.IsZero() {
+		return false
+	}
+	if time.Since(userState.LastUpdated) < b.flowTimeout {
+		return false
+	}
+
+	title := state.FlowTitle(userState.UserRegistrationStep)
+	logger.Info().Str("step", userState.UserRegistrationStep).Time("last_updated", userState.LastUpdated).Msg("flow expired")
+
+	userState.ResetFlow()
+	userState.LastUpdated = time.Now()
+	if err := b.state.SaveUserState(ctx, userID, *userState); err != nil {
+		logger.Error().Err(err).Msg("failed to save user state")
+	}
+
+	message := fmt.Sprintf("⌛ Время ожидания истекло, действие «%s» отменено. Начни заново, если нужно. Список команд — /help", title)
+	if err := b.SendText(ctx, recipient, message); err != nil {
+		logger.Error().Err(err).Msg("failed to send flow expired message")
+	}
+	return true
+}
+
+// handleGroupMessage обрабатывает сообщение из группового чата.
+// Состояние пользователя (регистрация, ввод текста для обращений и т.п.) в группах не используется
+// и не сохраняется, чтобы сообщения в чате не вмешивались в диалоги с ботом в личке
+func (b *Bot) handleGroupMessage(ctx context.Context, upd *schemes.MessageCreatedUpdate, logger zerolog.Logger) {
+	text, ok := addressedCommand(upd.Message.Body.Text, b.username)
+	if !ok {
+		return
+	}
+
+	handler, command, args := b.router.ResolveGroup(text)
+	if handler == nil {
+		if b.router.HasCommand(command) {
+			if err := b.SendText(ctx, upd.Message.Recipient, "Эта команда доступна только в личных сообщениях с ботом."); err != nil {
+				logger.Error().Err(err).Msg("failed to send group hint")
+			}
+		}
+		return
+	}
+
+	req := &Request{
+		Context: ctx,
+		Update:  upd,
+		Command: command,
+		Args:    args,
+		UserState: &state.UserState{
+			UserRegistrationData: make(map[string]string),
+		},
+	}
+
+	if !b.router.Allow(ctx, req, b, command) {
+		return
+	}
+
+	if err := handler.Handle(ctx, req, b); err != nil {
+		logger.Error().Err(err).Msg("group handler failed")
+	}
+}
+
+// handleEvent передает обновление, не являющееся сообщением или callback'ом,
+// handler'у, зарегистрированному для его типа через Router.OnBotStarted, OnMessageEdited и т.п.
+func (b *Bot) handleEvent(ctx context.Context, update schemes.UpdateInterface) {
+	logger := b.logger.With().
+		Str("update_type", string(update.GetUpdateType())).
+		Int64("chat_id", update.GetChatID()).
+		Int64("user_id", update.GetUserID()).
+		Logger()
+
+	handler := b.router.ResolveEvent(update.GetUpdateType())
+	if handler == nil {
+		logger.Debug().Str("type", fmt.Sprintf("%T", update)).Msg("update ignored")
+		return
+	}
+
+	req := &Request{
+		Context:  ctx,
+		Event:    update,
+		Metadata: eventMetadata(update),
+	}
+
+	// bot_started обрабатывается как /start с payload из deep-link
+	if upd, ok := update.(*schemes.BotStartedUpdate); ok {
+		req.Command = "/start"
+		req.Args = startPayload(upd)
+	}
+
+	if userID := update.GetUserID(); userID != 0 {
+		userState, err := b.state.GetUserState(ctx, fmt.Sprintf("%d", userID))
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to load user state")
+		}
+		req.UserState = userState
+	}
+	if req.UserState == nil {
+		req.UserState = &state.UserState{}
+	}
+	if req.UserState.UserRegistrationData == nil {
+		req.UserState.UserRegistrationData = make(map[string]string)
+	}
+
+	before := *req.UserState
+	before.UserRegistrationData = maps.Clone(req.UserState.UserRegistrationData)
+
+	if err := handler.Handle(ctx, req, b); err != nil {
+		logger.Error().Err(err).Msg("event handler failed")
+	}
+
+	// Сценарий, запущенный событием (например, /start по deep-link), может изменить состояние пользователя.
+	// Остальные события - не ввод пользователя: без изменений состояние не сохраняется, чтобы не создавать
+	// запись для каждого участника чата и не продлевать срок неактивности сценария
+	if userID := update.GetUserID(); userID != 0 && req.UserState != nil && userStateChanged(before, *req.UserState) {
+		stateToSave := *req.UserState
+		stateToSave.LastUpdated = time.Now()
+		if err := b.state.SaveUserState(ctx, fmt.Sprintf("%d", userID), stateToSave); err != nil {
+			logger.Error().Err(err).Msg("failed to save user state")
+		}
+	}
+}
+
+// userStateChanged сообщает, изменил ли handler состояние пользователя
+func userStateChanged(before, after state.UserState) bool {
+	return before.LastCommand != after.LastCommand ||
+		before.UserRegistrationStep != after.UserRegistrationStep ||
+		!maps.Equal(before.UserRegistrationData, after.UserRegistrationData)
+}
+
+// eventMetadata заполняет recipient и отправителя для события так же, как для callback'ов
+func eventMetadata(update schemes.UpdateInterface) map[string]any {
+	metadata := map[string]any{}
+
+	var (
+		sender    schemes.User
+		recipient schemes.Recipient
+	)
+	switch upd := update.(type) {
+	case *schemes.BotStartedUpdate:
+		sender = upd.User
+		recipient = schemes.Recipient{ChatId: upd.ChatId, UserId: upd.User.UserId, ChatType: schemes.DIALOG}
+	case *schemes.BotAddedToChatUpdate:
+		sender = upd.User
+		recipient = schemes.Recipient{ChatId: upd.ChatId, ChatType: schemes.CHAT}
+	case *schemes.BotRemovedFromChatUpdate:
+		sender = upd.User
+		recipient = schemes.Recipient{ChatId: upd.ChatId, ChatType: schemes.CHAT}
+	case *schemes.UserAddedToChatUpdate:
+		sender = upd.User
+		recipient = schemes.Recipient{ChatId: upd.ChatId, ChatType: schemes.CHAT}
+	case *schemes.UserRemovedFromChatUpdate:
+		sender = upd.User
+		recipient = schemes.Recipient{ChatId: upd.ChatId, ChatType: schemes.CHAT}
+	case *schemes.MessageEditedUpdate:
+		sender = upd.Message.Sender
+		recipient = upd.Message.Recipient
+		metadata["text"] = upd.Message.Body.Text
+		metadata["message_id"] = upd.Message.Body.Mid
+	case *schemes.MessageRemovedUpdate:
+		metadata["message_id"] = upd.MessageId
+	default:
+		recipient = schemes.Recipient{ChatId: update.GetChatID()}
+	}
+
+	metadata["recipient"] = recipient
+	metadata["sender"] = sender
+	if sender.UserId != 0 {
+		metadata["sender_id"] = fmt.Sprintf("%d", sender.UserId)
+	}
+	return metadata
+}
+
+// startPayload извлекает payload deep-link'а (https://max.ru/<bot>?start=<payload>) из bot_started.
+// В схеме клиента поля нет, поэтому payload читается из исходного JSON обновления,
+// который клиент сохраняет в DebugRaw
+func startPayload(upd *schemes.BotStartedUpdate) string {
+	if upd.DebugRaw == "" {
+		return ""
+	}
+	var raw struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(upd.DebugRaw), &raw); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(raw.Payload)
+}
+
+// IsChatAdmin проверяет, является ли пользователь администратором или владельцем чата
+func (b *Bot) IsChatAdmin(ctx context.Context, chatID, userID int64) (bool, error) {
+	var marker int64
+	for {
+		members, err := b.api.Chats.GetChatMembers(ctx, chatID, 100, marker)
+		if err != nil {
+			return false, fmt.Errorf("get chat members: %w", err)
+		}
+		for _, member := range members.Members {
+			if member.UserId == userID {
+				return member.IsAdmin || member.IsOwner, nil
+			}
+		}
+		if members.Marker == nil || *members.Marker == 0 || len(members.Members) == 0 {
+			return false, nil
+		}
+		marker = *members.Marker
+	}
+}
+
+func isGroupRecipient(recipient schemes.Recipient) bool {
+	return recipient.ChatId != 0 && recipient.ChatType != "" && recipient.ChatType != schemes.DIALOG
+}
+
+// addressedCommand проверяет, адресовано ли сообщение из группы этому боту,
+// и возвращает текст команды без упоминания бота.
+// Адресованными считаются "/cmd", "/cmd@bot" и "@bot cmd"; команды для других ботов ("/cmd@other") игнорируются
+func addressedCommand(text, username string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+
+	if username != "" {
+		mention := "@" + username
+		if len(text) >= len(mention) && strings.EqualFold(text[:len(mention)], mention) {
+			rest := strings.TrimSpace(text[len(mention):])
+			if rest == "" {
+				return "", false
+			}
+			return rest, true
+		}
+	}
+
+	if !strings.HasPrefix(text, "/") {
+		return "", false
+	}
+
+	fields := strings.Fields(text)
+	command := fields[0]
+	if at := strings.Index(command, "@"); at >= 0 {
+		if username == "" || !strings.EqualFold(command[at+1:], username) {
+			return "", false
+		}
+		text = command[:at] + strings.TrimPrefix(text, command)
+	}
+	return text, true
+}
+
 func extractUserID(upd *schemes.MessageCreatedUpdate) string {
 	if upd.Message.Sender.UserId != 0 {
 		return fmt.Sprintf("%d", upd.Message.Sender.UserId)
@@ -330,6 +680,57 @@ func (b *Bot) SendTextWithFile(ctx context.Context, recipient schemes.Recipient,
 	return err
 }
 
+// SendImage загружает изображение (например, QR-код) и отправляет его с подписью
+func (b *Bot) SendImage(ctx context.Context, recipient schemes.Recipient, text string, image io.Reader) error {
+	photo, err := b.api.Uploads.UploadPhotoFromReader(ctx, image)
+	if err != nil {
+		return fmt.Errorf("upload image: %w", err)
+	}
+
+	message := maxbot.NewMessage()
+	if recipient.ChatId != 0 {
+		message.SetChat(recipient.ChatId)
+	}
+	if recipient.UserId != 0 {
+		message.SetUser(recipient.UserId)
+	}
+	message.SetText(text)
+	message.AddPhoto(photo)
+
+	_, err = b.api.Messages.Send(ctx, message)
+	a, _ := err.(schemes.Error)
+	if a.Code == "" {
+		return nil
+	}
+	return err
+}
+
+// SendFile загружает файл под именем name (например, архив с данными пользователя "my_data.zip")
+// и отправляет его с подписью. Тип содержимого определяется по расширению имени
+func (b *Bot) SendFile(ctx context.Context, recipient schemes.Recipient, text, name string, file io.Reader) error {
+	uploaded, err := b.uploadFile(ctx, name, file)
+	if err != nil {
+		return fmt.Errorf("upload file: %w", err)
+	}
+
+	message := maxbot.NewMessage()
+	if recipient.ChatId != 0 {
+		message.SetChat(recipient.ChatId)
+	}
+	if recipient.UserId != 0 {
+		message.SetUser(recipient.UserId)
+	}
+	message.SetText(text)
+	message.AddFile(uploaded)
+
+	_, err = b.api.Messages.Send(ctx, message)
+	a, _ := err.(schemes.Error)
+	if a.Code == "" {
+		return nil
+	}
+	return err
+}
+
 func (b *Bot) handleCallback(ctx context.Context, upd *schemes.MessageCallbackUpdate) {
 	logger := b.logger.With().
 		Int64("user_id", upd.Callback.User.UserId).
@@ -385,6 +786,12 @@ func (b *Bot) handleCallback(ctx context.Context, upd *schemes.MessageCallbackUp
 		messageSeq = upd.Message.Body.Seq
 	}
 
+	// Текст исходного сообщения нужен, чтобы убрать из него кнопки после выполнения действия
+	messageText := ""
+	if upd.Message != nil {
+		messageText = upd.Message.Body.Text
+	}
+
 	// Создаем специальный request для callback
 	req := &Request{
 		Context:   ctx,
@@ -393,15 +800,21 @@ func (b *Bot) handleCallback(ctx context.Context, upd *schemes.MessageCallbackUp
 		Args:      upd.Callback.Payload,
 		UserState: userState, // передаем указатель, чтобы handler мог его изменять
 		Metadata: map[string]any{
-			"callback_id": upd.Callback.CallbackID,
-			"recipient":   recipient,
-			"sender":      upd.Callback.User,
-			"sender_id":   fmt.Sprintf("%d", upd.Callback.User.UserId),
-			"message_id":  messageID,
-			"message_seq": messageSeq,
+			"callback_id":  upd.Callback.CallbackID,
+			"recipient":    recipient,
+			"sender":       upd.Callback.User,
+			"sender_id":    fmt.Sprintf("%d", upd.Callback.User.UserId),
+			"message_id":   messageID,
+			"message_seq":  messageSeq,
+			"message_text": messageText,
 		},
 	}
 
+	// Кнопки учитываются в общем лимите и лимите пользователя; лимит команды для "cmd:" проверяет Router.Execute
+	if !b.router.Allow(ctx, req, b, "") {
+		return
+	}
+
 	if err := handler.Handle(ctx, req, b); err != nil {
 		logger.Error().Err(err).Msg("callback handler failed")
 	}
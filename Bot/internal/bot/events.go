@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+)
+
+// EventHandler обрабатывает событие конкретного типа. Request заполнен так же, как для прочих
+// обновлений (получатель, отправитель, состояние пользователя), а само событие передается уже приведенным
+type EventHandler[U schemes.UpdateInterface] func(ctx context.Context, req *Request, responder Responder, update U) error
+
+// onEvent регистрирует типизированный handler: Request.Event приводится к U до вызова
+func onEvent[U schemes.UpdateInterface](r *Router, updateType schemes.UpdateType, handler EventHandler[U]) {
+	r.events[updateType] = HandlerFunc(func(ctx context.Context, req *Request, responder Responder) error {
+		update, ok := req.Event.(U)
+		if !ok {
+			return fmt.Errorf("unexpected %T for %s event", req.Event, updateType)
+		}
+		return handler(ctx, req, responder, update)
+	})
+}
+
+// OnBotStarted - пользователь нажал "Начать", в том числе по deep-link. Request заполнен как для
+// команды /start с payload в Args
+func (r *Router) OnBotStarted(handler EventHandler[*schemes.BotStartedUpdate]) {
+	onEvent(r, schemes.TypeBotStarted, handler)
+}
+
+// OnBotAdded - бота добавили в чат
+func (r *Router) OnBotAdded(handler EventHandler[*schemes.BotAddedToChatUpdate]) {
+	onEvent(r, schemes.TypeBotAdded, handler)
+}
+
+// OnBotRemoved - бота удалили из чата
+func (r *Router) OnBotRemoved(handler EventHandler[*schemes.BotRemovedFromChatUpdate]) {
+	onEvent(r, schemes.TypeBotRemoved, handler)
+}
+
+// OnUserAdded - участник вошел в чат или его добавили
+func (r *Router) OnUserAdded(handler EventHandler[*schemes.UserAddedToChatUpdate]) {
+	onEvent(r, schemes.TypeUserAdded, handler)
+}
+
+// OnUserRemoved - участник вышел из чата или его удалили
+func (r *Router) OnUserRemoved(handler EventHandler[*schemes.UserRemovedFromChatUpdate]) {
+	onEvent(r, schemes.TypeUserRemoved, handler)
+}
+
+// OnMessageEdited - сообщение изменено
+func (r *Router) OnMessageEdited(handler EventHandler[*schemes.MessageEditedUpdate]) {
+	onEvent(r, schemes.TypeMessageEdited, handler)
+}
+
+// OnMessageRemoved - сообщение удалено. MAX сообщает только id сообщения, без чата и автора
+func (r *Router) OnMessageRemoved(handler EventHandler[*schemes.MessageRemovedUpdate]) {
+	onEvent(r, schemes.TypeMessageRemoved, handler)
+}
@@ -3,10 +3,12 @@ package handlers
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"first-max-bot/internal/bot"
+	"first-max-bot/internal/secret"
 	"first-max-bot/internal/services/ai"
 	"first-max-bot/internal/services/moodle"
 	"first-max-bot/internal/services/schedule"
@@ -19,15 +21,17 @@ type AskHandler struct {
 	scheduleService schedule.Service
 	moodleService  moodle.Service
 	userService    user.Service
+	secrets        *secret.Keyring
 	logger         zerolog.Logger
 }
 
-func NewAskHandler(aiService ai.Service, scheduleService schedule.Service, moodleService moodle.Service, userService user.Service, logger zerolog.Logger) *AskHandler {
+func NewAskHandler(aiService ai.Service, scheduleService schedule.Service, moodleService moodle.Service, userService user.Service, secrets *secret.Keyring, logger zerolog.Logger) *AskHandler {
 	return &AskHandler{
 		aiService:       aiService,
 		scheduleService: scheduleService,
 		moodleService:   moodleService,
 		userService:     userService,
+		secrets:         secrets,
 		logger:          logger,
 	}
 }
@@ -54,10 +58,33 @@ func (h *AskHandler) Handle(ctx context.Context, req *bot.Request, responder bot
 		return responder.SendMarkdown(ctx, req.Recipient(), message)
 	}
 
+	// В групповом чате ответ видят все участники: профиль, расписание и курсы отправителя в контекст не попадают
+	var contextData ai.ContextData
+	if !req.IsGroupChat() {
+		var ok bool
+		if contextData, ok = h.personalContext(ctx, userID); !ok {
+			return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден. Пожалуйста, зарегистрируйся через /register")
+		}
+	}
+
+	// Отправляем вопрос в AI
+	response, err := h.aiService.AskQuestion(ctx, question, contextData)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Str("question", question).Msg("failed to get AI response")
+		return responder.SendText(ctx, req.Recipient(), "❌ Извини, не удалось получить ответ. Попробуй позже.")
+	}
+
+	// Отправляем ответ пользователю
+	return responder.SendMarkdown(ctx, req.Recipient(), response)
+}
+
+// personalContext собирает контекст для AI из данных пользователя: профиль, расписание на сегодня
+// и курсы Moodle. false - пользователь не зарегистрирован
+func (h *AskHandler) personalContext(ctx context.Context, userID string) (ai.ContextData, bool) {
 	// Получаем информацию о пользователе
 	u, err := h.userService.GetUserByID(ctx, userID)
 	if err != nil || u == nil {
-		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден. Пожалуйста, зарегистрируйся через /register")
+		return ai.ContextData{}, false
 	}
 
 	// Формируем контекстные данные
@@ -68,12 +95,13 @@ func (h *AskHandler) Handle(ctx context.Context, req *bot.Request, responder bot
 			Age:       u.Age,
 			Gender:    u.Gender,
 			Email:     u.Email,
-			Role:      h.getRoleLabel(u.Role),
+			Role:      formatRoles(u),
 		},
 	}
 
-	// Получаем расписание
-	scheduleItems, err := h.scheduleService.GetSchedule(ctx, userID)
+	// Получаем расписание на сегодня
+	dayStart, dayEnd := schedule.DayRange(time.Now())
+	scheduleItems, err := h.scheduleService.GetSchedule(ctx, userID, dayStart, dayEnd)
 	if err == nil {
 		for _, item := range scheduleItems {
 			contextData.Schedule = append(contextData.Schedule, ai.ScheduleItem{
@@ -90,9 +118,12 @@ func (h *AskHandler) Handle(ctx context.Context, req *bot.Request, responder bot
 
 	// Получаем курсы из Moodle (если есть токен)
 	if u.MoodleToken != "" {
-		siteInfo, err := h.moodleService.GetSiteInfo(ctx, u.MoodleToken)
-		if err == nil {
-			courses, err := h.moodleService.GetUserCourses(ctx, u.MoodleToken, siteInfo.UserID)
+		// Токен расшифровываем только для запросов к Moodle
+		token, err := h.secrets.Open(u.MoodleToken)
+		if err != nil {
+			h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to decrypt moodle token for AI context")
+		} else if siteInfo, err := h.moodleService.GetSiteInfo(ctx, token); err == nil {
+			courses, err := h.moodleService.GetUserCourses(ctx, token, siteInfo.UserID)
 			if err == nil {
 				for _, course := range courses {
 					// Очищаем HTML из описания
@@ -118,31 +149,7 @@ func (h *AskHandler) Handle(ctx context.Context, req *bot.Request, responder bot
 		}
 	}
 
-	// Отправляем вопрос в AI
-	response, err := h.aiService.AskQuestion(ctx, question, contextData)
-	if err != nil {
-		h.logger.Error().Err(err).Str("user_id", userID).Str("question", question).Msg("failed to get AI response")
-		return responder.SendText(ctx, req.Recipient(), "❌ Извини, не удалось получить ответ. Попробуй позже.")
-	}
-
-	// Отправляем ответ пользователю
-	return responder.SendMarkdown(ctx, req.Recipient(), response)
-}
-
-// getRoleLabel возвращает русское название роли
-func (h *AskHandler) getRoleLabel(role user.Role) string {
-	switch role {
-	case user.RoleApplicant:
-		return "Абитуриент"
-	case user.RoleStudent:
-		return "Студент"
-	case user.RoleEmployee:
-		return "Сотрудник"
-	case user.RoleManager:
-		return "Руководитель"
-	default:
-		return string(role)
-	}
+	return contextData, true
 }
 
 // cleanHTML очищает HTML теги из текста
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/calendar"
+	"first-max-bot/internal/services/user"
+)
+
+const (
+	calendarLinkPayload   = "calendar:link"
+	calendarNewPayload    = "calendar:new"
+	calendarRevokePayload = "calendar:off"
+	calendarFilePayload   = "calendar:file"
+)
+
+// CalendarHandler обрабатывает команду /calendar: ссылка для подписки на личный календарь и файл .ics
+type CalendarHandler struct {
+	feed        *calendar.Feed
+	tokens      calendar.TokenStore // nil - HTTP-сервер календаря не настроен, доступен только файл
+	baseURL     string
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewCalendarHandler(feed *calendar.Feed, tokens calendar.TokenStore, baseURL string, userService user.Service, logger zerolog.Logger) *CalendarHandler {
+	return &CalendarHandler{
+		feed:        feed,
+		tokens:      tokens,
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *CalendarHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
+	}
+	if !u.HasCapability(user.CapabilityCalendar) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Календарь доступен студентам и сотрудникам.")
+	}
+
+	if strings.HasPrefix(req.Args, "calendar:") {
+		return h.handleCallback(ctx, req, responder)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(req.Args)) {
+	case "ссылка", "link":
+		return h.send(ctx, req, responder, h.link)
+	case "новая", "new":
+		return h.send(ctx, req, responder, h.rotate)
+	case "отключить", "off", "revoke":
+		return h.send(ctx, req, responder, h.revoke)
+	case "файл", "file":
+		return h.sendFile(ctx, req, responder)
+	default:
+		return h.send(ctx, req, responder, h.status)
+	}
+}
+
+func (h *CalendarHandler) handleCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	callbackID, _ := req.Metadata["callback_id"].(string)
+
+	var action func(ctx context.Context, userID string) (string, error)
+	switch req.Args {
+	case calendarLinkPayload:
+		action = h.link
+	case calendarNewPayload:
+		action = h.rotate
+	case calendarRevokePayload:
+		action = h.revoke
+	case calendarFilePayload:
+		responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{})
+		return h.sendFile(ctx, req, responder)
+	default:
+		return nil
+	}
+
+	text, err := action(ctx, req.UserID())
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", req.UserID()).Str("payload", req.Args).Msg("failed to update calendar link")
+		responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "❌ Не удалось выполнить действие. Попробуй позже."})
+		return nil
+	}
+	token, err := h.token(ctx, req.UserID())
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", req.UserID()).Msg("failed to get calendar token")
+	}
+	return responder.AnswerCallbackWithEdit(ctx, callbackID, text, h.keyboard(responder, token))
+}
+
+// send выполняет действие и отправляет результат с кнопками управления ссылкой
+func (h *CalendarHandler) send(ctx context.Context, req *bot.Request, responder bot.Responder, action func(ctx context.Context, userID string) (string, error)) error {
+	userID := req.UserID()
+	text, err := action(ctx, userID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to update calendar link")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось выполнить действие. Попробуй позже.")
+	}
+	token, err := h.token(ctx, userID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to get calendar token")
+	}
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), text, h.keyboard(responder, token))
+}
+
+func (h *CalendarHandler) status(ctx context.Context, userID string) (string, error) {
+	token, err := h.token(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return h.statusText(token), nil
+}
+
+func (h *CalendarHandler) link(ctx context.Context, userID string) (string, error) {
+	if h.tokens == nil {
+		return h.statusText(""), nil
+	}
+	token, err := h.tokens.Token(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		if token, err = h.tokens.Issue(ctx, userID); err != nil {
+			return "", err
+		}
+	}
+	return h.statusText(token), nil
+}
+
+func (h *CalendarHandler) rotate(ctx context.Context, userID string) (string, error) {
+	if h.tokens == nil {
+		return h.statusText(""), nil
+	}
+	token, err := h.tokens.Issue(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return "🔄 Выдана новая ссылка, прежняя больше не работает.\n\n" + h.statusText(token), nil
+}
+
+func (h *CalendarHandler) revoke(ctx context.Context, userID string) (string, error) {
+	if h.tokens == nil {
+		return h.statusText(""), nil
+	}
+	if err := h.tokens.Revoke(ctx, userID); err != nil {
+		return "", err
+	}
+	return "🚫 Ссылка отключена: календарь по ней больше не обновляется.\n\n" + h.statusText(""), nil
+}
+
+func (h *CalendarHandler) token(ctx context.Context, userID string) (string, error) {
+	if h.tokens == nil {
+		return "", nil
+	}
+	return h.tokens.Token(ctx, userID)
+}
+
+func (h *CalendarHandler) statusText(token string) string {
+	var message strings.Builder
+	message.WriteString("📆 Календарь\n\n")
+	message.WriteString("Занятия, напоминания, командировки и сроки возврата книг — в календаре на телефоне.\n\n")
+	switch {
+	case h.tokens == nil:
+		message.WriteString("Подписка по ссылке не настроена, но можно получить файл .ics: /calendar файл")
+	case token == "":
+		message.WriteString("Ссылки пока нет. Получить: /calendar ссылка\n")
+		message.WriteString("Файл .ics без автоматического обновления: /calendar файл")
+	default:
+		message.WriteString("Ссылка для подписки (добавь ее в приложении календаря как календарь по URL, он будет обновляться сам):\n")
+		message.WriteString(h.baseURL + calendar.FeedPath + token + ".ics\n\n")
+		message.WriteString("⚠️ Не пересылай ссылку: по ней видно твое расписание.\n")
+		message.WriteString("Новая ссылка: /calendar новая, отключить: /calendar отключить")
+	}
+	return message.String()
+}
+
+func (h *CalendarHandler) keyboard(responder bot.Responder, token string) *maxbot.Keyboard {
+	keyboard := responder.NewKeyboardBuilder()
+	if h.tokens != nil {
+		if token == "" {
+			keyboard.AddRow().AddCallback("🔗 Получить ссылку", schemes.POSITIVE, calendarLinkPayload)
+		} else {
+			keyboard.AddRow().
+				AddCallback("🔄 Новая ссылка", schemes.DEFAULT, calendarNewPayload).
+				AddCallback("🚫 Отключить", schemes.NEGATIVE, calendarRevokePayload)
+		}
+	}
+	keyboard.AddRow().AddCallback("📄 Файл .ics", schemes.DEFAULT, calendarFilePayload)
+	return keyboard
+}
+
+func (h *CalendarHandler) sendFile(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	data, err := h.feed.Build(ctx, userID, time.Now())
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to build calendar")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось собрать календарь. Попробуй позже.")
+	}
+
+	text := "📄 Календарь на ближайшие недели. Открой файл, чтобы добавить события в календарь телефона.\n\n" +
+		"Файл не обновляется сам"
+	if h.tokens != nil {
+		text += " — для подписки с обновлением используй ссылку: /calendar ссылка"
+	}
+	if err := responder.SendFile(ctx, req.Recipient(), text, calendar.FileName, bytes.NewReader(data)); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to send calendar file")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось отправить файл. Попробуй позже.")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/state"
+)
+
+// cancelFlowPayload - кнопка выхода из любого сценария ввода
+const cancelFlowPayload = "flow:cancel"
+
+// CancelHandler выходит из текущего сценария ввода по команде /cancel или кнопке "Отмена"
+type CancelHandler struct{}
+
+func NewCancelHandler() *CancelHandler {
+	return &CancelHandler{}
+}
+
+func (h *CancelHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	if req.Metadata != nil {
+		if cid, ok := req.Metadata["callback_id"].(string); ok && cid != "" {
+			responder.AnswerCallback(ctx, cid, &schemes.CallbackAnswer{})
+		}
+	}
+
+	if !req.UserState.InFlow() {
+		return responder.SendText(ctx, req.Recipient(), "Сейчас нечего отменять. Список команд — /help")
+	}
+
+	title := state.FlowTitle(req.UserState.UserRegistrationStep)
+	req.UserState.ResetFlow()
+	return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("❌ Отменено: %s. Список команд — /help", title))
+}
+
+// withCancelButton добавляет в клавиатуру кнопку отмены сценария; nil создает новую клавиатуру
+func withCancelButton(responder bot.Responder, keyboard *maxbot.Keyboard) *maxbot.Keyboard {
+	if keyboard == nil {
+		keyboard = responder.NewKeyboardBuilder()
+	}
+	keyboard.AddRow().AddCallback("❌ Отмена", schemes.NEGATIVE, cancelFlowPayload)
+	return keyboard
+}
+
+// sendFlowPrompt отправляет приглашение к вводу с кнопкой отмены
+func sendFlowPrompt(ctx context.Context, req *bot.Request, responder bot.Responder, text string) error {
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), text, withCancelButton(responder, nil))
+}
+
+// sendFlowPromptMarkdown - sendFlowPrompt для сообщений в markdown
+func sendFlowPromptMarkdown(ctx context.Context, req *bot.Request, responder bot.Responder, text string) error {
+	return responder.SendMarkdownWithKeyboard(ctx, req.Recipient(), text, withCancelButton(responder, nil))
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/user"
+	"first-max-bot/internal/state"
+)
+
+// ChatHandler управляет настройками группового чата: привязкой к учебной группе
+// или подразделению и подписками на расписание и новости. Менять настройки может администратор чата,
+// а что именно он может менять, определяет его роль в боте (см. checkRole)
+type ChatHandler struct {
+	chats       state.ChatRepository
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewChatHandler(chats state.ChatRepository, userService user.Service, logger zerolog.Logger) *ChatHandler {
+	return &ChatHandler{
+		chats:       chats,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *ChatHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	if !req.IsGroupChat() {
+		return responder.SendText(ctx, req.Recipient(), "Команда /chat работает только в групповых чатах. Добавь бота в чат и вызови /chat там.")
+	}
+
+	chatID := req.ChatID()
+	settings, err := h.chats.GetChatSettings(ctx, chatID)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", chatID).Msg("failed to load chat settings")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось загрузить настройки чата. Попробуй позже.")
+	}
+	if settings == nil {
+		settings = &state.ChatSettings{ChatID: chatID}
+	}
+
+	// Если это callback переключения подписки
+	if strings.HasPrefix(req.Args, "chat:") {
+		return h.handleChatCallback(ctx, req, responder, settings)
+	}
+
+	args := strings.Fields(req.Args)
+	if len(args) == 0 {
+		return h.showSettings(ctx, req, responder, settings)
+	}
+
+	if ok, err := h.checkAdmin(ctx, req, responder); !ok {
+		return err
+	}
+
+	value := strings.TrimSpace(strings.TrimPrefix(req.Args, args[0]))
+	switch strings.ToLower(args[0]) {
+	case "group":
+		if value == "" {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /chat group <учебная группа>, например /chat group ИВТ-21")
+		}
+		if ok, err := h.checkRole(ctx, req, responder, value); !ok {
+			return err
+		}
+		settings.StudyGroup = value
+	case "department":
+		if value == "" {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /chat department <подразделение>")
+		}
+		if ok, err := h.checkRole(ctx, req, responder, ""); !ok {
+			return err
+		}
+		settings.Department = value
+	case "unbind":
+		if ok, err := h.checkRole(ctx, req, responder, boundGroup(settings)); !ok {
+			return err
+		}
+		settings.StudyGroup = ""
+		settings.Department = ""
+		settings.SubscribeSchedule = false
+	case "schedule", "news":
+		enabled, ok := parseSwitch(value)
+		if !ok {
+			return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("Формат: /chat %s on или /chat %s off", args[0], args[0]))
+		}
+		if ok, err := h.checkRole(ctx, req, responder, boundGroup(settings)); !ok {
+			return err
+		}
+		if msg := h.setSubscription(settings, args[0], enabled); msg != "" {
+			return responder.SendText(ctx, req.Recipient(), msg)
+		}
+	default:
+		return responder.SendText(ctx, req.Recipient(), chatUsage)
+	}
+
+	if err := h.save(ctx, req, settings); err != nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сохранить настройки чата. Попробуй позже.")
+	}
+	return h.showSettings(ctx, req, responder, settings)
+}
+
+const chatUsage = "Настройка чата:\n" +
+	"/chat — текущие настройки\n" +
+	"/chat group <группа> — привязать чат к учебной группе\n" +
+	"/chat department <подразделение> — привязать чат к подразделению\n" +
+	"/chat unbind — отвязать чат\n" +
+	"/chat schedule on|off — публиковать расписание группы каждое утро\n" +
+	"/chat news on|off — присылать новости университета"
+
+func (h *ChatHandler) showSettings(ctx context.Context, req *bot.Request, responder bot.Responder, settings *state.ChatSettings) error {
+	var message strings.Builder
+	message.WriteString("⚙️ Настройки чата\n\n")
+	message.WriteString(fmt.Sprintf("Учебная группа: %s\n", valueOrDash(settings.StudyGroup)))
+	message.WriteString(fmt.Sprintf("Подразделение: %s\n", valueOrDash(settings.Department)))
+	message.WriteString(fmt.Sprintf("Расписание: %s\n", switchLabel(settings.SubscribeSchedule)))
+	message.WriteString(fmt.Sprintf("Новости: %s\n\n", switchLabel(settings.SubscribeNews)))
+	message.WriteString(chatUsage)
+	message.WriteString("\n\nМенять настройки могут администраторы чата: сотрудники и руководители — любые, студенты — только для своей учебной группы.")
+
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().AddCallback(fmt.Sprintf("📅 Расписание: %s", switchLabel(settings.SubscribeSchedule)), schemes.DEFAULT, "chat:schedule")
+	keyboard.AddRow().AddCallback(fmt.Sprintf("📰 Новости: %s", switchLabel(settings.SubscribeNews)), schemes.DEFAULT, "chat:news")
+
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message.String(), keyboard)
+}
+
+func (h *ChatHandler) handleChatCallback(ctx context.Context, req *bot.Request, responder bot.Responder, settings *state.ChatSettings) error {
+	callbackID := ""
+	if req.Metadata != nil {
+		callbackID, _ = req.Metadata["callback_id"].(string)
+	}
+
+	if ok, err := h.checkAdmin(ctx, req, responder); !ok {
+		if callbackID != "" {
+			responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Только для администраторов чата"})
+		}
+		return err
+	}
+
+	kind := strings.TrimPrefix(req.Args, "chat:")
+	if kind != "schedule" && kind != "news" {
+		return nil
+	}
+	if ok, err := h.checkRole(ctx, req, responder, boundGroup(settings)); !ok {
+		if callbackID != "" {
+			responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Недостаточно прав"})
+		}
+		return err
+	}
+
+	enabled := !settings.SubscribeNews
+	if kind == "schedule" {
+		enabled = !settings.SubscribeSchedule
+	}
+	if msg := h.setSubscription(settings, kind, enabled); msg != "" {
+		if callbackID != "" {
+			responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: msg})
+		}
+		return nil
+	}
+
+	if err := h.save(ctx, req, settings); err != nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сохранить настройки чата. Попробуй позже.")
+	}
+
+	if callbackID != "" {
+		responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{})
+	}
+	return h.showSettings(ctx, req, responder, settings)
+}
+
+// setSubscription включает или выключает подписку. Возвращает текст ошибки, если подписку включить нельзя
+func (h *ChatHandler) setSubscription(settings *state.ChatSettings, kind string, enabled bool) string {
+	switch kind {
+	case "schedule":
+		if enabled && settings.StudyGroup == "" {
+			return "❌ Сначала привяжи чат к учебной группе: /chat group <группа>"
+		}
+		settings.SubscribeSchedule = enabled
+	case "news":
+		settings.SubscribeNews = enabled
+	}
+	return ""
+}
+
+// checkAdmin проверяет, что отправитель - администратор чата.
+// Возвращает false, если выполнение нужно прервать (сообщение пользователю уже отправлено)
+func (h *ChatHandler) checkAdmin(ctx context.Context, req *bot.Request, responder bot.Responder) (bool, error) {
+	senderID, err := strconv.ParseInt(req.UserID(), 10, 64)
+	if err != nil {
+		return false, responder.SendText(ctx, req.Recipient(), "❌ Не удалось определить отправителя")
+	}
+
+	isAdmin, err := responder.IsChatAdmin(ctx, req.ChatID(), senderID)
+	if err != nil {
+		h.logger.Warn().Err(err).Int64("chat_id", req.ChatID()).Msg("failed to check chat admin")
+		return false, responder.SendText(ctx, req.Recipient(), "❌ Не удалось проверить права. Сделай бота администратором чата и попробуй снова.")
+	}
+	if !isAdmin {
+		return false, responder.SendText(ctx, req.Recipient(), "❌ Менять настройки чата могут только его администраторы.")
+	}
+	return true, nil
+}
+
+// checkRole проверяет права отправителя по его роли в боте: сотрудники и руководители меняют любые
+// настройки, студент - только настройки чата своей учебной группы (group - группа, которую затрагивает
+// изменение; пустая - изменение только для сотрудников). Незарегистрированным менять настройки нельзя.
+// Возвращает false, если выполнение нужно прервать (сообщение пользователю уже отправлено)
+func (h *ChatHandler) checkRole(ctx context.Context, req *bot.Request, responder bot.Responder, group string) (bool, error) {
+	u, err := h.userService.GetUserByID(ctx, req.UserID())
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", req.UserID()).Msg("failed to get user for chat settings")
+		return false, responder.SendText(ctx, req.Recipient(), "❌ Не удалось проверить права. Попробуй позже.")
+	}
+	if u == nil {
+		return false, responder.SendText(ctx, req.Recipient(), "❌ Менять настройки чата могут только зарегистрированные пользователи. Зарегистрируйся в личных сообщениях с ботом: /register")
+	}
+	if u.HasCapability(user.CapabilityChatSettings) {
+		return true, nil
+	}
+	if group != "" && u.HasCapability(user.CapabilityStudyGroup) && strings.EqualFold(u.StudyGroup, group) {
+		return true, nil
+	}
+	return false, responder.SendText(ctx, req.Recipient(), "❌ Студент может настроить только чат своей учебной группы (выбирается через /group в личных сообщениях). Остальные настройки меняют сотрудники и руководители.")
+}
+
+// boundGroup возвращает учебную группу, к которой привязан чат. Чат подразделения настраивают только сотрудники
+func boundGroup(settings *state.ChatSettings) string {
+	if settings.Department != "" {
+		return ""
+	}
+	return settings.StudyGroup
+}
+
+func (h *ChatHandler) save(ctx context.Context, req *bot.Request, settings *state.ChatSettings) error {
+	settings.ChatID = req.ChatID()
+	settings.UpdatedBy = req.UserID()
+	settings.UpdatedAt = time.Now()
+	if err := h.chats.SaveChatSettings(ctx, *settings); err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", settings.ChatID).Msg("failed to save chat settings")
+		return err
+	}
+	h.logger.Info().Int64("chat_id", settings.ChatID).Str("user_id", settings.UpdatedBy).Msg("chat settings updated")
+	return nil
+}
+
+func parseSwitch(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "вкл", "да":
+		return true, true
+	case "off", "выкл", "нет":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func switchLabel(enabled bool) string {
+	if enabled {
+		return "вкл"
+	}
+	return "выкл"
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "—"
+	}
+	return value
+}
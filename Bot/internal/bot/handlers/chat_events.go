@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/state"
+)
+
+// ChatEventsHandler обрабатывает события групповых чатов:
+// добавление и удаление бота, вход и выход участников
+type ChatEventsHandler struct {
+	chats  state.ChatRepository
+	logger zerolog.Logger
+}
+
+func NewChatEventsHandler(chats state.ChatRepository, logger zerolog.Logger) *ChatEventsHandler {
+	return &ChatEventsHandler{
+		chats:  chats,
+		logger: logger,
+	}
+}
+
+// HandleBotAdded приветствует чат, в который добавили бота, и создает для него настройки
+func (h *ChatEventsHandler) HandleBotAdded(ctx context.Context, req *bot.Request, responder bot.Responder, upd *schemes.BotAddedToChatUpdate) error {
+	h.logger.Info().Int64("chat_id", upd.ChatId).Int64("user_id", upd.User.UserId).Msg("bot added to chat")
+
+	settings, err := h.chats.GetChatSettings(ctx, upd.ChatId)
+	if err != nil {
+		h.logger.Error().Err(err).Int64("chat_id", upd.ChatId).Msg("failed to load chat settings")
+	}
+	if err == nil && settings == nil {
+		settings = &state.ChatSettings{
+			ChatID:    upd.ChatId,
+			UpdatedBy: fmt.Sprintf("%d", upd.User.UserId),
+			UpdatedAt: time.Now(),
+		}
+		if err := h.chats.SaveChatSettings(ctx, *settings); err != nil {
+			h.logger.Error().Err(err).Int64("chat_id", upd.ChatId).Msg("failed to save chat settings")
+		}
+	}
+
+	message := "👋 Привет! Я помощник университета.\n\n" +
+		"В чате я отвечаю только на команды, например /schedule или /news.\n" +
+		"Администраторы чата могут привязать его к учебной группе и подписать на расписание и новости: /chat"
+	return responder.SendText(ctx, req.Recipient(), message)
+}
+
+// HandleBotRemoved удаляет настройки чата, из которого удалили бота
+func (h *ChatEventsHandler) HandleBotRemoved(ctx context.Context, _ *bot.Request, _ bot.Responder, upd *schemes.BotRemovedFromChatUpdate) error {
+	h.logger.Info().Int64("chat_id", upd.ChatId).Int64("user_id", upd.User.UserId).Msg("bot removed from chat")
+	return h.chats.DeleteChatSettings(ctx, upd.ChatId)
+}
+
+// HandleUserAdded приветствует нового участника в чате, привязанном к группе или подразделению
+func (h *ChatEventsHandler) HandleUserAdded(ctx context.Context, req *bot.Request, responder bot.Responder, upd *schemes.UserAddedToChatUpdate) error {
+	h.logger.Info().Int64("chat_id", upd.ChatId).Int64("user_id", upd.User.UserId).Int64("inviter_id", upd.InviterId).Msg("user joined chat")
+
+	if upd.User.IsBot {
+		return nil
+	}
+
+	settings, err := h.chats.GetChatSettings(ctx, upd.ChatId)
+	if err != nil {
+		return err
+	}
+	if settings == nil || (settings.StudyGroup == "" && settings.Department == "") {
+		return nil
+	}
+
+	bound := settings.StudyGroup
+	if bound == "" {
+		bound = settings.Department
+	}
+	message := fmt.Sprintf("👋 Добро пожаловать в чат «%s», %s!\n\n", bound, upd.User.Name)
+	message += "Расписание — /schedule, новости — /news. Личные команды доступны в диалоге с ботом."
+	return responder.SendText(ctx, req.Recipient(), message)
+}
+
+// HandleUserRemoved записывает выход участника из чата: настройки чата от участников не зависят
+func (h *ChatEventsHandler) HandleUserRemoved(ctx context.Context, _ *bot.Request, _ bot.Responder, upd *schemes.UserRemovedFromChatUpdate) error {
+	h.logger.Info().Int64("chat_id", upd.ChatId).Int64("user_id", upd.User.UserId).Int64("admin_id", upd.AdminId).Msg("user left chat")
+	return nil
+}
+
+// maxEditHints - сколько сообщений с подсказкой об изменении помнит MessageEventsHandler
+const maxEditHints = 1000
+
+// MessageEventsHandler обрабатывает редактирование и удаление сообщений.
+// Команды повторно не выполняются: изменение уже отправленной команды не должно
+// приводить к повторному созданию обращений, заявлений и т.п. Вместо этого пользователь,
+// исправивший команду или ответ в сценарии ввода, получает подсказку отправить исправление
+// новым сообщением - иначе он ждал бы реакции на правку
+type MessageEventsHandler struct {
+	mu     sync.Mutex
+	hinted map[string]bool // сообщения, на правку которых подсказка уже отправлена
+	logger zerolog.Logger
+}
+
+func NewMessageEventsHandler(logger zerolog.Logger) *MessageEventsHandler {
+	return &MessageEventsHandler{
+		hinted: make(map[string]bool),
+		logger: logger,
+	}
+}
+
+// HandleMessageEdited подсказывает отправить исправленную команду или ответ новым сообщением.
+// Подсказка отправляется только в личной переписке и один раз на сообщение
+func (h *MessageEventsHandler) HandleMessageEdited(ctx context.Context, req *bot.Request, responder bot.Responder, upd *schemes.MessageEditedUpdate) error {
+	messageID := upd.Message.Body.Mid
+	text := strings.TrimSpace(upd.Message.Body.Text)
+	h.logger.Debug().
+		Str("message_id", messageID).
+		Int64("chat_id", upd.Message.Recipient.ChatId).
+		Str("user_id", req.UserID()).
+		Msg("message edited")
+
+	if upd.Message.Recipient.ChatType != schemes.DIALOG || upd.Message.Sender.IsBot {
+		return nil
+	}
+
+	var message string
+	switch {
+	case strings.HasPrefix(text, "/"):
+		message = "✏️ Изменение команды не выполняет её заново. Отправь исправленную команду новым сообщением."
+	case req.UserState.InFlow():
+		message = "✏️ Изменения отправленных сообщений я не вижу — учтен исходный текст. " +
+			"Если нужно исправить ответ, отправь его новым сообщением или начни заново через /cancel."
+	default:
+		return nil
+	}
+
+	if !h.markHinted(messageID) {
+		return nil
+	}
+	return responder.SendText(ctx, req.Recipient(), message)
+}
+
+// HandleMessageRemoved забывает удаленное сообщение: правок у него больше не будет
+func (h *MessageEventsHandler) HandleMessageRemoved(ctx context.Context, _ *bot.Request, _ bot.Responder, upd *schemes.MessageRemovedUpdate) error {
+	h.logger.Debug().Str("message_id", upd.MessageId).Msg("message removed")
+
+	h.mu.Lock()
+	delete(h.hinted, upd.MessageId)
+	h.mu.Unlock()
+	return nil
+}
+
+// markHinted отмечает, что подсказка по сообщению отправлена; false - уже отправлялась
+func (h *MessageEventsHandler) markHinted(messageID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if messageID == "" {
+		return true
+	}
+	if h.hinted[messageID] {
+		return false
+	}
+	if len(h.hinted) >= maxEditHints {
+		h.hinted = make(map[string]bool)
+	}
+	h.hinted[messageID] = true
+	return true
+}
@@ -12,6 +12,9 @@ import (
 	"first-max-bot/internal/services/deanery"
 )
 
+// docRequestPayload - префикс кнопки, подающей заявление: "doc:request:<тип>"
+const docRequestPayload = "doc:request:"
+
 // DeaneryHandler обрабатывает команду /deanery для студентов
 type DeaneryHandler struct {
 	deaneryService deanery.Service
@@ -36,6 +39,11 @@ func (h *DeaneryHandler) Handle(ctx context.Context, req *bot.Request, responder
 		return responder.SendText(ctx, req.Recipient(), "Не удалось определить пользователя")
 	}
 
+	// Если тип заявления передан аргументом (/deanery certificate), сразу предлагаем его оформить
+	if docType := strings.TrimSpace(req.Args); docType != "" {
+		return h.offerDocument(ctx, req, responder, docType)
+	}
+
 	// Получаем документы пользователя
 	documents, err := h.deaneryService.GetUserDocuments(ctx, userID)
 	if err != nil {
@@ -73,6 +81,30 @@ func (h *DeaneryHandler) Handle(ctx context.Context, req *bot.Request, responder
 	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message.String(), keyboard)
 }
 
+// offerDocument предлагает оформить заявление указанного типа одной кнопкой
+func (h *DeaneryHandler) offerDocument(ctx context.Context, req *bot.Request, responder bot.Responder, docType string) error {
+	var label string
+	switch deanery.DocumentType(docType) {
+	case deanery.DocumentTypeCertificate:
+		label = "📄 Заказать справку"
+	case deanery.DocumentTypePayment:
+		label = "💳 Оплата обучения"
+	case deanery.DocumentTypeTransfer:
+		label = "🔄 Заявление на перевод"
+	case deanery.DocumentTypeAcademicLeave:
+		label = "📋 Академический отпуск"
+	default:
+		return responder.SendText(ctx, req.Recipient(), "❌ Неизвестный тип документа. Используй /deanery, чтобы посмотреть доступные услуги.")
+	}
+
+	message := fmt.Sprintf("🏛️ Деканат\n\n%s: нажми кнопку, чтобы подать заявление.", h.getDocumentTypeLabel(deanery.DocumentType(docType)))
+
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().AddCallback(label, schemes.POSITIVE, docRequestPayload+docType)
+
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message, keyboard)
+}
+
 func (h *DeaneryHandler) handleDocumentCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
 	payload := req.Args
 	userID := req.UserID()
@@ -85,7 +117,13 @@ func (h *DeaneryHandler) handleDocumentCallback(ctx context.Context, req *bot.Re
 		}
 	}
 
-	docType := strings.TrimPrefix(payload, "doc:")
+	// Кнопки меню /deanery только предлагают заявление: подается оно отдельной кнопкой,
+	// которая после нажатия убирается вместе с сообщением-подтверждением, а не со всем меню
+	if !strings.HasPrefix(payload, docRequestPayload) {
+		return h.offerDocument(ctx, req, responder, strings.TrimPrefix(payload, "doc:"))
+	}
+
+	docType := strings.TrimPrefix(payload, docRequestPayload)
 	var docTypeEnum deanery.DocumentType
 	var description string
 
@@ -103,12 +141,14 @@ func (h *DeaneryHandler) handleDocumentCallback(ctx context.Context, req *bot.Re
 		docTypeEnum = deanery.DocumentTypeAcademicLeave
 		description = "Заявление на академический отпуск"
 	default:
+		bot.ActionFailed(responder)
 		return responder.SendText(ctx, req.Recipient(), "❌ Неизвестный тип документа")
 	}
 
 	doc, err := h.deaneryService.CreateDocument(ctx, userID, docTypeEnum, description)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to create document")
+		bot.ActionFailed(responder)
 		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при создании заявления")
 	}
 
@@ -35,7 +35,7 @@ func (h *DocumentsHandler) Handle(ctx context.Context, req *bot.Request, respond
 	// Проверяем, что пользователь - руководитель
 	userID := req.UserID()
 	u, err := h.userService.GetUserByID(ctx, userID)
-	if err != nil || u == nil || u.Role != user.RoleManager {
+	if err != nil || u == nil || !u.HasCapability(user.CapabilityDocuments) {
 		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только руководителям.")
 	}
 
@@ -169,7 +169,7 @@ func (h *DocumentsHandler) handleDocumentCallback(ctx context.Context, req *bot.
 		message := "✍️ Напиши ответ на заявление:\n\n"
 		message += "Отправь либо только текст, либо только файл (нельзя отправлять и то, и другое одновременно)."
 
-		return responder.SendText(ctx, req.Recipient(), message)
+		return sendFlowPrompt(ctx, req, responder, message)
 	}
 
 	return nil
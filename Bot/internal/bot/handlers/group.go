@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/schedule"
+	"first-max-bot/internal/services/user"
+)
+
+// maxGroupButtons - сколько групп показывать кнопками; остальные вводятся командой /group <группа>
+const maxGroupButtons = 24
+
+// GroupHandler обрабатывает команду /group: выбор учебной группы, по которой показывается расписание
+type GroupHandler struct {
+	userService user.Service
+	calendars   schedule.CalendarStore // nil - расписание не из файлов, список групп неизвестен
+	logger      zerolog.Logger
+}
+
+func NewGroupHandler(userService user.Service, scheduleService schedule.Service, logger zerolog.Logger) *GroupHandler {
+	calendars, _ := scheduleService.(schedule.CalendarStore)
+	return &GroupHandler{
+		userService: userService,
+		calendars:   calendars,
+		logger:      logger,
+	}
+}
+
+func (h *GroupHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
+	}
+
+	// Если это callback выбора группы
+	if strings.HasPrefix(req.Args, "group:") {
+		callbackID, _ := req.Metadata["callback_id"].(string)
+		responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{})
+		if req.Args == "group:clear" {
+			return h.setGroup(ctx, req, responder, userID, "")
+		}
+		return h.setGroup(ctx, req, responder, userID, strings.TrimPrefix(req.Args, "group:set:"))
+	}
+
+	switch arg := strings.TrimSpace(req.Args); strings.ToLower(arg) {
+	case "":
+		return h.showGroup(ctx, req, responder, u)
+	case "clear", "сброс", "-":
+		return h.setGroup(ctx, req, responder, userID, "")
+	default:
+		return h.setGroup(ctx, req, responder, userID, arg)
+	}
+}
+
+func (h *GroupHandler) showGroup(ctx context.Context, req *bot.Request, responder bot.Responder, u *user.User) error {
+	var message strings.Builder
+	message.WriteString("👥 Учебная группа\n\n")
+	message.WriteString(fmt.Sprintf("Твоя группа: %s\n", valueOrDash(u.StudyGroup)))
+	message.WriteString("\nВыбрать группу: /group <название>, например /group ИВТ-21\n")
+	message.WriteString("Сбросить: /group сброс")
+
+	names, err := h.groupNames(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list timetables")
+	}
+	if len(names) == 0 {
+		return responder.SendText(ctx, req.Recipient(), message.String())
+	}
+
+	keyboard := responder.NewKeyboardBuilder()
+	var row = keyboard.AddRow()
+	for i, name := range names {
+		if i == maxGroupButtons {
+			break
+		}
+		if i > 0 && i%3 == 0 {
+			row = keyboard.AddRow()
+		}
+		row.AddCallback(name, schemes.DEFAULT, "group:set:"+name)
+	}
+	if u.StudyGroup != "" {
+		keyboard.AddRow().AddCallback("Сбросить группу", schemes.NEGATIVE, "group:clear")
+	}
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message.String(), keyboard)
+}
+
+func (h *GroupHandler) setGroup(ctx context.Context, req *bot.Request, responder bot.Responder, userID, group string) error {
+	if group != "" && h.calendars != nil {
+		names, err := h.groupNames(ctx)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("failed to list timetables")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить список групп. Попробуй позже.")
+		}
+		known := ""
+		for _, name := range names {
+			if strings.EqualFold(strings.Join(strings.Fields(name), " "), strings.Join(strings.Fields(group), " ")) {
+				known = name
+				break
+			}
+		}
+		if known == "" {
+			text := fmt.Sprintf("❌ Расписания группы «%s» нет.", group)
+			if len(names) > 0 {
+				text += "\n\nДоступные расписания: " + strings.Join(names, ", ")
+			}
+			return responder.SendText(ctx, req.Recipient(), text)
+		}
+		group = known
+	}
+
+	if err := h.userService.SetStudyGroup(ctx, userID, group); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to set study group")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сохранить группу. Попробуй позже.")
+	}
+	if group == "" {
+		return responder.SendText(ctx, req.Recipient(), "✅ Группа сброшена.")
+	}
+	return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Группа %s выбрана. Расписание: /schedule", group))
+}
+
+func (h *GroupHandler) groupNames(ctx context.Context) ([]string, error) {
+	if h.calendars == nil {
+		return nil, nil
+	}
+	return h.calendars.CalendarNames(ctx)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/intent"
+	"first-max-bot/internal/services/user"
+)
+
+// IntentHandler распознает команду в свободном тексте ("когда у меня пара по матану?")
+// и выполняет её. Если уверенность низкая, просит подтверждение; если ничего не распознано,
+// передает запрос следующему handler'у (подсказкам команд)
+type IntentHandler struct {
+	classifier  intent.Classifier
+	userService user.Service
+	router      *bot.Router
+	next        bot.Handler
+	logger      zerolog.Logger
+}
+
+func NewIntentHandler(classifier intent.Classifier, userService user.Service, router *bot.Router, next bot.Handler, logger zerolog.Logger) *IntentHandler {
+	return &IntentHandler{
+		classifier:  classifier,
+		userService: userService,
+		router:      router,
+		next:        next,
+		logger:      logger,
+	}
+}
+
+func (h *IntentHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	text := strings.TrimSpace(req.Text())
+
+	// Неизвестные команды ("/shedule") - это опечатки, а не свободный текст
+	if text == "" || strings.HasPrefix(text, "/") {
+		return h.next.Handle(ctx, req, responder)
+	}
+
+	u, err := h.userService.GetUserByID(ctx, req.UserID())
+	if err != nil || u == nil {
+		return h.next.Handle(ctx, req, responder)
+	}
+
+	// Распознаем только команды, доступные пользователю по его ролям
+	commands := u.Commands()
+	allowed := make([]intent.Command, 0, len(commands))
+	descriptions := make(map[string]string, len(commands))
+	for _, cmd := range commands {
+		allowed = append(allowed, intent.Command{Name: cmd.Command, Description: cmd.Description})
+		descriptions[cmd.Command] = cmd.Description
+	}
+
+	result, err := h.classifier.Classify(ctx, text, allowed)
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("intent classification failed")
+	}
+	if result == nil {
+		return h.next.Handle(ctx, req, responder)
+	}
+
+	h.logger.Info().
+		Str("user_id", u.UserID).
+		Str("command", result.Command).
+		Str("args", result.Args).
+		Float64("confidence", result.Confidence).
+		Str("source", result.Source).
+		Msg("intent recognized")
+
+	if result.Confidence >= intent.HighConfidence {
+		return h.router.Execute(ctx, req, responder, result.Command, result.Args)
+	}
+
+	// Низкая уверенность - спрашиваем, правильно ли поняли
+	payload := strings.TrimSpace(fmt.Sprintf("cmd:%s %s", result.Command, result.Args))
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().
+		AddCallback("✅ Да", schemes.POSITIVE, payload).
+		AddCallback("❌ Нет, показать команды", schemes.DEFAULT, "cmd:/help")
+
+	message := fmt.Sprintf("Похоже, тебе нужно: %s — %s. Открыть?", result.Command, descriptions[result.Command])
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message, keyboard)
+}
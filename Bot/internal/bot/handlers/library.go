@@ -123,6 +123,7 @@ func (h *LibraryHandler) handleBookCallback(ctx context.Context, req *bot.Reques
 		
 		userBook, err := h.libraryService.BorrowBook(ctx, userID, userName, userSurname, bookID)
 		if err != nil {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("❌ Ошибка: %s", err.Error()))
 		}
 
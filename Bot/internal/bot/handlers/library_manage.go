@@ -35,7 +35,7 @@ func (h *LibraryManageHandler) Handle(ctx context.Context, req *bot.Request, res
 	// Проверяем, что пользователь - сотрудник (учитель)
 	userID := req.UserID()
 	u, err := h.userService.GetUserByID(ctx, userID)
-	if err != nil || u == nil || (u.Role != user.RoleEmployee && u.Role != user.RoleManager) {
+	if err != nil || u == nil || !u.HasCapability(user.CapabilityLibraryManage) {
 		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только сотрудникам и руководителям.")
 	}
 
@@ -210,6 +210,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		// Формат: lib_manage:issue:userID:bookID
 		parts := strings.Split(payload, ":")
 		if len(parts) != 4 {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка: неверный формат запроса")
 		}
 		userID := parts[2]
@@ -218,6 +219,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		userBook, err := h.libraryService.IssueBook(ctx, userID, bookID)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Str("book_id", bookID).Msg("failed to issue book")
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при выдаче книги")
 		}
 
@@ -265,6 +267,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		// Формат: lib_manage:taken:userID:bookID
 		parts := strings.Split(payload, ":")
 		if len(parts) != 4 {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка: неверный формат запроса")
 		}
 		userID := parts[2]
@@ -273,6 +276,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		err := h.libraryService.MarkBookTaken(ctx, userID, bookID)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Str("book_id", bookID).Msg("failed to mark book as taken")
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при отметке книги как забранной")
 		}
 
@@ -298,6 +302,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		// Формат: lib_manage:returned:userID:bookID
 		parts := strings.Split(payload, ":")
 		if len(parts) != 4 {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка: неверный формат запроса")
 		}
 		userID := parts[2]
@@ -306,6 +311,7 @@ func (h *LibraryManageHandler) handleManageCallback(ctx context.Context, req *bo
 		err := h.libraryService.MarkBookReturned(ctx, userID, bookID)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Str("book_id", bookID).Msg("failed to mark book as returned")
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при отметке книги как возвращенной")
 		}
 
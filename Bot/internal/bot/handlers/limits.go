@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/ratelimit"
+	"first-max-bot/internal/services/user"
+)
+
+// LimitsHandler позволяет руководителям просматривать и менять лимиты запросов,
+// снимать блокировки и освобождать пользователей от персональных лимитов
+type LimitsHandler struct {
+	limiter     ratelimit.Limiter
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewLimitsHandler(limiter ratelimit.Limiter, userService user.Service, logger zerolog.Logger) *LimitsHandler {
+	return &LimitsHandler{
+		limiter:     limiter,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *LimitsHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	u, err := h.userService.GetUserByID(ctx, req.UserID())
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден")
+	}
+	if !u.HasCapability(user.CapabilityLimits) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только руководителям.")
+	}
+
+	args := strings.Fields(req.Args)
+	if len(args) == 0 {
+		return h.showLimits(ctx, req, responder)
+	}
+
+	switch args[0] {
+	case "set":
+		if len(args) < 3 {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits set <global|user|/команда> <запросов>/<период>, например /limits set /ask 20/1h")
+		}
+		limit, err := ratelimit.ParseLimit(args[2])
+		if err != nil {
+			return responder.SendText(ctx, req.Recipient(), "❌ Неверный лимит. Пример: 20/1h, 5/30m, 3/10s")
+		}
+		target := limitTarget(args[1])
+		if err := h.limiter.SetLimit(ctx, target, limit); err != nil {
+			h.logger.Error().Err(err).Str("target", target).Msg("failed to set limit")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось изменить лимит")
+		}
+		h.logger.Info().Str("target", target).Str("limit", limit.String()).Str("by", req.UserID()).Msg("limit changed")
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Лимит «%s»: %s", limitTargetLabel(target), formatLimit(limit)))
+	case "reset":
+		if len(args) < 2 {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits reset <global|user|/команда>")
+		}
+		target := limitTarget(args[1])
+		if err := h.limiter.ResetLimit(ctx, target); err != nil {
+			h.logger.Error().Err(err).Str("target", target).Msg("failed to reset limit")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сбросить лимит")
+		}
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Для «%s» снова действует лимит по умолчанию", limitTargetLabel(target)))
+	case "user":
+		if len(args) < 2 {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits user <id пользователя>")
+		}
+		return h.showUser(ctx, req, responder, args[1])
+	case "unblock":
+		if len(args) < 2 {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits unblock <id пользователя>")
+		}
+		if err := h.limiter.Unblock(ctx, args[1]); err != nil {
+			h.logger.Error().Err(err).Str("user_id", args[1]).Msg("failed to unblock user")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось снять блокировку")
+		}
+		h.logger.Info().Str("user_id", args[1]).Str("by", req.UserID()).Msg("user unblocked")
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Блокировка пользователя %s снята", args[1]))
+	case "exempt":
+		if len(args) < 3 {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits exempt <id пользователя> on|off")
+		}
+		exempt, ok := parseSwitch(args[2])
+		if !ok {
+			return responder.SendText(ctx, req.Recipient(), "Формат: /limits exempt <id пользователя> on|off")
+		}
+		if err := h.limiter.SetExempt(ctx, args[1], exempt); err != nil {
+			h.logger.Error().Err(err).Str("user_id", args[1]).Msg("failed to set exempt")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось изменить настройки пользователя")
+		}
+		if exempt {
+			return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ На пользователя %s больше не действуют персональные лимиты", args[1]))
+		}
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ На пользователя %s снова действуют лимиты", args[1]))
+	default:
+		return responder.SendText(ctx, req.Recipient(), limitsUsage())
+	}
+}
+
+func (h *LimitsHandler) showLimits(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	limits, err := h.limiter.Limits(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to get limits")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить лимиты")
+	}
+
+	var message strings.Builder
+	message.WriteString("🚦 Лимиты запросов\n\n")
+	message.WriteString(fmt.Sprintf("Весь бот: %s\n", formatLimit(limits[ratelimit.TargetGlobal])))
+	message.WriteString(fmt.Sprintf("Один пользователь: %s\n", formatLimit(limits[ratelimit.TargetUser])))
+
+	commands := make([]string, 0, len(limits))
+	for target := range limits {
+		if target != ratelimit.TargetGlobal && target != ratelimit.TargetUser {
+			commands = append(commands, target)
+		}
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		message.WriteString(fmt.Sprintf("%s: %s\n", command, formatLimit(limits[command])))
+	}
+
+	message.WriteString("\n")
+	message.WriteString(limitsUsage())
+	return responder.SendText(ctx, req.Recipient(), message.String())
+}
+
+func (h *LimitsHandler) showUser(ctx context.Context, req *bot.Request, responder bot.Responder, userID string) error {
+	status, err := h.limiter.Status(ctx, userID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to get rate limit status")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить данные пользователя")
+	}
+
+	var message strings.Builder
+	message.WriteString(fmt.Sprintf("👤 Пользователь %s\n\n", userID))
+	if status.BlockedFor > 0 {
+		message.WriteString(fmt.Sprintf("🚫 Заблокирован еще на %s\n", formatDuration(status.BlockedFor)))
+	} else {
+		message.WriteString("Не заблокирован\n")
+	}
+	message.WriteString(fmt.Sprintf("Блокировок за сутки: %d\n", status.BlockLevel))
+	message.WriteString(fmt.Sprintf("Отказов за последнее время: %d\n", status.RecentDenied))
+	message.WriteString(fmt.Sprintf("Без персональных лимитов: %s\n", switchLabel(status.Exempt)))
+	return responder.SendText(ctx, req.Recipient(), message.String())
+}
+
+// limitTarget приводит цель к виду, в котором хранятся лимиты: global, user или /команда
+func limitTarget(target string) string {
+	target = strings.ToLower(strings.TrimSpace(target))
+	if target == ratelimit.TargetGlobal || target == ratelimit.TargetUser || strings.HasPrefix(target, "/") {
+		return target
+	}
+	return "/" + target
+}
+
+func limitTargetLabel(target string) string {
+	switch target {
+	case ratelimit.TargetGlobal:
+		return "весь бот"
+	case ratelimit.TargetUser:
+		return "один пользователь"
+	default:
+		return target
+	}
+}
+
+// formatLimit выводит лимит по-русски: "10 запросов за 1 ч"
+func formatLimit(limit ratelimit.Limit) string {
+	if limit.IsZero() {
+		return "без ограничений"
+	}
+	return fmt.Sprintf("%d за %s", limit.Requests, formatDuration(limit.Per))
+}
+
+// formatDuration выводит длительность в самых крупных целых единицах: "30 сек", "5 мин", "6 ч"
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d ч", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d мин", d/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%d мин %d сек", d/time.Minute, (d%time.Minute)/time.Second)
+	default:
+		return fmt.Sprintf("%d сек", (d+time.Second-1)/time.Second)
+	}
+}
+
+func limitsUsage() string {
+	return "Команды:\n" +
+		"/limits set <global|user|/команда> 20/1h — изменить лимит\n" +
+		"/limits reset <global|user|/команда> — вернуть лимит по умолчанию\n" +
+		"/limits user <id> — блокировки пользователя\n" +
+		"/limits unblock <id> — снять блокировку\n" +
+		"/limits exempt <id> on|off — освободить от персональных лимитов"
+}
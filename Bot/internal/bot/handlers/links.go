@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+	qrcode "github.com/skip2/go-qrcode"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/campaign"
+	"first-max-bot/internal/services/user"
+	"first-max-bot/internal/state"
+)
+
+// LinksHandler позволяет руководителям создавать отслеживаемые ссылки на бота,
+// получать для них QR-коды и смотреть статистику переходов и регистраций
+type LinksHandler struct {
+	campaigns   campaign.Service
+	userService user.Service
+	botUsername string
+	logger      zerolog.Logger
+}
+
+func NewLinksHandler(campaigns campaign.Service, userService user.Service, botUsername string, logger zerolog.Logger) *LinksHandler {
+	return &LinksHandler{
+		campaigns:   campaigns,
+		userService: userService,
+		botUsername: botUsername,
+		logger:      logger,
+	}
+}
+
+func (h *LinksHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден")
+	}
+	if !u.HasCapability(user.CapabilityLinks) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только руководителям.")
+	}
+
+	// Если это callback
+	if strings.HasPrefix(req.Args, "links:") {
+		return h.handleLinksCallback(ctx, req, responder)
+	}
+
+	// Если руководитель вводит код и название новой ссылки
+	if req.UserState != nil && req.UserState.UserRegistrationStep == "links_create" {
+		return h.handleCreateInput(ctx, req, responder, req.Args)
+	}
+
+	args := strings.Fields(req.Args)
+	if len(args) > 0 {
+		switch args[0] {
+		case "new":
+			return h.handleCreateInput(ctx, req, responder, strings.TrimSpace(strings.TrimPrefix(req.Args, args[0])))
+		case "qr":
+			if len(args) < 2 {
+				return responder.SendText(ctx, req.Recipient(), "Формат: /links qr <код>")
+			}
+			return h.sendQR(ctx, req, responder, args[1])
+		}
+	}
+
+	return h.showCampaigns(ctx, req, responder)
+}
+
+func (h *LinksHandler) showCampaigns(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	campaigns, err := h.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list campaigns")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить список ссылок")
+	}
+
+	var message strings.Builder
+	message.WriteString("🔗 Отслеживаемые ссылки\n\n")
+	if len(campaigns) == 0 {
+		message.WriteString("Ссылок пока нет.\n\n")
+	}
+	for _, c := range campaigns {
+		message.WriteString(fmt.Sprintf("• %s — %s\n", c.Code, c.Title))
+		message.WriteString(fmt.Sprintf("  Переходов: %d (уникальных: %d), регистраций: %d\n", c.Scans, c.UniqueUsers, c.Conversions))
+		message.WriteString(fmt.Sprintf("  %s\n\n", h.link(c.Code)))
+	}
+	message.WriteString("Префикс кода определяет, куда попадет пользователь:\n")
+	message.WriteString("openday_… — день открытых дверей, library_… — библиотека, dorm_… — общежитие, invite_… — приглашение к регистрации.\n\n")
+	message.WriteString("Создать ссылку: /links new <код> <название>\n")
+	message.WriteString("QR-код: /links qr <код>")
+
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().AddCallback("➕ Новая ссылка", schemes.POSITIVE, "links:new")
+	for _, c := range campaigns {
+		keyboard.AddRow().AddCallback(fmt.Sprintf("🔳 QR: %s", c.Code), schemes.DEFAULT, fmt.Sprintf("links:qr:%s", c.Code))
+	}
+
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message.String(), keyboard)
+}
+
+func (h *LinksHandler) handleLinksCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	payload := req.Args
+
+	if req.Metadata != nil {
+		if cid, ok := req.Metadata["callback_id"].(string); ok && cid != "" {
+			responder.AnswerCallback(ctx, cid, &schemes.CallbackAnswer{})
+		}
+	}
+
+	if payload == "links:new" {
+		if req.UserState == nil {
+			req.UserState = &state.UserState{}
+		}
+		req.UserState.UserRegistrationStep = "links_create"
+		return sendFlowPrompt(ctx, req, responder, "Введи код и название ссылки через пробел, например:\nopenday_2026_12 День открытых дверей, декабрь 2026")
+	}
+
+	if strings.HasPrefix(payload, "links:qr:") {
+		return h.sendQR(ctx, req, responder, strings.TrimPrefix(payload, "links:qr:"))
+	}
+
+	return nil
+}
+
+// handleCreateInput создает ссылку из строки "<код> <название>"
+func (h *LinksHandler) handleCreateInput(ctx context.Context, req *bot.Request, responder bot.Responder, input string) error {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return responder.SendText(ctx, req.Recipient(), "Формат: <код> <название>, например: library_desk Стойка библиотеки")
+	}
+
+	code := fields[0]
+	title := strings.TrimSpace(strings.TrimPrefix(input, code))
+	if title == "" {
+		title = code
+	}
+
+	c, err := h.campaigns.CreateCampaign(ctx, code, title, req.UserID())
+	if err != nil {
+		switch {
+		case errors.Is(err, campaign.ErrInvalidCode):
+			return responder.SendText(ctx, req.Recipient(), "❌ Код может содержать только латиницу, цифры, «_» и «-» и быть не длиннее 64 символов. Попробуй снова.")
+		case errors.Is(err, campaign.ErrAlreadyExist):
+			return responder.SendText(ctx, req.Recipient(), "❌ Ссылка с таким кодом уже есть. Введи другой код.")
+		default:
+			h.logger.Error().Err(err).Str("code", code).Msg("failed to create campaign")
+			return responder.SendText(ctx, req.Recipient(), "❌ Не удалось создать ссылку. Попробуй позже.")
+		}
+	}
+
+	if req.UserState != nil && req.UserState.UserRegistrationStep == "links_create" {
+		req.UserState.UserRegistrationStep = ""
+	}
+
+	h.logger.Info().Str("code", c.Code).Str("user_id", req.UserID()).Msg("campaign created")
+	return h.sendQR(ctx, req, responder, c.Code)
+}
+
+// sendQR отправляет ссылку кампании и её QR-код в PNG
+func (h *LinksHandler) sendQR(ctx context.Context, req *bot.Request, responder bot.Responder, code string) error {
+	c, err := h.campaigns.GetCampaign(ctx, code)
+	if err != nil {
+		if errors.Is(err, campaign.ErrNotFound) {
+			return responder.SendText(ctx, req.Recipient(), "❌ Ссылка не найдена")
+		}
+		h.logger.Error().Err(err).Str("code", code).Msg("failed to get campaign")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить ссылку")
+	}
+
+	link := h.link(c.Code)
+	caption := fmt.Sprintf("🔗 %s\n%s\n\nПереходов: %d, регистраций: %d", c.Title, link, c.Scans, c.Conversions)
+
+	if h.botUsername == "" {
+		return responder.SendText(ctx, req.Recipient(), caption+"\n\n⚠️ Имя бота неизвестно, QR-код не сформирован.")
+	}
+
+	png, err := qrcode.Encode(link, qrcode.Medium, 512)
+	if err != nil {
+		h.logger.Error().Err(err).Str("code", code).Msg("failed to encode qr code")
+		return responder.SendText(ctx, req.Recipient(), caption)
+	}
+
+	if err := responder.SendImage(ctx, req.Recipient(), caption, bytes.NewReader(png)); err != nil {
+		h.logger.Error().Err(err).Str("code", code).Msg("failed to send qr code")
+		return responder.SendText(ctx, req.Recipient(), caption+"\n\n❌ Не удалось отправить QR-код.")
+	}
+	return nil
+}
+
+// link формирует deep-link на бота с payload кампании
+func (h *LinksHandler) link(code string) string {
+	if h.botUsername == "" {
+		return fmt.Sprintf("start=%s", code)
+	}
+	return fmt.Sprintf("https://max.ru/%s?start=%s", h.botUsername, code)
+}
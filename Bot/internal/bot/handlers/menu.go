@@ -35,8 +35,8 @@ func (h *MenuHandler) Handle(ctx context.Context, req *bot.Request, responder bo
 		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
 	}
 
-	// Получаем команды для роли
-	commands := user.GetCommandsForRole(u.Role)
+	// Получаем команды для действующих ролей
+	commands := u.Commands()
 	if len(commands) == 0 {
 		return responder.SendText(ctx, req.Recipient(), "❌ Нет доступных команд для твоей роли.")
 	}
@@ -44,26 +44,22 @@ func (h *MenuHandler) Handle(ctx context.Context, req *bot.Request, responder bo
 	// Формируем меню
 	var builder strings.Builder
 	builder.WriteString("📋 Доступные команды:\n\n")
-	
+
 	// Группируем команды по категориям
-	roleLabel := h.getRoleLabel(u.Role)
-	builder.WriteString(fmt.Sprintf("Роль: %s\n\n", roleLabel))
+	builder.WriteString(fmt.Sprintf("Роль: %s\n\n", formatRoles(u)))
 
 	// Общие команды (для всех ролей)
 	generalCaps := map[user.Capability]bool{
-		user.CapabilityHelp:    true,
+		user.CapabilityHelp:     true,
 		user.CapabilitySchedule: true,
-		user.CapabilityContact: true,
+		user.CapabilityContact:  true,
+		user.CapabilityRoles:    true,
 	}
-	
+
 	generalCommands := []user.CommandInfo{}
-	roleSpecificCommands := []user.CommandInfo{}
-	
 	for _, cmd := range commands {
 		if generalCaps[cmd.Capability] {
 			generalCommands = append(generalCommands, cmd)
-		} else {
-			roleSpecificCommands = append(roleSpecificCommands, cmd)
 		}
 	}
 
@@ -76,8 +72,40 @@ func (h *MenuHandler) Handle(ctx context.Context, req *bot.Request, responder bo
 		builder.WriteString("\n")
 	}
 
-	// Команды в зависимости от роли
-	switch u.Role {
+	// Команды по каждой действующей роли; команда, уже показанная в другом разделе, не повторяется
+	shown := make(map[string]bool, len(commands))
+	for _, cmd := range generalCommands {
+		shown[cmd.Command] = true
+	}
+	sectionWritten := false
+	for _, role := range u.EffectiveRoles() {
+		roleSpecificCommands := []user.CommandInfo{}
+		for _, cmd := range user.GetCommandsForRole(role) {
+			if generalCaps[cmd.Capability] || shown[cmd.Command] {
+				continue
+			}
+			shown[cmd.Command] = true
+			roleSpecificCommands = append(roleSpecificCommands, cmd)
+		}
+		if len(roleSpecificCommands) == 0 {
+			continue
+		}
+		if sectionWritten {
+			builder.WriteString("\n")
+		}
+		h.writeRoleCommands(&builder, role, roleSpecificCommands)
+		sectionWritten = true
+	}
+
+	builder.WriteString("\n🔒 Твои данные: /my_data — выгрузить, /delete_me — удалить")
+	builder.WriteString("\n💡 Используй команды для взаимодействия с ботом.")
+
+	return responder.SendText(ctx, req.Recipient(), builder.String())
+}
+
+// writeRoleCommands добавляет в меню раздел с командами одной роли
+func (h *MenuHandler) writeRoleCommands(builder *strings.Builder, role user.Role, roleSpecificCommands []user.CommandInfo) {
+	switch role {
 	case user.RoleApplicant:
 		if len(roleSpecificCommands) > 0 {
 			builder.WriteString("🔹 Для абитуриентов:\n")
@@ -169,7 +197,7 @@ func (h *MenuHandler) Handle(ctx context.Context, req *bot.Request, responder bo
 				switch cmd.Capability {
 				case user.CapabilityDashboard, user.CapabilityAnalytics:
 					analyticsCommands = append(analyticsCommands, cmd)
-				case user.CapabilityNews, user.CapabilitySendNews:
+				case user.CapabilityNews, user.CapabilitySendNews, user.CapabilityLinks:
 					newsCommands = append(newsCommands, cmd)
 				case user.CapabilityTickets, user.CapabilityDocuments:
 					manageCommands = append(manageCommands, cmd)
@@ -195,24 +223,4 @@ func (h *MenuHandler) Handle(ctx context.Context, req *bot.Request, responder bo
 			}
 		}
 	}
-
-	builder.WriteString("\n💡 Используй команды для взаимодействия с ботом.")
-
-	return responder.SendText(ctx, req.Recipient(), builder.String())
 }
-
-func (h *MenuHandler) getRoleLabel(role user.Role) string {
-	switch role {
-	case user.RoleApplicant:
-		return "Абитуриент"
-	case user.RoleStudent:
-		return "Студент"
-	case user.RoleEmployee:
-		return "Сотрудник"
-	case user.RoleManager:
-		return "Руководитель"
-	default:
-		return string(role)
-	}
-}
-
@@ -10,6 +10,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"first-max-bot/internal/bot"
+	"first-max-bot/internal/secret"
 	"first-max-bot/internal/services/moodle"
 	"first-max-bot/internal/services/user"
 	"first-max-bot/internal/state"
@@ -19,13 +20,15 @@ import (
 type MoodleHandler struct {
 	moodleService moodle.Service
 	userService   user.Service
+	secrets       *secret.Keyring // токен хранится зашифрованным и расшифровывается только для запросов к Moodle
 	logger        zerolog.Logger
 }
 
-func NewMoodleHandler(moodleService moodle.Service, userService user.Service, logger zerolog.Logger) *MoodleHandler {
+func NewMoodleHandler(moodleService moodle.Service, userService user.Service, secrets *secret.Keyring, logger zerolog.Logger) *MoodleHandler {
 	return &MoodleHandler{
 		moodleService: moodleService,
 		userService:   userService,
+		secrets:       secrets,
 		logger:        logger,
 	}
 }
@@ -49,7 +52,7 @@ func (h *MoodleHandler) Handle(ctx context.Context, req *bot.Request, responder
 		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден")
 	}
 
-	if u.Role != user.RoleStudent {
+	if !u.HasCapability(user.CapabilityMoodle) {
 		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только студентам.")
 	}
 
@@ -70,11 +73,27 @@ func (h *MoodleHandler) Handle(ctx context.Context, req *bot.Request, responder
 		message += "Для работы с Moodle необходимо добавить токен доступа.\n\n"
 		message += "Введи свой токен Moodle:"
 
-		return responder.SendMarkdown(ctx, req.Recipient(), message)
+		return sendFlowPromptMarkdown(ctx, req, responder, message)
+	}
+
+	token, err := h.secrets.Open(u.MoodleToken)
+	if err != nil {
+		// Например, ключ шифрования сменили без перешифровки: просим привязать токен заново
+		h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to decrypt moodle token")
+		if req.UserState == nil {
+			req.UserState = &state.UserState{}
+		}
+		req.UserState.UserRegistrationStep = "moodle_token"
+
+		message := "🔗 **Интеграция с Moodle**\n\n"
+		message += "Не удалось прочитать сохраненный токен, его нужно привязать заново.\n\n"
+		message += "Введи свой токен Moodle:"
+
+		return sendFlowPromptMarkdown(ctx, req, responder, message)
 	}
 
 	// Если токен есть, получаем информацию о пользователе
-	siteInfo, err := h.moodleService.GetSiteInfo(ctx, u.MoodleToken)
+	siteInfo, err := h.moodleService.GetSiteInfo(ctx, token)
 	if err != nil {
 		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to get moodle site info")
 		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при подключении к Moodle. Проверь токен или попробуй позже.")
@@ -139,8 +158,13 @@ func (h *MoodleHandler) handleTokenInput(ctx context.Context, req *bot.Request,
 		return responder.SendText(ctx, req.Recipient(), "❌ Неверный токен. Проверь правильность токена и попробуй снова.")
 	}
 
-	// Сохраняем токен
-	if err := h.userService.SetMoodleToken(ctx, userID, token); err != nil {
+	// Сохраняем токен в зашифрованном виде
+	sealed, err := h.secrets.Seal(token)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to encrypt moodle token")
+		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при сохранении токена.")
+	}
+	if err := h.userService.SetMoodleToken(ctx, userID, sealed); err != nil {
 		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to save moodle token")
 		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при сохранении токена.")
 	}
@@ -174,9 +198,29 @@ func (h *MoodleHandler) HandleCallback(ctx context.Context, req *bot.Request, re
 		return responder.SendText(ctx, req.Recipient(), "❌ Токен Moodle не найден. Используй /moodle для привязки.")
 	}
 
+	if strings.HasPrefix(payload, "moodle:change_token") {
+		// Начинаем процесс смены токена
+		if req.UserState == nil {
+			req.UserState = &state.UserState{}
+		}
+		req.UserState.UserRegistrationStep = "moodle_token"
+
+		message := "🔑 **Изменение токена Moodle**\n\n"
+		message += "Введи новый токен:"
+
+		return sendFlowPromptMarkdown(ctx, req, responder, message)
+	}
+
+	// Токен расшифровываем только для запросов к Moodle
+	token, err := h.secrets.Open(u.MoodleToken)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to decrypt moodle token")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось прочитать токен Moodle. Используй /moodle, чтобы привязать его заново.")
+	}
+
 	if strings.HasPrefix(payload, "moodle:refresh") {
 		// Обновляем информацию
-		siteInfo, err := h.moodleService.GetSiteInfo(ctx, u.MoodleToken)
+		siteInfo, err := h.moodleService.GetSiteInfo(ctx, token)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to refresh moodle info")
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при обновлении информации.")
@@ -191,29 +235,16 @@ func (h *MoodleHandler) HandleCallback(ctx context.Context, req *bot.Request, re
 		return responder.SendMarkdown(ctx, req.Recipient(), message)
 	}
 
-	if strings.HasPrefix(payload, "moodle:change_token") {
-		// Начинаем процесс смены токена
-		if req.UserState == nil {
-			req.UserState = &state.UserState{}
-		}
-		req.UserState.UserRegistrationStep = "moodle_token"
-
-		message := "🔑 **Изменение токена Moodle**\n\n"
-		message += "Введи новый токен:"
-
-		return responder.SendMarkdown(ctx, req.Recipient(), message)
-	}
-
 	if strings.HasPrefix(payload, "moodle:courses") {
 		// Получаем информацию о пользователе для получения userID
-		siteInfo, err := h.moodleService.GetSiteInfo(ctx, u.MoodleToken)
+		siteInfo, err := h.moodleService.GetSiteInfo(ctx, token)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to get site info for courses")
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при получении информации о пользователе.")
 		}
 
 		// Получаем курсы пользователя
-		courses, err := h.moodleService.GetUserCourses(ctx, u.MoodleToken, siteInfo.UserID)
+		courses, err := h.moodleService.GetUserCourses(ctx, token, siteInfo.UserID)
 		if err != nil {
 			h.logger.Error().Err(err).Str("user_id", userID).Int("moodle_user_id", siteInfo.UserID).Msg("failed to get user courses")
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при получении курсов.")
@@ -158,7 +158,7 @@ func (h *MyTicketsHandler) handleTicketCallback(ctx context.Context, req *bot.Re
 		req.UserState.UserRegistrationStep = "ticket_user_reply"
 
 		message := "✍️ Напиши свой ответ на обращение:"
-		return responder.SendText(ctx, req.Recipient(), message)
+		return sendFlowPrompt(ctx, req, responder, message)
 	}
 
 	return nil
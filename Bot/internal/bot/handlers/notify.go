@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/schedulewatch"
+	"first-max-bot/internal/services/user"
+)
+
+const (
+	notifyOnPayload        = "notify:on"
+	notifyOffPayload       = "notify:off"
+	notifyQuietPayload     = "notify:quiet:default"
+	notifyQuietOffPayload  = "notify:quiet:off"
+	notifyRemindPayload    = "notify:remind:default"
+	notifyRemindOffPayload = "notify:remind:off"
+
+	// defaultQuietHours - тихие часы, которые включает кнопка
+	defaultQuietHours = "23:00-08:00"
+	// defaultRemindBefore - за сколько минут до пары напоминает кнопка
+	defaultRemindBefore = 15
+
+	notifyUsage = "/notify вкл | выкл | тихо 23:00-08:00 | тихо выкл | напоминать 15 | напоминать выкл"
+)
+
+// NotifyHandler обрабатывает команду /notify: уведомления об изменениях расписания, тихие часы
+// и напоминания перед парами
+type NotifyHandler struct {
+	store       schedulewatch.Store
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewNotifyHandler(store schedulewatch.Store, userService user.Service, logger zerolog.Logger) *NotifyHandler {
+	return &NotifyHandler{
+		store:       store,
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+// Handle показывает настройки или меняет их. Аргументы: "вкл", "выкл", "тихо 23:00-08:00", "тихо выкл",
+// "напоминать 15", "напоминать выкл"
+func (h *NotifyHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
+	}
+	if !u.HasCapability(user.CapabilityScheduleAlerts) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Уведомления о расписании доступны студентам и сотрудникам.")
+	}
+
+	settings, err := h.store.GetSettings(ctx, userID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to get notification settings")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось получить настройки. Попробуй позже.")
+	}
+
+	if strings.HasPrefix(req.Args, "notify:") {
+		return h.handleCallback(ctx, req, responder, settings)
+	}
+
+	command, value, _ := strings.Cut(strings.TrimSpace(req.Args), " ")
+	switch strings.ToLower(command) {
+	case "":
+		return responder.SendTextWithKeyboard(ctx, req.Recipient(), h.statusText(settings), h.keyboard(responder, settings))
+	case "вкл", "on":
+		settings.Disabled = false
+	case "выкл", "off":
+		settings.Disabled = true
+	case "тихо", "quiet":
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(value) {
+		case "выкл", "off", "нет":
+			settings.QuietFrom, settings.QuietTo = 0, 0
+		default:
+			from, to, err := schedulewatch.ParseQuietHours(value)
+			if err != nil {
+				return responder.SendText(ctx, req.Recipient(), "❌ Укажи тихие часы в формате ЧЧ:ММ-ЧЧ:ММ, например /notify тихо 23:00-08:00")
+			}
+			settings.QuietFrom, settings.QuietTo = from, to
+		}
+	case "напоминать", "remind":
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(value) {
+		case "выкл", "off", "нет":
+			settings.RemindBefore = 0
+		default:
+			minutes, err := schedulewatch.ParseRemindBefore(value)
+			if err != nil {
+				return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("❌ Укажи, за сколько минут до пары напоминать (от 1 до %d), например /notify напоминать 15", schedulewatch.MaxRemindBefore))
+			}
+			settings.RemindBefore = minutes
+		}
+	default:
+		return responder.SendText(ctx, req.Recipient(), "Использование: "+notifyUsage)
+	}
+
+	if err := h.store.SaveSettings(ctx, userID, settings); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to save notification settings")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сохранить настройки. Попробуй позже.")
+	}
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), "✅ Настройки сохранены.\n\n"+h.statusText(settings), h.keyboard(responder, settings))
+}
+
+func (h *NotifyHandler) handleCallback(ctx context.Context, req *bot.Request, responder bot.Responder, settings schedulewatch.Settings) error {
+	callbackID, _ := req.Metadata["callback_id"].(string)
+	userID := req.UserID()
+
+	switch req.Args {
+	case notifyOnPayload:
+		settings.Disabled = false
+	case notifyOffPayload:
+		settings.Disabled = true
+	case notifyQuietPayload:
+		settings.QuietFrom, settings.QuietTo, _ = schedulewatch.ParseQuietHours(defaultQuietHours)
+	case notifyQuietOffPayload:
+		settings.QuietFrom, settings.QuietTo = 0, 0
+	case notifyRemindPayload:
+		settings.RemindBefore = defaultRemindBefore
+	case notifyRemindOffPayload:
+		settings.RemindBefore = 0
+	default:
+		return nil
+	}
+
+	if err := h.store.SaveSettings(ctx, userID, settings); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to save notification settings")
+		responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "❌ Не удалось сохранить настройки. Попробуй позже."})
+		return nil
+	}
+	return responder.AnswerCallbackWithEdit(ctx, callbackID, h.statusText(settings), h.keyboard(responder, settings))
+}
+
+func (h *NotifyHandler) statusText(settings schedulewatch.Settings) string {
+	var message strings.Builder
+	message.WriteString("🔔 Уведомления об изменениях расписания\n\n")
+	message.WriteString("Бот пишет, если занятие в ближайшие две недели перенесли, отменили, добавили или сменились аудитория и преподаватель.\n\n")
+	if settings.Disabled {
+		message.WriteString("Сейчас: выключены\n")
+	} else {
+		message.WriteString("Сейчас: включены\n")
+	}
+	if settings.HasQuietHours() {
+		message.WriteString(fmt.Sprintf("Тихие часы: %s — изменения за это время придут одним сообщением после них\n", settings.QuietHours()))
+	} else {
+		message.WriteString("Тихие часы: нет\n")
+	}
+	if settings.RemindBefore > 0 {
+		message.WriteString(fmt.Sprintf("\n⏰ Напоминания о парах: за %d мин, с аудиторией и преподавателем. При изменении расписания напоминания обновятся сами\n", settings.RemindBefore))
+	} else {
+		message.WriteString("\n⏰ Напоминания о парах: выключены\n")
+	}
+	message.WriteString("\nИзменить: " + notifyUsage)
+	return message.String()
+}
+
+func (h *NotifyHandler) keyboard(responder bot.Responder, settings schedulewatch.Settings) *maxbot.Keyboard {
+	keyboard := responder.NewKeyboardBuilder()
+	if settings.Disabled {
+		keyboard.AddRow().AddCallback("🔔 Включить", schemes.POSITIVE, notifyOnPayload)
+	} else {
+		keyboard.AddRow().AddCallback("🔕 Выключить", schemes.NEGATIVE, notifyOffPayload)
+	}
+	if settings.HasQuietHours() {
+		keyboard.AddRow().AddCallback("Без тихих часов", schemes.DEFAULT, notifyQuietOffPayload)
+	} else {
+		keyboard.AddRow().AddCallback("🌙 Тихие часы "+strings.ReplaceAll(defaultQuietHours, "-", "–"), schemes.DEFAULT, notifyQuietPayload)
+	}
+	if settings.RemindBefore > 0 {
+		keyboard.AddRow().AddCallback("Не напоминать о парах", schemes.DEFAULT, notifyRemindOffPayload)
+	} else {
+		keyboard.AddRow().AddCallback(fmt.Sprintf("⏰ Напоминать за %d мин до пары", defaultRemindBefore), schemes.DEFAULT, notifyRemindPayload)
+	}
+	return keyboard
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/privacy"
+)
+
+const (
+	privacyDeleteConfirmPayload = "privacy:delete:confirm"
+	privacyDeleteCancelPayload  = "privacy:delete:cancel"
+)
+
+// PrivacyHandler обрабатывает /my_data (выгрузка всех данных пользователя)
+// и /delete_me (удаление данных с подтверждением)
+type PrivacyHandler struct {
+	privacy *privacy.Service
+	logger  zerolog.Logger
+}
+
+func NewPrivacyHandler(privacyService *privacy.Service, logger zerolog.Logger) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacy: privacyService,
+		logger:  logger,
+	}
+}
+
+func (h *PrivacyHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	if strings.HasPrefix(req.Args, "privacy:") {
+		return h.handleCallback(ctx, req, responder)
+	}
+
+	switch req.Command {
+	case "/delete_me":
+		return h.askDelete(ctx, req, responder)
+	default:
+		return h.sendData(ctx, req, responder)
+	}
+}
+
+func (h *PrivacyHandler) sendData(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	data, err := h.privacy.Export(ctx, userID)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to export user data")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось собрать данные. Попробуй позже.")
+	}
+
+	archive, err := privacy.Archive(data)
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to pack user data")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось собрать данные. Попробуй позже.")
+	}
+
+	counts := data.Counts()
+	var message strings.Builder
+	message.WriteString("📦 Твои данные\n\n")
+	if data.Profile == nil {
+		message.WriteString("Профиль: не зарегистрирован\n")
+	} else {
+		message.WriteString("Профиль: имя, возраст, пол, email, роли\n")
+	}
+	message.WriteString(fmt.Sprintf("Обращения: %d\n", counts["tickets"]))
+	message.WriteString(fmt.Sprintf("Заявления в деканат: %d\n", counts["documents"]))
+	message.WriteString(fmt.Sprintf("Книги: %d\n", counts["loans"]))
+	message.WriteString(fmt.Sprintf("Командировки: %d\n", counts["trips"]))
+	message.WriteString(fmt.Sprintf("Напоминания: %d\n", counts["reminders"]))
+	if counts["news"] > 0 {
+		message.WriteString(fmt.Sprintf("Опубликованные новости: %d\n", counts["news"]))
+	}
+	message.WriteString(fmt.Sprintf("\nВсе записи — в файле %s в архиве. Токен Moodle в файл не включается.\n", privacy.ArchiveFileName))
+	message.WriteString("Удалить данные — /delete_me")
+
+	if err := responder.SendFile(ctx, req.Recipient(), message.String(), privacy.ArchiveName, bytes.NewReader(archive)); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to send user data")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось отправить файл. Попробуй позже.")
+	}
+	return nil
+}
+
+func (h *PrivacyHandler) askDelete(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	text := "⚠️ Удаление данных\n\n" +
+		"Будут удалены профиль, обращения, заявления в деканат, командировки, напоминания и незавершенные диалоги с ботом.\n" +
+		"Записи о книгах и опубликованные тобой новости останутся, но без имени и ID.\n\n" +
+		"Отменить удаление нельзя. Чтобы сохранить копию данных, сначала используй /my_data.\n\n" +
+		"Удалить все данные?"
+
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().AddCallback("🗑 Да, удалить", schemes.NEGATIVE, privacyDeleteConfirmPayload)
+	keyboard.AddRow().AddCallback("Отмена", schemes.DEFAULT, privacyDeleteCancelPayload)
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), text, keyboard)
+}
+
+func (h *PrivacyHandler) handleCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	if req.Metadata != nil {
+		if cid, ok := req.Metadata["callback_id"].(string); ok && cid != "" {
+			responder.AnswerCallback(ctx, cid, &schemes.CallbackAnswer{})
+		}
+	}
+
+	switch req.Args {
+	case privacyDeleteCancelPayload:
+		return responder.SendText(ctx, req.Recipient(), "Удаление отменено, данные сохранены.")
+	case privacyDeleteConfirmPayload:
+		return h.deleteData(ctx, req, responder)
+	}
+	return nil
+}
+
+func (h *PrivacyHandler) deleteData(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	if _, err := h.privacy.Erase(ctx, userID); err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to erase user data")
+		bot.ActionFailed(responder)
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось удалить все данные. Попробуй еще раз через /delete_me — уже удаленное останется удаленным.")
+	}
+
+	// Состояние диалога уже удалено: без этого бот сохранил бы его снова после обработки кнопки
+	req.UserState = nil
+	return responder.SendText(ctx, req.Recipient(), "✅ Твои данные удалены. Если захочешь вернуться — используй /start.")
+}
@@ -132,7 +132,7 @@ func (h *ReminderHandler) showDateStep(ctx context.Context, req *bot.Request, re
 	row4 := keyboard.AddRow()
 	row4.AddCallback("✏️ Ввести свою дату", schemes.POSITIVE, "reminder:date:custom")
 
-	return responder.SendMarkdownWithKeyboard(ctx, req.Recipient(), message, keyboard)
+	return responder.SendMarkdownWithKeyboard(ctx, req.Recipient(), message, withCancelButton(responder, keyboard))
 }
 
 func (h *ReminderHandler) handleDateInput(ctx context.Context, req *bot.Request, responder bot.Responder, dateStr string) error {
@@ -157,7 +157,7 @@ func (h *ReminderHandler) showTimeStep(ctx context.Context, req *bot.Request, re
 	message += "**Шаг 3 из 3: Введи время**\n\n"
 	message += "Введи время в формате ЧЧ:ММ (например, 14:30):"
 
-	return responder.SendMarkdown(ctx, req.Recipient(), message)
+	return sendFlowPromptMarkdown(ctx, req, responder, message)
 }
 
 func (h *ReminderHandler) handleTimeInput(ctx context.Context, req *bot.Request, responder bot.Responder, timeStr string) error {
@@ -239,7 +239,7 @@ func (h *ReminderHandler) HandleCallback(ctx context.Context, req *bot.Request,
 		message += "**Шаг 1 из 3: Введи текст напоминания**\n\n"
 		message += "Напиши, о чём тебе напомнить:"
 
-		return responder.SendMarkdown(ctx, req.Recipient(), message)
+		return sendFlowPromptMarkdown(ctx, req, responder, message)
 	}
 
 	if payload == "reminder:list" {
@@ -279,7 +279,7 @@ func (h *ReminderHandler) HandleCallback(ctx context.Context, req *bot.Request,
 		if datePart == "custom" {
 			message := "✏️ **Введи дату**\n\n"
 			message += "Введи дату в формате ДД.ММ.ГГГГ (например, 25.12.2024):"
-			return responder.SendMarkdown(ctx, req.Recipient(), message)
+			return sendFlowPromptMarkdown(ctx, req, responder, message)
 		}
 
 		// Парсим дату из callback
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/user"
+)
+
+// RoleHandler обрабатывает команду /role: переключение активной роли
+// и назначение ролей пользователям (для руководителей)
+type RoleHandler struct {
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewRoleHandler(userService user.Service, logger zerolog.Logger) *RoleHandler {
+	return &RoleHandler{
+		userService: userService,
+		logger:      logger,
+	}
+}
+
+func (h *RoleHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
+	}
+
+	// Если это callback выбора роли
+	if strings.HasPrefix(req.Args, "role:") {
+		return h.handleRoleCallback(ctx, req, responder, u)
+	}
+
+	args := strings.Fields(req.Args)
+	if len(args) > 0 {
+		switch args[0] {
+		case "grant", "revoke":
+			return h.handleManage(ctx, req, responder, u, args)
+		case "all":
+			return h.setActiveRole(ctx, req, responder, u, "")
+		default:
+			role, ok := parseRole(args[0])
+			if !ok {
+				return responder.SendText(ctx, req.Recipient(), "❌ Неизвестная роль. Доступные роли: applicant, student, employee, manager.")
+			}
+			return h.setActiveRole(ctx, req, responder, u, role)
+		}
+	}
+
+	return h.showRoles(ctx, req, responder, u)
+}
+
+func (h *RoleHandler) showRoles(ctx context.Context, req *bot.Request, responder bot.Responder, u *user.User) error {
+	var message strings.Builder
+	message.WriteString("🎭 Роли\n\n")
+	message.WriteString(fmt.Sprintf("Твои роли: %s\n", formatRoles(u)))
+	if u.ActiveRole != "" {
+		message.WriteString(fmt.Sprintf("Активная роль: %s\n", roleLabel(u.ActiveRole)))
+	} else {
+		message.WriteString("Активная роль: все роли сразу\n")
+	}
+
+	if u.HasCapability(user.CapabilityManageRoles) {
+		message.WriteString("\nНазначить или снять роль пользователю:\n")
+		message.WriteString("/role grant <id пользователя> <роль>\n")
+		message.WriteString("/role revoke <id пользователя> <роль>\n")
+	}
+
+	roles := u.AllRoles()
+	if len(roles) < 2 {
+		message.WriteString("\nУ тебя одна роль, переключаться не на что.")
+		return responder.SendText(ctx, req.Recipient(), message.String())
+	}
+
+	message.WriteString("\nВыбери, в какой роли работать:")
+
+	keyboard := responder.NewKeyboardBuilder()
+	for _, role := range roles {
+		label := roleLabel(role)
+		intent := schemes.DEFAULT
+		if role == u.ActiveRole {
+			label = "✅ " + label
+			intent = schemes.POSITIVE
+		}
+		keyboard.AddRow().AddCallback(label, intent, fmt.Sprintf("role:use:%s", role))
+	}
+	allLabel := "Все роли"
+	if u.ActiveRole == "" {
+		allLabel = "✅ " + allLabel
+	}
+	keyboard.AddRow().AddCallback(allLabel, schemes.DEFAULT, "role:all")
+
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), message.String(), keyboard)
+}
+
+func (h *RoleHandler) handleRoleCallback(ctx context.Context, req *bot.Request, responder bot.Responder, u *user.User) error {
+	payload := req.Args
+
+	if req.Metadata != nil {
+		if cid, ok := req.Metadata["callback_id"].(string); ok && cid != "" {
+			responder.AnswerCallback(ctx, cid, &schemes.CallbackAnswer{})
+		}
+	}
+
+	if payload == "role:all" {
+		return h.setActiveRole(ctx, req, responder, u, "")
+	}
+
+	if strings.HasPrefix(payload, "role:use:") {
+		role, ok := parseRole(strings.TrimPrefix(payload, "role:use:"))
+		if !ok {
+			return responder.SendText(ctx, req.Recipient(), "❌ Неизвестная роль")
+		}
+		return h.setActiveRole(ctx, req, responder, u, role)
+	}
+
+	return nil
+}
+
+func (h *RoleHandler) setActiveRole(ctx context.Context, req *bot.Request, responder bot.Responder, u *user.User, role user.Role) error {
+	if role != "" && !u.HasRole(role) {
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("❌ У тебя нет роли «%s».", roleLabel(role)))
+	}
+
+	if err := h.userService.SetActiveRole(ctx, u.UserID, role); err != nil {
+		h.logger.Error().Err(err).Str("user_id", u.UserID).Str("role", string(role)).Msg("failed to set active role")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сменить роль. Попробуй позже.")
+	}
+
+	if role == "" {
+		return responder.SendText(ctx, req.Recipient(), "✅ Теперь действуют все твои роли. Используй /menu, чтобы посмотреть команды.")
+	}
+	return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Активная роль: %s. Используй /menu, чтобы посмотреть команды.", roleLabel(role)))
+}
+
+// handleManage назначает или снимает роль другому пользователю: /role grant|revoke <id> <роль>
+func (h *RoleHandler) handleManage(ctx context.Context, req *bot.Request, responder bot.Responder, u *user.User, args []string) error {
+	if !u.HasCapability(user.CapabilityManageRoles) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Назначать роли могут только руководители.")
+	}
+
+	if len(args) != 3 {
+		return responder.SendText(ctx, req.Recipient(), "Формат: /role grant <id пользователя> <роль> или /role revoke <id пользователя> <роль>")
+	}
+
+	role, ok := parseRole(args[2])
+	if !ok {
+		return responder.SendText(ctx, req.Recipient(), "❌ Неизвестная роль. Доступные роли: applicant, student, employee, manager.")
+	}
+
+	target, err := h.userService.GetUserByID(ctx, args[1])
+	if err != nil || target == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден")
+	}
+
+	var roles []user.Role
+	switch args[0] {
+	case "grant":
+		roles = append(roles, target.AllRoles()...)
+		roles = append(roles, role)
+	case "revoke":
+		for _, r := range target.AllRoles() {
+			if r != role {
+				roles = append(roles, r)
+			}
+		}
+		if len(roles) == 0 {
+			return responder.SendText(ctx, req.Recipient(), "❌ Нельзя снять у пользователя последнюю роль.")
+		}
+	}
+
+	if err := h.userService.SetUserRoles(ctx, target.UserID, roles); err != nil {
+		h.logger.Error().Err(err).Str("target_user_id", target.UserID).Msg("failed to update user roles")
+		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при сохранении ролей")
+	}
+
+	updated, err := h.userService.GetUserByID(ctx, target.UserID)
+	if err != nil || updated == nil {
+		updated = target
+	}
+
+	h.logger.Info().Str("admin_user_id", u.UserID).Str("target_user_id", target.UserID).Str("action", args[0]).Str("role", string(role)).Msg("user roles changed")
+	return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("✅ Роли пользователя %s %s: %s", updated.FirstName, updated.LastName, formatRoles(updated)))
+}
+
+// parseRole переводит строку в роль, принимая как коды ролей, так и русские названия
+func parseRole(value string) (user.Role, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "applicant", "абитуриент":
+		return user.RoleApplicant, true
+	case "student", "студент":
+		return user.RoleStudent, true
+	case "employee", "сотрудник":
+		return user.RoleEmployee, true
+	case "manager", "руководитель":
+		return user.RoleManager, true
+	default:
+		return "", false
+	}
+}
+
+// roleLabel возвращает русское название роли
+func roleLabel(role user.Role) string {
+	switch role {
+	case user.RoleApplicant:
+		return "Абитуриент"
+	case user.RoleStudent:
+		return "Студент"
+	case user.RoleEmployee:
+		return "Сотрудник"
+	case user.RoleManager:
+		return "Руководитель"
+	default:
+		return string(role)
+	}
+}
+
+// formatRoles перечисляет роли пользователя, отмечая активную
+func formatRoles(u *user.User) string {
+	roles := u.AllRoles()
+	labels := make([]string, 0, len(roles))
+	for _, role := range roles {
+		label := roleLabel(role)
+		if len(roles) > 1 && role == u.ActiveRole {
+			label += " (активна)"
+		}
+		labels = append(labels, label)
+	}
+	return strings.Join(labels, ", ")
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/rooms"
+	"first-max-bot/internal/services/schedule"
+	"first-max-bot/internal/services/user"
+)
+
+const (
+	roomsFreePayload      = "rooms:free"
+	roomsProjectorPayload = "rooms:free:projector"
+
+	// maxFreeRoomsShown - больше свободных аудиторий в одном сообщении не перечисляется
+	maxFreeRoomsShown = 20
+	// Поиск на другой день без времени - на весь учебный день
+	roomsDayStartHour, roomsDayEndHour = 8, 21
+
+	roomsUsage = "Свободные аудитории: /rooms свободные [завтра | 25.10] [14:00-15:30] [проектор] [от 30]\n" +
+		"Занятость аудитории: /rooms А 302 [завтра | 25.10]"
+	noRoomSchedulesText = "Занятость аудиторий недоступна: расписание всех групп известно, только если оно загружено файлами iCalendar (/timetable)."
+)
+
+// RoomsHandler обрабатывает команду /rooms: поиск свободных аудиторий и занятость аудитории за день
+type RoomsHandler struct {
+	rooms       *rooms.Service
+	userService user.Service
+	logger      zerolog.Logger
+	now         func() time.Time
+}
+
+func NewRoomsHandler(roomService *rooms.Service, userService user.Service, logger zerolog.Logger) *RoomsHandler {
+	return &RoomsHandler{
+		rooms:       roomService,
+		userService: userService,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Handle - "/rooms свободные [день] [время] [оборудование] [от N]" или "/rooms <аудитория> [день]"
+func (h *RoomsHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	u, err := h.userService.GetUserByID(ctx, req.UserID())
+	if err != nil || u == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Ты не зарегистрирован. Используй /register для регистрации.")
+	}
+	if !u.HasCapability(user.CapabilityRooms) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Поиск аудиторий доступен сотрудникам.")
+	}
+
+	if strings.HasPrefix(req.Args, "rooms:") {
+		return h.handleCallback(ctx, req, responder)
+	}
+
+	now := h.now()
+	command, rest, _ := strings.Cut(strings.TrimSpace(req.Args), " ")
+	switch strings.ToLower(command) {
+	case "":
+		return responder.SendTextWithKeyboard(ctx, req.Recipient(), "🏫 Аудитории\n\n"+roomsUsage, h.keyboard(responder))
+	case "свободные", "свободно", "свободна", "free":
+		from, to, filter, err := parseRoomSearch(rest, now)
+		if err != nil {
+			return responder.SendText(ctx, req.Recipient(), "❌ "+err.Error()+"\n\n"+roomsUsage)
+		}
+		text, err := h.freeRoomsText(ctx, from, to, filter, now)
+		if err != nil {
+			return responder.SendText(ctx, req.Recipient(), h.errorText(err))
+		}
+		return responder.SendTextWithKeyboard(ctx, req.Recipient(), text, h.keyboard(responder))
+	default:
+		text, err := h.roomDayText(ctx, strings.TrimSpace(req.Args), now)
+		if err != nil {
+			return responder.SendText(ctx, req.Recipient(), h.errorText(err))
+		}
+		return responder.SendText(ctx, req.Recipient(), text)
+	}
+}
+
+// handleCallback - кнопки поиска на ближайшую пару: все свободные аудитории или с проектором
+func (h *RoomsHandler) handleCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	callbackID, _ := req.Metadata["callback_id"].(string)
+
+	var filter rooms.Filter
+	switch req.Args {
+	case roomsFreePayload:
+	case roomsProjectorPayload:
+		filter.Equipment = []string{"проектор"}
+	default:
+		return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Команда не распознана"})
+	}
+
+	now := h.now()
+	text, err := h.freeRoomsText(ctx, now, now.Add(schedule.LessonDuration), filter, now)
+	if err != nil {
+		return responder.AnswerCallbackWithEdit(ctx, callbackID, h.errorText(err), nil)
+	}
+	return responder.AnswerCallbackWithEdit(ctx, callbackID, text, h.keyboard(responder))
+}
+
+func (h *RoomsHandler) freeRoomsText(ctx context.Context, from, to time.Time, filter rooms.Filter, now time.Time) (string, error) {
+	free, matched, err := h.rooms.FreeRooms(ctx, from, to, filter)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🟢 Свободные аудитории на %s, %s–%s\n", formatScheduleDay(from, now), from.Format("15:04"), to.Format("15:04")))
+	if conditions := formatRoomFilter(filter); conditions != "" {
+		b.WriteString("Условия: " + conditions + "\n")
+	}
+	b.WriteString("\n")
+
+	switch {
+	case matched == 0:
+		b.WriteString("Нет аудиторий, подходящих под условия.")
+	case len(free) == 0:
+		b.WriteString(fmt.Sprintf("Все подходящие аудитории (%d) заняты.", matched))
+	}
+	for i, room := range free {
+		if i == maxFreeRoomsShown {
+			b.WriteString(fmt.Sprintf("…и еще %d. Уточни условия, например /rooms свободные проектор от 30\n", len(free)-i))
+			break
+		}
+		until := "до конца дня"
+		if !room.Until.IsZero() {
+			until = "до " + room.Until.Format("15:04")
+		}
+		b.WriteString(fmt.Sprintf("• %s — %s\n", formatRoom(room.Room), until))
+	}
+	return b.String(), nil
+}
+
+func (h *RoomsHandler) roomDayText(ctx context.Context, args string, now time.Time) (string, error) {
+	query, day := args, now
+	if i := strings.LastIndex(args, " "); i > 0 {
+		if d, ok := parseRoomDay(args[i+1:], now); ok {
+			query, day = strings.TrimSpace(args[:i]), d
+		}
+	}
+
+	room, bookings, err := h.rooms.RoomDay(ctx, query, day)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🏫 %s\n", formatRoom(room)))
+	b.WriteString(fmt.Sprintf("Занятость на %s:\n\n", formatScheduleDay(day, now)))
+	if len(bookings) == 0 {
+		b.WriteString("Занятий нет — аудитория свободна весь день.")
+		return b.String(), nil
+	}
+	busyNow := false
+	for _, booking := range bookings {
+		b.WriteString(fmt.Sprintf("%s–%s %s", booking.Start.Format("15:04"), booking.End.Format("15:04"), booking.Discipline))
+		if booking.Instructor != "" {
+			b.WriteString(" (" + booking.Instructor + ")")
+		}
+		b.WriteString("\n")
+		if !now.Before(booking.Start) && now.Before(booking.End) {
+			busyNow = true
+		}
+	}
+	if sameDay(day, now) {
+		if busyNow {
+			b.WriteString("\n🔴 Сейчас занята")
+		} else {
+			b.WriteString("\n🟢 Сейчас свободна")
+		}
+	}
+	return b.String(), nil
+}
+
+func (h *RoomsHandler) errorText(err error) string {
+	switch {
+	case errors.Is(err, rooms.ErrNoSchedules):
+		return noRoomSchedulesText
+	case errors.Is(err, rooms.ErrRoomNotFound):
+		return "❌ Такой аудитории нет в реестре. Укажи корпус и номер, например /rooms А 302"
+	default:
+		h.logger.Error().Err(err).Msg("failed to get room occupancy")
+		return "❌ Не удалось получить занятость аудиторий. Попробуй позже."
+	}
+}
+
+func (h *RoomsHandler) keyboard(responder bot.Responder) *maxbot.Keyboard {
+	keyboard := responder.NewKeyboardBuilder()
+	keyboard.AddRow().AddCallback("🟢 Свободны сейчас", schemes.POSITIVE, roomsFreePayload)
+	keyboard.AddRow().AddCallback("📽 Свободны сейчас, с проектором", schemes.DEFAULT, roomsProjectorPayload)
+	return keyboard
+}
+
+// roomSearchFillers - слова условий поиска, которые ничего не значат: "от 30 мест с проектором"
+var roomSearchFillers = map[string]bool{"мест": true, "места": true, "человек": true, "чел": true, "чел.": true, "с": true, "и": true, "на": true}
+
+// parseRoomSearch разбирает условия поиска: день ("завтра", "25.10"), время ("14:00-15:30" или начало
+// пары "14:00"), вместимость ("от 30", "30+", "30") и оборудование (остальные слова).
+// Без времени ищется на ближайшие полтора часа, а на другой день - на весь учебный день
+func parseRoomSearch(args string, now time.Time) (from, to time.Time, filter rooms.Filter, err error) {
+	day, hasTime := now, false
+	var fromMinute, toMinute int
+
+	words := strings.Fields(strings.ToLower(args))
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		switch {
+		case roomSearchFillers[word]:
+		case word == "от" && i+1 < len(words):
+			i++
+			if filter.MinCapacity, err = strconv.Atoi(strings.TrimSuffix(words[i], "+")); err != nil {
+				return from, to, filter, fmt.Errorf("не понял вместимость %q", words[i])
+			}
+		default:
+			if d, ok := parseRoomDay(word, now); ok {
+				day = d
+			} else if strings.ContainsAny(word, ":-–") {
+				if fromMinute, toMinute, err = parseRoomTime(word); err != nil {
+					return from, to, filter, err
+				}
+				hasTime = true
+			} else if n, convErr := strconv.Atoi(strings.TrimSuffix(word, "+")); convErr == nil {
+				filter.MinCapacity = n
+			} else {
+				filter.Equipment = append(filter.Equipment, word)
+			}
+		}
+	}
+
+	dayStart, _ := schedule.DayRange(day)
+	switch {
+	case hasTime:
+		from = dayStart.Add(time.Duration(fromMinute) * time.Minute)
+		to = dayStart.Add(time.Duration(toMinute) * time.Minute)
+	case sameDay(day, now):
+		from, to = now, now.Add(schedule.LessonDuration)
+	default:
+		from, to = dayStart.Add(roomsDayStartHour*time.Hour), dayStart.Add(roomsDayEndHour*time.Hour)
+	}
+	return from, to, filter, nil
+}
+
+// parseRoomTime - "14:00-15:30", "14-16" или только начало "14:00" (тогда на одну пару)
+func parseRoomTime(s string) (from, to int, err error) {
+	fromText, toText, isRange := strings.Cut(strings.ReplaceAll(s, "–", "-"), "-")
+	if from, err = parseClock(fromText); err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return from, from + int(schedule.LessonDuration/time.Minute), nil
+	}
+	if to, err = parseClock(toText); err != nil {
+		return 0, 0, err
+	}
+	if to <= from {
+		return 0, 0, fmt.Errorf("время окончания раньше начала: %s", s)
+	}
+	return from, to, nil
+}
+
+// parseClock - "14:00" или "14" в минуты от полуночи
+func parseClock(s string) (int, error) {
+	hourText, minuteText, hasMinutes := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hourText)
+	minute := 0
+	if err == nil && hasMinutes {
+		minute, err = strconv.Atoi(minuteText)
+	}
+	if err != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("не понял время %q, нужно ЧЧ:ММ", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// parseRoomDay - "сегодня", "завтра" или дата, как в /schedule
+func parseRoomDay(s string, now time.Time) (time.Time, bool) {
+	switch strings.ToLower(s) {
+	case "сегодня", "today":
+		return now, true
+	case "завтра", "tomorrow":
+		return now.AddDate(0, 0, 1), true
+	}
+	return parseScheduleDate(s, now)
+}
+
+// formatRoom - "Корпус А, ауд. 302 (60 чел.; проектор, доска)"
+func formatRoom(room rooms.Room) string {
+	var details []string
+	if room.Capacity > 0 {
+		details = append(details, fmt.Sprintf("%d чел.", room.Capacity))
+	}
+	if len(room.Equipment) > 0 {
+		details = append(details, strings.Join(room.Equipment, ", "))
+	}
+	if len(details) == 0 {
+		return room.Name()
+	}
+	return room.Name() + " (" + strings.Join(details, "; ") + ")"
+}
+
+func formatRoomFilter(filter rooms.Filter) string {
+	var conditions []string
+	if filter.MinCapacity > 0 {
+		conditions = append(conditions, fmt.Sprintf("от %d чел.", filter.MinCapacity))
+	}
+	conditions = append(conditions, filter.Equipment...)
+	return strings.Join(conditions, ", ")
+}
@@ -2,51 +2,452 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
 	"github.com/rs/zerolog"
 
 	"first-max-bot/internal/bot"
 	"first-max-bot/internal/services/schedule"
 )
 
+// Кнопки навигации: schedule:day:<дата> и schedule:week:<понедельник недели>
+const (
+	scheduleDayPrefix  = "schedule:day:"
+	scheduleWeekPrefix = "schedule:week:"
+)
+
+// noGroupText - ответ, когда расписание зависит от учебной группы, а она не выбрана
+const noGroupText = "Учебная группа не выбрана или для нее нет расписания. Выбрать группу: /group"
+
+// windowGap - перерыв, начиная с которого между занятиями показывается "окно"
+const windowGap = schedule.LessonDuration
+
+var (
+	weekdayNames      = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+	weekdayShortNames = [...]string{"Вс", "Пн", "Вт", "Ср", "Чт", "Пт", "Сб"}
+	monthNames        = [...]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+)
+
 type ScheduleHandler struct {
 	service schedule.Service
 	logger  zerolog.Logger
+	now     func() time.Time
 }
 
 func NewScheduleHandler(service schedule.Service, logger zerolog.Logger) *ScheduleHandler {
 	return &ScheduleHandler{
 		service: service,
 		logger:  logger,
+		now:     time.Now,
 	}
 }
 
+// Handle показывает расписание на день или неделю. Аргументы: "неделя", "завтра", дата (25.10, 25.10.2025)
+// или название дисциплины (/schedule мат. анализ). Кнопки ◀ / ▶ листают дни и недели в том же сообщении
 func (h *ScheduleHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	if strings.HasPrefix(req.Args, "schedule:") {
+		return h.handleCallback(ctx, req, responder)
+	}
+
 	userID := req.UserID()
-	items, err := h.service.GetSchedule(ctx, userID)
+	now := h.now()
+	arg := strings.TrimSpace(req.Args)
+
+	var (
+		text     string
+		keyboard *maxbot.Keyboard
+		err      error
+	)
+	switch lower := strings.ToLower(arg); {
+	case lower == "" || lower == "сегодня" || lower == "today":
+		text, keyboard, err = h.dayView(ctx, responder, userID, now, now)
+	case lower == "завтра" || lower == "tomorrow":
+		text, keyboard, err = h.dayView(ctx, responder, userID, now.AddDate(0, 0, 1), now)
+	case lower == "неделя" || lower == "week":
+		text, keyboard, err = h.weekView(ctx, responder, userID, now, now)
+	default:
+		if day, ok := parseScheduleDate(arg, now); ok {
+			text, keyboard, err = h.dayView(ctx, responder, userID, day, now)
+		} else {
+			text, keyboard, err = h.disciplineView(ctx, responder, userID, arg, now)
+		}
+	}
+	if errors.Is(err, schedule.ErrNoGroup) {
+		return responder.SendText(ctx, req.Recipient(), noGroupText)
+	}
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to get schedule")
 		return responder.SendText(ctx, req.Recipient(), "Не удалось получить расписание. Попробуйте позже.")
 	}
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), text, keyboard)
+}
+
+// handleCallback перерисовывает сообщение с расписанием на выбранный день или неделю
+func (h *ScheduleHandler) handleCallback(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	callbackID, _ := req.Metadata["callback_id"].(string)
+	userID := req.UserID()
+	now := h.now()
+
+	var (
+		text     string
+		keyboard *maxbot.Keyboard
+		err      error
+	)
+	switch payload := req.Args; {
+	case strings.HasPrefix(payload, scheduleDayPrefix):
+		day, parseErr := time.ParseInLocation(time.DateOnly, strings.TrimPrefix(payload, scheduleDayPrefix), now.Location())
+		if parseErr != nil {
+			return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Неизвестная дата"})
+		}
+		text, keyboard, err = h.dayView(ctx, responder, userID, day, now)
+	case strings.HasPrefix(payload, scheduleWeekPrefix):
+		week, parseErr := time.ParseInLocation(time.DateOnly, strings.TrimPrefix(payload, scheduleWeekPrefix), now.Location())
+		if parseErr != nil {
+			return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Неизвестная неделя"})
+		}
+		text, keyboard, err = h.weekView(ctx, responder, userID, week, now)
+	default:
+		return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Команда не распознана"})
+	}
+	if errors.Is(err, schedule.ErrNoGroup) {
+		return responder.AnswerCallbackWithEdit(ctx, callbackID, noGroupText, nil)
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("payload", req.Args).Msg("failed to get schedule")
+		return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: "Не удалось получить расписание. Попробуйте позже."})
+	}
+	return responder.AnswerCallbackWithEdit(ctx, callbackID, text, keyboard)
+}
+
+// dayView - занятия за день с перерывами между ними; для сегодняшнего дня отмечены текущее и следующее занятия
+func (h *ScheduleHandler) dayView(ctx context.Context, responder bot.Responder, userID string, day, now time.Time) (string, *maxbot.Keyboard, error) {
+	dayStart, dayEnd := schedule.DayRange(day)
+	keyboard := h.dayKeyboard(responder, dayStart, now)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📅 Расписание на %s\n\n", formatScheduleDay(dayStart, now)))
+
+	items, err := h.service.GetSchedule(ctx, userID, dayStart, dayEnd)
+	if errors.Is(err, schedule.ErrOutOfRange) {
+		b.WriteString("На эту дату расписания в системе университета нет.")
+		return b.String(), keyboard, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	today := sameDay(dayStart, now)
+	if today {
+		if next := h.nextLesson(ctx, userID, items, now); next != nil {
+			b.WriteString(formatNextLesson(*next, now) + "\n\n")
+		}
+	}
 
 	if len(items) == 0 {
-		return responder.SendText(ctx, req.Recipient(), "Расписание на сегодня пустое. Используйте /contact, если нужен совет.")
+		b.WriteString("Занятий нет 🎉\n")
+	}
+	nextMarked := false
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString(formatBreak(items[i-1].End(), item.Time))
+		}
+		marker := "•"
+		switch {
+		case today && !now.Before(item.Time) && now.Before(item.End()):
+			marker = "🟢"
+		case today && !nextMarked && item.Time.After(now):
+			marker = "⏭"
+			nextMarked = true
+		}
+		b.WriteString(fmt.Sprintf("%s %s–%s — %s\n", marker, item.Time.Format("15:04"), item.End().Format("15:04"), item.Discipline))
+		b.WriteString(formatLessonDetails(item))
+	}
+
+	b.WriteString("\nДругая дата: /schedule ДД.ММ")
+	return b.String(), keyboard, nil
+}
+
+// weekView - занятия с понедельника по воскресенье, дни без занятий показаны явно
+func (h *ScheduleHandler) weekView(ctx context.Context, responder bot.Responder, userID string, day, now time.Time) (string, *maxbot.Keyboard, error) {
+	weekStart, weekEnd := schedule.WeekRange(day)
+	keyboard := h.weekKeyboard(responder, weekStart, now)
+
+	days, err := h.weekDays(ctx, userID, weekStart, weekEnd)
+	if err != nil {
+		return "", nil, err
 	}
 
 	var b strings.Builder
-	b.WriteString("📅 Ваше расписание на сегодня:\n\n")
+	b.WriteString(fmt.Sprintf("🗓 Неделя %s\n\n", formatWeekRange(weekStart, weekEnd.AddDate(0, 0, -1))))
+
+	if !now.Before(weekStart) && now.Before(weekEnd) {
+		var rest []schedule.Item
+		for _, d := range days {
+			rest = append(rest, d.items...)
+		}
+		if next := h.nextLesson(ctx, userID, rest, now); next != nil {
+			b.WriteString(formatNextLesson(*next, now) + "\n\n")
+		}
+	}
+
+	for _, d := range days {
+		title := fmt.Sprintf("%s, %d %s", weekdayShortNames[d.date.Weekday()], d.date.Day(), monthNames[d.date.Month()-1])
+		if sameDay(d.date, now) {
+			title += " (сегодня)"
+		}
+		b.WriteString(title + "\n")
+		switch {
+		case d.unknown:
+			b.WriteString("   нет данных\n")
+		case len(d.items) == 0:
+			b.WriteString("   занятий нет\n")
+		}
+		for _, item := range d.items {
+			line := fmt.Sprintf("   %s–%s %s", item.Time.Format("15:04"), item.End().Format("15:04"), item.Discipline)
+			if item.Location != "" {
+				line += ", " + item.Location
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n"), keyboard, nil
+}
+
+type scheduleDay struct {
+	date    time.Time
+	items   []schedule.Item
+	unknown bool // источник не знает расписания на этот день
+}
+
+// weekDays раскладывает занятия недели по дням. Если источник не отдает неделю целиком,
+// дни запрашиваются по одному, а недоступные помечаются как неизвестные
+func (h *ScheduleHandler) weekDays(ctx context.Context, userID string, weekStart, weekEnd time.Time) ([]scheduleDay, error) {
+	days := make([]scheduleDay, 0, 7)
+	for date := weekStart; date.Before(weekEnd); date = date.AddDate(0, 0, 1) {
+		days = append(days, scheduleDay{date: date})
+	}
+
+	items, err := h.service.GetSchedule(ctx, userID, weekStart, weekEnd)
+	if err == nil {
+		for _, item := range items {
+			for i := range days {
+				if sameDay(days[i].date, item.Time) {
+					days[i].items = append(days[i].items, item)
+				}
+			}
+		}
+		return days, nil
+	}
+	if !errors.Is(err, schedule.ErrOutOfRange) {
+		return nil, err
+	}
+
+	for i := range days {
+		dayStart, dayEnd := schedule.DayRange(days[i].date)
+		items, err := h.service.GetSchedule(ctx, userID, dayStart, dayEnd)
+		switch {
+		case errors.Is(err, schedule.ErrOutOfRange):
+			days[i].unknown = true
+		case err != nil:
+			return nil, err
+		default:
+			days[i].items = items
+		}
+	}
+	return days, nil
+}
+
+// nextLesson ищет ближайшее занятие после now среди items, а если их нет - в ближайшую неделю
+func (h *ScheduleHandler) nextLesson(ctx context.Context, userID string, items []schedule.Item, now time.Time) *schedule.Item {
 	for _, item := range items {
-		b.WriteString(fmt.Sprintf(
-			"• %s — %s\n  %s, %s\n  %s\n\n",
-			item.Time.Format("15:04"),
-			item.Discipline,
-			item.Instructor,
-			item.Location,
-			item.Description,
-		))
+		if item.Time.After(now) {
+			return &item
+		}
 	}
 
-	return responder.SendText(ctx, req.Recipient(), b.String())
+	_, dayEnd := schedule.DayRange(now)
+	upcoming, err := h.service.GetSchedule(ctx, userID, dayEnd, dayEnd.AddDate(0, 0, 7))
+	if err != nil {
+		if !errors.Is(err, schedule.ErrOutOfRange) {
+			h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to get next lesson")
+		}
+		return nil
+	}
+	if len(upcoming) == 0 {
+		return nil
+	}
+	return &upcoming[0]
+}
+
+// disciplineView - занятия по дисциплине сегодня; если их нет, показывается все расписание на сегодня
+func (h *ScheduleHandler) disciplineView(ctx context.Context, responder bot.Responder, userID, subject string, now time.Time) (string, *maxbot.Keyboard, error) {
+	dayStart, dayEnd := schedule.DayRange(now)
+	items, err := h.service.GetSchedule(ctx, userID, dayStart, dayEnd)
+	if err != nil {
+		return "", nil, err
+	}
+
+	filtered := filterByDiscipline(items, subject)
+	if len(filtered) == 0 {
+		text, keyboard, err := h.dayView(ctx, responder, userID, now, now)
+		return fmt.Sprintf("Сегодня занятий по «%s» нет.\n\n", subject) + text, keyboard, err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📅 Занятия по «%s» сегодня:\n\n", subject))
+	for _, item := range filtered {
+		b.WriteString(fmt.Sprintf("• %s–%s — %s\n", item.Time.Format("15:04"), item.End().Format("15:04"), item.Discipline))
+		b.WriteString(formatLessonDetails(item))
+	}
+	return b.String(), h.dayKeyboard(responder, dayStart, now), nil
+}
+
+func (h *ScheduleHandler) dayKeyboard(responder bot.Responder, day, now time.Time) *maxbot.Keyboard {
+	keyboard := responder.NewKeyboardBuilder()
+	nav := keyboard.AddRow()
+	nav.AddCallback("◀", schemes.DEFAULT, scheduleDayPrefix+day.AddDate(0, 0, -1).Format(time.DateOnly))
+	nav.AddCallback("Сегодня", schemes.DEFAULT, scheduleDayPrefix+now.Format(time.DateOnly))
+	nav.AddCallback("▶", schemes.DEFAULT, scheduleDayPrefix+day.AddDate(0, 0, 1).Format(time.DateOnly))
+	weekStart, _ := schedule.WeekRange(day)
+	keyboard.AddRow().AddCallback("🗓 Неделя", schemes.DEFAULT, scheduleWeekPrefix+weekStart.Format(time.DateOnly))
+	return keyboard
+}
+
+func (h *ScheduleHandler) weekKeyboard(responder bot.Responder, weekStart, now time.Time) *maxbot.Keyboard {
+	keyboard := responder.NewKeyboardBuilder()
+	nav := keyboard.AddRow()
+	nav.AddCallback("◀", schemes.DEFAULT, scheduleWeekPrefix+weekStart.AddDate(0, 0, -7).Format(time.DateOnly))
+	currentWeek, _ := schedule.WeekRange(now)
+	nav.AddCallback("Эта неделя", schemes.DEFAULT, scheduleWeekPrefix+currentWeek.Format(time.DateOnly))
+	nav.AddCallback("▶", schemes.DEFAULT, scheduleWeekPrefix+weekStart.AddDate(0, 0, 7).Format(time.DateOnly))
+
+	// Из текущей недели - на сегодня, из другой - на ее понедельник
+	day := weekStart
+	if weekStart.Equal(currentWeek) {
+		day = now
+	}
+	keyboard.AddRow().AddCallback("📅 День", schemes.DEFAULT, scheduleDayPrefix+day.Format(time.DateOnly))
+	return keyboard
+}
+
+// parseScheduleDate понимает 25.10, 25.10.2025 и 2025-10-25. Год без указания - текущий
+func parseScheduleDate(s string, now time.Time) (time.Time, bool) {
+	for _, layout := range []string{"02.01.2006", "2.1.2006", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range []string{"02.01", "2.1"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatScheduleDay - "среду, 22 октября (сегодня)"; винительный падеж для "Расписание на ..."
+func formatScheduleDay(day, now time.Time) string {
+	name := weekdayNames[day.Weekday()]
+	switch day.Weekday() {
+	case time.Wednesday, time.Friday, time.Saturday:
+		name = strings.TrimSuffix(name, "а") + "у"
+	}
+	text := fmt.Sprintf("%s, %d %s", name, day.Day(), monthNames[day.Month()-1])
+	if day.Year() != now.Year() {
+		text += fmt.Sprintf(" %d", day.Year())
+	}
+	switch {
+	case sameDay(day, now):
+		text += " (сегодня)"
+	case sameDay(day, now.AddDate(0, 0, 1)):
+		text += " (завтра)"
+	}
+	return text
+}
+
+// formatWeekRange - "20–26 октября" или "27 октября – 2 ноября"
+func formatWeekRange(from, to time.Time) string {
+	if from.Month() == to.Month() {
+		return fmt.Sprintf("%d–%d %s", from.Day(), to.Day(), monthNames[to.Month()-1])
+	}
+	return fmt.Sprintf("%d %s – %d %s", from.Day(), monthNames[from.Month()-1], to.Day(), monthNames[to.Month()-1])
+}
+
+func formatNextLesson(item schedule.Item, now time.Time) string {
+	when := "через " + formatSpan(item.Time.Sub(now))
+	if !sameDay(item.Time, now) {
+		when = fmt.Sprintf("%s, %d %s", weekdayShortNames[item.Time.Weekday()], item.Time.Day(), monthNames[item.Time.Month()-1])
+	}
+	text := fmt.Sprintf("⏭ Следующее занятие: %s %s (%s)", item.Time.Format("15:04"), item.Discipline, when)
+	if item.Location != "" {
+		text += "\n   " + item.Location
+	}
+	return text
+}
+
+func formatLessonDetails(item schedule.Item) string {
+	var b strings.Builder
+	if where := strings.Trim(item.Instructor+", "+item.Location, ", "); where != "" {
+		b.WriteString("   " + where + "\n")
+	}
+	if item.Description != "" {
+		b.WriteString("   " + item.Description + "\n")
+	}
+	return b.String()
+}
+
+// formatBreak - строка о перерыве между занятиями; длинный перерыв - "окно"
+func formatBreak(prevEnd, nextStart time.Time) string {
+	gap := nextStart.Sub(prevEnd)
+	switch {
+	case gap <= 0:
+		return ""
+	case gap >= windowGap:
+		return fmt.Sprintf("   🪟 Окно %s\n", formatSpan(gap))
+	default:
+		return fmt.Sprintf("   ☕ Перерыв %s\n", formatSpan(gap))
+	}
+}
+
+// formatSpan - "45 мин", "2 ч", "1 ч 20 мин"
+func formatSpan(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%d мин", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d ч", minutes/60)
+	default:
+		return fmt.Sprintf("%d ч %d мин", minutes/60, minutes%60)
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// filterByDiscipline оставляет занятия, в названии которых встречается subject (без учета регистра и пунктуации)
+func filterByDiscipline(items []schedule.Item, subject string) []schedule.Item {
+	needle := normalizeDiscipline(subject)
+	var result []schedule.Item
+	for _, item := range items {
+		if strings.Contains(normalizeDiscipline(item.Discipline), needle) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func normalizeDiscipline(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '.' || r == ',' || r == '-'
+	}), " ")
 }
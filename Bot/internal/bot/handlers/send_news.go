@@ -20,13 +20,15 @@ import (
 type SendNewsHandler struct {
 	newsService news.Service
 	userService user.Service
+	chats       state.ChatRepository // групповые чаты, подписанные на новости (может быть nil)
 	logger      zerolog.Logger
 }
 
-func NewSendNewsHandler(newsService news.Service, userService user.Service, logger zerolog.Logger) *SendNewsHandler {
+func NewSendNewsHandler(newsService news.Service, userService user.Service, chats state.ChatRepository, logger zerolog.Logger) *SendNewsHandler {
 	return &SendNewsHandler{
 		newsService: newsService,
 		userService: userService,
+		chats:       chats,
 		logger:      logger,
 	}
 }
@@ -40,7 +42,7 @@ func (h *SendNewsHandler) Handle(ctx context.Context, req *bot.Request, responde
 		return responder.SendText(ctx, req.Recipient(), "❌ Пользователь не найден")
 	}
 
-	if u.Role != user.RoleManager {
+	if !u.HasCapability(user.CapabilitySendNews) {
 		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только администраторам.")
 	}
 
@@ -61,7 +63,7 @@ func (h *SendNewsHandler) Handle(ctx context.Context, req *bot.Request, responde
 	message := "📰 **Отправка новости**\n\n"
 	message += "Введи заголовок новости:"
 
-	return responder.SendMarkdown(ctx, req.Recipient(), message)
+	return sendFlowPromptMarkdown(ctx, req, responder, message)
 }
 
 func (h *SendNewsHandler) HandleTextInput(ctx context.Context, req *bot.Request, responder bot.Responder) error {
@@ -84,7 +86,7 @@ func (h *SendNewsHandler) HandleTextInput(ctx context.Context, req *bot.Request,
 		req.UserState.UserRegistrationData["title"] = text
 		message := "✅ Заголовок сохранён.\n\n"
 		message += "Теперь введи текст новости (в markdown формате):"
-		return responder.SendText(ctx, req.Recipient(), message)
+		return sendFlowPrompt(ctx, req, responder, message)
 	}
 
 	// Если заголовок уже есть, значит это текст новости
@@ -157,6 +159,9 @@ func (h *SendNewsHandler) HandleTextInput(ctx context.Context, req *bot.Request,
 		}
 	}
 
+	// Отправляем новость в групповые чаты, подписанные на новости
+	chatsSent := h.sendToChats(ctx, responder, newsMessage)
+
 	// Очищаем состояние
 	req.UserState.UserRegistrationStep = ""
 	req.UserState.UserRegistrationData = nil
@@ -164,6 +169,9 @@ func (h *SendNewsHandler) HandleTextInput(ctx context.Context, req *bot.Request,
 	message := fmt.Sprintf("✅ Новость создана и отправлена!\n\n")
 	message += fmt.Sprintf("**%s**\n\n%s\n\n", title, content)
 	message += fmt.Sprintf("Отправлено: %d пользователей\n", sentCount)
+	if chatsSent > 0 {
+		message += fmt.Sprintf("Отправлено в чаты: %d\n", chatsSent)
+	}
 	if failedCount > 0 {
 		message += fmt.Sprintf("Ошибок: %d\n", failedCount)
 	}
@@ -171,6 +179,36 @@ func (h *SendNewsHandler) HandleTextInput(ctx context.Context, req *bot.Request,
 	return responder.SendMarkdown(ctx, req.Recipient(), message)
 }
 
+// sendToChats рассылает новость в групповые чаты с включенной подпиской и возвращает число успешных отправок
+func (h *SendNewsHandler) sendToChats(ctx context.Context, responder bot.Responder, newsMessage string) int {
+	if h.chats == nil {
+		return 0
+	}
+
+	chats, err := h.chats.ListChatSettings(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list chats")
+		return 0
+	}
+
+	sent := 0
+	for _, chat := range chats {
+		if !chat.SubscribeNews {
+			continue
+		}
+		recipient := schemes.Recipient{
+			ChatId:   chat.ChatID,
+			ChatType: schemes.CHAT,
+		}
+		if err := responder.SendMarkdown(ctx, recipient, newsMessage); err != nil {
+			h.logger.Warn().Err(err).Int64("chat_id", chat.ChatID).Msg("failed to send news to chat")
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
 func (h *SendNewsHandler) handleNewsContent(ctx context.Context, req *bot.Request, responder bot.Responder) error {
 	return h.HandleTextInput(ctx, req, responder)
 }
@@ -2,24 +2,66 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
 
 	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/campaign"
 	"first-max-bot/internal/services/user"
 )
 
+// invitePayloadPrefix - префикс payload'а персонального приглашения: start=invite_<code>
+const invitePayloadPrefix = "invite_"
+
 type StartHandler struct {
-	userService user.Service
+	userService   user.Service
+	campaigns     campaign.Service
+	payloadRoutes []payloadRoute
+	logger        zerolog.Logger
 }
 
-func NewStartHandler(userService user.Service, logger interface{}) *StartHandler {
+// payloadRoute связывает префикс payload'а deep-link'а с handler'ом сценария
+type payloadRoute struct {
+	prefix  string
+	handler bot.Handler
+}
+
+func NewStartHandler(userService user.Service, campaigns campaign.Service, logger zerolog.Logger) *StartHandler {
 	return &StartHandler{
 		userService: userService,
+		campaigns:   campaigns,
+		logger:      logger,
 	}
 }
 
+// RegisterPayload направляет /start с payload, начинающимся с prefix, в указанный handler.
+// Например, start=openday_2026_12 с префиксом "openday" открывает сценарий дня открытых дверей
+func (h *StartHandler) RegisterPayload(prefix string, handler bot.Handler) {
+	h.payloadRoutes = append(h.payloadRoutes, payloadRoute{prefix: prefix, handler: handler})
+}
+
+// HandleBotStarted обрабатывает нажатие "Начать" как /start: payload deep-link'а уже передан в req.Args
+func (h *StartHandler) HandleBotStarted(ctx context.Context, req *bot.Request, responder bot.Responder, _ *schemes.BotStartedUpdate) error {
+	return h.Handle(ctx, req, responder)
+}
+
 func (h *StartHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
 	userID := req.UserID()
+
+	payload := strings.TrimSpace(req.Args)
+	if payload != "" {
+		h.trackScan(ctx, payload, userID)
+		if route := h.resolvePayload(payload); route != nil {
+			h.logger.Info().Str("user_id", userID).Str("payload", payload).Str("route", route.prefix).Msg("start payload routed")
+			routed := *req
+			routed.Args = ""
+			return route.handler.Handle(ctx, &routed, responder)
+		}
+	}
 	
 	// Проверяем, зарегистрирован ли пользователь
 	existingUser, err := h.userService.GetUserByID(ctx, userID)
@@ -35,6 +77,9 @@ func (h *StartHandler) Handle(ctx context.Context, req *bot.Request, responder b
 Для начала работы нужно пройти регистрацию. Это займет всего пару минут!
 
 Нажми /register чтобы начать регистрацию.`
+		if strings.HasPrefix(payload, invitePayloadPrefix) {
+			message = "✉️ Тебя пригласили в MAX Helper!\n\n" + message
+		}
 		return responder.SendText(ctx, req.Recipient(), message)
 	}
 	
@@ -46,7 +91,7 @@ func (h *StartHandler) showWelcome(ctx context.Context, responder bot.Responder,
 	var message string
 	
 	if u != nil {
-		roleLabel := h.getRoleLabel(u.Role)
+		roleLabel := formatRoles(u)
 		message = fmt.Sprintf(`👋 Привет, %s %s!
 
 Твоя роль: %s
@@ -54,7 +99,7 @@ func (h *StartHandler) showWelcome(ctx context.Context, responder bot.Responder,
 Вот чем я могу помочь:`, u.FirstName, u.LastName, roleLabel)
 		
 		// Показываем команды в зависимости от роли
-		commands := user.GetCommandsForRole(u.Role)
+		commands := u.Commands()
 		for _, cmd := range commands {
 			message += fmt.Sprintf("\n• %s — %s", cmd.Command, cmd.Description)
 		}
@@ -71,17 +116,28 @@ func (h *StartHandler) showWelcome(ctx context.Context, responder bot.Responder,
 	return responder.SendText(ctx, req.Recipient(), message)
 }
 
-func (h *StartHandler) getRoleLabel(role user.Role) string {
-	switch role {
-	case user.RoleApplicant:
-		return "Абитуриент"
-	case user.RoleStudent:
-		return "Студент"
-	case user.RoleEmployee:
-		return "Сотрудник"
-	case user.RoleManager:
-		return "Руководитель"
-	default:
-		return string(role)
+// resolvePayload ищет сценарий для payload'а. Префикс должен совпадать целиком
+// или отделяться от остальной части payload'а символом "_"
+func (h *StartHandler) resolvePayload(payload string) *payloadRoute {
+	for i := range h.payloadRoutes {
+		prefix := h.payloadRoutes[i].prefix
+		if payload == prefix || strings.HasPrefix(payload, prefix+"_") {
+			return &h.payloadRoutes[i]
+		}
+	}
+	return nil
+}
+
+// trackScan засчитывает переход по ссылке кампании, если payload - код известной кампании
+func (h *StartHandler) trackScan(ctx context.Context, payload, userID string) {
+	if h.campaigns == nil || userID == "" {
+		return
+	}
+	if err := h.campaigns.RegisterScan(ctx, payload, userID); err != nil {
+		if !errors.Is(err, campaign.ErrNotFound) {
+			h.logger.Warn().Err(err).Str("payload", payload).Msg("failed to register campaign scan")
+		}
+		return
 	}
+	h.logger.Info().Str("user_id", userID).Str("campaign", payload).Msg("campaign scan registered")
 }
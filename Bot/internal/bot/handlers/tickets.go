@@ -34,7 +34,7 @@ func (h *TicketsHandler) Handle(ctx context.Context, req *bot.Request, responder
 	// Проверяем, что пользователь - руководитель
 	userID := req.UserID()
 	u, err := h.userService.GetUserByID(ctx, userID)
-	if err != nil || u == nil || u.Role != user.RoleManager {
+	if err != nil || u == nil || !u.HasCapability(user.CapabilityTickets) {
 		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только руководителям.")
 	}
 
@@ -159,7 +159,7 @@ func (h *TicketsHandler) handleTicketCallback(ctx context.Context, req *bot.Requ
 		req.UserState.UserRegistrationStep = "ticket_reply"
 
 		message := "✍️ Напиши ответ на обращение:"
-		return responder.SendText(ctx, req.Recipient(), message)
+		return sendFlowPrompt(ctx, req, responder, message)
 	}
 
 	if strings.HasPrefix(payload, "ticket:close:") {
@@ -168,11 +168,13 @@ func (h *TicketsHandler) handleTicketCallback(ctx context.Context, req *bot.Requ
 		// Получаем тикет перед закрытием, чтобы отправить уведомление пользователю
 		ticket, err := h.supportService.GetTicket(ctx, ticketID)
 		if err != nil || ticket == nil {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Обращение не найдено")
 		}
 		
 		err = h.supportService.UpdateTicketStatus(ctx, ticketID, "closed")
 		if err != nil {
+			bot.ActionFailed(responder)
 			return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при закрытии обращения")
 		}
 
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/schedule"
+	"first-max-bot/internal/services/user"
+)
+
+// maxTimetableSize - расписание на семестр занимает сотни килобайт, больше не принимаем
+const maxTimetableSize = 5 << 20
+
+// maxSkippedShown - сколько пропущенных событий перечислять в ответе на загрузку
+const maxSkippedShown = 5
+
+// TimetableHandler обрабатывает команду /timetable: загрузку расписаний iCalendar руководителями
+type TimetableHandler struct {
+	userService user.Service
+	calendars   schedule.CalendarStore // nil - расписание берется не из файлов
+	client      *http.Client
+	logger      zerolog.Logger
+}
+
+func NewTimetableHandler(userService user.Service, scheduleService schedule.Service, logger zerolog.Logger) *TimetableHandler {
+	calendars, _ := scheduleService.(schedule.CalendarStore)
+	return &TimetableHandler{
+		userService: userService,
+		calendars:   calendars,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		logger:      logger,
+	}
+}
+
+func (h *TimetableHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	userID := req.UserID()
+	u, err := h.userService.GetUserByID(ctx, userID)
+	if err != nil || u == nil || !u.HasCapability(user.CapabilityTimetable) {
+		return responder.SendText(ctx, req.Recipient(), "❌ Эта команда доступна только руководителям.")
+	}
+	if h.calendars == nil {
+		return responder.SendText(ctx, req.Recipient(), "❌ Расписание сейчас берется не из файлов. Чтобы загружать .ics, задай SCHEDULE_ICAL_DIR.")
+	}
+
+	file, ok := timetableAttachment(req)
+	if !ok {
+		return h.showUsage(ctx, req, responder)
+	}
+
+	name := strings.TrimSpace(req.Args)
+	if name == "" {
+		name = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+	}
+	if name == "" {
+		return responder.SendText(ctx, req.Recipient(), "❌ Укажи группу или преподавателя: /timetable ИВТ-21 с файлом .ics во вложении.")
+	}
+
+	data, err := h.download(ctx, file.Payload.Url)
+	if err != nil {
+		h.logger.Error().Err(err).Str("file", file.Filename).Msg("failed to download timetable")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось скачать файл. Попробуй отправить его еще раз.")
+	}
+
+	cal, err := h.calendars.ImportCalendar(ctx, name, data)
+	switch {
+	case errors.Is(err, schedule.ErrInvalidName):
+		return responder.SendText(ctx, req.Recipient(), "❌ Название не должно содержать символы / \\ : * ? \" < > |")
+	case errors.Is(err, schedule.ErrInvalidCalendar):
+		return responder.SendText(ctx, req.Recipient(), fmt.Sprintf("❌ Файл не похож на расписание iCalendar: %v", err))
+	case err != nil:
+		h.logger.Error().Err(err).Str("name", name).Msg("failed to import timetable")
+		return responder.SendText(ctx, req.Recipient(), "❌ Не удалось сохранить расписание. Попробуй позже.")
+	}
+
+	h.logger.Info().Str("user_id", userID).Str("name", name).Int("events", cal.Events()).Int("skipped", len(cal.Skipped)).Msg("timetable imported")
+	message := fmt.Sprintf("✅ Расписание «%s» загружено, событий в файле: %d.\n\nСтуденты выбирают группу командой /group.", name, cal.Events())
+	if len(cal.Skipped) > 0 {
+		message += fmt.Sprintf("\n\n⚠️ Пропущено событий с неподдерживаемым повторением: %d", len(cal.Skipped))
+		for i, skipped := range cal.Skipped {
+			if i == maxSkippedShown {
+				message += "\n…"
+				break
+			}
+			message += "\n• " + skipped
+		}
+		message += "\nПоддерживаются повторения по дням недели (FREQ=WEEKLY;BYDAY=MO,WE) и по числу месяца из даты начала."
+	}
+	return responder.SendText(ctx, req.Recipient(), message)
+}
+
+func (h *TimetableHandler) showUsage(ctx context.Context, req *bot.Request, responder bot.Responder) error {
+	var message strings.Builder
+	message.WriteString("📅 Загрузка расписания\n\n")
+	message.WriteString("Отправь файл .ics с подписью /timetable <группа или преподаватель>, например /timetable ИВТ-21. ")
+	message.WriteString("Без названия используется имя файла. Файл с тем же названием заменяется.\n")
+
+	names, err := h.calendars.CalendarNames(ctx)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list timetables")
+		return responder.SendText(ctx, req.Recipient(), message.String())
+	}
+	if len(names) == 0 {
+		message.WriteString("\nРасписаний пока нет.")
+	} else {
+		message.WriteString("\nЗагруженные расписания:\n")
+		for _, name := range names {
+			message.WriteString(fmt.Sprintf("• %s\n", name))
+		}
+	}
+	return responder.SendText(ctx, req.Recipient(), message.String())
+}
+
+func (h *TimetableHandler) download(ctx context.Context, url string) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxTimetableSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTimetableSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxTimetableSize)
+	}
+	return data, nil
+}
+
+// timetableAttachment возвращает первый файл из сообщения, у которого есть ссылка для скачивания
+func timetableAttachment(req *bot.Request) (schemes.FileAttachment, bool) {
+	if req.Update == nil {
+		return schemes.FileAttachment{}, false
+	}
+	for _, raw := range req.Update.Message.Body.RawAttachments {
+		var att schemes.FileAttachment
+		if err := json.Unmarshal(raw, &att); err != nil {
+			continue
+		}
+		if att.Type == schemes.AttachmentFile && att.Payload.Url != "" {
+			return att, true
+		}
+	}
+	for _, a := range req.Update.Message.Body.Attachments {
+		if att, ok := a.(*schemes.FileAttachment); ok && att.Payload.Url != "" {
+			return *att, true
+		}
+	}
+	return schemes.FileAttachment{}, false
+}
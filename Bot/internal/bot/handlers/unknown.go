@@ -2,16 +2,210 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
 
 	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/user"
 )
 
-type FallbackHandler struct{}
+// maxSuggestions - сколько вариантов команд предлагать пользователю
+const maxSuggestions = 3
+
+// commandSynonyms - ключевые слова, по которым свободный текст сопоставляется с командами
+var commandSynonyms = map[string][]string{
+	"/schedule":       {"расписание", "пары", "занятия", "лекции"},
+	"/myschedule":     {"расписание", "пары", "занятия"},
+	"/library":        {"библиотека", "книга", "книги", "книгу"},
+	"/library_manage": {"библиотека", "выдача"},
+	"/deanery":        {"деканат", "справка", "заявление"},
+	"/dormitory":      {"общежитие", "общага"},
+	"/contact":        {"поддержка", "обращение", "жалоба"},
+	"/mytickets":      {"обращения"},
+	"/reminder":       {"напоминание", "напомни", "напомнить"},
+	"/ask":            {"вопрос", "спросить"},
+	"/news":           {"новости", "новость"},
+	"/send_news":      {"рассылка", "новость"},
+	"/admission":      {"поступление", "поступить", "приемная", "приёмная"},
+	"/programs":       {"программы", "направления", "специальности"},
+	"/openday":        {"открытых", "экскурсия"},
+	"/moodle":         {"moodle", "мудл", "курсы"},
+	"/businesstrip":   {"командировка", "командировки"},
+	"/vacation":       {"отпуск"},
+	"/office":         {"офис", "пропуск"},
+	"/dashboard":      {"дашборд", "статистика"},
+	"/analytics":      {"аналитика"},
+	"/tickets":        {"обращения"},
+	"/documents":      {"заявления"},
+	"/role":           {"роль", "роли"},
+	"/links":          {"ссылка", "ссылки", "qr"},
+	"/help":           {"помощь", "команды", "меню"},
+}
 
-func NewFallbackHandler() *FallbackHandler {
-	return &FallbackHandler{}
+// FallbackHandler отвечает на неизвестные команды и текст, предлагая похожие команды,
+// доступные пользователю по его ролям
+type FallbackHandler struct {
+	userService user.Service
+	logger      zerolog.Logger
+}
+
+func NewFallbackHandler(userService user.Service, logger zerolog.Logger) *FallbackHandler {
+	return &FallbackHandler{
+		userService: userService,
+		logger:      logger,
+	}
 }
 
 func (h *FallbackHandler) Handle(ctx context.Context, req *bot.Request, responder bot.Responder) error {
-	return responder.SendText(ctx, req.Recipient(), "Я пока не знаю такой команды. Попробуй /help, чтобы посмотреть, что я уже умею.")
+	suggestions := suggestCommands(req.Text(), h.allowedCommands(ctx, req.UserID()))
+	if len(suggestions) == 0 {
+		return responder.SendText(ctx, req.Recipient(), "Я пока не знаю такой команды. Попробуй /help, чтобы посмотреть, что я уже умею.")
+	}
+
+	keyboard := responder.NewKeyboardBuilder()
+	for _, cmd := range suggestions {
+		keyboard.AddRow().AddCallback(fmt.Sprintf("%s — %s", cmd.Command, cmd.Description), schemes.DEFAULT, "cmd:"+cmd.Command)
+	}
+
+	return responder.SendTextWithKeyboard(ctx, req.Recipient(), "Я пока не знаю такой команды. Возможно, ты имел в виду:", keyboard)
+}
+
+// allowedCommands возвращает команды, доступные пользователю; незарегистрированным - регистрацию и справку
+func (h *FallbackHandler) allowedCommands(ctx context.Context, userID string) []user.CommandInfo {
+	if userID != "" {
+		u, err := h.userService.GetUserByID(ctx, userID)
+		if err != nil {
+			h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to get user for suggestions")
+		}
+		if u != nil {
+			return u.Commands()
+		}
+	}
+
+	commands := []user.CommandInfo{{Command: "/register", Description: "Регистрация"}}
+	return append(commands, user.GetCommandsForRole("")...)
+}
+
+// suggestCommands ранжирует доступные команды по близости к вводу пользователя:
+// опечатки в командах ("/shedule") - по расстоянию Левенштейна, свободный текст ("расписание") - по ключевым словам
+func suggestCommands(input string, commands []user.CommandInfo) []user.CommandInfo {
+	words := splitWords(input)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		cmd   user.CommandInfo
+		score int
+		order int
+	}
+
+	var ranked []scored
+	for i, cmd := range commands {
+		name := strings.TrimPrefix(cmd.Command, "/")
+		best := -1
+		for _, word := range words {
+			// Сравниваем с названием команды: "/shedule" -> "schedule"
+			if d, ok := closeEnough(word, name); ok && (best < 0 || d < best) {
+				best = d
+			}
+			// Сравниваем с ключевыми словами команды
+			for _, synonym := range commandSynonyms[cmd.Command] {
+				if d, ok := matchKeyword(word, synonym); ok && (best < 0 || d < best) {
+					best = d
+				}
+			}
+		}
+		if best >= 0 {
+			ranked = append(ranked, scored{cmd: cmd, score: best, order: i})
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score < ranked[j].score
+		}
+		return ranked[i].order < ranked[j].order
+	})
+
+	var result []user.CommandInfo
+	for _, r := range ranked {
+		if len(result) == maxSuggestions {
+			break
+		}
+		result = append(result, r.cmd)
+	}
+	return result
+}
+
+// splitWords разбивает ввод на слова в нижнем регистре, отбрасывая "/" и знаки препинания
+func splitWords(input string) []string {
+	return strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// matchKeyword сопоставляет слово с ключевым словом: общая основа (падежи, "книгу" - "книга") дает 0,
+// иначе допускается опечатка
+func matchKeyword(word, keyword string) (int, bool) {
+	if word == keyword {
+		return 0, true
+	}
+	if commonPrefixLen(word, keyword) >= 4 && abs(len([]rune(word))-len([]rune(keyword))) <= 3 {
+		return 0, true
+	}
+	return closeEnough(word, keyword)
+}
+
+// closeEnough проверяет, что слова отличаются не больше чем на треть длины (минимум одна правка)
+func closeEnough(a, b string) (int, bool) {
+	d := levenshtein(a, b)
+	limit := len([]rune(b)) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	return d, d <= limit
+}
+
+func commonPrefixLen(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for n < len(ra) && n < len(rb) && ra[n] == rb[n] {
+		n++
+	}
+	return n
+}
+
+// levenshtein считает расстояние редактирования между строками (по символам, не байтам)
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
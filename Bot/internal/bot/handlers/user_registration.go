@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -12,18 +13,21 @@ import (
 	"github.com/max-messenger/max-bot-api-client-go/schemes"
 
 	"first-max-bot/internal/bot"
+	"first-max-bot/internal/services/campaign"
 	"first-max-bot/internal/services/user"
 	"first-max-bot/internal/state"
 )
 
 type UserRegistrationHandler struct {
 	userService user.Service
+	campaigns   campaign.Service // для учета регистраций, пришедших по ссылкам кампаний (может быть nil)
 	logger      zerolog.Logger
 }
 
-func NewUserRegistrationHandler(userService user.Service, logger zerolog.Logger) *UserRegistrationHandler {
+func NewUserRegistrationHandler(userService user.Service, campaigns campaign.Service, logger zerolog.Logger) *UserRegistrationHandler {
 	return &UserRegistrationHandler{
 		userService: userService,
+		campaigns:   campaigns,
 		logger:      logger,
 	}
 }
@@ -86,15 +90,36 @@ func (h *UserRegistrationHandler) handleTextInput(ctx context.Context, req *bot.
 		}
 		// Сохраняем email и переходим к подтверждению
 		userState.UserRegistrationData["email"] = text
+
+		// Код подтверждения отправляет бэкенд университета, если сервис пользователей с ним работает
+		if verifier, ok := h.userService.(user.Verifier); ok {
+			sent, err := verifier.RequestCode(ctx, h.newUser(req.UserID(), userState))
+			if err != nil {
+				h.logger.Error().Err(err).Str("user_id", req.UserID()).Msg("failed to request verification code")
+				return responder.SendText(ctx, req.Recipient(), "❌ Не удалось отправить код подтверждения. Проверь email или попробуй позже.")
+			}
+			if !sent {
+				// Email уже подтвержден в системе университета
+				userState.UserRegistrationStep = "completed"
+				return h.showCompletion(ctx, req, responder, userState)
+			}
+		}
 		userState.UserRegistrationStep = "email_verification"
 		return h.showEmailVerificationStep(ctx, req, responder, userState)
 
 	case "email_verification":
 		// Проверяем код подтверждения
 		code := strings.TrimSpace(text)
-		expectedCode := "1111" // По умолчанию код 1111
 
-		if code != expectedCode {
+		if verifier, ok := h.userService.(user.Verifier); ok {
+			if err := verifier.VerifyCode(ctx, req.UserID(), code); err != nil {
+				if errors.Is(err, user.ErrInvalidCode) {
+					return responder.SendText(ctx, req.Recipient(), "❌ Неверный код подтверждения. Попробуй ещё раз.")
+				}
+				h.logger.Error().Err(err).Str("user_id", req.UserID()).Msg("failed to verify code")
+				return responder.SendText(ctx, req.Recipient(), "❌ Не удалось проверить код. Попробуй позже.")
+			}
+		} else if code != "1111" { // По умолчанию код 1111
 			return responder.SendText(ctx, req.Recipient(), "❌ Неверный код подтверждения. Попробуй ещё раз.")
 		}
 
@@ -125,7 +150,7 @@ func (h *UserRegistrationHandler) startRegistration(ctx context.Context, req *bo
 		text := fmt.Sprintf("✅ Ты уже зарегистрирован!\n\n")
 		text += fmt.Sprintf("Имя: %s %s\n", existingUser.FirstName, existingUser.LastName)
 		text += fmt.Sprintf("Email: %s\n", existingUser.Email)
-		text += fmt.Sprintf("Роль: %s\n\n", h.getRoleLabel(existingUser.Role))
+		text += fmt.Sprintf("Роль: %s\n\n", formatRoles(existingUser))
 		text += "Если хочешь изменить данные, напиши /register ещё раз."
 		return responder.SendText(ctx, req.Recipient(), text)
 	}
@@ -325,6 +350,40 @@ func (h *UserRegistrationHandler) showEmailVerificationStep(ctx context.Context,
 func (h *UserRegistrationHandler) showCompletion(ctx context.Context, req *bot.Request, responder bot.Responder, userState *state.UserState) error {
 	userID := req.UserID()
 
+	createdUser, err := h.userService.CreateUser(ctx, h.newUser(userID, userState))
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to create user")
+		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при сохранении данных. Попробуй позже.")
+	}
+
+	// Засчитываем регистрацию кампании, по ссылке которой пришел пользователь
+	if h.campaigns != nil {
+		if err := h.campaigns.RegisterConversion(ctx, userID); err != nil {
+			h.logger.Warn().Err(err).Str("user_id", userID).Msg("failed to register campaign conversion")
+		}
+	}
+
+	// Перечитываем пользователя: роли может назначить бэкенд
+	if u, err := h.userService.GetUserByID(ctx, userID); err == nil && u != nil {
+		createdUser = u
+	}
+
+	var result strings.Builder
+	result.WriteString("✅ Регистрация завершена!\n\n")
+	result.WriteString("📋 Твои данные:\n")
+	result.WriteString(fmt.Sprintf("• Имя: %s %s\n", createdUser.FirstName, createdUser.LastName))
+	result.WriteString(fmt.Sprintf("• Возраст: %d лет\n", createdUser.Age))
+	result.WriteString(fmt.Sprintf("• Пол: %s\n", h.getGenderLabel(createdUser.Gender)))
+	result.WriteString(fmt.Sprintf("• Email: %s\n", createdUser.Email))
+	result.WriteString(fmt.Sprintf("• Роль: %s\n\n", formatRoles(createdUser)))
+	result.WriteString("Теперь ты можешь пользоваться всеми возможностями бота! 🎉")
+
+	// Удаляем старое сообщение и отправляем новое
+	return h.deleteAndSendNew(ctx, req, responder, result.String(), nil)
+}
+
+// newUser собирает пользователя из данных, введенных при регистрации
+func (h *UserRegistrationHandler) newUser(userID string, userState *state.UserState) user.User {
 	// Парсим возраст (теперь это просто число)
 	ageStr := userState.UserRegistrationData["age"]
 	age, _ := strconv.Atoi(ageStr)
@@ -332,7 +391,6 @@ func (h *UserRegistrationHandler) showCompletion(ctx context.Context, req *bot.R
 		age = 20 // Значение по умолчанию, если не удалось распарсить
 	}
 
-	// Создаем пользователя
 	newUser := user.User{
 		UserID:    userID,
 		FirstName: userState.UserRegistrationData["first_name"],
@@ -355,27 +413,7 @@ func (h *UserRegistrationHandler) showCompletion(ctx context.Context, req *bot.R
 		newUser.Role = user.RoleApplicant
 	}
 
-	createdUser, err := h.userService.CreateUser(ctx, newUser)
-	if err != nil {
-		h.logger.Error().Err(err).Msg("failed to create user")
-		return responder.SendText(ctx, req.Recipient(), "❌ Ошибка при сохранении данных. Попробуй позже.")
-	}
-
-	// Получаем роль пользователя (может быть изменена бэкендом)
-	role, _ := h.userService.GetUserRole(ctx, userID)
-
-	var result strings.Builder
-	result.WriteString("✅ Регистрация завершена!\n\n")
-	result.WriteString("📋 Твои данные:\n")
-	result.WriteString(fmt.Sprintf("• Имя: %s %s\n", createdUser.FirstName, createdUser.LastName))
-	result.WriteString(fmt.Sprintf("• Возраст: %d лет\n", createdUser.Age))
-	result.WriteString(fmt.Sprintf("• Пол: %s\n", h.getGenderLabel(createdUser.Gender)))
-	result.WriteString(fmt.Sprintf("• Email: %s\n", createdUser.Email))
-	result.WriteString(fmt.Sprintf("• Роль: %s\n\n", h.getRoleLabel(role)))
-	result.WriteString("Теперь ты можешь пользоваться всеми возможностями бота! 🎉")
-
-	// Удаляем старое сообщение и отправляем новое
-	return h.deleteAndSendNew(ctx, req, responder, result.String(), nil)
+	return newUser
 }
 
 func (h *UserRegistrationHandler) handleBack(ctx context.Context, req *bot.Request, responder bot.Responder, userState *state.UserState, callbackID string) error {
@@ -429,21 +467,6 @@ func (h *UserRegistrationHandler) getGenderLabel(gender string) string {
 	}
 }
 
-func (h *UserRegistrationHandler) getRoleLabel(role user.Role) string {
-	switch role {
-	case user.RoleApplicant:
-		return "Абитуриент"
-	case user.RoleStudent:
-		return "Студент"
-	case user.RoleEmployee:
-		return "Сотрудник"
-	case user.RoleManager:
-		return "Руководитель"
-	default:
-		return string(role)
-	}
-}
-
 // respondWithKeyboard отправляет или редактирует сообщение с клавиатурой
 func (h *UserRegistrationHandler) respondWithKeyboard(ctx context.Context, req *bot.Request, responder bot.Responder, text string, keyboard *maxbot.Keyboard) error {
 	callbackID := ""
@@ -0,0 +1,216 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	maxbot "github.com/max-messenger/max-bot-api-client-go"
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+
+	"first-max-bot/internal/idempotency"
+)
+
+// IdempotencyScope определяет, какие нажатия считаются повтором одного действия
+type IdempotencyScope int
+
+const (
+	// ScopeMessage - повторное нажатие той же кнопки в том же сообщении (двойной клик)
+	ScopeMessage IdempotencyScope = iota
+	// ScopeGlobal - нажатие той же кнопки кем угодно (например, закрытие обращения двумя руководителями)
+	ScopeGlobal
+)
+
+// pendingTTL - сколько держится ключ выполняющегося действия, если экземпляр упал, не завершив его
+const pendingTTL = 5 * time.Minute
+
+// idempotentRoute - callback'и с префиксом payload, действие которых выполняется один раз
+type idempotentRoute struct {
+	prefix         string
+	scope          IdempotencyScope
+	ttl            time.Duration
+	removeKeyboard bool
+}
+
+// IdempotentOption настраивает идемпотентный callback
+type IdempotentOption func(*idempotentRoute)
+
+// WithKeyboardRemoval убирает кнопки из исходного сообщения после успешного выполнения действия,
+// если handler сам не отредактировал сообщение
+func WithKeyboardRemoval() IdempotentOption {
+	return func(r *idempotentRoute) {
+		r.removeKeyboard = true
+	}
+}
+
+// ActionFailed сообщает, что действие идемпотентного callback'а не выполнено, хотя handler ответил
+// пользователю и вернул nil (например, книгу уже заказали или хранилище вернуло ошибку). Ключ действия
+// снимается, чтобы кнопку можно было нажать снова, а кнопки в исходном сообщении остаются.
+// Для callback'ов, не отмеченных через MarkIdempotent, ничего не делает
+func ActionFailed(responder Responder) {
+	if r, ok := responder.(interface{ failAction() }); ok {
+		r.failAction()
+	}
+}
+
+// UseIdempotencyStore задает хранилище ключей для callback'ов, отмеченных через MarkIdempotent
+func (r *Router) UseIdempotencyStore(store idempotency.Store) {
+	r.idempotency = store
+}
+
+// MarkIdempotent отмечает callback'и с префиксом payload (например, "book:borrow:") как изменяющие данные.
+// Повтор в пределах scope в течение ttl не выполняет действие, а отвечает уведомлением
+func (r *Router) MarkIdempotent(prefix string, scope IdempotencyScope, ttl time.Duration, opts ...IdempotentOption) {
+	route := idempotentRoute{prefix: prefix, scope: scope, ttl: ttl}
+	for _, opt := range opts {
+		opt(&route)
+	}
+	r.idempotent = append(r.idempotent, route)
+}
+
+// idempotentRouteFor возвращает правило для payload; при нескольких подходящих выбирается самый длинный префикс
+func (r *Router) idempotentRouteFor(payload string) (idempotentRoute, bool) {
+	var (
+		best  idempotentRoute
+		found bool
+	)
+	for _, route := range r.idempotent {
+		if strings.HasPrefix(payload, route.prefix) && (!found || len(route.prefix) > len(best.prefix)) {
+			best, found = route, true
+		}
+	}
+	return best, found
+}
+
+// wrapIdempotent оборачивает handler callback'а проверкой ключа, если payload отмечен как идемпотентный
+func (r *Router) wrapIdempotent(payload string, handler Handler) Handler {
+	if r.idempotency == nil || handler == nil {
+		return handler
+	}
+	route, ok := r.idempotentRouteFor(payload)
+	if !ok {
+		return handler
+	}
+	return &idempotentHandler{store: r.idempotency, route: route, next: handler}
+}
+
+type idempotentHandler struct {
+	store idempotency.Store
+	route idempotentRoute
+	next  Handler
+}
+
+func (h *idempotentHandler) Handle(ctx context.Context, req *Request, responder Responder) error {
+	callbackID, _ := req.Metadata["callback_id"].(string)
+	key := h.key(req)
+
+	started, status, err := h.store.Begin(ctx, key, callbackID, pendingTTL)
+	if err != nil {
+		// Хранилище недоступно - выполняем действие, защита от повторов в этом случае не работает
+		return h.next.Handle(ctx, req, responder)
+	}
+	if !started {
+		notification := "⏳ Действие уже выполняется"
+		if status == idempotency.StatusDone {
+			notification = "✅ Действие уже выполнено"
+		}
+		return responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: notification})
+	}
+
+	cr := &callbackResponder{Responder: responder, callbackID: callbackID}
+	if err := h.next.Handle(ctx, req, cr); err != nil || cr.failed {
+		if abortErr := h.store.Abort(ctx, key, callbackID); abortErr != nil {
+			err = errors.Join(err, abortErr)
+		}
+		if flushErr := cr.flush(ctx, nil); err == nil {
+			err = flushErr
+		}
+		return err
+	}
+
+	completeErr := h.store.Complete(ctx, key, callbackID, h.route.ttl)
+
+	// Убираем кнопки, если handler не отредактировал сообщение сам
+	var edit *schemes.NewMessageBody
+	if h.route.removeKeyboard {
+		if text, _ := req.Metadata["message_text"].(string); text != "" {
+			edit = &schemes.NewMessageBody{Text: text}
+		}
+	}
+	if err := cr.flush(ctx, edit); err != nil {
+		return err
+	}
+	return completeErr
+}
+
+// key строит ключ действия из payload и, для ScopeMessage, сообщения с кнопкой
+func (h *idempotentHandler) key(req *Request) string {
+	payload := req.Args
+	if h.route.scope == ScopeGlobal {
+		return "callback:" + payload
+	}
+
+	if messageID, _ := req.Metadata["message_id"].(string); messageID != "" {
+		return "callback:" + messageID + ":" + payload
+	}
+	// Без id сообщения различаем повторы по пользователю
+	return "callback:user:" + req.UserID() + ":" + payload
+}
+
+// callbackResponder откладывает пустой ответ на callback до окончания действия,
+// чтобы исходное сообщение можно было отредактировать одним ответом
+type callbackResponder struct {
+	Responder
+	callbackID string
+	answered   bool
+	failed     bool // handler сообщил о неудаче через ActionFailed
+	pending    *schemes.CallbackAnswer
+}
+
+func (r *callbackResponder) failAction() {
+	r.failed = true
+}
+
+func (r *callbackResponder) AnswerCallback(ctx context.Context, callbackID string, answer *schemes.CallbackAnswer) error {
+	if callbackID != r.callbackID || r.answered {
+		return r.Responder.AnswerCallback(ctx, callbackID, answer)
+	}
+	if answer == nil {
+		answer = &schemes.CallbackAnswer{}
+	}
+	// Ответ с уведомлением или правкой сообщения отправляем сразу
+	if answer.Message != nil || answer.Notification != "" {
+		r.answered = true
+		return r.Responder.AnswerCallback(ctx, callbackID, answer)
+	}
+	r.pending = answer
+	return nil
+}
+
+func (r *callbackResponder) AnswerCallbackWithEdit(ctx context.Context, callbackID string, text string, keyboard *maxbot.Keyboard) error {
+	if callbackID == r.callbackID {
+		r.answered = true
+	}
+	return r.Responder.AnswerCallbackWithEdit(ctx, callbackID, text, keyboard)
+}
+
+// flush отправляет отложенный ответ на callback; edit, если задан, заменяет исходное сообщение
+func (r *callbackResponder) flush(ctx context.Context, edit *schemes.NewMessageBody) error {
+	if r.answered || r.callbackID == "" {
+		return nil
+	}
+	if r.pending == nil && edit == nil {
+		return nil
+	}
+	r.answered = true
+
+	answer := r.pending
+	if answer == nil {
+		answer = &schemes.CallbackAnswer{}
+	}
+	if edit != nil {
+		answer.Message = edit
+	}
+	return r.Responder.AnswerCallback(ctx, r.callbackID, answer)
+}
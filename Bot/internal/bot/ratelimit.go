@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/ratelimit"
+)
+
+// UseRateLimiter включает ограничение частоты запросов. Ошибки хранилища лимитов не блокируют запросы
+func (r *Router) UseRateLimiter(limiter ratelimit.Limiter, logger zerolog.Logger) {
+	r.limiter = limiter
+	r.limiterLogger = logger
+}
+
+// Allow проверяет общий лимит, лимит пользователя и, если command зарегистрирована, лимит команды.
+// При отказе сообщает пользователю, когда можно повторить, и возвращает false
+func (r *Router) Allow(ctx context.Context, req *Request, responder Responder, command string) bool {
+	if r.limiter == nil {
+		return true
+	}
+	if !r.HasCommand(command) {
+		command = ""
+	}
+	decision, err := r.limiter.Allow(ctx, req.UserID(), command)
+	return r.applyDecision(ctx, req, responder, command, decision, err)
+}
+
+// allowCommand проверяет только лимит команды: запрос, из которого она запущена, уже учтен в Allow
+func (r *Router) allowCommand(ctx context.Context, req *Request, responder Responder, command string) bool {
+	if r.limiter == nil {
+		return true
+	}
+	decision, err := r.limiter.AllowCommand(ctx, req.UserID(), command)
+	return r.applyDecision(ctx, req, responder, command, decision, err)
+}
+
+func (r *Router) applyDecision(ctx context.Context, req *Request, responder Responder, command string, decision ratelimit.Decision, err error) bool {
+	if err != nil {
+		r.limiterLogger.Warn().Err(err).Str("user_id", req.UserID()).Str("command", command).Msg("rate limiter failed, request allowed")
+		return true
+	}
+	if decision.Allowed {
+		return true
+	}
+
+	logger := r.limiterLogger.With().
+		Str("user_id", req.UserID()).
+		Str("command", command).
+		Str("scope", string(decision.Scope)).
+		Dur("retry_after", decision.RetryAfter).
+		Logger()
+	logger.Info().Msg("request rate limited")
+
+	// На callback отвечаем всегда, иначе у кнопки останется индикатор загрузки
+	callbackID, _ := req.Metadata["callback_id"].(string)
+	switch {
+	case callbackID != "" && decision.Notify:
+		err = responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{Notification: cooldownMessage(decision, command)})
+	case callbackID != "":
+		err = responder.AnswerCallback(ctx, callbackID, &schemes.CallbackAnswer{})
+	case decision.Notify:
+		err = responder.SendText(ctx, req.Recipient(), cooldownMessage(decision, command))
+	}
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to send cooldown message")
+	}
+	return false
+}
+
+// cooldownMessage объясняет пользователю, какое ограничение сработало и когда можно повторить
+func cooldownMessage(decision ratelimit.Decision, command string) string {
+	wait := formatWait(decision.RetryAfter)
+	switch decision.Scope {
+	case ratelimit.ScopeBlocked:
+		return fmt.Sprintf("🚫 Слишком много запросов подряд, поэтому бот временно не отвечает тебе. Попробуй через %s.", wait)
+	case ratelimit.ScopeCommand:
+		return fmt.Sprintf("⏳ Команду %s можно использовать не так часто. Попробуй через %s.", command, wait)
+	case ratelimit.ScopeGlobal:
+		return fmt.Sprintf("⏳ Бот сейчас перегружен. Попробуй через %s.", wait)
+	default:
+		return fmt.Sprintf("⏳ Ты отправляешь сообщения слишком часто. Подожди %s.", wait)
+	}
+}
+
+// formatWait форматирует ожидание по-русски с округлением вверх: "40 сек", "12 мин", "2 ч 5 мин"
+func formatWait(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%d сек", int((d+time.Second-1)/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%d мин", int((d+time.Minute-1)/time.Minute))
+	default:
+		minutes := int((d + time.Minute - 1) / time.Minute)
+		if minutes%60 == 0 {
+			return fmt.Sprintf("%d ч", minutes/60)
+		}
+		return fmt.Sprintf("%d ч %d мин", minutes/60, minutes%60)
+	}
+}
@@ -12,6 +12,7 @@ import (
 type Request struct {
 	Context   context.Context
 	Update    *schemes.MessageCreatedUpdate
+	Event     schemes.UpdateInterface // для событий, зарегистрированных через Router.OnBotStarted, OnMessageEdited и т.п.
 	Command   string
 	Args      string
 	UserState *state.UserState
@@ -83,3 +84,14 @@ func (r *Request) MessageID() string {
 	}
 	return ""
 }
+
+// IsGroupChat сообщает, пришел ли запрос из группового чата или канала
+func (r *Request) IsGroupChat() bool {
+	recipient := r.Recipient()
+	return recipient.ChatId != 0 && recipient.ChatType != "" && recipient.ChatType != schemes.DIALOG
+}
+
+// ChatID возвращает идентификатор чата, из которого пришел запрос
+func (r *Request) ChatID() int64 {
+	return r.Recipient().ChatId
+}
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"io"
 
 	"github.com/max-messenger/max-bot-api-client-go"
 	"github.com/max-messenger/max-bot-api-client-go/schemes"
@@ -14,9 +15,12 @@ type Responder interface {
 	SendTextWithKeyboard(ctx context.Context, recipient schemes.Recipient, text string, keyboard *maxbot.Keyboard) error
 	SendMarkdownWithKeyboard(ctx context.Context, recipient schemes.Recipient, text string, keyboard *maxbot.Keyboard) error
 	SendTextWithFile(ctx context.Context, recipient schemes.Recipient, text string, fileToken string) error
+	SendImage(ctx context.Context, recipient schemes.Recipient, text string, image io.Reader) error
+	SendFile(ctx context.Context, recipient schemes.Recipient, text, name string, file io.Reader) error
 	AnswerCallback(ctx context.Context, callbackID string, answer *schemes.CallbackAnswer) error
 	AnswerCallbackWithEdit(ctx context.Context, callbackID string, text string, keyboard *maxbot.Keyboard) error
 	DeleteMessageBySeq(ctx context.Context, messageSeq int64) error
 	DeleteMessageByMid(ctx context.Context, messageID string) error
 	NewKeyboardBuilder() *maxbot.Keyboard
+	IsChatAdmin(ctx context.Context, chatID, userID int64) (bool, error)
 }
@@ -1,14 +1,26 @@
 package bot
 
 import (
+	"context"
 	"strings"
 
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/idempotency"
+	"first-max-bot/internal/ratelimit"
 	"first-max-bot/internal/state"
 )
 
 type Router struct {
 	handlers       map[string]Handler
-	callbackRoutes map[string]Handler // маршруты для callback payloads
+	callbackRoutes map[string]Handler             // маршруты для callback payloads
+	groupCommands  map[string]bool                // команды, разрешенные в групповых чатах
+	events         map[schemes.UpdateType]Handler // обработчики событий (bot_started, user_added и т.п.), см. events.go
+	idempotent     []idempotentRoute              // callback'и, действие которых выполняется один раз
+	idempotency    idempotency.Store
+	limiter        ratelimit.Limiter
+	limiterLogger  zerolog.Logger
 	fallback       Handler
 }
 
@@ -16,6 +28,8 @@ func NewRouter() *Router {
 	return &Router{
 		handlers:       make(map[string]Handler),
 		callbackRoutes: make(map[string]Handler),
+		groupCommands:  make(map[string]bool),
+		events:         make(map[schemes.UpdateType]Handler),
 	}
 }
 
@@ -24,6 +38,25 @@ func (r *Router) Register(command string, handler Handler) {
 	r.handlers[command] = handler
 }
 
+// ResolveEvent возвращает handler для типа обновления или nil, если он не зарегистрирован
+func (r *Router) ResolveEvent(updateType schemes.UpdateType) Handler {
+	return r.events[updateType]
+}
+
+// AllowInGroups разрешает выполнение команд в групповых чатах.
+// Остальные команды работают только в личной переписке с ботом
+func (r *Router) AllowInGroups(commands ...string) {
+	for _, command := range commands {
+		r.groupCommands[normalizeCommand(command)] = true
+	}
+}
+
+// HasCommand сообщает, зарегистрирован ли handler для команды
+func (r *Router) HasCommand(command string) bool {
+	_, ok := r.handlers[normalizeCommand(command)]
+	return ok
+}
+
 func (r *Router) SetFallback(handler Handler) {
 	r.fallback = handler
 }
@@ -36,6 +69,20 @@ func (r *Router) Resolve(text string) (Handler, string, string) {
 	return r.fallback, command, args
 }
 
+// ResolveGroup разрешает handler для сообщения из группового чата.
+// Состояние пользователя не учитывается, fallback не используется:
+// если команда не разрешена в группах, возвращается nil handler
+func (r *Router) ResolveGroup(text string) (Handler, string, string) {
+	command, args := parseCommand(text)
+	if !r.groupCommands[command] {
+		return nil, command, args
+	}
+	if h, ok := r.handlers[command]; ok {
+		return h, command, args
+	}
+	return nil, command, args
+}
+
 // ResolveByState разрешает handler на основе состояния пользователя
 // Используется для обработки текстовых сообщений во время регистрации и ответов на обращения
 func (r *Router) ResolveByState(text string, userState *state.UserState) (Handler, string, string) {
@@ -107,8 +154,18 @@ func (r *Router) ResolveByState(text string, userState *state.UserState) (Handle
 		}
 	}
 
+	// Если руководитель создает отслеживаемую ссылку
+	if userState.UserRegistrationStep == "links_create" {
+		textTrimmed := strings.TrimSpace(text)
+		if !strings.HasPrefix(textTrimmed, "/") {
+			if h, ok := r.handlers["/links"]; ok {
+				return h, "", text // Передаем текст как args
+			}
+		}
+	}
+
 	// Если пользователь в процессе регистрации
-	if userState.UserRegistrationStep != "" && userState.UserRegistrationStep != "completed" && userState.UserRegistrationStep != "ticket_reply" && userState.UserRegistrationStep != "ticket_user_reply" && userState.UserRegistrationStep != "doc_response" && userState.UserRegistrationStep != "send_news" && userState.UserRegistrationStep != "moodle_token" && userState.UserRegistrationStep != "reminder_create" {
+	if userState.UserRegistrationStep != "" && userState.UserRegistrationStep != "completed" && userState.UserRegistrationStep != "ticket_reply" && userState.UserRegistrationStep != "ticket_user_reply" && userState.UserRegistrationStep != "doc_response" && userState.UserRegistrationStep != "send_news" && userState.UserRegistrationStep != "moodle_token" && userState.UserRegistrationStep != "reminder_create" && userState.UserRegistrationStep != "links_create" {
 		// Если это не команда (не начинается с /), то это текстовый ввод для регистрации
 		if !strings.HasPrefix(strings.TrimSpace(text), "/") {
 			if h, ok := r.handlers["/register"]; ok {
@@ -121,11 +178,60 @@ func (r *Router) ResolveByState(text string, userState *state.UserState) (Handle
 	return r.Resolve(text)
 }
 
+// CommandCallback возвращает handler для callback'ов "cmd:/команда аргументы": команда выполняется так,
+// как если бы пользователь отправил её сообщением (используется кнопками-подсказками и подтверждениями)
+func (r *Router) CommandCallback() Handler {
+	return HandlerFunc(func(ctx context.Context, req *Request, responder Responder) error {
+		if req.Metadata != nil {
+			if cid, ok := req.Metadata["callback_id"].(string); ok && cid != "" {
+				responder.AnswerCallback(ctx, cid, &schemes.CallbackAnswer{})
+			}
+		}
+
+		command, args := parseCommand(strings.TrimPrefix(req.Args, "cmd:"))
+		return r.Execute(ctx, req, responder, command, args)
+	})
+}
+
+// Execute выполняет зарегистрированную команду с аргументами от имени автора запроса
+func (r *Router) Execute(ctx context.Context, req *Request, responder Responder, command, args string) error {
+	command = normalizeCommand(command)
+	h, ok := r.handlers[command]
+	if !ok {
+		return responder.SendText(ctx, req.Recipient(), "Я пока не знаю такой команды. Попробуй /help, чтобы посмотреть, что я уже умею.")
+	}
+
+	if !r.allowCommand(ctx, req, responder, command) {
+		return nil
+	}
+
+	// callback_id не передаем: на callback уже ответили, а handler команды не должен считать запрос callback'ом
+	metadata := make(map[string]any, len(req.Metadata)+1)
+	for k, v := range req.Metadata {
+		if k != "callback_id" {
+			metadata[k] = v
+		}
+	}
+	metadata["text"] = strings.TrimSpace(command + " " + args)
+
+	cmdReq := *req
+	cmdReq.Command = command
+	cmdReq.Args = args
+	cmdReq.Metadata = metadata
+	return h.Handle(ctx, &cmdReq, responder)
+}
+
 func (r *Router) RegisterCallback(payload string, handler Handler) {
 	r.callbackRoutes[payload] = handler
 }
 
+// ResolveCallback возвращает handler для payload. Если payload отмечен через MarkIdempotent,
+// handler оборачивается защитой от повторного выполнения
 func (r *Router) ResolveCallback(payload string, userState *state.UserState) Handler {
+	return r.wrapIdempotent(payload, r.resolveCallback(payload))
+}
+
+func (r *Router) resolveCallback(payload string) Handler {
 	// Сначала проверяем точное совпадение payload
 	if h, ok := r.callbackRoutes[payload]; ok {
 		return h
@@ -143,6 +249,17 @@ func (r *Router) ResolveCallback(payload string, userState *state.UserState) Han
 		"lib_manage:": "lib_manage:*",
 		"moodle:":     "moodle:*",
 		"reminder:":   "reminder:*",
+		"role:":       "role:*",
+		"chat:":       "chat:*",
+		"links:":      "links:*",
+		"flow:":       "flow:*",
+		"privacy:":    "privacy:*",
+		"schedule:":   "schedule:*",
+		"group:":      "group:*",
+		"calendar:":   "calendar:*",
+		"notify:":     "notify:*",
+		"rooms:":      "rooms:*",
+		"cmd:":        "cmd:*",
 	}
 
 	for prefix, wildcard := range prefixToWildcard {
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/max-messenger/max-bot-api-client-go/schemes"
+)
+
+// apiURL - адрес MAX Bot API для получения ссылки на загрузку файла
+const apiURL = "https://botapi.max.ru"
+
+// uploadTimeout - сколько ждать загрузки файла
+const uploadTimeout = time.Minute
+
+// fileContentTypes - типы файлов, которые отправляет бот; остальные определяются по расширению через mime
+var fileContentTypes = map[string]string{
+	".zip":  "application/zip",
+	".ics":  "text/calendar; charset=utf-8",
+	".json": "application/json",
+	".csv":  "text/csv; charset=utf-8",
+	".pdf":  "application/pdf",
+}
+
+// WithToken задает токен бота для загрузки файлов (SendFile). Клиент MAX загружает файлы под именем
+// "file" без типа, поэтому файлы загружаются напрямую через Bot API
+func WithToken(token string) Option {
+	return func(b *Bot) {
+		b.token = token
+	}
+}
+
+// fileContentType возвращает тип содержимого по расширению имени файла
+func fileContentType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if contentType, ok := fileContentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// uploadFile загружает файл под именем name и возвращает токен для вложения
+func (b *Bot) uploadFile(ctx context.Context, name string, file io.Reader) (*schemes.UploadedInfo, error) {
+	if b.token == "" {
+		return nil, errors.New("bot token is not set, see WithToken")
+	}
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	endpoint, err := b.uploadEndpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "data", "filename": name}))
+	header.Set("Content-Type", fileContentType(name))
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upload returned %s", resp.Status)
+	}
+
+	// Для файлов токен приходит в ответе загрузки; если его нет, используется токен из ссылки на загрузку
+	uploaded := &schemes.UploadedInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(uploaded); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decode upload response: %w", err)
+	}
+	if uploaded.Token == "" {
+		uploaded.Token = endpoint.Token
+	}
+	if uploaded.Token == "" {
+		return nil, errors.New("upload response has no token")
+	}
+	return uploaded, nil
+}
+
+// uploadEndpoint получает ссылку на загрузку файла: POST /uploads?type=file
+func (b *Bot) uploadEndpoint(ctx context.Context) (*schemes.UploadEndpoint, error) {
+	query := url.Values{}
+	query.Set("type", string(schemes.FILE))
+	query.Set("access_token", b.token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/uploads?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// В ошибке net/http есть URL с токеном
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("get upload url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get upload url: %s", resp.Status)
+	}
+
+	endpoint := &schemes.UploadEndpoint{}
+	if err := json.NewDecoder(resp.Body).Decode(endpoint); err != nil {
+		return nil, fmt.Errorf("decode upload url: %w", err)
+	}
+	if endpoint.Url == "" {
+		return nil, errors.New("get upload url: empty url")
+	}
+	return endpoint, nil
+}
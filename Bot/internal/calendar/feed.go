@@ -0,0 +1,178 @@
+// Package calendar собирает личный календарь пользователя в формате iCalendar: занятия, напоминания,
+// командировки и сроки возврата книг. Календарь доступен по секретной ссылке для подписки
+// в приложении календаря (см. Handler) и файлом .ics в боте (/calendar)
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/schedule"
+)
+
+const (
+	// Name - название календаря в приложении
+	Name = "Университет"
+
+	uidDomain = "first-max-bot"
+
+	// Занятия попадают в календарь за неделю назад и на пять недель вперед: приложения календаря
+	// обновляют подписку каждый час, а долгий диапазон замедлил бы ответ источника расписания
+	lessonsBefore = 7
+	lessonsAfter  = 35
+)
+
+// Sources - откуда берутся события календаря. Trips и Library можно не задавать
+type Sources struct {
+	Schedule  schedule.Service
+	Reminders reminder.Service
+	Trips     businesstrip.Service
+	Library   library.Service
+}
+
+// Feed собирает календарь пользователя из сервисов бота
+type Feed struct {
+	sources Sources
+}
+
+func NewFeed(sources Sources) *Feed {
+	return &Feed{sources: sources}
+}
+
+// Build возвращает календарь пользователя в формате iCalendar
+func (f *Feed) Build(ctx context.Context, userID string, now time.Time) ([]byte, error) {
+	events, err := f.Events(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return encode(Name, events, now), nil
+}
+
+// Events возвращает события календаря пользователя по времени начала
+func (f *Feed) Events(ctx context.Context, userID string, now time.Time) ([]Event, error) {
+	lessons, err := f.lessons(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	events := lessons
+
+	reminders, err := f.sources.Reminders.GetActiveReminders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get reminders: %w", err)
+	}
+	for _, r := range reminders {
+		if r.Kind == reminder.KindLesson {
+			// Сама пара уже есть в календаре, а напоминание о ней календарь сделает сам
+			continue
+		}
+		events = append(events, Event{
+			UID:     "reminder-" + r.ID + "@" + uidDomain,
+			Start:   r.DateTime,
+			End:     r.DateTime,
+			Summary: "🔔 " + r.Text,
+		})
+	}
+
+	if f.sources.Trips != nil {
+		trips, err := f.sources.Trips.GetUserTrips(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("get business trips: %w", err)
+		}
+		for _, t := range trips {
+			if t.Status == "rejected" {
+				continue
+			}
+			start, _ := schedule.DayRange(t.StartDate)
+			_, end := schedule.DayRange(t.EndDate)
+			events = append(events, Event{
+				UID:         "trip-" + t.ID + "@" + uidDomain,
+				Start:       start,
+				End:         end,
+				AllDay:      true,
+				Summary:     "✈️ Командировка: " + t.Destination,
+				Location:    t.Destination,
+				Description: t.Purpose,
+				Tentative:   t.Status == "pending",
+			})
+		}
+	}
+
+	if f.sources.Library != nil {
+		loans, err := f.sources.Library.GetUserBooks(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("get library loans: %w", err)
+		}
+		for _, loan := range loans {
+			// Срок возврата есть только у выданных книг
+			if loan.Returned || (loan.Status != "issued" && loan.Status != "taken") || loan.ReturnDate.IsZero() {
+				continue
+			}
+			title := loan.BookID
+			if loan.Book != nil {
+				title = loan.Book.Title
+			}
+			start, end := schedule.DayRange(loan.ReturnDate)
+			events = append(events, Event{
+				UID:     "loan-" + loan.ID + "@" + uidDomain,
+				Start:   start,
+				End:     end,
+				AllDay:  true,
+				Summary: fmt.Sprintf("📚 Вернуть книгу «%s»", title),
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+// lessons возвращает занятия за неделю до и пять недель после now. Если источник расписания
+// знает только сегодняшние занятия (uni-back), в календарь попадают они
+func (f *Feed) lessons(ctx context.Context, userID string, now time.Time) ([]Event, error) {
+	today, tomorrow := schedule.DayRange(now)
+	items, err := f.sources.Schedule.GetSchedule(ctx, userID, today.AddDate(0, 0, -lessonsBefore), today.AddDate(0, 0, lessonsAfter))
+	if errors.Is(err, schedule.ErrOutOfRange) {
+		items, err = f.sources.Schedule.GetSchedule(ctx, userID, today, tomorrow)
+	}
+	if errors.Is(err, schedule.ErrNoGroup) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get schedule: %w", err)
+	}
+
+	events := make([]Event, 0, len(items))
+	for _, item := range items {
+		description := item.Description
+		if item.Instructor != "" {
+			description = "Преподаватель: " + item.Instructor
+			if item.Description != "" {
+				description += "\n" + item.Description
+			}
+		}
+		events = append(events, Event{
+			UID:         lessonUID(item),
+			Start:       item.Time,
+			End:         item.End(),
+			Summary:     item.Discipline,
+			Location:    item.Location,
+			Description: description,
+		})
+	}
+	return events, nil
+}
+
+// lessonUID - у занятий нет ID, поэтому UID строится из времени начала и дисциплины: при обновлении
+// подписки то же занятие заменяется, а не дублируется
+func lessonUID(item schedule.Item) string {
+	h := fnv.New32a()
+	h.Write([]byte(item.Discipline))
+	return fmt.Sprintf("lesson-%s-%08x@%s", item.Time.UTC().Format(utcFormat), h.Sum32(), uidDomain)
+}
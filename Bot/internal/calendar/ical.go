@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcFormat  = "20060102T150405Z"
+	dateFormat = "20060102"
+
+	// maxLineOctets - длина строки iCalendar без CRLF (RFC 5545, 3.1); длинные строки переносятся
+	maxLineOctets = 75
+)
+
+// Event - событие календаря. Время хранится в UTC, у событий на весь день учитывается только дата
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time // для событий на весь день - день после последнего
+	AllDay      bool
+	Summary     string
+	Location    string
+	Description string
+	Tentative   bool // событие еще не подтверждено (например, командировка на согласовании)
+}
+
+// encode записывает события в формате iCalendar (RFC 5545)
+func encode(name string, events []Event, now time.Time) []byte {
+	var w icalWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//first-max-bot//calendar//RU")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", escapeText(name))
+	// Подсказка приложениям календаря, как часто обновлять подписку
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	w.line("X-PUBLISHED-TTL", "PT1H")
+
+	stamp := now.UTC().Format(utcFormat)
+	for _, e := range events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", e.UID)
+		w.line("DTSTAMP", stamp)
+		if e.AllDay {
+			w.line("DTSTART;VALUE=DATE", e.Start.Format(dateFormat))
+			w.line("DTEND;VALUE=DATE", e.End.Format(dateFormat))
+		} else {
+			w.line("DTSTART", e.Start.UTC().Format(utcFormat))
+			w.line("DTEND", e.End.UTC().Format(utcFormat))
+		}
+		w.line("SUMMARY", escapeText(e.Summary))
+		if e.Location != "" {
+			w.line("LOCATION", escapeText(e.Location))
+		}
+		if e.Description != "" {
+			w.line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Tentative {
+			w.line("STATUS", "TENTATIVE")
+		} else {
+			w.line("STATUS", "CONFIRMED")
+		}
+		w.line("TRANSP", transparency(e))
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// transparency - занятия занимают время в календаре, события на весь день и напоминания - нет
+func transparency(e Event) string {
+	if e.AllDay || !e.End.After(e.Start) {
+		return "TRANSPARENT"
+	}
+	return "OPAQUE"
+}
+
+type icalWriter struct {
+	buf bytes.Buffer
+}
+
+// line записывает свойство, перенося строку длиннее maxLineOctets байт. Перенос не разрезает символы UTF-8
+func (w *icalWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.buf.WriteString(content[:cut])
+		w.buf.WriteString("\r\n ")
+		content = content[cut:]
+		limit = maxLineOctets - 1 // пробел в начале строки продолжения входит в длину
+	}
+	w.buf.WriteString(content)
+	w.buf.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
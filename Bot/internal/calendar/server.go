@@ -0,0 +1,69 @@
+package calendar
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// FeedPath - путь календаря на HTTP-сервере: FeedPath + токен + ".ics"
+const FeedPath = "/calendar/"
+
+// FileName и ContentType - имя и тип календаря в ответе сервера и в файле, который присылает бот
+const (
+	FileName    = "calendar.ics"
+	ContentType = "text/calendar; charset=utf-8"
+)
+
+// Handler отдает календарь по секретной ссылке FeedPath<токен>.ics. Неизвестный или отозванный
+// токен получает 404, чтобы по ответу нельзя было отличить отозванную ссылку от несуществующей
+type Handler struct {
+	tokens TokenStore
+	feed   *Feed
+	logger zerolog.Logger
+}
+
+func NewHandler(tokens TokenStore, feed *Feed, logger zerolog.Logger) *Handler {
+	return &Handler{tokens: tokens, feed: feed, logger: logger}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token, ok := strings.CutPrefix(r.URL.Path, FeedPath)
+	token, hasExt := strings.CutSuffix(token, ".ics")
+	if !ok || !hasExt || token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, err := h.tokens.UserID(r.Context(), token)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to check calendar token")
+		http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	if userID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// При ошибке источника отвечаем 503: приложение календаря оставит прежнюю копию,
+	// а не удалит занятия из-за временного сбоя
+	data, err := h.feed.Build(r.Context(), userID, time.Now())
+	if err != nil {
+		h.logger.Error().Err(err).Str("user_id", userID).Msg("failed to build calendar")
+		http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+FileName+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(data)
+}
@@ -0,0 +1,102 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// TokenStore хранит секретные токены ссылок на календарь. У пользователя не больше одного токена
+type TokenStore interface {
+	// Token возвращает действующий токен пользователя или "", если ссылки нет
+	Token(ctx context.Context, userID string) (string, error)
+	// Issue выпускает новый токен; прежняя ссылка перестает работать
+	Issue(ctx context.Context, userID string) (string, error)
+	// Revoke отключает ссылку пользователя
+	Revoke(ctx context.Context, userID string) error
+	// UserID возвращает владельца токена или "", если токен неизвестен или отозван
+	UserID(ctx context.Context, token string) (string, error)
+}
+
+// RedisTokenStore хранит токены в Redis без срока действия: ссылка работает, пока ее не отзовут.
+// Ключи: "<prefix>user:<ID пользователя>" -> токен и "<prefix>token:<токен>" -> ID пользователя
+type RedisTokenStore struct {
+	client redis2.Cmdable
+	prefix string
+}
+
+func NewRedisTokenStore(client redis2.Cmdable, prefix string) *RedisTokenStore {
+	if prefix == "" {
+		prefix = "maxbot:calendar:"
+	}
+	return &RedisTokenStore{client: client, prefix: prefix}
+}
+
+func (s *RedisTokenStore) Token(ctx context.Context, userID string) (string, error) {
+	token, err := s.client.Get(ctx, s.prefix+"user:"+userID).Result()
+	if err == redis2.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get calendar token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *RedisTokenStore) Issue(ctx context.Context, userID string) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	old, err := s.Token(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis2.Pipeliner) error {
+		if old != "" {
+			pipe.Del(ctx, s.prefix+"token:"+old)
+		}
+		pipe.Set(ctx, s.prefix+"token:"+token, userID, 0)
+		pipe.Set(ctx, s.prefix+"user:"+userID, token, 0)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("issue calendar token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *RedisTokenStore) Revoke(ctx context.Context, userID string) error {
+	old, err := s.Token(ctx, userID)
+	if err != nil || old == "" {
+		return err
+	}
+	if err := s.client.Del(ctx, s.prefix+"token:"+old, s.prefix+"user:"+userID).Err(); err != nil {
+		return fmt.Errorf("revoke calendar token: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisTokenStore) UserID(ctx context.Context, token string) (string, error) {
+	userID, err := s.client.Get(ctx, s.prefix+"token:"+token).Result()
+	if err == redis2.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get calendar token owner: %w", err)
+	}
+	return userID, nil
+}
+
+// newToken возвращает 32 случайных символа, пригодных для URL
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate calendar token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
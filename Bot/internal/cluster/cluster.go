@@ -0,0 +1,52 @@
+// Package cluster координирует несколько реплик бота: дедупликация обновлений
+// и выбор лидера для фоновых задач, которые должны выполняться в одном экземпляре
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Deduplicator отмечает обновления как обработанные.
+// ClaimUpdate захватывает обновление на время обработки и возвращает true, только если его не обрабатывает
+// и не обработал другой экземпляр. CompleteUpdate вызывается после обработки и помечает обновление обработанным.
+// Если экземпляр упал, не завершив обработку, захват истекает и повторно доставленное обновление будет обработано
+type Deduplicator interface {
+	ClaimUpdate(ctx context.Context, key string) (bool, error)
+	CompleteUpdate(ctx context.Context, key string) error
+}
+
+// Elector запускает задачу только на экземпляре-лидере.
+// RunAsLeader блокируется до отмены ctx; контекст run отменяется при потере лидерства
+type Elector interface {
+	RunAsLeader(ctx context.Context, job string, run func(ctx context.Context))
+}
+
+// DefaultInstanceID - идентификатор экземпляра по умолчанию: имя хоста и PID
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Local - координация для единственного экземпляра: каждое обновление обрабатывается, каждая задача выполняется
+type Local struct{}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (Local) ClaimUpdate(ctx context.Context, key string) (bool, error) {
+	return true, nil
+}
+
+func (Local) CompleteUpdate(ctx context.Context, key string) error {
+	return nil
+}
+
+func (Local) RunAsLeader(ctx context.Context, job string, run func(ctx context.Context)) {
+	run(ctx)
+}
@@ -0,0 +1,213 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+// renewScript продлевает аренду, только если она все еще принадлежит этому экземпляру
+var renewScript = redis2.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript снимает аренду, только если она принадлежит этому экземпляру
+var releaseScript = redis2.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// completeScript помечает обновление обработанным, только если его захват принадлежит этому экземпляру
+// или уже истек: ARGV - значение захвата, отметка об обработке, TTL отметки в миллисекундах
+var completeScript = redis2.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current == ARGV[1] or not current then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return 0
+`)
+
+// Redis координирует экземпляры через общий Redis:
+// обновления захватываются через SETNX на время обработки и после нее помечаются обработанными,
+// лидер держит аренду (lease) и продлевает ее
+type Redis struct {
+	client     redis2.Cmdable
+	instanceID string
+	prefix     string
+	updateTTL  time.Duration
+	pendingTTL time.Duration
+	leaseTTL   time.Duration
+	logger     zerolog.Logger
+}
+
+type Option func(*options)
+
+type options struct {
+	prefix     string
+	updateTTL  time.Duration
+	pendingTTL time.Duration
+	leaseTTL   time.Duration
+}
+
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithUpdateTTL задает, сколько помнить обработанные обновления. Должно превышать время повторной доставки
+func WithUpdateTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.updateTTL = ttl
+	}
+}
+
+// WithPendingTTL задает срок захвата обновления на время обработки. Если экземпляр упал во время обработки,
+// повторно доставленное обновление будет обработано после этого срока. Должно превышать время работы handler'а
+func WithPendingTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.pendingTTL = ttl
+	}
+}
+
+// WithLeaseTTL задает срок аренды лидера. Если лидер упал, задача перейдет к другому экземпляру не позже чем через этот срок
+func WithLeaseTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.leaseTTL = ttl
+	}
+}
+
+func NewRedis(client redis2.Cmdable, instanceID string, logger zerolog.Logger, opts ...Option) *Redis {
+	cfg := options{
+		prefix:     "maxbot:cluster:",
+		updateTTL:  24 * time.Hour,
+		pendingTTL: 2 * time.Minute,
+		leaseTTL:   30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &Redis{
+		client:     client,
+		instanceID: instanceID,
+		prefix:     cfg.prefix,
+		updateTTL:  cfg.updateTTL,
+		pendingTTL: cfg.pendingTTL,
+		leaseTTL:   cfg.leaseTTL,
+		logger:     logger,
+	}
+}
+
+// InstanceID возвращает идентификатор этого экземпляра
+func (c *Redis) InstanceID() string {
+	return c.instanceID
+}
+
+func (c *Redis) ClaimUpdate(ctx context.Context, key string) (bool, error) {
+	ok, err := c.client.SetNX(ctx, c.prefix+"update:"+key, c.pendingValue(), c.pendingTTL).Result()
+	if err != nil {
+		return false, fmt.Errorf("claim update %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+// CompleteUpdate заменяет захват отметкой об обработке со сроком updateTTL
+func (c *Redis) CompleteUpdate(ctx context.Context, key string) error {
+	err := completeScript.Run(ctx, c.client, []string{c.prefix + "update:" + key}, c.pendingValue(), "done:"+c.instanceID, c.updateTTL.Milliseconds()).Err()
+	if err != nil && err != redis2.Nil {
+		return fmt.Errorf("complete update %s: %w", key, err)
+	}
+	return nil
+}
+
+func (c *Redis) pendingValue() string {
+	return "pending:" + c.instanceID
+}
+
+func (c *Redis) RunAsLeader(ctx context.Context, job string, run func(ctx context.Context)) {
+	key := c.prefix + "leader:" + job
+	logger := c.logger.With().Str("job", job).Str("instance_id", c.instanceID).Logger()
+	interval := c.leaseTTL / 3
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := c.client.SetNX(ctx, key, c.instanceID, c.leaseTTL).Result()
+		if err != nil && ctx.Err() == nil {
+			logger.Warn().Err(err).Msg("failed to acquire leadership")
+		}
+		if acquired {
+			logger.Info().Msg("leadership acquired")
+			c.lead(ctx, key, run, logger)
+			if ctx.Err() == nil {
+				logger.Warn().Msg("leadership lost")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead выполняет задачу и продлевает аренду, пока она не будет потеряна или ctx не отменен
+func (c *Redis) lead(ctx context.Context, key string, run func(ctx context.Context), logger zerolog.Logger) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(jobCtx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+		// Отпускаем аренду, чтобы другой экземпляр подхватил задачу сразу, а не по истечении TTL.
+		// ctx уже может быть отменен, поэтому используем отдельный короткий таймаут
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer releaseCancel()
+		if err := releaseScript.Run(releaseCtx, c.client, []string{key}, c.instanceID).Err(); err != nil {
+			logger.Warn().Err(err).Msg("failed to release leadership")
+		}
+	}()
+
+	ticker := time.NewTicker(c.leaseTTL / 3)
+	defer ticker.Stop()
+	renewedAt := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(ctx, c.client, []string{key}, c.instanceID, c.leaseTTL.Milliseconds()).Int()
+		switch {
+		case err != nil:
+			// Временная ошибка Redis: пока аренда не истекла, задача продолжает работать
+			logger.Warn().Err(err).Msg("failed to renew leadership")
+			if time.Since(renewedAt) >= c.leaseTTL {
+				return
+			}
+		case renewed == 0:
+			return
+		default:
+			renewedAt = time.Now()
+		}
+	}
+}
@@ -30,6 +30,21 @@ type Config struct {
 	MockScheduleLag   time.Duration `mapstructure:"MOCK_SCHEDULE_LAG"`
 	YandexGPTAPIKey   string        `mapstructure:"YANDEX_GPT_API_KEY"`
 	YandexGPTFolderID string        `mapstructure:"YANDEX_GPT_FOLDER_ID"`
+	InstanceID        string        `mapstructure:"INSTANCE_ID"`
+	StorageDriver     string        `mapstructure:"STORAGE_DRIVER"` // "memory" (по умолчанию), "postgres" или "sqlite"
+	PostgresDSN       string        `mapstructure:"POSTGRES_DSN"`
+	PostgresMaxConns  int32         `mapstructure:"POSTGRES_MAX_CONNS"`
+	SQLitePath        string        `mapstructure:"SQLITE_PATH"`
+	ReminderStorage   string        `mapstructure:"REMINDER_STORAGE"`  // "redis" или пусто - как STORAGE_DRIVER
+	SecretKeys        string        `mapstructure:"SECRET_KEYS"`       // ключи шифрования токенов: "<id>:<base64 32 байта>,..."
+	SecretActiveKey   string        `mapstructure:"SECRET_ACTIVE_KEY"` // ID ключа для новых значений, по умолчанию первый
+	UniBackURL        string        `mapstructure:"UNI_BACK_URL"`      // адрес uni-back; если задан, пользователи берутся из него
+	UniBackTimeout    time.Duration `mapstructure:"UNI_BACK_TIMEOUT"`  // таймаут одного запроса к uni-back
+	ScheduleICalDir   string        `mapstructure:"SCHEDULE_ICAL_DIR"` // каталог с расписаниями .ics; если задан, расписание берется из него
+	CalendarAddr      string        `mapstructure:"CALENDAR_ADDR"`     // адрес HTTP-сервера календарей, например ":8080"; пусто - только файл .ics
+	CalendarURL       string        `mapstructure:"CALENDAR_URL"`      // внешний адрес сервера календарей для ссылок, например https://bot.example.ru
+	RoomsFile         string        `mapstructure:"ROOMS_FILE"`        // реестр аудиторий (JSON); пусто - демонстрационный список
+	PseudonymKey      string        `mapstructure:"PSEUDONYM_KEY"`     // ключ псевдонимов удаленных пользователей; пусто - случайные псевдонимы
 }
 
 func Load() (*Config, error) {
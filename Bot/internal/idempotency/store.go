@@ -0,0 +1,91 @@
+// Package idempotency хранит отметки о выполненных действиях, чтобы повторное нажатие кнопки
+// или одновременное нажатие несколькими пользователями не выполняло действие дважды
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// Status - состояние действия с данным ключом
+type Status string
+
+const (
+	StatusPending Status = "pending" // действие выполняется
+	StatusDone    Status = "done"    // действие выполнено
+)
+
+// Store атомарно заявляет ключи действий
+type Store interface {
+	// Begin заявляет ключ за владельцем (например, callback ID). Если ключ уже заявлен,
+	// возвращает false и текущий статус действия
+	Begin(ctx context.Context, key, owner string, ttl time.Duration) (bool, Status, error)
+	// Complete отмечает действие выполненным; повторы с этим ключом отклоняются до истечения ttl
+	Complete(ctx context.Context, key, owner string, ttl time.Duration) error
+	// Abort снимает ключ после неудачного выполнения, чтобы действие можно было повторить
+	Abort(ctx context.Context, key, owner string) error
+}
+
+// abortScript удаляет ключ, только если он все еще принадлежит владельцу и действие не выполнено
+var abortScript = redis2.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore хранит ключи в Redis в виде "<статус>:<владелец>"
+type RedisStore struct {
+	client redis2.Cmdable
+	prefix string
+}
+
+func NewRedisStore(client redis2.Cmdable, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "maxbot:idem:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key, owner string, ttl time.Duration) (bool, Status, error) {
+	ok, err := s.client.SetNX(ctx, s.prefix+key, value(StatusPending, owner), ttl).Result()
+	if err != nil {
+		return false, "", fmt.Errorf("begin %s: %w", key, err)
+	}
+	if ok {
+		return true, StatusPending, nil
+	}
+
+	raw, err := s.client.Get(ctx, s.prefix+key).Result()
+	if err != nil {
+		if err == redis2.Nil {
+			// Ключ истек между SETNX и GET - считаем, что действие еще выполняется
+			return false, StatusPending, nil
+		}
+		return false, "", fmt.Errorf("begin %s: %w", key, err)
+	}
+	status, _, _ := strings.Cut(raw, ":")
+	return false, Status(status), nil
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key, owner string, ttl time.Duration) error {
+	if err := s.client.Set(ctx, s.prefix+key, value(StatusDone, owner), ttl).Err(); err != nil {
+		return fmt.Errorf("complete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Abort(ctx context.Context, key, owner string) error {
+	if err := abortScript.Run(ctx, s.client, []string{s.prefix + key}, value(StatusPending, owner)).Err(); err != nil {
+		return fmt.Errorf("abort %s: %w", key, err)
+	}
+	return nil
+}
+
+func value(status Status, owner string) string {
+	return string(status) + ":" + owner
+}
@@ -0,0 +1,53 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// AuditEntry - запись журнала о выгрузке или удалении данных пользователя.
+// Запись о выгрузке содержит ID пользователя. Запись об удалении содержит только псевдоним:
+// после удаления ID в журнале не остается, а владелец ключа псевдонимов может проверить удаление,
+// вычислив псевдоним по ID
+type AuditEntry struct {
+	Action    string         `json:"action"`              // "export", "erase"
+	UserID    string         `json:"user_id,omitempty"`   // только для "export"
+	Pseudonym string         `json:"pseudonym,omitempty"` // чем заменен ID в обезличенных записях
+	Counts    map[string]int `json:"counts,omitempty"`    // сколько записей выгружено, удалено или обезличено
+	Error     string         `json:"error,omitempty"`     // если действие выполнено не полностью
+	At        time.Time      `json:"at"`
+}
+
+// AuditLog хранит журнал действий с персональными данными
+type AuditLog interface {
+	Record(ctx context.Context, entry AuditEntry) error
+}
+
+// RedisAuditLog хранит журнал в списке Redis. Журнал не истекает и не обрезается:
+// он подтверждает, что данные удалены по запросу пользователя
+type RedisAuditLog struct {
+	client redis2.Cmdable
+	key    string
+}
+
+func NewRedisAuditLog(client redis2.Cmdable, key string) *RedisAuditLog {
+	if key == "" {
+		key = "maxbot:privacy:audit"
+	}
+	return &RedisAuditLog{client: client, key: key}
+}
+
+func (l *RedisAuditLog) Record(ctx context.Context, entry AuditEntry) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := l.client.LPush(ctx, l.key, payload).Err(); err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+	return nil
+}
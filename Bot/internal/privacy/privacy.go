@@ -0,0 +1,380 @@
+// Package privacy выгружает пользователю все данные, связанные с его ID (/my_data), и удаляет их
+// по его запросу (/delete_me). Личные записи (профиль, обращения, заявления, командировки, напоминания,
+// состояние диалога) удаляются. Общие записи (выдачи книг, опубликованные новости) обезличиваются:
+// ID заменяется псевдонимом, имя стирается. Каждая выгрузка и удаление записываются в журнал
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/calendar"
+	"first-max-bot/internal/ratelimit"
+	"first-max-bot/internal/schedulewatch"
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/campaign"
+	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/news"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/support"
+	"first-max-bot/internal/services/user"
+	"first-max-bot/internal/state"
+)
+
+// DeletedAuthorName - имя автора в новостях удаленного пользователя
+const DeletedAuthorName = "Удаленный пользователь"
+
+// ArchiveFileName - имя JSON-файла внутри ZIP-архива с данными
+const ArchiveFileName = "my_data.json"
+
+// ArchiveName - имя ZIP-архива, который получает пользователь
+const ArchiveName = "my_data.zip"
+
+// ErrNotSupported - хранилище сервиса не умеет удалять данные пользователя
+var ErrNotSupported = errors.New("service does not support erasing user data")
+
+// Services - откуда собираются и где удаляются данные пользователя. Необязательные поля (Campaigns, Chats,
+// Limiter, Calendars, ScheduleWatch) можно не задавать
+type Services struct {
+	Users         user.Service
+	Support       support.Service
+	Deanery       deanery.Service
+	Library       library.Service
+	Trips         businesstrip.Service
+	News          news.Service
+	Reminders     reminder.Service
+	Campaigns     campaign.Service
+	State         state.Repository
+	Chats         state.ChatRepository
+	Limiter       ratelimit.Limiter
+	Calendars     calendar.TokenStore
+	ScheduleWatch schedulewatch.Store
+	Audit         AuditLog
+}
+
+// Data - все, что бот хранит о пользователе
+type Data struct {
+	UserID     string                `json:"user_id"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Profile    *user.User            `json:"profile"`
+	Tickets    []support.Ticket      `json:"tickets"`
+	Documents  []deanery.Document    `json:"documents"`
+	Loans      []library.UserBook    `json:"library_loans"`
+	Trips      []businesstrip.Trip   `json:"business_trips"`
+	Reminders  []reminder.Reminder   `json:"reminders"`
+	News       []news.News           `json:"news"` // новости, опубликованные пользователем
+	State      *state.UserState      `json:"dialog_state,omitempty"`
+	RateLimit  *ratelimit.UserStatus `json:"rate_limit,omitempty"`
+}
+
+// Counts возвращает число записей по разделам - для сообщения пользователю и журнала
+func (d *Data) Counts() map[string]int {
+	counts := map[string]int{
+		"tickets":   len(d.Tickets),
+		"documents": len(d.Documents),
+		"loans":     len(d.Loans),
+		"trips":     len(d.Trips),
+		"reminders": len(d.Reminders),
+		"news":      len(d.News),
+	}
+	if d.Profile != nil {
+		counts["profile"] = 1
+	}
+	return counts
+}
+
+type Service struct {
+	services     Services
+	pseudonymKey []byte
+	logger       zerolog.Logger
+}
+
+type Option func(*Service)
+
+// WithPseudonymKey задает секретный ключ псевдонимов, см. Service.Pseudonym
+func WithPseudonymKey(key string) Option {
+	return func(s *Service) {
+		s.pseudonymKey = []byte(key)
+	}
+}
+
+func New(services Services, logger zerolog.Logger, opts ...Option) *Service {
+	s := &Service{services: services, logger: logger}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Pseudonym - чем заменяется ID пользователя в обезличенных записях: HMAC-SHA256 от ID на ключе
+// из WithPseudonymKey. Перебором ID псевдоним восстанавливается только при известном ключе: владелец
+// ключа может по ID найти записи удаленного пользователя, остальные - нет. Без ключа псевдоним
+// случайный и ни с каким ID не связан
+func (s *Service) Pseudonym(userID string) string {
+	if len(s.pseudonymKey) == 0 {
+		random := make([]byte, 8)
+		rand.Read(random)
+		return "deleted-" + hex.EncodeToString(random)
+	}
+	mac := hmac.New(sha256.New, s.pseudonymKey)
+	mac.Write([]byte(userID))
+	return "deleted-" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Export собирает данные пользователя из всех сервисов
+func (s *Service) Export(ctx context.Context, userID string) (*Data, error) {
+	data := &Data{UserID: userID, ExportedAt: time.Now()}
+
+	var err error
+	if data.Profile, err = s.services.Users.GetUserByID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export profile: %w", err)
+	}
+	if data.Profile != nil && data.Profile.MoodleToken != "" {
+		// Сам токен не выгружается: файл может попасть в чужие руки, а токен дает доступ к Moodle
+		profile := *data.Profile
+		profile.MoodleToken = "(сохранен)"
+		data.Profile = &profile
+	}
+	if data.Tickets, err = s.services.Support.GetUserTickets(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export tickets: %w", err)
+	}
+	if data.Documents, err = s.services.Deanery.GetUserDocuments(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export documents: %w", err)
+	}
+	if data.Trips, err = s.services.Trips.GetUserTrips(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export trips: %w", err)
+	}
+	if data.Reminders, err = s.services.Reminders.GetUserReminders(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+
+	// История выдач и авторство новостей доступны только через выгрузку всех записей;
+	// без нее выгружаются книги на руках
+	if archiver, ok := s.services.Library.(library.Archiver); ok {
+		loans, err := archiver.ExportLoans(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("export loans: %w", err)
+		}
+		for _, loan := range loans {
+			if loan.UserID == userID {
+				data.Loans = append(data.Loans, loan)
+			}
+		}
+	} else if data.Loans, err = s.services.Library.GetUserBooks(ctx, userID); err != nil {
+		return nil, fmt.Errorf("export loans: %w", err)
+	}
+	if archiver, ok := s.services.News.(news.Archiver); ok {
+		all, err := archiver.ExportNews(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("export news: %w", err)
+		}
+		for _, n := range all {
+			if n.AuthorID == userID {
+				data.News = append(data.News, n)
+			}
+		}
+	}
+
+	if s.services.State != nil {
+		if data.State, err = s.services.State.GetUserState(ctx, userID); err != nil {
+			return nil, fmt.Errorf("export dialog state: %w", err)
+		}
+	}
+	if s.services.Limiter != nil {
+		status, err := s.services.Limiter.Status(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("export rate limit status: %w", err)
+		}
+		data.RateLimit = &status
+	}
+
+	s.audit(ctx, AuditEntry{Action: "export", UserID: userID, Counts: data.Counts()}, nil)
+	return data, nil
+}
+
+// Archive упаковывает данные в ZIP с одним файлом ArchiveFileName
+func Archive(data *Data) ([]byte, error) {
+	payload, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode user data: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: ArchiveFileName, Method: zip.Deflate, Modified: data.ExportedAt})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Erase удаляет и обезличивает данные пользователя во всех сервисах и записывает результат в журнал.
+// Операция повторяема: при ошибке ее можно запустить снова, уже удаленное просто не найдется.
+// Профиль удаляется последним, чтобы пользователь оставался зарегистрированным, пока удалено не все
+func (s *Service) Erase(ctx context.Context, userID string) (map[string]int, error) {
+	pseudonym := s.Pseudonym(userID)
+	counts := make(map[string]int)
+	err := s.erase(ctx, userID, pseudonym, counts)
+	// В журнал удаления ID не пишется: рядом с псевдонимом он снова связал бы обезличенные записи с пользователем
+	s.audit(ctx, AuditEntry{Action: "erase", Pseudonym: pseudonym, Counts: counts}, err)
+	return counts, err
+}
+
+func (s *Service) erase(ctx context.Context, userID, pseudonym string, counts map[string]int) error {
+	reminders, err := s.services.Reminders.GetUserReminders(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("erase reminders: %w", err)
+	}
+	for _, r := range reminders {
+		if err := s.services.Reminders.DeleteReminder(ctx, r.ID); err != nil {
+			return fmt.Errorf("erase reminders: %w", err)
+		}
+		counts["reminders"]++
+	}
+
+	tickets, ok := s.services.Support.(support.Eraser)
+	if !ok {
+		return fmt.Errorf("erase tickets: %w", ErrNotSupported)
+	}
+	if counts["tickets"], err = tickets.DeleteUserTickets(ctx, userID); err != nil {
+		return fmt.Errorf("erase tickets: %w", err)
+	}
+
+	documents, ok := s.services.Deanery.(deanery.Eraser)
+	if !ok {
+		return fmt.Errorf("erase documents: %w", ErrNotSupported)
+	}
+	if counts["documents"], err = documents.DeleteUserDocuments(ctx, userID); err != nil {
+		return fmt.Errorf("erase documents: %w", err)
+	}
+
+	trips, ok := s.services.Trips.(businesstrip.Eraser)
+	if !ok {
+		return fmt.Errorf("erase trips: %w", ErrNotSupported)
+	}
+	if counts["trips"], err = trips.DeleteUserTrips(ctx, userID); err != nil {
+		return fmt.Errorf("erase trips: %w", err)
+	}
+
+	loans, ok := s.services.Library.(library.Eraser)
+	if !ok {
+		return fmt.Errorf("anonymize loans: %w", ErrNotSupported)
+	}
+	if counts["loans"], err = loans.AnonymizeUserLoans(ctx, userID, pseudonym); err != nil {
+		return fmt.Errorf("anonymize loans: %w", err)
+	}
+
+	authored, ok := s.services.News.(news.Eraser)
+	if !ok {
+		return fmt.Errorf("anonymize news: %w", ErrNotSupported)
+	}
+	if counts["news"], err = authored.AnonymizeAuthor(ctx, userID, pseudonym, DeletedAuthorName); err != nil {
+		return fmt.Errorf("anonymize news: %w", err)
+	}
+
+	if campaigns, ok := s.services.Campaigns.(campaign.Eraser); ok {
+		if err := campaigns.ForgetUser(ctx, userID); err != nil {
+			return fmt.Errorf("erase campaign visits: %w", err)
+		}
+	}
+
+	if s.services.Chats != nil {
+		chats, err := s.services.Chats.ListChatSettings(ctx)
+		if err != nil {
+			return fmt.Errorf("erase chat settings author: %w", err)
+		}
+		for _, chat := range chats {
+			if chat.UpdatedBy != userID {
+				continue
+			}
+			chat.UpdatedBy = ""
+			if err := s.services.Chats.SaveChatSettings(ctx, chat); err != nil {
+				return fmt.Errorf("erase chat settings author: %w", err)
+			}
+			counts["chats"]++
+		}
+	}
+
+	if s.services.Limiter != nil {
+		if err := s.services.Limiter.Unblock(ctx, userID); err != nil {
+			return fmt.Errorf("erase rate limit state: %w", err)
+		}
+		if err := s.services.Limiter.SetExempt(ctx, userID, false); err != nil {
+			return fmt.Errorf("erase rate limit state: %w", err)
+		}
+	}
+
+	// Ссылка на календарь отключается: по ней больше нечего отдавать
+	if s.services.Calendars != nil {
+		if err := s.services.Calendars.Revoke(ctx, userID); err != nil {
+			return fmt.Errorf("revoke calendar link: %w", err)
+		}
+	}
+
+	if s.services.ScheduleWatch != nil {
+		if err := s.services.ScheduleWatch.DeleteUser(ctx, userID); err != nil {
+			return fmt.Errorf("erase schedule notification settings: %w", err)
+		}
+	}
+
+	if s.services.State != nil {
+		if err := s.services.State.DeleteUserState(ctx, userID); err != nil {
+			return fmt.Errorf("erase dialog state: %w", err)
+		}
+	}
+
+	users, ok := s.services.Users.(user.Eraser)
+	if !ok {
+		return fmt.Errorf("erase profile: %w", ErrNotSupported)
+	}
+	profile, err := s.services.Users.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("erase profile: %w", err)
+	}
+	if profile != nil {
+		if err := users.DeleteUser(ctx, userID); err != nil {
+			return fmt.Errorf("erase profile: %w", err)
+		}
+		counts["profile"] = 1
+	}
+	return nil
+}
+
+// audit записывает действие в журнал. Ошибка журнала не отменяет уже выполненное действие,
+// поэтому она только логируется вместе с содержимым записи
+func (s *Service) audit(ctx context.Context, entry AuditEntry, actionErr error) {
+	entry.At = time.Now()
+	if actionErr != nil {
+		entry.Error = actionErr.Error()
+	}
+
+	logger := s.logger.With().Str("action", entry.Action).Str("user_id", entry.UserID).Str("pseudonym", entry.Pseudonym).Interface("counts", entry.Counts).Logger()
+	if s.services.Audit == nil {
+		logger.Info().Msg("personal data " + entry.Action)
+		return
+	}
+	// Запись в журнал не должна зависеть от отмены запроса, иначе удаление останется без следа
+	if err := s.services.Audit.Record(context.WithoutCancel(ctx), entry); err != nil {
+		logger.Error().Err(err).Str("action_error", entry.Error).Msg("failed to record privacy audit entry")
+		return
+	}
+	logger.Info().Msg("personal data " + entry.Action)
+}
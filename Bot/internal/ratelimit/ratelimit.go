@@ -0,0 +1,130 @@
+// Package ratelimit ограничивает частоту запросов к боту: token bucket'ы на всех пользователей,
+// на пользователя и на пару пользователь-команда, с временной блокировкой за злоупотребления
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("invalid limit")
+
+// Limit - не больше Requests запросов за Per. Запросы восполняются равномерно, всплеск до Requests допускается
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// String возвращает лимит в формате ParseLimit, например "10/1h0m0s"
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// ParseLimit разбирает лимит вида "10/1h" или "5/30s"
+func ParseLimit(s string) (Limit, error) {
+	countPart, perPart, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q, ожидается формат 10/1h", ErrInvalidLimit, s)
+	}
+	count, err := strconv.Atoi(countPart)
+	if err != nil || count <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q, количество должно быть положительным числом", ErrInvalidLimit, s)
+	}
+	per, err := time.ParseDuration(perPart)
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q, период задается как 30s, 1m или 1h", ErrInvalidLimit, s)
+	}
+	return Limit{Requests: count, Per: per}, nil
+}
+
+// Scope - какое ограничение сработало
+type Scope string
+
+const (
+	ScopeGlobal  Scope = "global"  // общий лимит бота
+	ScopeUser    Scope = "user"    // лимит пользователя на все команды
+	ScopeCommand Scope = "command" // лимит пользователя на команду
+	ScopeBlocked Scope = "blocked" // пользователь временно заблокирован
+)
+
+// Цели для переопределения лимитов, кроме команд
+const (
+	TargetGlobal = "global"
+	TargetUser   = "user"
+)
+
+// Decision - результат проверки запроса
+type Decision struct {
+	Allowed    bool
+	Scope      Scope
+	RetryAfter time.Duration
+	// Notify - первый отказ в текущем ожидании: пользователю стоит сообщить, последующие отказы - молча игнорировать
+	Notify bool
+}
+
+// Escalation - временные блокировки за злоупотребления: после Violations отказов за Window пользователь блокируется.
+// Каждая следующая блокировка в течение суток берется из Blocks дальше по списку
+type Escalation struct {
+	Violations int
+	Window     time.Duration
+	Blocks     []time.Duration
+}
+
+// Config - лимиты по умолчанию. Руководители могут переопределить их через Limiter.SetLimit
+type Config struct {
+	Global     Limit
+	User       Limit
+	Commands   map[string]Limit
+	Escalation Escalation
+}
+
+// DefaultConfig - лимиты по умолчанию: /ask расходует токены YandexGPT, /contact создает обращения
+func DefaultConfig() Config {
+	return Config{
+		Global: Limit{Requests: 30, Per: time.Second},
+		User:   Limit{Requests: 20, Per: time.Minute},
+		Commands: map[string]Limit{
+			"/ask":     {Requests: 10, Per: time.Hour},
+			"/contact": {Requests: 3, Per: time.Hour},
+		},
+		Escalation: Escalation{
+			Violations: 10,
+			Window:     time.Minute,
+			Blocks:     []time.Duration{5 * time.Minute, 30 * time.Minute, 6 * time.Hour},
+		},
+	}
+}
+
+// UserStatus - состояние ограничений пользователя
+type UserStatus struct {
+	Exempt       bool
+	BlockedFor   time.Duration // сколько осталось до снятия блокировки; 0 - не заблокирован
+	BlockLevel   int           // сколько раз пользователь блокировался за последние сутки
+	RecentDenied int           // отказов в текущем окне эскалации
+}
+
+// Limiter проверяет запросы и позволяет руководителям управлять лимитами
+type Limiter interface {
+	// Allow расходует по одному токену из общего bucket'а, bucket'а пользователя и, если command не пустая, bucket'а команды
+	Allow(ctx context.Context, userID, command string) (Decision, error)
+	// AllowCommand расходует токен только из bucket'а команды. Используется, когда команда запускается
+	// из уже учтенного запроса (кнопка-подсказка, распознанный свободный текст)
+	AllowCommand(ctx context.Context, userID, command string) (Decision, error)
+
+	// Limits возвращает действующие лимиты: target - TargetGlobal, TargetUser или команда
+	Limits(ctx context.Context) (map[string]Limit, error)
+	SetLimit(ctx context.Context, target string, limit Limit) error
+	ResetLimit(ctx context.Context, target string) error
+
+	Status(ctx context.Context, userID string) (UserStatus, error)
+	Unblock(ctx context.Context, userID string) error
+	SetExempt(ctx context.Context, userID string, exempt bool) error
+}
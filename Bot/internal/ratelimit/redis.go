@@ -0,0 +1,286 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// bucketScript атомарно проверяет несколько token bucket'ов и расходует по токену из каждого,
+// только если токены есть во всех. ARGV: now_ms, затем для каждого ключа емкость и скорость (токенов в мс).
+// Возвращает {1, 0, 0} или {0, ожидание_мс, номер_исчерпанного_ключа}
+var bucketScript = redis2.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens = {}
+local wait = 0
+local denied = 0
+for i = 1, #KEYS do
+	local capacity = tonumber(ARGV[i * 2])
+	local rate = tonumber(ARGV[i * 2 + 1])
+	local data = redis.call("HMGET", KEYS[i], "tokens", "ts")
+	local t = tonumber(data[1])
+	local ts = tonumber(data[2])
+	if t == nil or ts == nil then
+		t = capacity
+		ts = now
+	end
+	t = math.min(capacity, t + math.max(0, now - ts) * rate)
+	tokens[i] = t
+	if t < 1 then
+		local w = math.ceil((1 - t) / rate)
+		if w > wait then
+			wait = w
+			denied = i
+		end
+	end
+end
+if denied > 0 then
+	return {0, wait, denied}
+end
+for i = 1, #KEYS do
+	local capacity = tonumber(ARGV[i * 2])
+	local rate = tonumber(ARGV[i * 2 + 1])
+	redis.call("HSET", KEYS[i], "tokens", tostring(tokens[i] - 1), "ts", now)
+	redis.call("PEXPIRE", KEYS[i], math.ceil(capacity / rate))
+end
+return {1, 0, 0}
+`)
+
+// escalateScript учитывает отказ и, если отказов в окне набралось достаточно, блокирует пользователя.
+// Счетчик и срок его окна задаются одним шагом, чтобы счетчик не остался без срока и не копился вечно.
+// KEYS: violations, level, block; ARGV: окно_мс, порог, срок_уровня_мс, затем длительности блокировок в мс.
+// Возвращает длительность новой блокировки в мс или 0
+var escalateScript = redis2.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+if count < tonumber(ARGV[2]) then
+	return 0
+end
+local level = redis.call("INCR", KEYS[2])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+local block = tonumber(ARGV[3 + math.min(level, #ARGV - 3)])
+redis.call("SET", KEYS[3], level, "PX", block)
+redis.call("DEL", KEYS[1])
+return block
+`)
+
+// levelTTL - сколько помнить прошлые блокировки пользователя для эскалации
+const levelTTL = 24 * time.Hour
+
+// RedisLimiter хранит bucket'ы, блокировки и переопределенные лимиты в Redis,
+// поэтому лимиты общие для всех экземпляров бота
+type RedisLimiter struct {
+	client redis2.Cmdable
+	prefix string
+	config Config
+}
+
+func NewRedisLimiter(client redis2.Cmdable, config Config, prefix string) *RedisLimiter {
+	if prefix == "" {
+		prefix = "maxbot:ratelimit:"
+	}
+	return &RedisLimiter{client: client, prefix: prefix, config: config}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, userID, command string) (Decision, error) {
+	return l.allow(ctx, userID, command, false)
+}
+
+func (l *RedisLimiter) AllowCommand(ctx context.Context, userID, command string) (Decision, error) {
+	return l.allow(ctx, userID, command, true)
+}
+
+func (l *RedisLimiter) allow(ctx context.Context, userID, command string, commandOnly bool) (Decision, error) {
+	pipe := l.client.Pipeline()
+	exemptCmd := pipe.SIsMember(ctx, l.prefix+"exempt", userID)
+	blockCmd := pipe.PTTL(ctx, l.prefix+"block:"+userID)
+	overridesCmd := pipe.HGetAll(ctx, l.prefix+"limits")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis2.Nil {
+		return Decision{Allowed: true}, fmt.Errorf("load limits: %w", err)
+	}
+
+	exempt := exemptCmd.Val()
+	if blocked := blockCmd.Val(); !exempt && blocked > 0 {
+		return l.deny(ctx, userID, ScopeBlocked, blocked), nil
+	}
+
+	limits := l.merge(overridesCmd.Val())
+	var (
+		keys         []string
+		scopes       []Scope
+		bucketLimits []Limit
+	)
+	add := func(key string, scope Scope, limit Limit) {
+		if !limit.IsZero() {
+			keys = append(keys, key)
+			scopes = append(scopes, scope)
+			bucketLimits = append(bucketLimits, limit)
+		}
+	}
+	if !commandOnly {
+		add(l.prefix+"bucket:global", ScopeGlobal, limits[TargetGlobal])
+	}
+	if userID != "" && !exempt {
+		if !commandOnly {
+			add(l.prefix+"bucket:user:"+userID, ScopeUser, limits[TargetUser])
+		}
+		if command != "" {
+			add(l.prefix+"bucket:cmd:"+userID+":"+command, ScopeCommand, limits[command])
+		}
+	}
+	if len(keys) == 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	args := []any{time.Now().UnixMilli()}
+	for _, limit := range bucketLimits {
+		rate := float64(limit.Requests) / float64(limit.Per.Milliseconds())
+		args = append(args, limit.Requests, strconv.FormatFloat(rate, 'g', -1, 64))
+	}
+
+	res, err := bucketScript.Run(ctx, l.client, keys, args...).Int64Slice()
+	if err != nil {
+		return Decision{Allowed: true}, fmt.Errorf("take tokens: %w", err)
+	}
+	if res[0] == 1 {
+		return Decision{Allowed: true}, nil
+	}
+
+	scope := scopes[res[2]-1]
+	wait := time.Duration(res[1]) * time.Millisecond
+	if scope == ScopeGlobal {
+		// Общий лимит превышен не по вине пользователя - не эскалируем
+		return l.deny(ctx, userID, scope, wait), nil
+	}
+
+	if block, err := l.escalate(ctx, userID); err != nil {
+		return l.deny(ctx, userID, scope, wait), err
+	} else if block > 0 {
+		return l.deny(ctx, userID, ScopeBlocked, block), nil
+	}
+	return l.deny(ctx, userID, scope, wait), nil
+}
+
+// deny формирует отказ; уведомлять пользователя нужно один раз за время ожидания
+func (l *RedisLimiter) deny(ctx context.Context, userID string, scope Scope, wait time.Duration) Decision {
+	if wait < time.Second {
+		wait = time.Second
+	}
+	notify, err := l.client.SetNX(ctx, l.prefix+"notice:"+userID+":"+string(scope), 1, wait).Result()
+	if err != nil {
+		notify = true
+	}
+	return Decision{Scope: scope, RetryAfter: wait, Notify: notify}
+}
+
+// escalate учитывает отказ и блокирует пользователя, если отказов в окне слишком много.
+// Возвращает длительность новой блокировки или 0
+func (l *RedisLimiter) escalate(ctx context.Context, userID string) (time.Duration, error) {
+	esc := l.config.Escalation
+	if esc.Violations <= 0 || len(esc.Blocks) == 0 || userID == "" {
+		return 0, nil
+	}
+
+	keys := []string{l.prefix + "violations:" + userID, l.prefix + "level:" + userID, l.prefix + "block:" + userID}
+	args := []any{esc.Window.Milliseconds(), esc.Violations, levelTTL.Milliseconds()}
+	for _, block := range esc.Blocks {
+		args = append(args, block.Milliseconds())
+	}
+	block, err := escalateScript.Run(ctx, l.client, keys, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("escalate: %w", err)
+	}
+	return time.Duration(block) * time.Millisecond, nil
+}
+
+// merge накладывает переопределения руководителей на лимиты по умолчанию
+func (l *RedisLimiter) merge(overrides map[string]string) map[string]Limit {
+	limits := make(map[string]Limit, len(l.config.Commands)+2)
+	limits[TargetGlobal] = l.config.Global
+	limits[TargetUser] = l.config.User
+	for command, limit := range l.config.Commands {
+		limits[command] = limit
+	}
+	for target, raw := range overrides {
+		if limit, err := ParseLimit(raw); err == nil {
+			limits[target] = limit
+		}
+	}
+	return limits
+}
+
+func (l *RedisLimiter) Limits(ctx context.Context) (map[string]Limit, error) {
+	overrides, err := l.client.HGetAll(ctx, l.prefix+"limits").Result()
+	if err != nil {
+		return nil, fmt.Errorf("get limits: %w", err)
+	}
+	return l.merge(overrides), nil
+}
+
+func (l *RedisLimiter) SetLimit(ctx context.Context, target string, limit Limit) error {
+	if limit.IsZero() {
+		return fmt.Errorf("%w: %s", ErrInvalidLimit, limit)
+	}
+	if err := l.client.HSet(ctx, l.prefix+"limits", target, limit.String()).Err(); err != nil {
+		return fmt.Errorf("set limit %s: %w", target, err)
+	}
+	return nil
+}
+
+func (l *RedisLimiter) ResetLimit(ctx context.Context, target string) error {
+	if err := l.client.HDel(ctx, l.prefix+"limits", target).Err(); err != nil {
+		return fmt.Errorf("reset limit %s: %w", target, err)
+	}
+	return nil
+}
+
+func (l *RedisLimiter) Status(ctx context.Context, userID string) (UserStatus, error) {
+	pipe := l.client.Pipeline()
+	exemptCmd := pipe.SIsMember(ctx, l.prefix+"exempt", userID)
+	blockCmd := pipe.PTTL(ctx, l.prefix+"block:"+userID)
+	levelCmd := pipe.Get(ctx, l.prefix+"level:"+userID)
+	violationsCmd := pipe.Get(ctx, l.prefix+"violations:"+userID)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis2.Nil {
+		return UserStatus{}, fmt.Errorf("get status %s: %w", userID, err)
+	}
+
+	status := UserStatus{Exempt: exemptCmd.Val()}
+	if blocked := blockCmd.Val(); blocked > 0 {
+		status.BlockedFor = blocked
+	}
+	status.BlockLevel, _ = strconv.Atoi(levelCmd.Val())
+	status.RecentDenied, _ = strconv.Atoi(violationsCmd.Val())
+	return status, nil
+}
+
+// Unblock снимает блокировку и сбрасывает счетчики эскалации
+func (l *RedisLimiter) Unblock(ctx context.Context, userID string) error {
+	err := l.client.Del(ctx,
+		l.prefix+"block:"+userID,
+		l.prefix+"level:"+userID,
+		l.prefix+"violations:"+userID,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("unblock %s: %w", userID, err)
+	}
+	return nil
+}
+
+// SetExempt снимает с пользователя персональные лимиты (общий лимит бота продолжает действовать)
+func (l *RedisLimiter) SetExempt(ctx context.Context, userID string, exempt bool) error {
+	var err error
+	if exempt {
+		err = l.client.SAdd(ctx, l.prefix+"exempt", userID).Err()
+	} else {
+		err = l.client.SRem(ctx, l.prefix+"exempt", userID).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("set exempt %s: %w", userID, err)
+	}
+	return nil
+}
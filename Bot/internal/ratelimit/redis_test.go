@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+const testPrefix = "test:"
+
+func newTestLimiter(t *testing.T, config Config) (*miniredis.Miniredis, *RedisLimiter) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis2.NewClient(&redis2.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, NewRedisLimiter(client, config, testPrefix)
+}
+
+func allow(t *testing.T, l *RedisLimiter, userID, command string) Decision {
+	t.Helper()
+	d, err := l.Allow(context.Background(), userID, command)
+	if err != nil {
+		t.Fatalf("Allow(%s, %s): %v", userID, command, err)
+	}
+	return d
+}
+
+func TestCommandBucket(t *testing.T) {
+	ctx := context.Background()
+	_, l := newTestLimiter(t, Config{Commands: map[string]Limit{"/ask": {Requests: 2, Per: time.Hour}}})
+
+	for i := 0; i < 2; i++ {
+		if d := allow(t, l, "100", "/ask"); !d.Allowed {
+			t.Fatalf("request %d denied: %+v", i+1, d)
+		}
+	}
+	d := allow(t, l, "100", "/ask")
+	if d.Allowed || d.Scope != ScopeCommand || !d.Notify {
+		t.Fatalf("third request = %+v, want command limit with notice", d)
+	}
+	// Токен восполняется за полчаса
+	if d.RetryAfter < 29*time.Minute || d.RetryAfter > 30*time.Minute {
+		t.Errorf("retry after = %v, want about 30m", d.RetryAfter)
+	}
+	if d := allow(t, l, "100", "/ask"); d.Allowed || d.Notify {
+		t.Errorf("fourth request = %+v, want silent denial", d)
+	}
+
+	// Bucket'ы отдельные для каждой пары пользователь-команда
+	if d := allow(t, l, "100", "/news"); !d.Allowed {
+		t.Errorf("other command denied: %+v", d)
+	}
+	if d := allow(t, l, "200", "/ask"); !d.Allowed {
+		t.Errorf("other user denied: %+v", d)
+	}
+	if d, err := l.AllowCommand(ctx, "200", "/ask"); err != nil || !d.Allowed {
+		t.Errorf("AllowCommand = %+v, %v, want allowed", d, err)
+	}
+	if d, err := l.AllowCommand(ctx, "200", "/ask"); err != nil || d.Allowed || d.Scope != ScopeCommand {
+		t.Errorf("AllowCommand over limit = %+v, %v, want command limit", d, err)
+	}
+
+	// Переопределенный лимит действует сразу
+	if err := l.SetLimit(ctx, "/news", Limit{Requests: 1, Per: time.Hour}); err != nil {
+		t.Fatalf("SetLimit: %v", err)
+	}
+	if d := allow(t, l, "300", "/news"); !d.Allowed {
+		t.Errorf("first /news denied: %+v", d)
+	}
+	if d := allow(t, l, "300", "/news"); d.Allowed || d.Scope != ScopeCommand {
+		t.Errorf("second /news = %+v, want command limit", d)
+	}
+
+	// Без лимитов пользователя освобождение от лимитов пропускает все
+	if err := l.SetExempt(ctx, "100", true); err != nil {
+		t.Fatalf("SetExempt: %v", err)
+	}
+	if d := allow(t, l, "100", "/ask"); !d.Allowed {
+		t.Errorf("exempt user denied: %+v", d)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	ctx := context.Background()
+	server, l := newTestLimiter(t, Config{
+		User: Limit{Requests: 1, Per: time.Hour},
+		Escalation: Escalation{
+			Violations: 3,
+			Window:     time.Minute,
+			Blocks:     []time.Duration{5 * time.Minute, 30 * time.Minute},
+		},
+	})
+	violationsKey := testPrefix + "violations:100"
+
+	if d := allow(t, l, "100", ""); !d.Allowed {
+		t.Fatalf("first request denied: %+v", d)
+	}
+	if d := allow(t, l, "100", ""); d.Allowed || d.Scope != ScopeUser {
+		t.Fatalf("second request = %+v, want user limit", d)
+	}
+	// Окно отказов задается вместе с первым отказом
+	if ttl := server.TTL(violationsKey); ttl != time.Minute {
+		t.Errorf("violations ttl = %v, want 1m", ttl)
+	}
+
+	// Окно истекло: отказы считаются заново
+	server.FastForward(time.Minute)
+	if server.Exists(violationsKey) {
+		t.Fatal("violations are kept after the window")
+	}
+
+	block := func(want time.Duration) {
+		t.Helper()
+		for i := 0; i < 2; i++ {
+			if d := allow(t, l, "100", ""); d.Scope != ScopeUser {
+				t.Fatalf("violation %d = %+v, want user limit", i+1, d)
+			}
+		}
+		d := allow(t, l, "100", "")
+		if d.Scope != ScopeBlocked || d.RetryAfter != want {
+			t.Fatalf("third violation = %+v, want block for %v", d, want)
+		}
+	}
+	block(5 * time.Minute)
+
+	status, err := l.Status(ctx, "100")
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if status.BlockLevel != 1 || status.BlockedFor != 5*time.Minute || status.RecentDenied != 0 {
+		t.Errorf("status = %+v, want level 1 blocked for 5m", status)
+	}
+	if ttl := server.TTL(testPrefix + "level:100"); ttl != levelTTL {
+		t.Errorf("level ttl = %v, want %v", ttl, levelTTL)
+	}
+	if d := allow(t, l, "100", ""); d.Scope != ScopeBlocked {
+		t.Errorf("request while blocked = %+v, want blocked", d)
+	}
+
+	// Повторная блокировка в течение суток длиннее, последняя длительность повторяется
+	server.FastForward(5 * time.Minute)
+	block(30 * time.Minute)
+	server.FastForward(30 * time.Minute)
+	block(30 * time.Minute)
+
+	// Снятие блокировки сбрасывает уровень
+	if err := l.Unblock(ctx, "100"); err != nil {
+		t.Fatalf("Unblock: %v", err)
+	}
+	block(5 * time.Minute)
+}
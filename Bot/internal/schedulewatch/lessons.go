@@ -0,0 +1,103 @@
+package schedulewatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/schedule"
+)
+
+// lessonReminderWindow - напоминания создаются только о парах в ближайшие сутки, чтобы не заполнять
+// список /reminder занятиями на две недели вперед
+const lessonReminderWindow = 24 * time.Hour
+
+// syncLessonReminders приводит напоминания о парах (reminder.KindLesson) в соответствие с расписанием:
+// недостающие создаются, а напоминания о перенесенных, отмененных или измененных занятиях удаляются.
+// Рассматриваются только напоминания до to - дальше расписание не загружено. При before == 0
+// удаляются все будущие напоминания о парах. Наступившие напоминания не трогаются: их уже отправляет
+// обработчик напоминаний
+func (w *Watcher) syncLessonReminders(ctx context.Context, userID string, before int, items []schedule.Item, now, to time.Time) error {
+	if w.reminders == nil {
+		return nil
+	}
+	generator, ok := w.reminders.(reminder.Generator)
+	if !ok {
+		return errors.New("reminder service does not support generated reminders")
+	}
+
+	lead := time.Duration(before) * time.Minute
+	if limit := now.Add(lessonReminderWindow); to.After(limit) {
+		to = limit
+	}
+
+	type key struct {
+		at   int64
+		text string
+	}
+	desired := make(map[key]bool)
+	var missing []reminder.Reminder
+	if before > 0 {
+		for _, item := range items {
+			at := item.Time.Add(-lead)
+			if !at.After(now) || !at.Before(to) {
+				continue
+			}
+			text := formatLessonReminder(item, before)
+			k := key{at.UnixMilli(), text}
+			if !desired[k] {
+				desired[k] = true
+				missing = append(missing, reminder.Reminder{DateTime: at, Text: text})
+			}
+		}
+	}
+
+	existing, err := w.reminders.GetActiveReminders(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("get reminders: %w", err)
+	}
+	kept := make(map[key]bool)
+	for _, r := range existing {
+		if r.Kind != reminder.KindLesson || !r.DateTime.After(now) {
+			continue
+		}
+		k := key{r.DateTime.UnixMilli(), r.Text}
+		if desired[k] && !kept[k] {
+			kept[k] = true
+			continue
+		}
+		if before > 0 && !r.DateTime.Before(to) {
+			continue
+		}
+		if err := w.reminders.DeleteReminder(ctx, r.ID); err != nil {
+			return fmt.Errorf("delete lesson reminder: %w", err)
+		}
+	}
+
+	for _, r := range missing {
+		if kept[key{r.DateTime.UnixMilli(), r.Text}] {
+			continue
+		}
+		if _, err := generator.CreateGeneratedReminder(ctx, reminder.KindLesson, userID, r.Text, r.DateTime); err != nil {
+			return fmt.Errorf("create lesson reminder: %w", err)
+		}
+	}
+	return nil
+}
+
+// formatLessonReminder - текст напоминания о паре: дисциплина, время, аудитория и преподаватель
+func formatLessonReminder(item schedule.Item, before int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("📚 Через %d мин: %s\n", before, item.Discipline))
+	b.WriteString(fmt.Sprintf("🕐 %s–%s", item.Time.Format("15:04"), item.End().Format("15:04")))
+	if item.Location != "" {
+		b.WriteString("\n🏫 " + item.Location)
+	}
+	if item.Instructor != "" {
+		b.WriteString("\n👤 " + item.Instructor)
+	}
+	return b.String()
+}
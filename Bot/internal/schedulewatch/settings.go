@@ -0,0 +1,96 @@
+package schedulewatch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRemindBefore - напомнить о паре можно не раньше, чем за столько минут
+const MaxRemindBefore = 180
+
+var (
+	// ErrInvalidQuietHours - тихие часы записаны не в формате "23:00-08:00"
+	ErrInvalidQuietHours = errors.New("invalid quiet hours")
+	// ErrInvalidRemindBefore - время напоминания о паре не число минут от 1 до MaxRemindBefore
+	ErrInvalidRemindBefore = errors.New("invalid lesson reminder lead time")
+)
+
+// Settings - настройки уведомлений пользователя об изменениях расписания и напоминаний о парах.
+// Нулевое значение - уведомления включены, тихих часов нет, о парах не напоминать
+type Settings struct {
+	Disabled     bool `json:"disabled,omitempty"`
+	QuietFrom    int  `json:"quiet_from,omitempty"`    // начало тихих часов, минуты от полуночи
+	QuietTo      int  `json:"quiet_to,omitempty"`      // конец тихих часов; равен началу - тихих часов нет
+	RemindBefore int  `json:"remind_before,omitempty"` // за сколько минут до пары напоминать; 0 - не напоминать
+}
+
+// HasQuietHours сообщает, заданы ли тихие часы
+func (s Settings) HasQuietHours() bool {
+	return s.QuietFrom != s.QuietTo
+}
+
+// InQuietHours сообщает, попадает ли t в тихие часы. Тихие часы могут переходить через полночь (23:00-08:00)
+func (s Settings) InQuietHours(t time.Time) bool {
+	if !s.HasQuietHours() {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if s.QuietFrom < s.QuietTo {
+		return minute >= s.QuietFrom && minute < s.QuietTo
+	}
+	return minute >= s.QuietFrom || minute < s.QuietTo
+}
+
+// QuietHours возвращает тихие часы в виде "23:00–08:00"
+func (s Settings) QuietHours() string {
+	return formatMinute(s.QuietFrom) + "–" + formatMinute(s.QuietTo)
+}
+
+// ParseQuietHours разбирает тихие часы "23:00-08:00" (допускаются "23-8" и длинное тире)
+func ParseQuietHours(s string) (from, to int, err error) {
+	s = strings.NewReplacer("–", "-", "—", "-", " ", "").Replace(s)
+	fromText, toText, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %q", ErrInvalidQuietHours, s)
+	}
+	if from, err = parseMinute(fromText); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parseMinute(toText); err != nil {
+		return 0, 0, err
+	}
+	if from == to {
+		return 0, 0, fmt.Errorf("%w: empty interval", ErrInvalidQuietHours)
+	}
+	return from, to, nil
+}
+
+// ParseRemindBefore разбирает, за сколько минут до пары напоминать: "15" или "15 мин"
+func ParseRemindBefore(s string) (int, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "мин"))
+	minutes, err := strconv.Atoi(s)
+	if err != nil || minutes < 1 || minutes > MaxRemindBefore {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRemindBefore, s)
+	}
+	return minutes, nil
+}
+
+func parseMinute(s string) (int, error) {
+	hourText, minuteText, hasMinutes := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hourText)
+	minute := 0
+	if err == nil && hasMinutes {
+		minute, err = strconv.Atoi(minuteText)
+	}
+	if err != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidQuietHours, s)
+	}
+	return hour*60 + minute, nil
+}
+
+func formatMinute(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
@@ -0,0 +1,113 @@
+package schedulewatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+
+	"first-max-bot/internal/services/schedule"
+)
+
+// snapshotTTL - снимок пользователя, которого давно не проверяли (удалил бота, потерял роль), удаляется
+const snapshotTTL = 30 * 24 * time.Hour
+
+// Snapshot - расписание пользователя на момент последней проверки
+type Snapshot struct {
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	StudyGroup string          `json:"study_group,omitempty"` // при смене группы снимок создается заново без уведомлений
+	Items      []schedule.Item `json:"items"`
+}
+
+// Store хранит настройки уведомлений и снимки расписания
+type Store interface {
+	GetSettings(ctx context.Context, userID string) (Settings, error) // нулевые настройки, если пользователь их не менял
+	SaveSettings(ctx context.Context, userID string, settings Settings) error
+	GetSnapshot(ctx context.Context, userID string) (*Snapshot, error) // nil, если снимка нет
+	SaveSnapshot(ctx context.Context, userID string, snapshot Snapshot) error
+	DeleteSnapshot(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, userID string) error // настройки и снимок, по запросу пользователя (/delete_me)
+}
+
+// RedisStore хранит настройки без срока действия, а снимки - snapshotTTL.
+// Ключи: "<prefix>settings:<ID пользователя>" и "<prefix>snapshot:<ID пользователя>"
+type RedisStore struct {
+	client redis2.Cmdable
+	prefix string
+}
+
+func NewRedisStore(client redis2.Cmdable, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "maxbot:schedwatch:"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) GetSettings(ctx context.Context, userID string) (Settings, error) {
+	var settings Settings
+	raw, err := s.client.Get(ctx, s.prefix+"settings:"+userID).Bytes()
+	if err == redis2.Nil {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, fmt.Errorf("get notification settings: %w", err)
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return settings, fmt.Errorf("decode notification settings: %w", err)
+	}
+	return settings, nil
+}
+
+func (s *RedisStore) SaveSettings(ctx context.Context, userID string, settings Settings) error {
+	payload, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.prefix+"settings:"+userID, payload, 0).Err(); err != nil {
+		return fmt.Errorf("save notification settings: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetSnapshot(ctx context.Context, userID string) (*Snapshot, error) {
+	raw, err := s.client.Get(ctx, s.prefix+"snapshot:"+userID).Bytes()
+	if err == redis2.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get schedule snapshot: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, fmt.Errorf("decode schedule snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (s *RedisStore) SaveSnapshot(ctx context.Context, userID string, snapshot Snapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.prefix+"snapshot:"+userID, payload, snapshotTTL).Err(); err != nil {
+		return fmt.Errorf("save schedule snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) DeleteSnapshot(ctx context.Context, userID string) error {
+	if err := s.client.Del(ctx, s.prefix+"snapshot:"+userID).Err(); err != nil {
+		return fmt.Errorf("delete schedule snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) DeleteUser(ctx context.Context, userID string) error {
+	if err := s.client.Del(ctx, s.prefix+"settings:"+userID, s.prefix+"snapshot:"+userID).Err(); err != nil {
+		return fmt.Errorf("delete schedule notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// Package schedulewatch следит за изменениями расписания: периодически сохраняет снимок ближайших занятий
+// каждого пользователя, сравнивает его с предыдущим и сообщает о переносах, отменах, смене аудитории
+// и преподавателя. Пользователь может отключить уведомления и задать тихие часы (/notify), а также
+// включить напоминания перед каждой парой - они создаются в сервисе напоминаний и пересоздаются
+// при изменении расписания
+package schedulewatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/schedule"
+	"first-max-bot/internal/services/user"
+)
+
+// maxChangesInMessage - больше изменений в одном сообщении не перечисляется
+const maxChangesInMessage = 10
+
+var weekdayShortNames = [...]string{"вс", "пн", "вт", "ср", "чт", "пт", "сб"}
+
+// Notifier отправляет пользователю сообщение
+type Notifier func(ctx context.Context, userID, text string) error
+
+type options struct {
+	horizon time.Duration
+}
+
+type Option func(*options)
+
+// WithHorizon задает, на сколько вперед отслеживаются занятия (по умолчанию две недели)
+func WithHorizon(horizon time.Duration) Option {
+	return func(o *options) {
+		o.horizon = horizon
+	}
+}
+
+// Watcher сравнивает расписание пользователей со снимками, отправляет уведомления об изменениях
+// и поддерживает напоминания о парах
+type Watcher struct {
+	schedule  schedule.Service
+	users     user.Service
+	reminders reminder.Service // nil - напоминания о парах не создаются
+	store     Store
+	notify    Notifier
+	opts      options
+	logger    zerolog.Logger
+}
+
+func NewWatcher(scheduleService schedule.Service, users user.Service, reminders reminder.Service, store Store, notify Notifier, logger zerolog.Logger, opts ...Option) *Watcher {
+	o := options{horizon: 14 * 24 * time.Hour}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Watcher{
+		schedule:  scheduleService,
+		users:     users,
+		reminders: reminders,
+		store:     store,
+		notify:    notify,
+		opts:      o,
+		logger:    logger,
+	}
+}
+
+// Check проверяет расписание всех пользователей с доступом к уведомлениям. В тихие часы снимок
+// не обновляется: изменения накопятся и придут одним сообщением после тихих часов. Напоминания о парах
+// обновляются и в тихие часы
+func (w *Watcher) Check(ctx context.Context, now time.Time) {
+	users, err := w.users.GetAllUsers(ctx)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("failed to list users")
+		return
+	}
+
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return
+		}
+		if !u.HasCapability(user.CapabilityScheduleAlerts) {
+			continue
+		}
+		if err := w.checkUser(ctx, u, now); err != nil {
+			w.logger.Error().Err(err).Str("user_id", u.UserID).Msg("failed to check schedule changes")
+		}
+	}
+}
+
+func (w *Watcher) checkUser(ctx context.Context, u user.User, now time.Time) error {
+	settings, err := w.store.GetSettings(ctx, u.UserID)
+	if err != nil {
+		return err
+	}
+	if settings.Disabled {
+		// Без снимка повторное включение не пришлет изменения за время, пока уведомления были выключены
+		if err := w.store.DeleteSnapshot(ctx, u.UserID); err != nil {
+			return err
+		}
+	}
+	watchChanges := !settings.Disabled && !settings.InQuietHours(now)
+	if !watchChanges && settings.RemindBefore == 0 {
+		// Напоминания, созданные до отключения, больше не нужны
+		return w.syncLessonReminders(ctx, u.UserID, 0, nil, now, now)
+	}
+
+	from, to := now, now.Add(w.opts.horizon)
+	items, err := w.schedule.GetSchedule(ctx, u.UserID, from, to)
+	if errors.Is(err, schedule.ErrOutOfRange) {
+		// Источник знает только сегодняшние занятия (uni-back)
+		_, to = schedule.DayRange(now)
+		items, err = w.schedule.GetSchedule(ctx, u.UserID, from, to)
+	}
+	if errors.Is(err, schedule.ErrNoGroup) {
+		return w.syncLessonReminders(ctx, u.UserID, 0, nil, now, now)
+	}
+	if err != nil {
+		// Снимок и напоминания не трогаем: после сбоя источника сравнение продолжится с последним
+		// известным расписанием
+		return fmt.Errorf("get schedule: %w", err)
+	}
+
+	if err := w.syncLessonReminders(ctx, u.UserID, settings.RemindBefore, items, now, to); err != nil {
+		return err
+	}
+	if !watchChanges {
+		return nil
+	}
+
+	current := Snapshot{From: from, To: to, StudyGroup: u.StudyGroup, Items: items}
+	previous, err := w.store.GetSnapshot(ctx, u.UserID)
+	if err != nil {
+		return err
+	}
+	if previous == nil || previous.StudyGroup != u.StudyGroup {
+		return w.store.SaveSnapshot(ctx, u.UserID, current)
+	}
+
+	// Сравниваются только занятия, которые есть в обоих снимках: еще не начавшиеся и не дальше
+	// горизонта предыдущего снимка
+	compareTo := previous.To
+	if to.Before(compareTo) {
+		compareTo = to
+	}
+	changes := schedule.Diff(previous.Items, items, from, compareTo)
+	if len(changes) > 0 {
+		if err := w.notify(ctx, u.UserID, FormatChanges(changes)); err != nil {
+			// Снимок не обновляем, чтобы отправить изменения при следующей проверке
+			return fmt.Errorf("send schedule changes: %w", err)
+		}
+		w.logger.Info().Str("user_id", u.UserID).Int("changes", len(changes)).Msg("schedule changes sent")
+	}
+	return w.store.SaveSnapshot(ctx, u.UserID, current)
+}
+
+// FormatChanges описывает изменения расписания для пользователя: что было и что стало
+func FormatChanges(changes []schedule.Change) string {
+	var b strings.Builder
+	b.WriteString("🔔 Изменения в расписании\n")
+
+	for i, c := range changes {
+		if i == maxChangesInMessage {
+			b.WriteString(fmt.Sprintf("\n…и еще изменений: %d. Расписание на неделю: /schedule неделя\n", len(changes)-i))
+			break
+		}
+		b.WriteString("\n")
+		switch c.Kind {
+		case schedule.ChangeMoved:
+			b.WriteString(fmt.Sprintf("🔄 Перенесено: %s\n", c.Before.Discipline))
+			b.WriteString(fmt.Sprintf("было: %s\n", formatLesson(c.Before)))
+			b.WriteString(fmt.Sprintf("стало: %s\n", formatLesson(c.After)))
+			if c.InstructorChanged() {
+				b.WriteString(fmt.Sprintf("👤 Преподаватель: %s → %s\n", valueOrDash(c.Before.Instructor), valueOrDash(c.After.Instructor)))
+			}
+		case schedule.ChangeUpdated:
+			b.WriteString(fmt.Sprintf("✏️ Изменено: %s, %s\n", c.Before.Discipline, formatWhen(c.Before)))
+			if c.LocationChanged() {
+				b.WriteString(fmt.Sprintf("🏫 Аудитория: %s → %s\n", valueOrDash(c.Before.Location), valueOrDash(c.After.Location)))
+			}
+			if c.InstructorChanged() {
+				b.WriteString(fmt.Sprintf("👤 Преподаватель: %s → %s\n", valueOrDash(c.Before.Instructor), valueOrDash(c.After.Instructor)))
+			}
+			if !c.Before.End().Equal(c.After.End()) {
+				b.WriteString(fmt.Sprintf("🕐 Окончание: %s → %s\n", c.Before.End().Format("15:04"), c.After.End().Format("15:04")))
+			}
+		case schedule.ChangeCancelled:
+			b.WriteString(fmt.Sprintf("❌ Отменено: %s\n", c.Before.Discipline))
+			b.WriteString(fmt.Sprintf("было: %s\n", formatLesson(c.Before)))
+		case schedule.ChangeAdded:
+			b.WriteString(fmt.Sprintf("➕ Добавлено: %s\n", c.After.Discipline))
+			b.WriteString(formatLesson(c.After) + "\n")
+			if c.After.Instructor != "" {
+				b.WriteString(fmt.Sprintf("👤 %s\n", c.After.Instructor))
+			}
+		}
+	}
+
+	b.WriteString("\nРасписание: /schedule\nНастроить уведомления: /notify")
+	return b.String()
+}
+
+// formatLesson - "пн 13.10, 09:00–10:30, Корпус А, ауд. 302"
+func formatLesson(item schedule.Item) string {
+	text := formatWhen(item)
+	if item.Location != "" {
+		text += ", " + item.Location
+	}
+	return text
+}
+
+func formatWhen(item schedule.Item) string {
+	return fmt.Sprintf("%s %s, %s–%s", weekdayShortNames[item.Time.Weekday()], item.Time.Format("02.01"),
+		item.Time.Format("15:04"), item.End().Format("15:04"))
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
@@ -0,0 +1,229 @@
+// Package secret шифрует чувствительные поля пользователей (токен Moodle) ключами AES-256-GCM.
+// Формат шифротекста - как у AesEncryptionService в uni-back (base64 IV и base64 шифротекста через ":"),
+// с версией и ID ключа впереди, чтобы ключи можно было менять: "v1:<ID ключа>:<IV>:<шифротекст>"
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	version  = "v1"
+	keySize  = 32 // AES-256
+	ivLength = 12 // как GCM_IV_LENGTH в uni-back
+)
+
+// Redacted выводится вместо значения в логах и выгрузках
+const Redacted = "[redacted]"
+
+var (
+	ErrNotSealed  = errors.New("value is not encrypted")
+	ErrUnknownKey = errors.New("unknown encryption key")
+	ErrInvalidKey = errors.New("invalid encryption key")
+)
+
+// Sealed - зашифрованное значение в формате "v1:<ID ключа>:<IV>:<шифротекст>".
+// В JSON и базе хранится как есть, а String (и значит логи через fmt) значение скрывает
+type Sealed string
+
+func (s Sealed) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+func (s Sealed) GoString() string {
+	return s.String()
+}
+
+func (s Sealed) IsZero() bool {
+	return s == ""
+}
+
+// KeyID возвращает ID ключа, которым зашифровано значение; ok=false, если значение не зашифровано
+func (s Sealed) KeyID() (string, bool) {
+	parts := strings.Split(string(s), ":")
+	if len(parts) != 4 || parts[0] != version || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// Keyring хранит ключи по ID. Новые значения шифруются активным ключом,
+// расшифровать можно любым ключом из набора - старые ключи остаются, пока данные не перешифрованы
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewKeyring создает набор ключей. Ключ - 32 байта; active должен быть в keys
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidKey)
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active key %q not found", ErrUnknownKey, active)
+	}
+
+	k := &Keyring{active: active, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("%w: key id %q", ErrInvalidKey, id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("%w: key %q must be %d bytes, got %d", ErrInvalidKey, id, keySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidKey, id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidKey, id, err)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeys разбирает ключи из строки вида "2026-10:<base64>,2026-01:<base64>".
+// Если active пуст, активным становится первый ключ в списке
+func ParseKeys(spec, active string) (*Keyring, error) {
+	keys := make(map[string][]byte)
+	first := ""
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q, expected <id>:<base64>", ErrInvalidKey, item)
+		}
+		id = strings.TrimSpace(id)
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q is not base64: %v", ErrInvalidKey, id, err)
+		}
+		if _, dup := keys[id]; dup {
+			return nil, fmt.Errorf("%w: duplicate key id %q", ErrInvalidKey, id)
+		}
+		keys[id] = key
+		if first == "" {
+			first = id
+		}
+	}
+	if active == "" {
+		active = first
+	}
+	return NewKeyring(active, keys)
+}
+
+// NewEphemeralKeyring создает набор из одного случайного ключа, как uni-back при запуске.
+// Значения, зашифрованные им, после перезапуска не расшифровать - только для разработки
+func NewEphemeralKeyring() (*Keyring, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring("ephemeral", map[string][]byte{"ephemeral": key})
+}
+
+// GenerateKey возвращает случайный 32-байтный ключ
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	return key, nil
+}
+
+// ActiveKeyID возвращает ID ключа, которым шифруются новые значения
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// KeyIDs возвращает ID всех ключей набора
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.aeads))
+	for id := range k.aeads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Seal шифрует значение активным ключом. Пустая строка остается пустой
+func (k *Keyring) Seal(plaintext string) (Sealed, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	iv := make([]byte, ivLength)
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("generate iv: %w", err)
+	}
+	ciphertext := k.aeads[k.active].Seal(nil, iv, []byte(plaintext), nil)
+	return Sealed(version + ":" + k.active + ":" +
+		base64.StdEncoding.EncodeToString(iv) + ":" + base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
+
+// Open расшифровывает значение ключом, которым оно зашифровано. Пустое значение - пустая строка
+func (k *Keyring) Open(s Sealed) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	parts := strings.Split(string(s), ":")
+	if len(parts) != 4 || parts[0] != version {
+		return "", ErrNotSealed
+	}
+	aead, ok := k.aeads[parts[1]]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, parts[1])
+	}
+	iv, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil || len(iv) != ivLength {
+		return "", fmt.Errorf("invalid iv")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	plaintext, err := aead.Open(nil, iv, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt with key %q: %w", parts[1], err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRekey сообщает, что значение нужно перешифровать активным ключом:
+// оно хранится открытым текстом (до включения шифрования) или зашифровано другим ключом
+func (k *Keyring) NeedsRekey(s Sealed) bool {
+	if s == "" {
+		return false
+	}
+	id, ok := s.KeyID()
+	return !ok || id != k.active
+}
+
+// Rekey перешифровывает значение активным ключом. Незашифрованное значение считается открытым текстом
+func (k *Keyring) Rekey(s Sealed) (Sealed, error) {
+	if !k.NeedsRekey(s) {
+		return s, nil
+	}
+	plaintext := string(s)
+	if _, ok := s.KeyID(); ok {
+		var err error
+		if plaintext, err = k.Open(s); err != nil {
+			return "", err
+		}
+	}
+	return k.Seal(plaintext)
+}
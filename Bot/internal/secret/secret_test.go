@@ -0,0 +1,165 @@
+package secret
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, active string, ids ...string) (*Keyring, map[string][]byte) {
+	t.Helper()
+	keys := make(map[string][]byte, len(ids))
+	for _, id := range ids {
+		key, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[id] = key
+	}
+	k, err := NewKeyring(active, keys)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	return k, keys
+}
+
+func TestSealOpen(t *testing.T) {
+	k, _ := newTestKeyring(t, "2026-10", "2026-10")
+
+	for _, plaintext := range []string{"moodle-token", "токен с пробелами и : двоеточием"} {
+		sealed, err := k.Seal(plaintext)
+		if err != nil {
+			t.Fatalf("Seal: %v", err)
+		}
+		if strings.Contains(string(sealed), plaintext) {
+			t.Errorf("sealed value %q contains plaintext", sealed)
+		}
+		if id, ok := sealed.KeyID(); !ok || id != "2026-10" {
+			t.Errorf("key id = %q, %v, want 2026-10", id, ok)
+		}
+		got, err := k.Open(sealed)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		if got != plaintext {
+			t.Errorf("Open = %q, want %q", got, plaintext)
+		}
+	}
+
+	// Один и тот же текст каждый раз шифруется с новым IV
+	a, _ := k.Seal("moodle-token")
+	b, _ := k.Seal("moodle-token")
+	if a == b {
+		t.Error("two seals of the same value are equal")
+	}
+
+	if sealed, err := k.Seal(""); err != nil || sealed != "" {
+		t.Errorf("Seal(\"\") = %q, %v, want empty", sealed, err)
+	}
+}
+
+func TestOpenAfterRotation(t *testing.T) {
+	old, keys := newTestKeyring(t, "2026-01", "2026-01")
+	sealed, err := old.Seal("moodle-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// Новый активный ключ, старый оставлен для чтения
+	newKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := fmt.Sprintf("2026-10:%s,2026-01:%s",
+		base64.StdEncoding.EncodeToString(newKey), base64.StdEncoding.EncodeToString(keys["2026-01"]))
+	rotated, err := ParseKeys(spec, "")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	if rotated.ActiveKeyID() != "2026-10" {
+		t.Fatalf("active key = %q, want first key 2026-10", rotated.ActiveKeyID())
+	}
+
+	got, err := rotated.Open(sealed)
+	if err != nil || got != "moodle-token" {
+		t.Fatalf("Open with old key = %q, %v", got, err)
+	}
+	if !rotated.NeedsRekey(sealed) {
+		t.Error("value sealed with old key does not need rekey")
+	}
+
+	rekeyed, err := rotated.Rekey(sealed)
+	if err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if id, _ := rekeyed.KeyID(); id != "2026-10" {
+		t.Errorf("rekeyed key id = %q, want 2026-10", id)
+	}
+	if got, err := rotated.Open(rekeyed); err != nil || got != "moodle-token" {
+		t.Errorf("Open rekeyed = %q, %v", got, err)
+	}
+	if rotated.NeedsRekey(rekeyed) {
+		t.Error("rekeyed value still needs rekey")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	k, _ := newTestKeyring(t, "a", "a")
+	other, _ := newTestKeyring(t, "b", "b")
+
+	sealed, err := k.Seal("moodle-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	parts := strings.Split(string(sealed), ":")
+	ciphertext, _ := base64.StdEncoding.DecodeString(parts[3])
+	ciphertext[0] ^= 0xff
+	tampered := Sealed(strings.Join([]string{parts[0], parts[1], parts[2], base64.StdEncoding.EncodeToString(ciphertext)}, ":"))
+
+	fromOther, err := other.Seal("moodle-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value Sealed
+		want  error // nil - достаточно любой ошибки
+	}{
+		{"tampered ciphertext", tampered, nil},
+		{"unknown key id", fromOther, ErrUnknownKey},
+		{"plaintext", "moodle-token", ErrNotSealed},
+	}
+	for _, tt := range tests {
+		got, err := k.Open(tt.value)
+		if err == nil {
+			t.Errorf("%s: Open = %q, want error", tt.name, got)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSealedStringRedacts(t *testing.T) {
+	k, _ := newTestKeyring(t, "a", "a")
+	sealed, err := k.Seal("moodle-token")
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	for _, out := range []string{sealed.String(), fmt.Sprint(sealed), fmt.Sprintf("%v %+v %#v", sealed, sealed, sealed)} {
+		if strings.Contains(out, string(sealed)) || strings.Contains(out, "moodle-token") {
+			t.Errorf("output %q reveals the value", out)
+		}
+	}
+	if sealed.String() != Redacted {
+		t.Errorf("String = %q, want %q", sealed.String(), Redacted)
+	}
+	if Sealed("").String() != "" {
+		t.Error("empty value is not printed as empty")
+	}
+}
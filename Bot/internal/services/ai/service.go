@@ -15,6 +15,12 @@ type Service interface {
 	AskQuestion(ctx context.Context, question string, contextData ContextData) (string, error)
 }
 
+// Completer - необязательное расширение Service: ответ модели на произвольный промпт
+// без обертки для вопросов пользователя. Используется, например, для классификации запросов
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
 // ContextData содержит контекстную информацию о пользователе
 type ContextData struct {
 	UserInfo    UserInfo    `json:"user_info"`
@@ -130,19 +136,32 @@ func (s *YandexGPTService) AskQuestion(ctx context.Context, question string, con
 	return cleaned, nil
 }
 
+// Complete отправляет промпт в YandexGPT как есть
+func (s *YandexGPTService) Complete(ctx context.Context, prompt string) (string, error) {
+	response, err := s.callYandexGPT(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to call YandexGPT: %w", err)
+	}
+	return s.cleanResponse(response), nil
+}
+
 // buildPrompt создает промпт для YandexGPT с контекстом пользователя
 func (s *YandexGPTService) buildPrompt(question string, contextData ContextData) string {
 	var contextParts []string
 
-	// Информация о пользователе
-	contextParts = append(contextParts, fmt.Sprintf("Информация о пользователе:\n- Имя: %s %s\n- Возраст: %d\n- Пол: %s\n- Email: %s\n- Роль: %s",
-		contextData.UserInfo.FirstName,
-		contextData.UserInfo.LastName,
-		contextData.UserInfo.Age,
-		contextData.UserInfo.Gender,
-		contextData.UserInfo.Email,
-		contextData.UserInfo.Role,
-	))
+	// Информация о пользователе; в групповых чатах она не передается
+	if contextData.UserInfo != (UserInfo{}) {
+		contextParts = append(contextParts, fmt.Sprintf("Информация о пользователе:\n- Имя: %s %s\n- Возраст: %d\n- Пол: %s\n- Email: %s\n- Роль: %s",
+			contextData.UserInfo.FirstName,
+			contextData.UserInfo.LastName,
+			contextData.UserInfo.Age,
+			contextData.UserInfo.Gender,
+			contextData.UserInfo.Email,
+			contextData.UserInfo.Role,
+		))
+	} else {
+		contextParts = append(contextParts, "Информация о пользователе: не передается")
+	}
 
 	// Расписание
 	if len(contextData.Schedule) > 0 {
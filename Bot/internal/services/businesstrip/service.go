@@ -24,6 +24,17 @@ type Service interface {
 	GetTrip(ctx context.Context, tripID string) (*Trip, error)
 }
 
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех командировок с сохранением ID
+type Archiver interface {
+	ExportTrips(ctx context.Context) ([]Trip, error)
+	RestoreTrip(ctx context.Context, trip Trip) error // создает командировку или заменяет существующую с тем же ID
+}
+
+// Eraser - необязательная возможность сервиса: удаление командировок пользователя по его запросу (/delete_me)
+type Eraser interface {
+	DeleteUserTrips(ctx context.Context, userID string) (int, error) // возвращает число удаленных командировок
+}
+
 type mockService struct {
 	trips map[string]*Trip
 }
@@ -88,3 +99,44 @@ func (s *mockService) GetTrip(ctx context.Context, tripID string) (*Trip, error)
 	return trip, nil
 }
 
+func (s *mockService) ExportTrips(ctx context.Context) ([]Trip, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	var result []Trip
+	for _, trip := range s.trips {
+		result = append(result, *trip)
+	}
+	return result, nil
+}
+
+func (s *mockService) RestoreTrip(ctx context.Context, trip Trip) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.trips[trip.ID] = &trip
+	return nil
+}
+
+func (s *mockService) DeleteUserTrips(ctx context.Context, userID string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	deleted := 0
+	for id, trip := range s.trips {
+		if trip.UserID == userID {
+			delete(s.trips, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
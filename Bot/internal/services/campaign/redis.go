@@ -0,0 +1,189 @@
+package campaign
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// redisService хранит кампании и счетчики в Redis, чтобы статистика /links переживала перезапуск
+// и была общей для всех экземпляров бота. Ключи:
+//   - "<prefix>c:<code>" - hash кампании (title, created_by, created_at, scans, unique_users, conversions)
+//   - "<prefix>codes" - sorted set кодов по времени создания
+//   - "<prefix>visitors:<code>" - set пользователей, открывавших ссылку
+//   - "<prefix>first:<userID>" - код кампании, по которой пользователь пришел впервые
+//   - "<prefix>converted:<userID>" - регистрация пользователя уже засчитана
+type redisService struct {
+	client redis2.Cmdable
+	prefix string
+}
+
+// NewRedisService возвращает Service, хранящий данные в Redis; пустой prefix - "maxbot:campaigns:"
+func NewRedisService(client redis2.Cmdable, prefix string) Service {
+	if prefix == "" {
+		prefix = "maxbot:campaigns:"
+	}
+	return &redisService{client: client, prefix: prefix}
+}
+
+// scanScript засчитывает переход: KEYS - hash кампании, посетители, первая кампания пользователя;
+// ARGV - userID, code. Возвращает 0, если кампании нет
+var scanScript = redis2.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HINCRBY", KEYS[1], "scans", 1)
+if redis.call("SADD", KEYS[2], ARGV[1]) == 1 then
+	redis.call("HINCRBY", KEYS[1], "unique_users", 1)
+end
+redis.call("SETNX", KEYS[3], ARGV[2])
+return 1
+`)
+
+// conversionScript засчитывает регистрацию один раз: KEYS - первая кампания пользователя, отметка о конверсии;
+// ARGV - префикс ключей кампаний
+var conversionScript = redis2.NewScript(`
+local code = redis.call("GET", KEYS[1])
+if not code then
+	return 0
+end
+local campaign = ARGV[1] .. code
+if redis.call("EXISTS", campaign) == 0 then
+	return 0
+end
+if redis.call("SETNX", KEYS[2], "1") == 0 then
+	return 0
+end
+redis.call("HINCRBY", campaign, "conversions", 1)
+return 1
+`)
+
+func (s *redisService) campaignKey(code string) string {
+	return s.prefix + "c:" + code
+}
+
+func (s *redisService) CreateCampaign(ctx context.Context, code, title, createdBy string) (*Campaign, error) {
+	if err := ValidateCode(code); err != nil {
+		return nil, err
+	}
+
+	c := &Campaign{
+		Code:      code,
+		Title:     title,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	added, err := s.client.ZAddNX(ctx, s.prefix+"codes", redis2.Z{Score: float64(c.CreatedAt.UnixMilli()), Member: code}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("create campaign: %w", err)
+	}
+	if added == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyExist, code)
+	}
+	err = s.client.HSet(ctx, s.campaignKey(code),
+		"title", c.Title,
+		"created_by", c.CreatedBy,
+		"created_at", c.CreatedAt.UnixMilli(),
+		"scans", 0,
+		"unique_users", 0,
+		"conversions", 0,
+	).Err()
+	if err != nil {
+		s.client.ZRem(ctx, s.prefix+"codes", code)
+		return nil, fmt.Errorf("create campaign: %w", err)
+	}
+	return c, nil
+}
+
+func (s *redisService) GetCampaign(ctx context.Context, code string) (*Campaign, error) {
+	fields, err := s.client.HGetAll(ctx, s.campaignKey(code)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get campaign: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNotFound
+	}
+	return decodeCampaign(code, fields), nil
+}
+
+func (s *redisService) ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	// Новые кампании первыми
+	codes, err := s.client.ZRevRange(ctx, s.prefix+"codes", 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("list campaigns: %w", err)
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis2.MapStringStringCmd, len(codes))
+	for i, code := range codes {
+		cmds[i] = pipe.HGetAll(ctx, s.campaignKey(code))
+	}
+	if len(codes) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("list campaigns: %w", err)
+		}
+	}
+
+	result := make([]Campaign, 0, len(codes))
+	for i, cmd := range cmds {
+		if fields := cmd.Val(); len(fields) > 0 {
+			result = append(result, *decodeCampaign(codes[i], fields))
+		}
+	}
+	return result, nil
+}
+
+func (s *redisService) RegisterScan(ctx context.Context, code, userID string) error {
+	keys := []string{s.campaignKey(code), s.prefix + "visitors:" + code, s.prefix + "first:" + userID}
+	found, err := scanScript.Run(ctx, s.client, keys, userID, code).Int()
+	if err != nil {
+		return fmt.Errorf("register scan: %w", err)
+	}
+	if found == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *redisService) RegisterConversion(ctx context.Context, userID string) error {
+	keys := []string{s.prefix + "first:" + userID, s.prefix + "converted:" + userID}
+	if err := conversionScript.Run(ctx, s.client, keys, s.prefix+"c:").Err(); err != nil {
+		return fmt.Errorf("register conversion: %w", err)
+	}
+	return nil
+}
+
+func (s *redisService) ForgetUser(ctx context.Context, userID string) error {
+	codes, err := s.client.ZRange(ctx, s.prefix+"codes", 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("forget campaign visits: %w", err)
+	}
+
+	pipe := s.client.TxPipeline()
+	for _, code := range codes {
+		pipe.SRem(ctx, s.prefix+"visitors:"+code, userID)
+	}
+	pipe.Del(ctx, s.prefix+"first:"+userID, s.prefix+"converted:"+userID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("forget campaign visits: %w", err)
+	}
+	return nil
+}
+
+func decodeCampaign(code string, fields map[string]string) *Campaign {
+	c := &Campaign{
+		Code:      code,
+		Title:     fields["title"],
+		CreatedBy: fields["created_by"],
+	}
+	if ms, err := strconv.ParseInt(fields["created_at"], 10, 64); err == nil {
+		c.CreatedAt = time.UnixMilli(ms)
+	}
+	c.Scans, _ = strconv.Atoi(fields["scans"])
+	c.UniqueUsers, _ = strconv.Atoi(fields["unique_users"])
+	c.Conversions, _ = strconv.Atoi(fields["conversions"])
+	return c
+}
@@ -0,0 +1,208 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("campaign not found")
+	ErrAlreadyExist = errors.New("campaign already exists")
+	ErrInvalidCode  = errors.New("invalid campaign code")
+)
+
+// codePattern - допустимый payload deep-link'а: латиница, цифры, "_" и "-", не длиннее 64 символов
+var codePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Campaign представляет отслеживаемую ссылку (QR-код) на бота.
+// Code передается боту как payload deep-link'а: https://max.ru/<bot>?start=<code>
+type Campaign struct {
+	Code        string    `json:"code"`
+	Title       string    `json:"title"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	Scans       int       `json:"scans"`        // сколько раз открыли ссылку
+	UniqueUsers int       `json:"unique_users"` // сколько разных пользователей открыли ссылку
+	Conversions int       `json:"conversions"`  // сколько пришедших по ссылке завершили регистрацию
+}
+
+// Service определяет интерфейс для работы с кампаниями
+type Service interface {
+	CreateCampaign(ctx context.Context, code, title, createdBy string) (*Campaign, error)
+	GetCampaign(ctx context.Context, code string) (*Campaign, error)
+	ListCampaigns(ctx context.Context) ([]Campaign, error)
+	RegisterScan(ctx context.Context, code, userID string) error // переход по ссылке; первая кампания пользователя запоминается для конверсии
+	RegisterConversion(ctx context.Context, userID string) error // пользователь завершил регистрацию
+}
+
+// ValidateCode проверяет, что код можно использовать как payload deep-link'а
+func ValidateCode(code string) error {
+	if !codePattern.MatchString(code) {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// Eraser - необязательная возможность сервиса: забыть, по каким ссылкам приходил пользователь (/delete_me).
+// Счетчики кампаний не меняются - в них нет персональных данных
+type Eraser interface {
+	ForgetUser(ctx context.Context, userID string) error
+}
+
+type mockService struct {
+	campaigns map[string]*Campaign
+	visitors  map[string]map[string]bool // code -> userID, кто открывал ссылку
+	firstSeen map[string]string          // userID -> code кампании, по которой пользователь пришел впервые
+	converted map[string]bool            // userID, чья регистрация уже засчитана
+	mu        sync.RWMutex
+}
+
+func NewMockService() Service {
+	return &mockService{
+		campaigns: make(map[string]*Campaign),
+		visitors:  make(map[string]map[string]bool),
+		firstSeen: make(map[string]string),
+		converted: make(map[string]bool),
+	}
+}
+
+func (s *mockService) CreateCampaign(ctx context.Context, code, title, createdBy string) (*Campaign, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	if err := ValidateCode(code); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.campaigns[code]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyExist, code)
+	}
+
+	c := &Campaign{
+		Code:      code,
+		Title:     title,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	s.campaigns[code] = c
+	result := *c
+	return &result, nil
+}
+
+func (s *mockService) GetCampaign(ctx context.Context, code string) (*Campaign, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.campaigns[code]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *c
+	return &result, nil
+}
+
+func (s *mockService) ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	result := make([]Campaign, 0, len(s.campaigns))
+	for _, c := range s.campaigns {
+		result = append(result, *c)
+	}
+	s.mu.RUnlock()
+
+	// Новые кампании первыми
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+func (s *mockService) RegisterScan(ctx context.Context, code, userID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.campaigns[code]
+	if !ok {
+		return ErrNotFound
+	}
+
+	c.Scans++
+	if s.visitors[code] == nil {
+		s.visitors[code] = make(map[string]bool)
+	}
+	if !s.visitors[code][userID] {
+		s.visitors[code][userID] = true
+		c.UniqueUsers++
+	}
+	if _, ok := s.firstSeen[userID]; !ok {
+		s.firstSeen[userID] = code
+	}
+	return nil
+}
+
+func (s *mockService) RegisterConversion(ctx context.Context, userID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.firstSeen[userID]
+	if !ok || s.converted[userID] {
+		return nil
+	}
+	if c, ok := s.campaigns[code]; ok {
+		c.Conversions++
+		s.converted[userID] = true
+	}
+	return nil
+}
+
+func (s *mockService) ForgetUser(ctx context.Context, userID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, visitors := range s.visitors {
+		delete(visitors, userID)
+	}
+	delete(s.firstSeen, userID)
+	delete(s.converted, userID)
+	return nil
+}
@@ -36,6 +36,17 @@ type Service interface {
 	AddDocumentResponse(ctx context.Context, documentID, response, responseFile, responseBy string) error
 }
 
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех заявлений с сохранением ID
+type Archiver interface {
+	ExportDocuments(ctx context.Context) ([]Document, error)
+	RestoreDocument(ctx context.Context, document Document) error // создает заявление или заменяет существующее с тем же ID
+}
+
+// Eraser - необязательная возможность сервиса: удаление заявлений пользователя по его запросу (/delete_me)
+type Eraser interface {
+	DeleteUserDocuments(ctx context.Context, userID string) (int, error) // возвращает число удаленных заявлений
+}
+
 type mockService struct {
 	documents map[string]*Document
 }
@@ -132,3 +143,34 @@ func (s *mockService) AddDocumentResponse(ctx context.Context, documentID, respo
 	return nil
 }
 
+func (s *mockService) ExportDocuments(ctx context.Context) ([]Document, error) {
+	return s.GetAllDocuments(ctx)
+}
+
+func (s *mockService) RestoreDocument(ctx context.Context, document Document) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.documents[document.ID] = &document
+	return nil
+}
+
+func (s *mockService) DeleteUserDocuments(ctx context.Context, userID string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	deleted := 0
+	for id, document := range s.documents {
+		if document.UserID == userID {
+			delete(s.documents, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,84 @@
+package intent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"first-max-bot/internal/services/ai"
+)
+
+// AIClassifier распознает команду с помощью языковой модели
+type AIClassifier struct {
+	completer ai.Completer
+}
+
+func NewAIClassifier(completer ai.Completer) *AIClassifier {
+	return &AIClassifier{completer: completer}
+}
+
+type aiAnswer struct {
+	Command    string  `json:"command"`
+	Args       string  `json:"args"`
+	Confidence float64 `json:"confidence"`
+}
+
+func (c *AIClassifier) Classify(ctx context.Context, text string, allowed []Command) (*Intent, error) {
+	if len(allowed) == 0 {
+		return nil, nil
+	}
+
+	raw, err := c.completer.Complete(ctx, buildPrompt(text, allowed))
+	if err != nil {
+		return nil, fmt.Errorf("classify with ai: %w", err)
+	}
+
+	// Модель иногда добавляет текст вокруг JSON - берем первый объект
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("classify with ai: no json in answer %q", raw)
+	}
+
+	var answer aiAnswer
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &answer); err != nil {
+		return nil, fmt.Errorf("classify with ai: %w", err)
+	}
+
+	if answer.Command == "" || !isAllowed(answer.Command, allowed) {
+		return nil, nil
+	}
+	if answer.Confidence < 0 {
+		answer.Confidence = 0
+	}
+	if answer.Confidence > 1 {
+		answer.Confidence = 1
+	}
+
+	return &Intent{
+		Command:    answer.Command,
+		Args:       strings.TrimSpace(answer.Args),
+		Confidence: answer.Confidence,
+		Source:     "ai",
+	}, nil
+}
+
+func buildPrompt(text string, allowed []Command) string {
+	var commands strings.Builder
+	for _, c := range allowed {
+		commands.WriteString(fmt.Sprintf("- %s — %s\n", c.Name, c.Description))
+	}
+
+	return fmt.Sprintf(`Ты - классификатор запросов чат-бота университета. Определи, какая команда бота нужна пользователю.
+
+Доступные команды:
+%s
+Аргументы передавай только для этих случаев:
+- /deanery: тип заявления - certificate (справка), payment (оплата обучения), transfer (перевод), academic_leave (академический отпуск)
+- /schedule и /myschedule: название дисциплины, если пользователь спрашивает о конкретном предмете
+
+Сообщение пользователя: %s
+
+Ответь строго одной строкой JSON без пояснений: {"command": "/команда", "args": "аргументы", "confidence": число от 0 до 1}.
+Если ни одна команда не подходит, верни {"command": "", "args": "", "confidence": 0}.`, commands.String(), text)
+}
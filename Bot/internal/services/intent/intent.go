@@ -0,0 +1,73 @@
+package intent
+
+import (
+	"context"
+)
+
+// HighConfidence - порог уверенности, начиная с которого команда выполняется без подтверждения
+const HighConfidence = 0.75
+
+// Intent - команда, распознанная в свободном тексте пользователя
+type Intent struct {
+	Command    string  // зарегистрированная команда, например "/deanery"
+	Args       string  // извлеченные аргументы, например "certificate"
+	Confidence float64 // уверенность от 0 до 1
+	Source     string  // кто распознал: "rules" или "ai"
+}
+
+// Command - команда, доступная пользователю
+type Command struct {
+	Name        string
+	Description string
+}
+
+// Classifier сопоставляет свободный текст с одной из доступных пользователю команд.
+// Если подходящей команды нет, возвращает nil
+type Classifier interface {
+	Classify(ctx context.Context, text string, allowed []Command) (*Intent, error)
+}
+
+// Chain опрашивает классификаторы по порядку и останавливается на первом уверенном результате.
+// Если уверенного результата нет, возвращается лучший из полученных
+func Chain(classifiers ...Classifier) Classifier {
+	return chain(classifiers)
+}
+
+type chain []Classifier
+
+func (c chain) Classify(ctx context.Context, text string, allowed []Command) (*Intent, error) {
+	var (
+		best    *Intent
+		lastErr error
+	)
+	for _, classifier := range c {
+		result, err := classifier.Classify(ctx, text, allowed)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if result == nil {
+			continue
+		}
+		if result.Confidence >= HighConfidence {
+			return result, nil
+		}
+		if best == nil || result.Confidence > best.Confidence {
+			best = result
+		}
+	}
+	if best == nil {
+		return nil, lastErr
+	}
+	return best, nil
+}
+
+// isAllowed проверяет, что команда есть среди доступных пользователю
+func isAllowed(command string, allowed []Command) bool {
+	for _, c := range allowed {
+		if c.Name == command {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package intent
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Уверенность правил: совпадение шаблона - фраза однозначна, совпадение ключевых слов - требуется подтверждение
+const (
+	patternConfidence = 0.9
+	keywordConfidence = 0.5
+	keywordStep       = 0.1
+	keywordMax        = 0.7
+)
+
+// Rule описывает, как распознать команду в тексте
+type Rule struct {
+	Command   string
+	Patterns  []*regexp.Regexp    // при совпадении первая группа (если есть) становится аргументами
+	Keywords  []string            // основы слов; каждая найденная повышает уверенность
+	Args      string              // аргументы по умолчанию, например тип справки
+	Normalize func(string) string // приведение извлеченных аргументов к виду, понятному команде
+}
+
+// RuleEngine - локальный классификатор на ключевых словах и регулярных выражениях
+type RuleEngine struct {
+	rules []Rule
+}
+
+func NewRuleEngine(rules ...Rule) *RuleEngine {
+	return &RuleEngine{rules: rules}
+}
+
+func (e *RuleEngine) Classify(ctx context.Context, text string, allowed []Command) (*Intent, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil, nil
+	}
+
+	var best *Intent
+	for _, rule := range e.rules {
+		if !isAllowed(rule.Command, allowed) {
+			continue
+		}
+		result := rule.match(text)
+		if result != nil && (best == nil || result.Confidence > best.Confidence) {
+			best = result
+		}
+	}
+	return best, nil
+}
+
+func (r Rule) match(text string) *Intent {
+	for _, pattern := range r.Patterns {
+		groups := pattern.FindStringSubmatch(text)
+		if groups == nil {
+			continue
+		}
+		args := r.Args
+		if len(groups) > 1 && strings.TrimSpace(groups[1]) != "" {
+			args = strings.TrimSpace(groups[1])
+			if r.Normalize != nil {
+				args = r.Normalize(args)
+			}
+		}
+		return &Intent{Command: r.Command, Args: args, Confidence: patternConfidence, Source: "rules"}
+	}
+
+	found := 0
+	for _, keyword := range r.Keywords {
+		if strings.Contains(text, keyword) {
+			found++
+		}
+	}
+	if found == 0 {
+		return nil
+	}
+
+	confidence := keywordConfidence + keywordStep*float64(found-1)
+	if confidence > keywordMax {
+		confidence = keywordMax
+	}
+	return &Intent{Command: r.Command, Args: r.Args, Confidence: confidence, Source: "rules"}
+}
+
+// subjectAliases - разговорные названия дисциплин
+var subjectAliases = map[string]string{
+	"матан":            "мат. анализ",
+	"матану":           "мат. анализ",
+	"матана":           "мат. анализ",
+	"прога":            "программирование",
+	"проге":            "программирование",
+	"программированию": "программирование",
+}
+
+// NormalizeSubject приводит разговорное название дисциплины к названию из расписания
+func NormalizeSubject(subject string) string {
+	subject = strings.Trim(strings.ToLower(strings.TrimSpace(subject)), "?!.")
+	if full, ok := subjectAliases[subject]; ok {
+		return full
+	}
+	return subject
+}
+
+// DefaultRules - правила для основных сценариев бота
+func DefaultRules() []Rule {
+	scheduleKeywords := []string{"расписан", "пара", "пары", "занят", "лекци", "семинар"}
+	subjectPattern := regexp.MustCompile(`(?:пара|пары|занятие|занятия|лекция|семинар)\s+по\s+([\p{L}\d .-]+?)\s*[?!.]*$`)
+
+	return []Rule{
+		{
+			Command:   "/schedule",
+			Patterns:  []*regexp.Regexp{subjectPattern, regexp.MustCompile(`расписани\p{L}*\s+на\s+сегодня`)},
+			Keywords:  scheduleKeywords,
+			Normalize: NormalizeSubject,
+		},
+		{
+			Command:   "/myschedule",
+			Patterns:  []*regexp.Regexp{subjectPattern},
+			Keywords:  scheduleKeywords,
+			Normalize: NormalizeSubject,
+		},
+		{
+			Command:  "/deanery",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?:нужна|нужно|хочу|получить|заказать|оформить|взять)\s+справк`), regexp.MustCompile(`справк\p{L}*\s+(?:для|в)\s+военкомат`)},
+			Keywords: []string{"справк", "военкомат"},
+			Args:     "certificate",
+		},
+		{
+			Command:  "/deanery",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`академ\p{L}*(?:\s+отпуск)?`)},
+			Args:     "academic_leave",
+		},
+		{
+			Command:  "/deanery",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?:перевестись|перевод\s+на)`)},
+			Args:     "transfer",
+		},
+		{
+			Command:  "/deanery",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?:оплат\p{L}*\s+(?:обучени|учеб))`)},
+			Keywords: []string{"оплат"},
+			Args:     "payment",
+		},
+		{
+			Command:  "/office",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`справк\p{L}*\s+с\s+места\s+работы`), regexp.MustCompile(`(?:оформить|нужен|заказать)\s+пропуск`)},
+			Keywords: []string{"справк", "пропуск"},
+		},
+		{
+			Command:  "/library",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?:взять|найти|заказать|вернуть)\s+книг`)},
+			Keywords: []string{"книг", "библиотек"},
+		},
+		{
+			Command:  "/dormitory",
+			Keywords: []string{"общежит", "общаг", "заселени"},
+		},
+		{
+			Command:  "/reminder",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`^напомни(?:ть)?(?:\s|$)`)},
+			Keywords: []string{"напомин"},
+		},
+		{
+			Command:  "/contact",
+			Keywords: []string{"жалоб", "проблем", "обратиться"},
+		},
+		{
+			Command:  "/news",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`(?:какие|что)\s+(?:новост|нового)`)},
+			Keywords: []string{"новост"},
+		},
+		{
+			Command:  "/vacation",
+			Keywords: []string{"отпуск"},
+		},
+		{
+			Command:  "/businesstrip",
+			Keywords: []string{"командировк"},
+		},
+		{
+			Command:  "/admission",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`как\s+поступить`)},
+			Keywords: []string{"поступ", "приемн", "приёмн"},
+		},
+		{
+			Command:  "/programs",
+			Keywords: []string{"направлени", "специальност", "программы обучения"},
+		},
+		{
+			Command:  "/openday",
+			Patterns: []*regexp.Regexp{regexp.MustCompile(`день\s+открытых\s+дверей`)},
+		},
+	}
+}
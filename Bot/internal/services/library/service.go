@@ -42,6 +42,22 @@ type Service interface {
 	MarkBookReturned(ctx context.Context, userID string, bookID string) error       // Отметить как "возвращена в библиотеку"
 }
 
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка каталога и всех выдач
+// (включая возвращенные) с сохранением ID
+type Archiver interface {
+	ExportBooks(ctx context.Context) ([]Book, error)
+	ExportLoans(ctx context.Context) ([]UserBook, error)
+	RestoreBook(ctx context.Context, book Book) error     // создает книгу или заменяет существующую с тем же ID
+	RestoreLoan(ctx context.Context, loan UserBook) error // создает выдачу или заменяет существующую с тем же ID
+}
+
+// Eraser - необязательная возможность сервиса: обезличивание выдач по запросу пользователя (/delete_me).
+// Выдачи не удаляются, чтобы не потерять учет книг на руках: ID пользователя заменяется на pseudonym,
+// имя и фамилия стираются
+type Eraser interface {
+	AnonymizeUserLoans(ctx context.Context, userID, pseudonym string) (int, error) // возвращает число обезличенных выдач
+}
+
 type mockService struct {
 	books     map[string]*Book
 	userBooks map[string]*UserBook
@@ -248,3 +264,77 @@ func (s *mockService) ReturnBook(ctx context.Context, userID, bookID string) err
 	}
 	return fmt.Errorf("книга не найдена")
 }
+
+func (s *mockService) ExportBooks(ctx context.Context) ([]Book, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	result := make([]Book, 0, len(s.books))
+	for _, book := range s.books {
+		result = append(result, *book)
+	}
+	return result, nil
+}
+
+func (s *mockService) ExportLoans(ctx context.Context) ([]UserBook, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	result := make([]UserBook, 0, len(s.userBooks))
+	for _, ub := range s.userBooks {
+		result = append(result, *ub)
+	}
+	return result, nil
+}
+
+func (s *mockService) RestoreBook(ctx context.Context, book Book) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.books[book.ID] = &book
+	return nil
+}
+
+func (s *mockService) RestoreLoan(ctx context.Context, loan UserBook) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	book, exists := s.books[loan.BookID]
+	if !exists {
+		return fmt.Errorf("book %s not found", loan.BookID)
+	}
+	loan.Book = book
+	s.userBooks[loan.ID] = &loan
+	return nil
+}
+
+func (s *mockService) AnonymizeUserLoans(ctx context.Context, userID, pseudonym string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	anonymized := 0
+	for _, ub := range s.userBooks {
+		if ub.UserID == userID {
+			ub.UserID = pseudonym
+			ub.UserName = ""
+			ub.UserSurname = ""
+			anonymized++
+		}
+	}
+	return anonymized, nil
+}
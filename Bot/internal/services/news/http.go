@@ -0,0 +1,179 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"first-max-bot/internal/uniback"
+)
+
+var ErrUnavailable = errors.New("news backend unavailable")
+
+// backendAuthor - подпись новостей, опубликованных не через бота: автора uni-back не хранит
+const backendAuthor = "Университет"
+
+// backendNews - сущность News из uni-back: только текст и дата
+type backendNews struct {
+	ID   int64     `json:"id,omitempty"`
+	Text string    `json:"text"`
+	Date time.Time `json:"date"`
+}
+
+type httpService struct {
+	client *uniback.Client
+	local  Service // заголовок и автор новостей, опубликованных через бота: в uni-back их нет
+}
+
+// NewHTTPService публикует и читает новости через uni-back (/news), чтобы новости бота и системы
+// университета были общими. Заголовок, текст и автор новости, опубликованной через бота,
+// копируются в local под ID из uni-back
+func NewHTTPService(client *uniback.Client, local Service) Service {
+	return &httpService{client: client, local: local}
+}
+
+// GetLatestNews возвращает не больше 10 новостей: больше uni-back не отдает
+func (s *httpService) GetLatestNews(ctx context.Context, count int) ([]News, error) {
+	remote, err := s.latest(ctx)
+	if errors.Is(err, ErrUnavailable) {
+		return s.local.GetLatestNews(ctx, count)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := s.mirrored(ctx)
+	result := make([]News, 0, len(remote))
+	for _, n := range remote {
+		id := strconv.FormatInt(n.ID, 10)
+		if local, ok := mirrored[id]; ok {
+			result = append(result, local)
+			continue
+		}
+		title, content := splitText(n.Text)
+		result = append(result, News{
+			ID:        id,
+			Title:     title,
+			Content:   content,
+			Author:    backendAuthor,
+			CreatedAt: n.Date,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.After(result[j].CreatedAt) })
+	if count < len(result) {
+		result = result[:count]
+	}
+	return result, nil
+}
+
+func (s *httpService) CreateNews(ctx context.Context, title, content, authorID, author string) (*News, error) {
+	// uni-back хранит время с точностью до микросекунд, секунд достаточно, чтобы найти новость после сохранения
+	now := time.Now().Truncate(time.Second)
+	text := title + "\n\n" + content
+	if err := s.client.Post(ctx, "/news/save", backendNews{Text: text, Date: now}, nil); err != nil {
+		return nil, mapBackendError(err)
+	}
+
+	news := News{
+		Title:     title,
+		Content:   content,
+		AuthorID:  authorID,
+		Author:    author,
+		CreatedAt: now,
+	}
+
+	// uni-back не возвращает ID сохраненной новости: ищем ее среди последних. Новость уже опубликована,
+	// поэтому ошибки поиска и копии ее не отменяют: без ID новость возвращается без копии, и в ленте
+	// она показывается так, как ее хранит uni-back
+	remote, err := s.latest(ctx)
+	if err != nil {
+		return &news, nil
+	}
+	for _, n := range remote {
+		if n.Text == text && n.Date.Equal(now) {
+			news.ID = strconv.FormatInt(n.ID, 10)
+			break
+		}
+	}
+	if news.ID == "" {
+		return &news, nil
+	}
+
+	if archiver, ok := s.local.(Archiver); ok {
+		_ = archiver.RestoreNews(ctx, news)
+	}
+	return &news, nil
+}
+
+// ExportNews, RestoreNews и AnonymizeAuthor работают с копией бота: в uni-back нет авторов
+func (s *httpService) ExportNews(ctx context.Context) ([]News, error) {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return nil, fmt.Errorf("local news storage does not support export")
+	}
+	return archiver.ExportNews(ctx)
+}
+
+func (s *httpService) RestoreNews(ctx context.Context, news News) error {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return fmt.Errorf("local news storage does not support import")
+	}
+	return archiver.RestoreNews(ctx, news)
+}
+
+func (s *httpService) AnonymizeAuthor(ctx context.Context, authorID, pseudonym, name string) (int, error) {
+	eraser, ok := s.local.(Eraser)
+	if !ok {
+		return 0, fmt.Errorf("local news storage does not support anonymization")
+	}
+	return eraser.AnonymizeAuthor(ctx, authorID, pseudonym, name)
+}
+
+func (s *httpService) latest(ctx context.Context) ([]backendNews, error) {
+	var remote []backendNews
+	err := s.client.Get(ctx, "/news", &remote)
+	if errors.Is(err, uniback.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, mapBackendError(err)
+	}
+	return remote, nil
+}
+
+// mirrored возвращает копии новостей, опубликованных через бота, по ID. Без копий новости
+// показываются так, как их хранит uni-back
+func (s *httpService) mirrored(ctx context.Context) map[string]News {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return nil
+	}
+	all, err := archiver.ExportNews(ctx)
+	if err != nil {
+		return nil
+	}
+	result := make(map[string]News, len(all))
+	for _, n := range all {
+		result[n.ID] = n
+	}
+	return result
+}
+
+// splitText делит текст новости из uni-back на заголовок (первая строка) и остальной текст
+func splitText(text string) (string, string) {
+	title, content, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(title), strings.TrimSpace(content)
+}
+
+func mapBackendError(err error) error {
+	if errors.Is(err, uniback.ErrUnavailable) || errors.Is(err, uniback.ErrServer) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
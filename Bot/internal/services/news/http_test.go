@@ -0,0 +1,193 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"first-max-bot/internal/uniback"
+)
+
+// fakeBackend повторяет контракт NewsController из uni-back-java: GET /news - последние 10 новостей,
+// POST /news/save - 204 без ID, ошибка в данных - 500
+type fakeBackend struct {
+	mu       sync.Mutex
+	news     []backendNews
+	nextID   int64
+	status   int    // если не 0, все запросы отвечают этим статусом
+	listBody string // если не пусто, GET /news отвечает этим телом
+	listFail bool   // GET /news отвечает 500, сохранение работает
+}
+
+func (b *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.status != 0 {
+		w.WriteHeader(b.status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/news":
+		if b.listFail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if b.listBody != "" {
+			_, _ = w.Write([]byte(b.listBody))
+			return
+		}
+		last := append([]backendNews(nil), b.news...)
+		sort.Slice(last, func(i, j int) bool { return last[i].Date.After(last[j].Date) })
+		if len(last) > 10 {
+			last = last[:10]
+		}
+		_ = json.NewEncoder(w).Encode(last)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/news/save":
+		var n backendNews
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Пустой текст или дата нарушают NOT NULL: uni-back отвечает 500
+		if n.Text == "" || n.Date.IsZero() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		b.add(n.Text, n.Date)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *fakeBackend) add(text string, date time.Time) int64 {
+	b.nextID++
+	// uni-back отдает OffsetDateTime в UTC
+	b.news = append(b.news, backendNews{ID: b.nextID, Text: text, Date: date.UTC()})
+	return b.nextID
+}
+
+func newTestService(t *testing.T) (*fakeBackend, Service, Service) {
+	t.Helper()
+	backend := &fakeBackend{}
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	local := NewMockService()
+	client := uniback.New(server.URL, uniback.WithRetries(0, 0))
+	return backend, NewHTTPService(client, local), local
+}
+
+func TestCreateNewsFindsSavedID(t *testing.T) {
+	ctx := context.Background()
+	backend, service, _ := newTestService(t)
+
+	backend.add("Старая новость\n\nТекст", time.Now().Add(-time.Hour))
+	created, err := service.CreateNews(ctx, "Заголовок", "Текст новости", "42", "Иван Иванов")
+	if err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if created.ID != "2" {
+		t.Fatalf("id = %q, want 2", created.ID)
+	}
+	if backend.news[1].Text != "Заголовок\n\nТекст новости" {
+		t.Errorf("backend text = %q", backend.news[1].Text)
+	}
+
+	latest, err := service.GetLatestNews(ctx, 5)
+	if err != nil {
+		t.Fatalf("GetLatestNews: %v", err)
+	}
+	if len(latest) != 2 {
+		t.Fatalf("latest = %+v, want 2 news", latest)
+	}
+	if latest[0].ID != "2" || latest[0].Title != "Заголовок" || latest[0].Author != "Иван Иванов" || latest[0].AuthorID != "42" {
+		t.Errorf("bot news = %+v, want mirrored copy", latest[0])
+	}
+	if latest[1].Title != "Старая новость" || latest[1].Content != "Текст" || latest[1].Author != backendAuthor {
+		t.Errorf("backend news = %+v", latest[1])
+	}
+}
+
+func TestCreateNewsWithoutFoundID(t *testing.T) {
+	// Новость сохранена, но найти ее не удалось: публикация не отменяется
+	ctx := context.Background()
+	backend, service, _ := newTestService(t)
+	backend.listFail = true
+
+	created, err := service.CreateNews(ctx, "Заголовок", "Текст", "42", "Иван Иванов")
+	if err != nil {
+		t.Fatalf("CreateNews: %v", err)
+	}
+	if created.ID != "" || created.Title != "Заголовок" {
+		t.Errorf("created = %+v, want news without id", created)
+	}
+	if len(backend.news) != 1 {
+		t.Errorf("backend has %d news, want 1", len(backend.news))
+	}
+}
+
+func TestCreateNewsBadDataIsUnavailable(t *testing.T) {
+	backend, service, _ := newTestService(t)
+	backend.status = http.StatusInternalServerError
+
+	_, err := service.CreateNews(context.Background(), "Заголовок", "Текст", "42", "Иван Иванов")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestGetLatestNewsNullBody(t *testing.T) {
+	backend, service, _ := newTestService(t)
+	backend.listBody = "null"
+
+	latest, err := service.GetLatestNews(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetLatestNews: %v", err)
+	}
+	if len(latest) != 0 {
+		t.Errorf("latest = %+v, want empty", latest)
+	}
+}
+
+func TestGetLatestNewsLimit(t *testing.T) {
+	backend, service, _ := newTestService(t)
+	now := time.Now()
+	for i := 0; i < 12; i++ {
+		backend.add("Новость "+strconv.Itoa(i), now.Add(time.Duration(i)*time.Minute))
+	}
+
+	latest, err := service.GetLatestNews(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("GetLatestNews: %v", err)
+	}
+	if len(latest) != 3 || latest[0].Title != "Новость 11" || latest[2].Title != "Новость 9" {
+		t.Errorf("latest = %+v, want 3 newest", latest)
+	}
+}
+
+func TestGetLatestNewsFallsBackToLocal(t *testing.T) {
+	ctx := context.Background()
+	backend, service, local := newTestService(t)
+	backend.status = http.StatusBadGateway
+
+	latest, err := service.GetLatestNews(ctx, 2)
+	if err != nil {
+		t.Fatalf("GetLatestNews: %v", err)
+	}
+	want, _ := local.GetLatestNews(ctx, 2)
+	if len(latest) != len(want) || latest[0].ID != want[0].ID {
+		t.Errorf("latest = %+v, want local news %+v", latest, want)
+	}
+}
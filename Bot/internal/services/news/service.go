@@ -22,6 +22,18 @@ type Service interface {
 	CreateNews(ctx context.Context, title, content, authorID, author string) (*News, error)
 }
 
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех новостей с сохранением ID
+type Archiver interface {
+	ExportNews(ctx context.Context) ([]News, error)
+	RestoreNews(ctx context.Context, news News) error // создает новость или заменяет существующую с тем же ID
+}
+
+// Eraser - необязательная возможность сервиса: обезличивание автора новостей по его запросу (/delete_me).
+// Новости остаются опубликованными, ID и имя автора заменяются
+type Eraser interface {
+	AnonymizeAuthor(ctx context.Context, authorID, pseudonym, name string) (int, error) // возвращает число измененных новостей
+}
+
 type mockService struct {
 	news []*News
 }
@@ -101,3 +113,51 @@ func (s *mockService) CreateNews(ctx context.Context, title, content, authorID,
 	return news, nil
 }
 
+func (s *mockService) ExportNews(ctx context.Context) ([]News, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	result := make([]News, 0, len(s.news))
+	for _, n := range s.news {
+		result = append(result, *n)
+	}
+	return result, nil
+}
+
+func (s *mockService) RestoreNews(ctx context.Context, news News) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	for i, n := range s.news {
+		if n.ID == news.ID {
+			s.news[i] = &news
+			return nil
+		}
+	}
+	s.news = append(s.news, &news)
+	return nil
+}
+
+func (s *mockService) AnonymizeAuthor(ctx context.Context, authorID, pseudonym, name string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	anonymized := 0
+	for _, n := range s.news {
+		if n.AuthorID == authorID {
+			n.AuthorID = pseudonym
+			n.Author = name
+			anonymized++
+		}
+	}
+	return anonymized, nil
+}
@@ -0,0 +1,423 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+// Ключи (с префиксом):
+//   - r:<id> - hash с полями напоминания
+//   - due - sorted set активных напоминаний, score - время в мс, когда напоминание нужно отправить.
+//     Захваченное напоминание получает score окончания захвата, поэтому повторно выбирается только после него
+//   - user:<userID> - sorted set всех напоминаний пользователя по дате
+//   - seq - счетчик номеров
+//
+// Скрипты обращаются к ключам r:<id> по префиксу, поэтому все ключи должны быть в одном Redis (не Cluster)
+
+// claimScript выбирает наступившие напоминания и захватывает их на время lease.
+// KEYS[1] - due; ARGV: now_ms, владелец, until_ms, лимит, префикс. Возвращает ID захваченных
+var claimScript = redis2.NewScript(`
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[4]))
+local claimed = {}
+for _, id in ipairs(ids) do
+	local key = ARGV[5] .. "r:" .. id
+	if redis.call("HGET", key, "status") == "active" then
+		redis.call("HSET", key, "claimed_by", ARGV[2], "claimed_until", ARGV[3])
+		redis.call("ZADD", KEYS[1], ARGV[3], id)
+		table.insert(claimed, id)
+	else
+		redis.call("ZREM", KEYS[1], id)
+	end
+end
+return claimed
+`)
+
+// releaseScript снимает захват и возвращает напоминание в очередь на его исходное время.
+// KEYS[1] - r:<id>, KEYS[2] - due. Возвращает 0, если напоминания нет
+var releaseScript = redis2.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "claimed_by", "", "claimed_until", "0")
+if redis.call("HGET", KEYS[1], "status") == "active" then
+	redis.call("ZADD", KEYS[2], redis.call("HGET", KEYS[1], "date_time"), ARGV[1])
+end
+return 1
+`)
+
+// completeScript помечает напоминание выполненным и убирает его из очереди.
+// KEYS[1] - r:<id>, KEYS[2] - due; ARGV: id, ttl_ms выполненного напоминания. Возвращает 0, если напоминания нет
+var completeScript = redis2.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "status", "completed", "claimed_by", "", "claimed_until", "0")
+redis.call("ZREM", KEYS[2], ARGV[1])
+if tonumber(ARGV[2]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// restoreScript записывает напоминание как есть (восстановление из архива) и обновляет индексы и счетчик.
+// KEYS[1] - r:<id>, KEYS[2] - due, KEYS[3] - user:<userID>, KEYS[4] - seq;
+// ARGV: id, user_id, text, date_time, created_at, status, номер для счетчика (0 - не менять), ttl_ms неактивного, префикс, вид
+var restoreScript = redis2.NewScript(`
+local previous = redis.call("HGET", KEYS[1], "user_id")
+if previous and previous ~= ARGV[2] then
+	redis.call("ZREM", ARGV[9] .. "user:" .. previous, ARGV[1])
+end
+redis.call("DEL", KEYS[1])
+redis.call("HSET", KEYS[1], "id", ARGV[1], "user_id", ARGV[2], "text", ARGV[3], "date_time", ARGV[4],
+	"created_at", ARGV[5], "status", ARGV[6], "kind", ARGV[10], "claimed_by", "", "claimed_until", "0")
+redis.call("ZADD", KEYS[3], ARGV[4], ARGV[1])
+if ARGV[6] == "active" then
+	redis.call("ZADD", KEYS[2], ARGV[4], ARGV[1])
+else
+	redis.call("ZREM", KEYS[2], ARGV[1])
+	if tonumber(ARGV[8]) > 0 then
+		redis.call("PEXPIRE", KEYS[1], ARGV[8])
+	end
+end
+if tonumber(ARGV[7]) > tonumber(redis.call("GET", KEYS[4]) or "0") then
+	redis.call("SET", KEYS[4], ARGV[7])
+end
+return 1
+`)
+
+type redisOptions struct {
+	prefix       string
+	completedTTL time.Duration
+	claimBatch   int64
+}
+
+type Option func(*redisOptions)
+
+// WithPrefix задает префикс ключей (по умолчанию "maxbot:reminders:")
+func WithPrefix(prefix string) Option {
+	return func(o *redisOptions) {
+		o.prefix = prefix
+	}
+}
+
+// WithCompletedTTL задает, сколько хранить выполненные напоминания (по умолчанию 30 дней, 0 - всегда)
+func WithCompletedTTL(ttl time.Duration) Option {
+	return func(o *redisOptions) {
+		o.completedTTL = ttl
+	}
+}
+
+// WithClaimBatch ограничивает число напоминаний, захватываемых за одну проверку (по умолчанию 500).
+// Остальные будут захвачены при следующей проверке
+func WithClaimBatch(n int64) Option {
+	return func(o *redisOptions) {
+		o.claimBatch = n
+	}
+}
+
+type redisService struct {
+	client redis2.Cmdable
+	opts   redisOptions
+}
+
+// NewRedisService хранит напоминания в Redis: они переживают перезапуск, а проверка
+// читает только наступившие напоминания из sorted set вместо перебора всех активных
+func NewRedisService(client redis2.Cmdable, opts ...Option) Service {
+	o := redisOptions{
+		prefix:       "maxbot:reminders:",
+		completedTTL: 30 * 24 * time.Hour,
+		claimBatch:   500,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &redisService{client: client, opts: o}
+}
+
+func (s *redisService) reminderKey(id string) string {
+	return s.opts.prefix + "r:" + id
+}
+
+func (s *redisService) dueKey() string {
+	return s.opts.prefix + "due"
+}
+
+func (s *redisService) userKey(userID string) string {
+	return s.opts.prefix + "user:" + userID
+}
+
+func (s *redisService) CreateReminder(ctx context.Context, userID, text string, dateTime time.Time) (*Reminder, error) {
+	return s.CreateGeneratedReminder(ctx, KindManual, userID, text, dateTime)
+}
+
+func (s *redisService) CreateGeneratedReminder(ctx context.Context, kind, userID, text string, dateTime time.Time) (*Reminder, error) {
+	seq, err := s.client.Incr(ctx, s.opts.prefix+"seq").Result()
+	if err != nil {
+		return nil, fmt.Errorf("create reminder: %w", err)
+	}
+
+	reminder := &Reminder{
+		ID:        fmt.Sprintf("REM-%d", seq),
+		UserID:    userID,
+		Text:      text,
+		DateTime:  dateTime,
+		CreatedAt: time.Now(),
+		Status:    "active",
+		Kind:      kind,
+	}
+
+	score := float64(dateTime.UnixMilli())
+	_, err = s.client.TxPipelined(ctx, func(pipe redis2.Pipeliner) error {
+		pipe.HSet(ctx, s.reminderKey(reminder.ID), map[string]any{
+			"id":            reminder.ID,
+			"user_id":       reminder.UserID,
+			"text":          reminder.Text,
+			"date_time":     dateTime.UnixMilli(),
+			"created_at":    reminder.CreatedAt.UnixMilli(),
+			"status":        reminder.Status,
+			"kind":          reminder.Kind,
+			"claimed_by":    "",
+			"claimed_until": 0,
+		})
+		pipe.ZAdd(ctx, s.dueKey(), redis2.Z{Score: score, Member: reminder.ID})
+		pipe.ZAdd(ctx, s.userKey(userID), redis2.Z{Score: score, Member: reminder.ID})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// load читает напоминания по ID в порядке ids. Отсутствующие (удаленные или истекшие) пропускаются
+// и возвращаются отдельно, чтобы их можно было убрать из индексов
+func (s *redisService) load(ctx context.Context, ids []string) ([]Reminder, []string, error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	pipe := s.client.Pipeline()
+	cmds := make([]*redis2.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, s.reminderKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		reminders []Reminder
+		missing   []string
+	)
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			missing = append(missing, ids[i])
+			continue
+		}
+		reminders = append(reminders, decodeReminder(fields))
+	}
+	return reminders, missing, nil
+}
+
+func decodeReminder(fields map[string]string) Reminder {
+	r := Reminder{
+		ID:        fields["id"],
+		UserID:    fields["user_id"],
+		Text:      fields["text"],
+		DateTime:  parseMillis(fields["date_time"]),
+		CreatedAt: parseMillis(fields["created_at"]),
+		Status:    fields["status"],
+		Kind:      fields["kind"],
+		ClaimedBy: fields["claimed_by"],
+	}
+	if until, _ := strconv.ParseInt(fields["claimed_until"], 10, 64); until > 0 {
+		r.ClaimedUntil = time.UnixMilli(until)
+	}
+	return r
+}
+
+func parseMillis(value string) time.Time {
+	ms, _ := strconv.ParseInt(value, 10, 64)
+	return time.UnixMilli(ms)
+}
+
+// userReminders возвращает напоминания пользователя по дате и чистит индекс от истекших
+func (s *redisService) userReminders(ctx context.Context, userID string) ([]Reminder, error) {
+	ids, err := s.client.ZRange(ctx, s.userKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	reminders, missing, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		s.client.ZRem(ctx, s.userKey(userID), toMembers(missing)...)
+	}
+	return reminders, nil
+}
+
+func toMembers(ids []string) []any {
+	members := make([]any, len(ids))
+	for i, id := range ids {
+		members[i] = id
+	}
+	return members
+}
+
+func (s *redisService) GetUserReminders(ctx context.Context, userID string) ([]Reminder, error) {
+	reminders, err := s.userReminders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *redisService) GetActiveReminders(ctx context.Context, userID string) ([]Reminder, error) {
+	reminders, err := s.userReminders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get active reminders: %w", err)
+	}
+
+	var active []Reminder
+	for _, r := range reminders {
+		if r.Status == "active" {
+			active = append(active, r)
+		}
+	}
+	return active, nil
+}
+
+func (s *redisService) GetAllActiveReminders(ctx context.Context) ([]Reminder, error) {
+	// В due лежат только активные напоминания, но захваченные стоят по времени окончания захвата
+	ids, err := s.client.ZRange(ctx, s.dueKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get all active reminders: %w", err)
+	}
+	reminders, _, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get all active reminders: %w", err)
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].DateTime.Before(reminders[j].DateTime)
+	})
+	return reminders, nil
+}
+
+func (s *redisService) DeleteReminder(ctx context.Context, reminderID string) error {
+	userID, err := s.client.HGet(ctx, s.reminderKey(reminderID), "user_id").Result()
+	if err == redis2.Nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("delete reminder: %w", err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis2.Pipeliner) error {
+		pipe.Del(ctx, s.reminderKey(reminderID))
+		pipe.ZRem(ctx, s.dueKey(), reminderID)
+		pipe.ZRem(ctx, s.userKey(userID), reminderID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete reminder: %w", err)
+	}
+	return nil
+}
+
+func (s *redisService) GetReminderByID(ctx context.Context, reminderID string) (*Reminder, error) {
+	fields, err := s.client.HGetAll(ctx, s.reminderKey(reminderID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get reminder: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("reminder not found")
+	}
+	reminder := decodeReminder(fields)
+	return &reminder, nil
+}
+
+func (s *redisService) MarkReminderCompleted(ctx context.Context, reminderID string) error {
+	found, err := completeScript.Run(ctx, s.client, []string{s.reminderKey(reminderID), s.dueKey()},
+		reminderID, s.opts.completedTTL.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("complete reminder: %w", err)
+	}
+	if found == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+func (s *redisService) ClaimDueReminders(ctx context.Context, now time.Time, owner string, lease time.Duration) ([]Reminder, error) {
+	ids, err := claimScript.Run(ctx, s.client, []string{s.dueKey()},
+		now.UnixMilli(), owner, now.Add(lease).UnixMilli(), s.opts.claimBatch, s.opts.prefix).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("claim due reminders: %w", err)
+	}
+
+	claimed, _, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("claim due reminders: %w", err)
+	}
+
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].DateTime.Before(claimed[j].DateTime)
+	})
+	return claimed, nil
+}
+
+func (s *redisService) ReleaseReminder(ctx context.Context, reminderID string) error {
+	found, err := releaseScript.Run(ctx, s.client, []string{s.reminderKey(reminderID), s.dueKey()}, reminderID).Int()
+	if err != nil {
+		return fmt.Errorf("release reminder: %w", err)
+	}
+	if found == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+func (s *redisService) ExportReminders(ctx context.Context) ([]Reminder, error) {
+	var ids []string
+	iter := s.client.Scan(ctx, 0, s.opts.prefix+"r:*", 500).Iterator()
+	for iter.Next(ctx) {
+		ids = append(ids, strings.TrimPrefix(iter.Val(), s.opts.prefix+"r:"))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+
+	reminders, _, err := s.load(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		return reminders[i].DateTime.Before(reminders[j].DateTime)
+	})
+	return reminders, nil
+}
+
+func (s *redisService) RestoreReminder(ctx context.Context, r Reminder) error {
+	// Счетчик сдвигается за номер восстановленного напоминания, чтобы новые не получили занятый ID
+	var seq int64
+	if strings.HasPrefix(r.ID, "REM-") {
+		seq, _ = strconv.ParseInt(strings.TrimPrefix(r.ID, "REM-"), 10, 64)
+	}
+
+	keys := []string{s.reminderKey(r.ID), s.dueKey(), s.userKey(r.UserID), s.opts.prefix + "seq"}
+	_, err := restoreScript.Run(ctx, s.client, keys, r.ID, r.UserID, r.Text, r.DateTime.UnixMilli(), r.CreatedAt.UnixMilli(),
+		r.Status, seq, s.opts.completedTTL.Milliseconds(), s.opts.prefix, r.Kind).Result()
+	if err != nil {
+		return fmt.Errorf("restore reminder %s: %w", r.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redis2 "github.com/redis/go-redis/v9"
+)
+
+func newTestService(t *testing.T) (*miniredis.Miniredis, Service) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis2.NewClient(&redis2.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, NewRedisService(client, WithPrefix("test:"))
+}
+
+func TestClaimDueRemindersConcurrently(t *testing.T) {
+	ctx := context.Background()
+	_, service := newTestService(t)
+
+	now := time.Now()
+	const total = 50
+	for i := 0; i < total; i++ {
+		if _, err := service.CreateReminder(ctx, "100", fmt.Sprintf("Напоминание %d", i), now.Add(-time.Minute)); err != nil {
+			t.Fatalf("CreateReminder: %v", err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		owners   = make(map[string]string, total)
+		wg       sync.WaitGroup
+		claimErr error
+	)
+	for i := 0; i < 5; i++ {
+		owner := fmt.Sprintf("instance-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := service.ClaimDueReminders(ctx, now, owner, time.Minute)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				claimErr = err
+				return
+			}
+			for _, r := range claimed {
+				if previous, ok := owners[r.ID]; ok {
+					t.Errorf("reminder %s claimed by %s and %s", r.ID, previous, owner)
+				}
+				owners[r.ID] = owner
+				if r.ClaimedBy != owner {
+					t.Errorf("reminder %s claimed_by = %q, want %q", r.ID, r.ClaimedBy, owner)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if claimErr != nil {
+		t.Fatalf("ClaimDueReminders: %v", claimErr)
+	}
+	if len(owners) != total {
+		t.Errorf("claimed %d reminders, want %d", len(owners), total)
+	}
+}
+
+func TestClaimAfterLeaseExpires(t *testing.T) {
+	ctx := context.Background()
+	_, service := newTestService(t)
+
+	now := time.Now()
+	due, err := service.CreateReminder(ctx, "100", "Пара", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	if _, err := service.CreateReminder(ctx, "100", "Будущее", now.Add(time.Hour)); err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+
+	claimed, err := service.ClaimDueReminders(ctx, now, "a", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != due.ID {
+		t.Fatalf("claimed = %+v, want only %s", claimed, due.ID)
+	}
+
+	// Экземпляр a упал, не отправив напоминание: до конца захвата его никто не берет
+	claimed, err = service.ClaimDueReminders(ctx, now.Add(30*time.Second), "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("claimed under lease = %+v, want none", claimed)
+	}
+
+	claimed, err = service.ClaimDueReminders(ctx, now.Add(time.Minute), "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != due.ID || claimed[0].ClaimedBy != "b" {
+		t.Errorf("claimed after lease = %+v, want %s by b", claimed, due.ID)
+	}
+}
+
+func TestCompletedReminderLeavesDueSet(t *testing.T) {
+	ctx := context.Background()
+	server, service := newTestService(t)
+
+	now := time.Now()
+	first, err := service.CreateReminder(ctx, "100", "Первое", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	second, err := service.CreateReminder(ctx, "100", "Второе", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+
+	if _, err := service.ClaimDueReminders(ctx, now, "a", time.Minute); err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if err := service.MarkReminderCompleted(ctx, first.ID); err != nil {
+		t.Fatalf("MarkReminderCompleted: %v", err)
+	}
+	if err := service.ReleaseReminder(ctx, second.ID); err != nil {
+		t.Fatalf("ReleaseReminder: %v", err)
+	}
+
+	members, err := server.ZMembers("test:due")
+	if err != nil {
+		t.Fatalf("due set: %v", err)
+	}
+	if len(members) != 1 || members[0] != second.ID {
+		t.Errorf("due set = %v, want only %s", members, second.ID)
+	}
+
+	// Выполненное больше не захватывается, освобожденное - сразу
+	claimed, err := service.ClaimDueReminders(ctx, now, "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != second.ID {
+		t.Errorf("claimed = %+v, want only %s", claimed, second.ID)
+	}
+
+	// Выполненное хранится до истечения срока
+	if ttl := server.TTL("test:r:" + first.ID); ttl != 30*24*time.Hour {
+		t.Errorf("completed reminder ttl = %v, want 720h", ttl)
+	}
+	if err := service.MarkReminderCompleted(ctx, "REM-999"); err == nil {
+		t.Error("MarkReminderCompleted of unknown reminder: want error")
+	}
+}
@@ -15,9 +15,20 @@ type Reminder struct {
 	Text      string    `json:"text"`
 	DateTime  time.Time `json:"date_time"`
 	CreatedAt time.Time `json:"created_at"`
-	Status    string    `json:"status"` // "active", "completed", "cancelled"
+	Status    string    `json:"status"`         // "active", "completed", "cancelled"
+	Kind      string    `json:"kind,omitempty"` // KindManual или вид напоминания, созданного ботом
+
+	// Захват напоминания на время отправки: пока срок не истек, другие экземпляры его не отправляют
+	ClaimedBy    string    `json:"claimed_by,omitempty"`
+	ClaimedUntil time.Time `json:"claimed_until,omitempty"`
 }
 
+// Виды напоминаний
+const (
+	KindManual = ""       // создано пользователем (/reminder)
+	KindLesson = "lesson" // перед парой, создается по расписанию (/notify)
+)
+
 // Service определяет интерфейс для работы с напоминаниями
 type Service interface {
 	CreateReminder(ctx context.Context, userID, text string, dateTime time.Time) (*Reminder, error)
@@ -27,6 +38,24 @@ type Service interface {
 	DeleteReminder(ctx context.Context, reminderID string) error
 	GetReminderByID(ctx context.Context, reminderID string) (*Reminder, error)
 	MarkReminderCompleted(ctx context.Context, reminderID string) error // Пометить как выполненное
+
+	// ClaimDueReminders атомарно захватывает наступившие напоминания на время lease.
+	// Если отправивший экземпляр упал, не завершив напоминание, после истечения lease его отправит другой
+	ClaimDueReminders(ctx context.Context, now time.Time, owner string, lease time.Duration) ([]Reminder, error)
+	// ReleaseReminder снимает захват, чтобы напоминание было отправлено при следующей проверке
+	ReleaseReminder(ctx context.Context, reminderID string) error
+}
+
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех напоминаний с сохранением ID
+type Archiver interface {
+	ExportReminders(ctx context.Context) ([]Reminder, error)
+	RestoreReminder(ctx context.Context, reminder Reminder) error // создает напоминание или заменяет существующее с тем же ID
+}
+
+// Generator - необязательная возможность сервиса: напоминания, которые создает бот. По виду их можно
+// отличить от напоминаний пользователя и пересоздать, когда изменился источник (например, расписание)
+type Generator interface {
+	CreateGeneratedReminder(ctx context.Context, kind, userID, text string, dateTime time.Time) (*Reminder, error)
 }
 
 type mockService struct {
@@ -42,6 +71,10 @@ func NewMockService() Service {
 }
 
 func (s *mockService) CreateReminder(ctx context.Context, userID, text string, dateTime time.Time) (*Reminder, error) {
+	return s.CreateGeneratedReminder(ctx, KindManual, userID, text, dateTime)
+}
+
+func (s *mockService) CreateGeneratedReminder(ctx context.Context, kind, userID, text string, dateTime time.Time) (*Reminder, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -58,6 +91,7 @@ func (s *mockService) CreateReminder(ctx context.Context, userID, text string, d
 		DateTime:  dateTime,
 		CreatedAt: now,
 		Status:    "active",
+		Kind:      kind,
 	}
 
 	s.mu.Lock()
@@ -185,7 +219,85 @@ func (s *mockService) MarkReminderCompleted(ctx context.Context, reminderID stri
 	}
 
 	reminder.Status = "completed"
+	reminder.ClaimedBy = ""
+	reminder.ClaimedUntil = time.Time{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *mockService) ClaimDueReminders(ctx context.Context, now time.Time, owner string, lease time.Duration) ([]Reminder, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	var claimed []Reminder
+	for _, r := range s.reminders {
+		if r.Status != "active" || r.DateTime.After(now) {
+			continue
+		}
+		if r.ClaimedBy != "" && r.ClaimedUntil.After(now) {
+			continue
+		}
+		r.ClaimedBy = owner
+		r.ClaimedUntil = now.Add(lease)
+		claimed = append(claimed, *r)
+	}
 	s.mu.Unlock()
+
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].DateTime.Before(claimed[j].DateTime)
+	})
+
+	return claimed, nil
+}
+
+func (s *mockService) ReleaseReminder(ctx context.Context, reminderID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reminder, ok := s.reminders[reminderID]
+	if !ok {
+		return fmt.Errorf("reminder not found")
+	}
+
+	reminder.ClaimedBy = ""
+	reminder.ClaimedUntil = time.Time{}
 	return nil
 }
 
+func (s *mockService) ExportReminders(ctx context.Context) ([]Reminder, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	result := make([]Reminder, 0, len(s.reminders))
+	for _, r := range s.reminders {
+		result = append(result, *r)
+	}
+	s.mu.RUnlock()
+	return result, nil
+}
+
+func (s *mockService) RestoreReminder(ctx context.Context, reminder Reminder) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.Lock()
+	s.reminders[reminder.ID] = &reminder
+	s.mu.Unlock()
+	return nil
+}
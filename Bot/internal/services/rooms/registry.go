@@ -0,0 +1,202 @@
+package rooms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Room - аудитория из реестра
+type Room struct {
+	Building  string   `json:"building"` // корпус: "А"
+	Number    string   `json:"number"`   // номер: "302"
+	Capacity  int      `json:"capacity"` // мест; 0 - неизвестно
+	Equipment []string `json:"equipment,omitempty"`
+}
+
+// Name - название аудитории в том же виде, что в расписании: "Корпус А, ауд. 302"
+func (r Room) Name() string {
+	if r.Building == "" {
+		return "ауд. " + r.Number
+	}
+	return fmt.Sprintf("Корпус %s, ауд. %s", r.Building, r.Number)
+}
+
+// Key - ключ аудитории для сравнения с местом занятия, см. LocationKey
+func (r Room) Key() string {
+	return LocationKey(r.Building + " " + r.Number)
+}
+
+// HasEquipment сообщает, есть ли в аудитории оборудование (без учета регистра, допускается начало слова:
+// "проект" найдет "проектор")
+func (r Room) HasEquipment(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, e := range r.Equipment {
+		if strings.HasPrefix(strings.ToLower(e), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry - реестр аудиторий
+type Registry interface {
+	ListRooms(ctx context.Context) ([]Room, error) // по корпусу и номеру
+}
+
+// locationNoise - слова, которые пропускаются при сравнении мест занятий: "Корпус А, ауд. 302" = "А 302"
+var locationNoise = map[string]bool{
+	"корпус": true, "корп": true,
+	"аудитория": true, "ауд": true,
+	"кабинет": true, "каб": true,
+}
+
+// LocationKey приводит место занятия или название аудитории к ключу: "Корпус А, ауд. 302" -> "а 302".
+// Регистр, знаки препинания и слова "корпус", "ауд." и т.п. не учитываются
+func LocationKey(location string) string {
+	words := strings.FieldsFunc(strings.ReplaceAll(strings.ToLower(location), "ё", "е"), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '.' || r == '-' || r == '№'
+	})
+	kept := words[:0]
+	for _, w := range words {
+		if !locationNoise[w] {
+			kept = append(kept, w)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// Find ищет аудиторию по названию ("Корпус А, ауд. 302", "А 302") или только по номеру, если он
+// встречается в одном корпусе
+func Find(rooms []Room, query string) (Room, bool) {
+	key := LocationKey(query)
+	if key == "" {
+		return Room{}, false
+	}
+	for _, r := range rooms {
+		if r.Key() == key {
+			return r, true
+		}
+	}
+
+	var found []Room
+	for _, r := range rooms {
+		if LocationKey(r.Number) == key {
+			found = append(found, r)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return Room{}, false
+}
+
+func sortRooms(rooms []Room) {
+	sort.SliceStable(rooms, func(i, j int) bool {
+		if rooms[i].Building != rooms[j].Building {
+			return rooms[i].Building < rooms[j].Building
+		}
+		return rooms[i].Number < rooms[j].Number
+	})
+}
+
+// mockRooms - аудитории из расписания мока и несколько свободных
+var mockRooms = []Room{
+	{"А", "112", 24, []string{"лаборатория", "проектор"}},
+	{"А", "210", 120, []string{"проектор", "микрофон"}},
+	{"А", "302", 60, []string{"проектор", "доска"}},
+	{"А", "305", 30, []string{"доска"}},
+	{"Б", "115", 30, []string{"компьютеры", "проектор"}},
+	{"Б", "201", 40, []string{"проектор", "доска"}},
+	{"В", "101", 100, []string{"проектор", "микрофон"}},
+	{"В", "310", 20, []string{"доска"}},
+	{"В", "405", 25, []string{"доска"}},
+}
+
+type mockRegistry struct{}
+
+func NewMockRegistry() Registry {
+	return mockRegistry{}
+}
+
+func (mockRegistry) ListRooms(ctx context.Context) ([]Room, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	rooms := make([]Room, len(mockRooms))
+	copy(rooms, mockRooms)
+	return rooms, nil
+}
+
+// fileRegistry читает реестр из JSON-файла и перечитывает его после изменения
+type fileRegistry struct {
+	path string
+
+	mu       sync.Mutex
+	rooms    []Room
+	modified time.Time
+}
+
+// NewFileRegistry берет аудитории из JSON-файла со списком объектов
+// {"building": "А", "number": "302", "capacity": 60, "equipment": ["проектор"]}.
+// Файл читается сразу; измененный файл перечитывается при следующем обращении
+func NewFileRegistry(path string) (Registry, error) {
+	r := &fileRegistry{path: path}
+	if _, err := r.ListRooms(context.Background()); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileRegistry) ListRooms(ctx context.Context) ([]Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("read rooms: %w", err)
+	}
+	if r.rooms == nil || !info.ModTime().Equal(r.modified) {
+		rooms, err := readRooms(r.path)
+		if err != nil {
+			// После неудачной правки файла остается последняя прочитанная версия
+			if r.rooms != nil {
+				return r.copyRooms(), nil
+			}
+			return nil, err
+		}
+		r.rooms, r.modified = rooms, info.ModTime()
+	}
+	return r.copyRooms(), nil
+}
+
+func (r *fileRegistry) copyRooms() []Room {
+	rooms := make([]Room, len(r.rooms))
+	copy(rooms, r.rooms)
+	return rooms
+}
+
+func readRooms(path string) ([]Room, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rooms: %w", err)
+	}
+	rooms := []Room{}
+	if err := json.Unmarshal(data, &rooms); err != nil {
+		return nil, fmt.Errorf("decode rooms %s: %w", path, err)
+	}
+	for i, room := range rooms {
+		if strings.TrimSpace(room.Number) == "" {
+			return nil, fmt.Errorf("decode rooms %s: room %d has no number", path, i+1)
+		}
+	}
+	sortRooms(rooms)
+	return rooms, nil
+}
@@ -0,0 +1,171 @@
+// Package rooms - реестр аудиторий и их занятость по расписанию: поиск свободных аудиторий на время
+// с фильтрами по вместимости и оборудованию и занятость одной аудитории за день
+package rooms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"first-max-bot/internal/services/schedule"
+)
+
+var (
+	// ErrNoSchedules - источник расписания не отдает занятия всех групп (например, uni-back),
+	// поэтому занятость аудиторий неизвестна
+	ErrNoSchedules = errors.New("schedule source does not provide all timetables")
+	// ErrRoomNotFound - аудитории нет в реестре
+	ErrRoomNotFound = errors.New("room not found")
+)
+
+// Booking - занятие в аудитории
+type Booking struct {
+	Start, End time.Time
+	Discipline string
+	Instructor string
+}
+
+// Occupancy - индекс занятости: занятия по ключу аудитории (LocationKey), по времени начала
+type Occupancy map[string][]Booking
+
+// NewOccupancy строит индекс по занятиям; занятия без места пропускаются
+func NewOccupancy(items []schedule.Item) Occupancy {
+	index := make(Occupancy)
+	for _, item := range items {
+		key := LocationKey(item.Location)
+		if key == "" {
+			continue
+		}
+		index[key] = append(index[key], Booking{Start: item.Time, End: item.End(), Discipline: item.Discipline, Instructor: item.Instructor})
+	}
+	for _, bookings := range index {
+		sort.SliceStable(bookings, func(i, j int) bool { return bookings[i].Start.Before(bookings[j].Start) })
+	}
+	return index
+}
+
+// Busy возвращает занятия аудитории, пересекающиеся с [from, to)
+func (o Occupancy) Busy(room Room, from, to time.Time) []Booking {
+	var busy []Booking
+	for _, b := range o[room.Key()] {
+		if b.Start.Before(to) && b.End.After(from) {
+			busy = append(busy, b)
+		}
+	}
+	return busy
+}
+
+// FreeUntil - когда аудиторию, свободную в момент t, займут: начало следующего занятия
+// или нулевое время, если занятий больше нет
+func (o Occupancy) FreeUntil(room Room, t time.Time) time.Time {
+	for _, b := range o[room.Key()] {
+		if !b.Start.Before(t) {
+			return b.Start
+		}
+	}
+	return time.Time{}
+}
+
+// Filter - условия поиска свободных аудиторий
+type Filter struct {
+	MinCapacity int      // 0 - любая вместимость
+	Equipment   []string // все перечисленное должно быть в аудитории
+}
+
+func (f Filter) match(room Room) bool {
+	if f.MinCapacity > 0 && room.Capacity < f.MinCapacity {
+		return false
+	}
+	for _, e := range f.Equipment {
+		if !room.HasEquipment(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// FreeRoom - свободная аудитория и время, до которого она свободна (нулевое - до конца дня)
+type FreeRoom struct {
+	Room
+	Until time.Time
+}
+
+// Service ищет свободные аудитории по реестру и расписанию всех групп
+type Service struct {
+	registry  Registry
+	scheduler schedule.AllScheduler // nil - источник расписания не отдает все занятия
+}
+
+func NewService(registry Registry, scheduleService schedule.Service) *Service {
+	scheduler, _ := scheduleService.(schedule.AllScheduler)
+	return &Service{registry: registry, scheduler: scheduler}
+}
+
+// Rooms возвращает реестр аудиторий
+func (s *Service) Rooms(ctx context.Context) ([]Room, error) {
+	return s.registry.ListRooms(ctx)
+}
+
+// Occupancy строит индекс занятости по всем занятиям дней, на которые приходится [from, to)
+func (s *Service) Occupancy(ctx context.Context, from, to time.Time) (Occupancy, error) {
+	if s.scheduler == nil {
+		return nil, ErrNoSchedules
+	}
+	dayStart, _ := schedule.DayRange(from)
+	_, dayEnd := schedule.DayRange(to.Add(-time.Nanosecond))
+	items, err := s.scheduler.GetAllSchedules(ctx, dayStart, dayEnd)
+	if err != nil {
+		return nil, fmt.Errorf("get schedules: %w", err)
+	}
+	return NewOccupancy(items), nil
+}
+
+// FreeRooms возвращает аудитории, в которых нет занятий в [from, to) и которые подходят под фильтр.
+// Вместе с ними возвращается число аудиторий, подходящих под фильтр без учета занятости
+func (s *Service) FreeRooms(ctx context.Context, from, to time.Time, filter Filter) ([]FreeRoom, int, error) {
+	rooms, err := s.registry.ListRooms(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	occupancy, err := s.Occupancy(ctx, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		free    []FreeRoom
+		matched int
+	)
+	for _, room := range rooms {
+		if !filter.match(room) {
+			continue
+		}
+		matched++
+		if len(occupancy.Busy(room, from, to)) > 0 {
+			continue
+		}
+		// Индекс заканчивается концом дня, поэтому нулевое время - свободна до конца дня
+		free = append(free, FreeRoom{Room: room, Until: occupancy.FreeUntil(room, to)})
+	}
+	return free, matched, nil
+}
+
+// RoomDay находит аудиторию в реестре (см. Find) и возвращает ее занятия за день
+func (s *Service) RoomDay(ctx context.Context, query string, day time.Time) (Room, []Booking, error) {
+	rooms, err := s.registry.ListRooms(ctx)
+	if err != nil {
+		return Room{}, nil, err
+	}
+	room, ok := Find(rooms, query)
+	if !ok {
+		return Room{}, nil, fmt.Errorf("%w: %q", ErrRoomNotFound, query)
+	}
+	from, to := schedule.DayRange(day)
+	occupancy, err := s.Occupancy(ctx, from, to)
+	if err != nil {
+		return room, nil, err
+	}
+	return room, occupancy.Busy(room, from, to), nil
+}
@@ -0,0 +1,143 @@
+package schedule
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxMoveDistance - дальше этого занятие считается не перенесенным, а отмененным (и, возможно, добавленным новым)
+const maxMoveDistance = 7 * 24 * time.Hour
+
+// ChangeKind - вид изменения занятия
+type ChangeKind string
+
+const (
+	ChangeMoved     ChangeKind = "moved"     // другое время начала; аудитория и преподаватель могли тоже измениться
+	ChangeUpdated   ChangeKind = "updated"   // то же время, другие аудитория, преподаватель или время окончания
+	ChangeCancelled ChangeKind = "cancelled" // занятия больше нет
+	ChangeAdded     ChangeKind = "added"     // новое занятие
+)
+
+// Change - изменение одного занятия между двумя снимками расписания
+type Change struct {
+	Kind   ChangeKind
+	Before Item // пусто для ChangeAdded
+	After  Item // пусто для ChangeCancelled
+}
+
+// LocationChanged, InstructorChanged - что изменилось у перенесенного или обновленного занятия
+func (c Change) LocationChanged() bool {
+	return c.Before.Location != c.After.Location
+}
+
+func (c Change) InstructorChanged() bool {
+	return c.Before.Instructor != c.After.Instructor
+}
+
+// Time - время занятия, по которому изменения упорядочиваются: прежнее, а у добавленного - новое
+func (c Change) Time() time.Time {
+	if c.Kind == ChangeAdded {
+		return c.After.Time
+	}
+	return c.Before.Time
+}
+
+// Diff сравнивает занятия двух снимков расписания, начинающиеся в [from, to). У занятий нет ID,
+// поэтому занятие с тем же временем и дисциплиной считается обновленным, а с той же дисциплиной
+// в пределах недели - перенесенным на ближайшее свободное время. Изменения описания не учитываются
+func Diff(before, after []Item, from, to time.Time) []Change {
+	old := itemsIn(before, from, to)
+	cur := itemsIn(after, from, to)
+
+	// Неизмененные занятия
+	for i := range old {
+		for j := range cur {
+			if cur[j] != nil && old[i] != nil && sameLesson(*old[i], *cur[j]) {
+				old[i], cur[j] = nil, nil
+			}
+		}
+	}
+
+	var changes []Change
+	// То же время и дисциплина - изменились аудитория, преподаватель или окончание
+	for i := range old {
+		for j := range cur {
+			if old[i] == nil || cur[j] == nil {
+				continue
+			}
+			if old[i].Time.Equal(cur[j].Time) && sameDiscipline(*old[i], *cur[j]) {
+				changes = append(changes, Change{Kind: ChangeUpdated, Before: *old[i], After: *cur[j]})
+				old[i], cur[j] = nil, nil
+			}
+		}
+	}
+
+	// Та же дисциплина в другое время - перенос на ближайшее время
+	for i := range old {
+		if old[i] == nil {
+			continue
+		}
+		best := -1
+		for j := range cur {
+			if cur[j] == nil || !sameDiscipline(*old[i], *cur[j]) {
+				continue
+			}
+			distance := absDuration(cur[j].Time.Sub(old[i].Time))
+			if distance > maxMoveDistance {
+				continue
+			}
+			if best < 0 || distance < absDuration(cur[best].Time.Sub(old[i].Time)) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			changes = append(changes, Change{Kind: ChangeMoved, Before: *old[i], After: *cur[best]})
+			old[i], cur[best] = nil, nil
+		}
+	}
+
+	for _, item := range old {
+		if item != nil {
+			changes = append(changes, Change{Kind: ChangeCancelled, Before: *item})
+		}
+	}
+	for _, item := range cur {
+		if item != nil {
+			changes = append(changes, Change{Kind: ChangeAdded, After: *item})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Time().Before(changes[j].Time()) })
+	return changes
+}
+
+// itemsIn возвращает указатели на копии занятий из [from, to) по времени начала
+func itemsIn(items []Item, from, to time.Time) []*Item {
+	var result []*Item
+	for _, item := range items {
+		if item.Time.Before(from) || !item.Time.Before(to) {
+			continue
+		}
+		item := item
+		result = append(result, &item)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
+
+func sameLesson(a, b Item) bool {
+	return a.Time.Equal(b.Time) && a.End().Equal(b.End()) && sameDiscipline(a, b) &&
+		a.Location == b.Location && a.Instructor == b.Instructor
+}
+
+func sameDiscipline(a, b Item) bool {
+	return strings.EqualFold(strings.TrimSpace(a.Discipline), strings.TrimSpace(b.Discipline))
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,194 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"first-max-bot/internal/services/user"
+	"first-max-bot/internal/uniback"
+)
+
+var ErrUnavailable = errors.New("schedule unavailable")
+
+// dayLesson - DayScheduleResponse из uni-back: занятие на сегодня, время в формате "HH:mm"
+type dayLesson struct {
+	Time        string `json:"time"`
+	Discipline  string `json:"discipline"`
+	Instructor  string `json:"instructor"`
+	Location    string `json:"location"`
+	Description string `json:"description"`
+}
+
+type httpOptions struct {
+	freshFor time.Duration
+	location *time.Location
+	now      func() time.Time
+}
+
+type Option func(*httpOptions)
+
+// WithFreshFor задает, сколько расписание из кэша считается свежим (по умолчанию 5 минут).
+// Устаревшее расписание за тот же день отдается, если uni-back недоступен
+func WithFreshFor(ttl time.Duration) Option {
+	return func(o *httpOptions) {
+		o.freshFor = ttl
+	}
+}
+
+// WithLocation задает часовой пояс университета, в котором uni-back отдает время занятий (по умолчанию time.Local)
+func WithLocation(loc *time.Location) Option {
+	return func(o *httpOptions) {
+		o.location = loc
+	}
+}
+
+type cachedDay struct {
+	items   []Item
+	fetched time.Time
+}
+
+type httpService struct {
+	client *uniback.Client
+	users  user.Service
+	opts   httpOptions
+
+	mu    sync.Mutex
+	day   string               // день, за который лежит кэш; при смене дня кэш очищается
+	cache map[string]cachedDay // ключ - ID пользователя
+}
+
+// NewHTTPService берет расписание на сегодня из uni-back: преподавателям (сотрудникам) -
+// из /schedule/professor/{maxId}, остальным - из /schedule/student/{maxId}
+func NewHTTPService(client *uniback.Client, users user.Service, opts ...Option) Service {
+	o := httpOptions{
+		freshFor: 5 * time.Minute,
+		location: time.Local,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &httpService{
+		client: client,
+		users:  users,
+		opts:   o,
+		cache:  make(map[string]cachedDay),
+	}
+}
+
+// GetSchedule отдает занятия только в пределах сегодняшнего дня: на другие даты в uni-back расписания нет
+func (s *httpService) GetSchedule(ctx context.Context, userID string, from, to time.Time) ([]Item, error) {
+	now := s.opts.now().In(s.opts.location)
+	dayStart, dayEnd := DayRange(now)
+	if from.Before(dayStart) || to.After(dayEnd) {
+		return nil, ErrOutOfRange
+	}
+
+	items, err := s.today(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Item, 0, len(items))
+	for _, item := range items {
+		if !item.Time.Before(from) && item.Time.Before(to) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *httpService) today(ctx context.Context, userID string, now time.Time) ([]Item, error) {
+	day := now.Format(time.DateOnly)
+
+	cached, ok := s.cached(day, userID)
+	if ok && now.Sub(cached.fetched) < s.opts.freshFor {
+		return cached.items, nil
+	}
+
+	items, err := s.fetch(ctx, userID, now)
+	if err != nil {
+		if ok && !errors.Is(err, uniback.ErrRejected) {
+			// uni-back недоступен: лучше расписание, полученное раньше сегодня, чем никакого
+			return cached.items, nil
+		}
+		return nil, err
+	}
+
+	s.store(day, userID, cachedDay{items: items, fetched: now})
+	return items, nil
+}
+
+func (s *httpService) fetch(ctx context.Context, userID string, now time.Time) ([]Item, error) {
+	maxID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user id %q is not a MAX id", userID)
+	}
+
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	// Сотрудник, который еще и учится, видит оба расписания
+	paths := []string{"/schedule/student/"}
+	if u != nil && u.HasRole(user.RoleEmployee) {
+		paths = []string{"/schedule/professor/"}
+		if u.HasRole(user.RoleStudent) {
+			paths = append(paths, "/schedule/student/")
+		}
+	}
+
+	var lessons []dayLesson
+	for _, path := range paths {
+		var part []dayLesson
+		err := s.client.Get(ctx, path+strconv.FormatInt(maxID, 10), &part)
+		if errors.Is(err, uniback.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		lessons = append(lessons, part...)
+	}
+
+	items := make([]Item, 0, len(lessons))
+	for _, lesson := range lessons {
+		at, err := time.ParseInLocation("15:04", lesson.Time, s.opts.location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lesson time %q: %w", lesson.Time, err)
+		}
+		items = append(items, Item{
+			Time:        time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, s.opts.location),
+			Discipline:  lesson.Discipline,
+			Instructor:  lesson.Instructor,
+			Location:    lesson.Location,
+			Description: lesson.Description,
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+	return items, nil
+}
+
+func (s *httpService) cached(day, userID string) (cachedDay, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.day != day {
+		return cachedDay{}, false
+	}
+	entry, ok := s.cache[userID]
+	return entry, ok
+}
+
+func (s *httpService) store(day, userID string, entry cachedDay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.day != day {
+		s.day = day
+		s.cache = make(map[string]cachedDay)
+	}
+	s.cache[userID] = entry
+}
@@ -0,0 +1,708 @@
+package schedule
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences ограничивает разворачивание одного повторяющегося события
+const maxOccurrences = 5000
+
+var ErrInvalidCalendar = errors.New("invalid iCalendar data")
+
+// errUnsupportedRule - правило повторения, которое разворачивается неверно (BYMONTHDAY, BYSETPOS, 1MO и т.п.).
+// Такое событие пропускается, а не портит расписание
+var errUnsupportedRule = errors.New("unsupported recurrence rule")
+
+// Calendar - расписание из файла iCalendar (RFC 5545): занятия VEVENT с повторениями RRULE, RDATE и EXDATE,
+// переносами отдельных занятий (RECURRENCE-ID) и часовыми поясами TZID
+type Calendar struct {
+	Name    string   // X-WR-CALNAME, если есть
+	Skipped []string // пропущенные события с неподдерживаемым повторением: "Матанализ (uid): причина"
+	events  []icalEvent
+}
+
+// Events возвращает число событий календаря, включая переносы отдельных занятий
+func (c *Calendar) Events() int {
+	return len(c.events)
+}
+
+type icalEvent struct {
+	uid          string
+	start        time.Time
+	duration     time.Duration
+	rule         *recurrence
+	rdates       []time.Time
+	exdates      map[int64]bool
+	exdays       map[string]bool // EXDATE;VALUE=DATE исключает все занятия за день
+	recurrenceID time.Time       // перенос одного занятия повторяющегося события
+	cancelled    bool
+	item         Item
+}
+
+type recurrence struct {
+	freq      string
+	interval  int
+	count     int
+	until     time.Time
+	byDay     []time.Weekday
+	weekStart time.Weekday
+}
+
+// contentLine - строка iCalendar вида NAME;PARAM=VALUE:value
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseCalendar читает календарь. Время без часового пояса (floating) считается в X-WR-TIMEZONE,
+// а если его нет - в loc. События на целый день (VALUE=DATE) не считаются занятиями и пропускаются
+func ParseCalendar(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	zones := map[string]*time.Location{}
+	var (
+		stack   []string
+		event   []contentLine
+		zone    []contentLine
+		zoneID  string
+		pending [][]contentLine
+	)
+	for n, raw := range lines {
+		line, err := parseContentLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCalendar, n+1, err)
+		}
+
+		switch line.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(line.value))
+			switch strings.ToUpper(line.value) {
+			case "VEVENT":
+				event = nil
+			case "VTIMEZONE":
+				zoneID = ""
+			case "STANDARD":
+				zone = nil
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(line.value) {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrInvalidCalendar, n+1, line.value)
+			}
+			stack = stack[:len(stack)-1]
+			switch strings.ToUpper(line.value) {
+			case "VEVENT":
+				pending = append(pending, event)
+			case "STANDARD":
+				if zoneID != "" {
+					if l := fixedZone(zoneID, zone); l != nil {
+						zones[zoneID] = l
+					}
+				}
+			}
+			continue
+		}
+
+		if len(stack) == 0 {
+			continue
+		}
+		switch stack[len(stack)-1] {
+		case "VCALENDAR":
+			switch line.name {
+			case "X-WR-CALNAME":
+				cal.Name = unescapeText(line.value)
+			case "X-WR-TIMEZONE":
+				if l, err := time.LoadLocation(line.value); err == nil {
+					loc = l
+				}
+			}
+		case "VEVENT":
+			event = append(event, line)
+		case "VTIMEZONE":
+			if line.name == "TZID" {
+				zoneID = line.value
+			}
+		case "STANDARD":
+			zone = append(zone, line)
+		}
+	}
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: unterminated %s", ErrInvalidCalendar, stack[len(stack)-1])
+	}
+
+	tz := &timeZones{defaultLoc: loc, declared: zones}
+	for _, props := range pending {
+		ev, ok, err := buildEvent(props, tz)
+		if errors.Is(err, errUnsupportedRule) {
+			cal.Skipped = append(cal.Skipped, fmt.Sprintf("%s (%s): %v", ev.item.Discipline, ev.uid, err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+		}
+		if ok {
+			cal.events = append(cal.events, ev)
+		}
+	}
+	return cal, nil
+}
+
+// Items возвращает занятия, которые начинаются в [from, to), по времени начала
+func (c *Calendar) Items(from, to time.Time) []Item {
+	// Перенесенные и отмененные занятия исключаются из повторений своего события
+	moved := map[string]map[int64]bool{}
+	for _, ev := range c.events {
+		if ev.recurrenceID.IsZero() {
+			continue
+		}
+		if moved[ev.uid] == nil {
+			moved[ev.uid] = map[int64]bool{}
+		}
+		moved[ev.uid][ev.recurrenceID.Unix()] = true
+	}
+
+	var items []Item
+	for _, ev := range c.events {
+		if ev.cancelled {
+			continue
+		}
+		for _, start := range ev.occurrences(to) {
+			if start.Before(from) || !start.Before(to) {
+				continue
+			}
+			if ev.recurrenceID.IsZero() && moved[ev.uid][start.Unix()] {
+				continue
+			}
+			item := ev.item
+			item.Time = start
+			item.EndTime = start.Add(ev.duration)
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+	return items
+}
+
+// occurrences возвращает начала занятий события до limit с учетом RRULE, RDATE и EXDATE
+func (ev icalEvent) occurrences(limit time.Time) []time.Time {
+	starts := []time.Time{ev.start}
+	if ev.rule != nil {
+		starts = ev.rule.expand(ev.start, limit)
+	}
+	starts = append(starts, ev.rdates...)
+
+	result := make([]time.Time, 0, len(starts))
+	seen := map[int64]bool{}
+	for _, s := range starts {
+		if ev.exdates[s.Unix()] || ev.exdays[s.Format(time.DateOnly)] || seen[s.Unix()] {
+			continue
+		}
+		seen[s.Unix()] = true
+		result = append(result, s)
+	}
+	return result
+}
+
+// expand разворачивает правило повторения. Время занятия сохраняется по местным часам,
+// поэтому при переходе на летнее время занятие остается в 9:00, а не сдвигается на час
+func (r *recurrence) expand(start, limit time.Time) []time.Time {
+	var result []time.Time
+	add := func(t time.Time) bool {
+		if t.Before(start) {
+			return true
+		}
+		if !r.until.IsZero() && t.After(r.until) {
+			return false
+		}
+		if !t.Before(limit) || (r.count > 0 && len(result) >= r.count) || len(result) >= maxOccurrences {
+			return false
+		}
+		result = append(result, t)
+		return true
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	switch r.freq {
+	case "DAILY":
+		for day := start; ; day = day.AddDate(0, 0, r.interval) {
+			if len(r.byDay) > 0 && !containsWeekday(r.byDay, day.Weekday()) {
+				if day.After(limit) {
+					return result
+				}
+				continue
+			}
+			if !add(at(day)) {
+				return result
+			}
+		}
+	case "WEEKLY":
+		days := r.byDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		offsets := make([]int, 0, len(days))
+		for _, d := range days {
+			offsets = append(offsets, (int(d)-int(r.weekStart)+7)%7)
+		}
+		sort.Ints(offsets)
+
+		shift := (int(start.Weekday()) - int(r.weekStart) + 7) % 7
+		week := time.Date(start.Year(), start.Month(), start.Day()-shift, 0, 0, 0, 0, start.Location())
+		for ; ; week = week.AddDate(0, 0, 7*r.interval) {
+			for _, off := range offsets {
+				if !add(at(week.AddDate(0, 0, off))) {
+					return result
+				}
+			}
+		}
+	case "MONTHLY", "YEARLY":
+		for i := 0; ; i++ {
+			months := i * r.interval
+			if r.freq == "YEARLY" {
+				months *= 12
+			}
+			first := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, start.Location())
+			day := first.AddDate(0, 0, start.Day()-1)
+			if day.Month() != first.Month() {
+				// 31 число в коротком месяце пропускается, как требует RFC 5545
+				if first.After(limit) {
+					return result
+				}
+				continue
+			}
+			if !add(at(day)) {
+				return result
+			}
+		}
+	}
+	return []time.Time{start}
+}
+
+func buildEvent(props []contentLine, tz *timeZones) (icalEvent, bool, error) {
+	ev := icalEvent{exdates: map[int64]bool{}, exdays: map[string]bool{}}
+	var (
+		end       time.Time
+		hasEnd    bool
+		duration  time.Duration
+		hasDur    bool
+		allDay    bool
+		ruleValue string
+		organizer string
+	)
+	for _, p := range props {
+		switch p.name {
+		case "UID":
+			ev.uid = p.value
+		case "SUMMARY":
+			ev.item.Discipline = unescapeText(p.value)
+		case "LOCATION":
+			ev.item.Location = unescapeText(p.value)
+		case "DESCRIPTION":
+			ev.item.Description = unescapeText(p.value)
+		case "ORGANIZER":
+			organizer = strings.Trim(p.params["CN"], `"`)
+		case "STATUS":
+			ev.cancelled = strings.EqualFold(p.value, "CANCELLED")
+		case "DTSTART":
+			t, dateOnly, err := tz.parse(p)
+			if err != nil {
+				return ev, false, fmt.Errorf("DTSTART: %w", err)
+			}
+			ev.start, allDay = t, dateOnly
+		case "DTEND":
+			t, _, err := tz.parse(p)
+			if err != nil {
+				return ev, false, fmt.Errorf("DTEND: %w", err)
+			}
+			end, hasEnd = t, true
+		case "DURATION":
+			d, err := parseDuration(p.value)
+			if err != nil {
+				return ev, false, fmt.Errorf("DURATION: %w", err)
+			}
+			duration, hasDur = d, true
+		case "RRULE":
+			ruleValue = p.value
+		case "RDATE", "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				t, dateOnly, err := tz.parse(contentLine{name: p.name, params: p.params, value: v})
+				if err != nil {
+					return ev, false, fmt.Errorf("%s: %w", p.name, err)
+				}
+				switch {
+				case p.name == "RDATE" && !dateOnly:
+					ev.rdates = append(ev.rdates, t)
+				case p.name == "RDATE":
+					// Дополнительный день без времени занятием не считается, как и события на целый день
+				case dateOnly:
+					ev.exdays[t.Format(time.DateOnly)] = true
+				default:
+					ev.exdates[t.Unix()] = true
+				}
+			}
+		case "RECURRENCE-ID":
+			t, _, err := tz.parse(p)
+			if err != nil {
+				return ev, false, fmt.Errorf("RECURRENCE-ID: %w", err)
+			}
+			ev.recurrenceID = t
+		}
+	}
+
+	if ev.start.IsZero() {
+		return ev, false, fmt.Errorf("event %q has no DTSTART", ev.uid)
+	}
+	if allDay {
+		return ev, false, nil
+	}
+	switch {
+	case hasEnd:
+		ev.duration = end.Sub(ev.start)
+	case hasDur:
+		ev.duration = duration
+	default:
+		ev.duration = LessonDuration
+	}
+	if ruleValue != "" {
+		rule, err := parseRule(ruleValue, tz)
+		if errors.Is(err, errUnsupportedRule) {
+			return ev, false, fmt.Errorf("RRULE: %w", err)
+		}
+		if err != nil {
+			return ev, false, fmt.Errorf("event %q: RRULE: %w", ev.uid, err)
+		}
+		ev.rule = rule
+	}
+	// Дополнительные даты показываются в часовом поясе DTSTART, как и остальные занятия события
+	for i, t := range ev.rdates {
+		ev.rdates[i] = t.In(ev.start.Location())
+	}
+
+	ev.item.Instructor, ev.item.Description = splitInstructor(organizer, ev.item.Description)
+	return ev, true, nil
+}
+
+// splitInstructor берет преподавателя из ORGANIZER или строки "Преподаватель: ..." в описании,
+// строка с преподавателем из описания убирается
+func splitInstructor(organizer, description string) (string, string) {
+	instructor := organizer
+	var rest []string
+	for _, line := range strings.Split(description, "\n") {
+		trimmed := strings.TrimSpace(line)
+		name, value, found := strings.Cut(trimmed, ":")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "преподаватель", "преп.", "teacher", "instructor", "lecturer":
+			if found {
+				if instructor == "" {
+					instructor = strings.TrimSpace(value)
+				}
+				continue
+			}
+		}
+		rest = append(rest, line)
+	}
+	return instructor, strings.TrimSpace(strings.Join(rest, "\n"))
+}
+
+func parseRule(value string, tz *timeZones) (*recurrence, error) {
+	r := &recurrence{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			r.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			r.count = n
+		case "UNTIL":
+			t, dateOnly, err := tz.parse(contentLine{name: "UNTIL", params: map[string]string{}, value: val})
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			if dateOnly {
+				// UNTIL в виде даты включает весь день
+				t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+			r.until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, ok := parseWeekday(d)
+				if !ok {
+					return nil, fmt.Errorf("%w: BYDAY %q", errUnsupportedRule, d)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "WKST":
+			wd, ok := parseWeekday(val)
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			r.weekStart = wd
+		default:
+			// BYMONTH, BYMONTHDAY, BYSETPOS и т.п. меняют даты повторений: без них даты были бы неверными
+			return nil, fmt.Errorf("%w: %s", errUnsupportedRule, strings.ToUpper(key))
+		}
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY":
+	case "MONTHLY", "YEARLY":
+		// Повторение по дню месяца DTSTART; дни недели в месяце (каждый понедельник) не поддерживаются
+		if len(r.byDay) > 0 {
+			return nil, fmt.Errorf("%w: BYDAY with FREQ=%s", errUnsupportedRule, r.freq)
+		}
+	case "":
+		return nil, errors.New("missing FREQ")
+	default:
+		return nil, fmt.Errorf("%w: FREQ %q", errUnsupportedRule, r.freq)
+	}
+	return r, nil
+}
+
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseWeekday понимает MO, TU, ...; номера вроде 1MO (первый понедельник месяца) не поддерживаются
+func parseWeekday(s string) (time.Weekday, bool) {
+	wd, ok := icalWeekdays[strings.ToUpper(strings.TrimSpace(s))]
+	return wd, ok
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// timeZones переводит значения DATE-TIME в time.Time: UTC (суффикс Z), TZID или floating
+type timeZones struct {
+	defaultLoc *time.Location
+	declared   map[string]*time.Location // из VTIMEZONE, если TZID не из базы IANA
+}
+
+func (z *timeZones) location(tzid string) *time.Location {
+	if tzid == "" {
+		return z.defaultLoc
+	}
+	tzid = strings.Trim(tzid, `"`)
+	if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+		return l
+	}
+	if l, ok := z.declared[tzid]; ok {
+		return l
+	}
+	return z.defaultLoc
+}
+
+// parse возвращает время и признак того, что значение - дата без времени
+func (z *timeZones) parse(p contentLine) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.value)
+	loc := z.location(p.params["TZID"])
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t.In(loc), false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// fixedZone строит часовой пояс из TZOFFSETTO компонента STANDARD для TZID, которого нет в базе IANA
+// (например, "Russian Standard Time" из Outlook). Переход на летнее время не учитывается
+func fixedZone(tzid string, props []contentLine) *time.Location {
+	for _, p := range props {
+		if p.name != "TZOFFSETTO" {
+			continue
+		}
+		v := strings.TrimSpace(p.value)
+		if len(v) < 5 {
+			return nil
+		}
+		sign := 1
+		if v[0] == '-' {
+			sign = -1
+		}
+		h, errH := strconv.Atoi(v[1:3])
+		m, errM := strconv.Atoi(v[3:5])
+		if errH != nil || errM != nil {
+			return nil
+		}
+		return time.FixedZone(tzid, sign*(h*3600+m*60))
+	}
+	return nil
+}
+
+// parseDuration разбирает DURATION вида P1W, P1D, PT1H30M, -PT15M
+func parseDuration(s string) (time.Duration, error) {
+	orig := s
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign, s = -1, s[1:]
+	}
+	s = strings.TrimPrefix(s, "+")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	s = s[1:]
+
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case r == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			num = ""
+			switch {
+			case r == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+	return sign * total, nil
+}
+
+// unfoldLines склеивает перенесенные строки (продолжение начинается с пробела или табуляции)
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff") // BOM
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseContentLine разбирает NAME;PARAM=VALUE;PARAM="VALUE:WITH:COLONS":value
+func parseContentLine(line string) (contentLine, error) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		}
+		if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return contentLine{}, fmt.Errorf("no value in %q", line)
+	}
+
+	head, value := line[:colon], line[colon+1:]
+	parts := splitParams(head)
+	result := contentLine{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		result.params[strings.ToUpper(key)] = val
+	}
+	return result, nil
+}
+
+func splitParams(s string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ';' && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeText раскрывает \n, \, \; и \\ в значениях TEXT
+func unescapeText(s string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range s {
+		if escaped {
+			switch r {
+			case 'n', 'N':
+				b.WriteRune('\n')
+			default:
+				b.WriteRune(r)
+			}
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,342 @@
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"first-max-bot/internal/services/user"
+)
+
+// ErrNoGroup - у пользователя не выбрана учебная группа или для нее нет расписания
+var ErrNoGroup = errors.New("study group is not set or has no timetable")
+
+// ErrInvalidName - имя расписания не годится для имени файла
+var ErrInvalidName = errors.New("invalid timetable name")
+
+// CalendarStore - необязательное расширение Service: расписания, загружаемые файлами iCalendar
+type CalendarStore interface {
+	ImportCalendar(ctx context.Context, name string, data []byte) (*Calendar, error) // возвращает разобранный файл
+	CalendarNames(ctx context.Context) ([]string, error)
+	Reload() error // перечитать измененные файлы
+}
+
+type icalOptions struct {
+	location *time.Location
+	reload   time.Duration
+}
+
+type ICalOption func(*icalOptions)
+
+// WithCalendarLocation задает часовой пояс для времени без пояса в файлах (по умолчанию time.Local)
+func WithCalendarLocation(loc *time.Location) ICalOption {
+	return func(o *icalOptions) {
+		o.location = loc
+	}
+}
+
+// WithReloadInterval задает, как часто проверять изменения файлов в каталоге (по умолчанию раз в минуту)
+func WithReloadInterval(interval time.Duration) ICalOption {
+	return func(o *icalOptions) {
+		o.reload = interval
+	}
+}
+
+type calendarFile struct {
+	name     string // имя файла без .ics - группа или преподаватель
+	modified time.Time
+	calendar *Calendar
+}
+
+type icalService struct {
+	dir   string
+	users user.Service
+	opts  icalOptions
+
+	// reloadMu упорядочивает Reload и ImportCalendar: файлы читаются с диска один раз, а импорт
+	// не теряется из-за одновременного перечитывания каталога. Map files меняется только под reloadMu
+	reloadMu sync.Mutex
+
+	mu       sync.RWMutex
+	files    map[string]calendarFile // ключ - normalizeName(name)
+	loadedAt time.Time
+}
+
+// NewICalService берет расписание из файлов iCalendar в каталоге dir. Имя файла - учебная группа
+// (ИВТ-21.ics) или преподаватель (Петров Алексей.ics). Студентам показывается расписание группы из /group,
+// сотрудникам - их файл, а без него - занятия из всех файлов, где они указаны преподавателем
+func NewICalService(dir string, users user.Service, opts ...ICalOption) (Service, error) {
+	o := icalOptions{location: time.Local, reload: time.Minute}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create timetable dir: %w", err)
+	}
+	return &icalService{
+		dir:   dir,
+		users: users,
+		opts:  o,
+		files: make(map[string]calendarFile),
+	}, nil
+}
+
+// Reload перечитывает измененные файлы каталога. Файлы с ошибками пропускаются (предыдущая версия
+// остается в силе), ошибки возвращаются вместе
+func (s *icalService) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	return s.reload()
+}
+
+// reload выполняется под reloadMu: files никто не меняет, поэтому читать его можно без mu
+func (s *icalService) reload() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.ics"))
+	if err != nil {
+		return fmt.Errorf("list timetables: %w", err)
+	}
+
+	current := s.files
+
+	files := make(map[string]calendarFile, len(paths))
+	var errs []error
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key := normalizeName(name)
+		info, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			continue
+		}
+		if prev, ok := current[key]; ok && prev.modified.Equal(info.ModTime()) {
+			files[key] = prev
+			continue
+		}
+
+		cal, err := s.parseFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+			if prev, ok := current[key]; ok {
+				files[key] = prev
+			}
+			continue
+		}
+		files[key] = calendarFile{name: name, modified: info.ModTime(), calendar: cal}
+	}
+
+	s.mu.Lock()
+	s.files = files
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+func (s *icalService) parseFile(path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCalendar(f, s.opts.location)
+}
+
+func (s *icalService) GetSchedule(ctx context.Context, userID string, from, to time.Time) ([]Item, error) {
+	s.reloadIfStale()
+
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if u == nil {
+		return nil, ErrNoGroup
+	}
+
+	// Сотрудник, который еще и учится, видит и свои занятия, и занятия своей группы
+	var (
+		items []Item
+		found bool
+	)
+	if u.StudyGroup != "" && (u.HasRole(user.RoleStudent) || !u.HasRole(user.RoleEmployee)) {
+		if cal, ok := s.calendar(u.StudyGroup); ok {
+			items = append(items, cal.Items(from, to)...)
+			found = true
+		}
+	}
+	if u.HasRole(user.RoleEmployee) && u.LastName != "" {
+		items = append(items, s.instructorItems(u, from, to)...)
+		found = true
+	}
+	if !found {
+		return nil, ErrNoGroup
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+	return items, nil
+}
+
+func (s *icalService) GetGroupSchedule(ctx context.Context, group string, from, to time.Time) ([]Item, error) {
+	s.reloadIfStale()
+	cal, ok := s.calendar(group)
+	if !ok {
+		return nil, ErrNoGroup
+	}
+	return cal.Items(from, to), nil
+}
+
+// ImportCalendar проверяет файл и сохраняет его в каталог под именем name, заменяя прежний
+func (s *icalService) ImportCalendar(ctx context.Context, name string, data []byte) (*Calendar, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+
+	cal, err := ParseCalendar(bytes.NewReader(data), s.opts.location)
+	if err != nil {
+		return nil, err
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	// Файл с тем же именем в другом регистре заменяется новым
+	prev, replaced := s.files[normalizeName(name)]
+	if replaced && prev.name != name {
+		os.Remove(filepath.Join(s.dir, prev.name+".ics"))
+	}
+
+	// Запись через временный файл: читатели каталога не увидят наполовину записанный календарь
+	path := filepath.Join(s.dir, name+".ics")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, fmt.Errorf("save timetable: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("save timetable: %w", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("save timetable: %w", err)
+	}
+
+	// Новая map вместо изменения текущей: ее могут читать без mu другие методы под reloadMu
+	files := make(map[string]calendarFile, len(s.files)+1)
+	for key, f := range s.files {
+		files[key] = f
+	}
+	files[normalizeName(name)] = calendarFile{name: name, modified: info.ModTime(), calendar: cal}
+	s.mu.Lock()
+	s.files = files
+	s.mu.Unlock()
+	return cal, nil
+}
+
+func (s *icalService) CalendarNames(ctx context.Context) ([]string, error) {
+	s.reloadIfStale()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.files))
+	for _, f := range s.files {
+		names = append(names, f.name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// instructorItems - файл преподавателя ("Фамилия Имя" или "Фамилия"), а без него - занятия из всех файлов,
+// где он указан преподавателем. Одна лекция у нескольких групп показывается один раз
+func (s *icalService) instructorItems(u *user.User, from, to time.Time) []Item {
+	for _, name := range []string{u.LastName + " " + u.FirstName, u.LastName} {
+		if cal, ok := s.calendar(name); ok {
+			return cal.Items(from, to)
+		}
+	}
+
+	return s.allItems(from, to, func(item Item) bool {
+		return teaches(item.Instructor, u.LastName, u.FirstName)
+	})
+}
+
+func (s *icalService) GetAllSchedules(ctx context.Context, from, to time.Time) ([]Item, error) {
+	s.reloadIfStale()
+	return s.allItems(from, to, nil), nil
+}
+
+// allItems - занятия из всех файлов, для которых keep возвращает true (nil - все). Одно занятие
+// из файлов нескольких групп и преподавателя возвращается один раз
+func (s *icalService) allItems(from, to time.Time, keep func(Item) bool) []Item {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := map[string]bool{}
+	var items []Item
+	for _, f := range s.files {
+		for _, item := range f.calendar.Items(from, to) {
+			if keep != nil && !keep(item) {
+				continue
+			}
+			key := item.Time.String() + "|" + item.Discipline + "|" + item.Location
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			items = append(items, item)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.Before(items[j].Time) })
+	return items
+}
+
+// teaches сравнивает преподавателя занятия с фамилией и именем (или инициалом) сотрудника
+func teaches(instructor, lastName, firstName string) bool {
+	words := strings.FieldsFunc(strings.ToLower(instructor), func(r rune) bool {
+		return r == ' ' || r == '.' || r == ','
+	})
+	last, first := strings.ToLower(lastName), []rune(strings.ToLower(firstName))
+	hasLast, hasFirst := false, len(first) == 0
+	for _, w := range words {
+		switch {
+		case w == last:
+			hasLast = true
+		case len(first) > 0 && (w == string(first) || w == string(first[0])):
+			hasFirst = true
+		}
+	}
+	return hasLast && hasFirst
+}
+
+func (s *icalService) calendar(name string) (*Calendar, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.files[normalizeName(name)]
+	return f.calendar, ok
+}
+
+// reloadIfStale перечитывает каталог, если он давно не проверялся. Пока каталог перечитывает другой
+// запрос, остальные не ждут и не читают файлы повторно, а отвечают по последним прочитанным версиям
+func (s *icalService) reloadIfStale() {
+	if !s.stale() || !s.reloadMu.TryLock() {
+		return
+	}
+	defer s.reloadMu.Unlock()
+	if s.stale() {
+		// Ошибки файлов возвращает Reload при запуске; здесь остаются последние прочитанные версии
+		_ = s.reload()
+	}
+}
+
+func (s *icalService) stale() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.loadedAt) >= s.opts.reload
+}
+
+// normalizeName - имена групп и преподавателей сравниваются без учета регистра и лишних пробелов
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
@@ -0,0 +1,155 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestRecurrenceExpand(t *testing.T) {
+	moscow := loadLocation(t, "Europe/Moscow")
+	berlin := loadLocation(t, "Europe/Berlin")
+	at := func(loc *time.Location, date string, hour int) time.Time {
+		t.Helper()
+		day, err := time.ParseInLocation(time.DateOnly, date, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		limit time.Time
+		want  []time.Time
+	}{
+		{
+			name:  "weekly by day every second week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE;INTERVAL=2;COUNT=5",
+			start: at(moscow, "2026-09-07", 9),
+			limit: at(moscow, "2027-01-01", 0),
+			want: []time.Time{
+				at(moscow, "2026-09-07", 9), at(moscow, "2026-09-09", 9),
+				at(moscow, "2026-09-21", 9), at(moscow, "2026-09-23", 9),
+				at(moscow, "2026-10-05", 9),
+			},
+		},
+		{
+			name:  "weekly by day starting mid-week",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3",
+			start: at(moscow, "2026-09-09", 9),
+			limit: at(moscow, "2027-01-01", 0),
+			want:  []time.Time{at(moscow, "2026-09-09", 9), at(moscow, "2026-09-14", 9), at(moscow, "2026-09-16", 9)},
+		},
+		{
+			name:  "date-only until includes the whole day",
+			rule:  "FREQ=WEEKLY;UNTIL=20260921",
+			start: at(moscow, "2026-09-07", 18),
+			limit: at(moscow, "2027-01-01", 0),
+			want:  []time.Time{at(moscow, "2026-09-07", 18), at(moscow, "2026-09-14", 18), at(moscow, "2026-09-21", 18)},
+		},
+		{
+			name:  "limit stops an endless rule",
+			rule:  "FREQ=DAILY;INTERVAL=3",
+			start: at(moscow, "2026-09-07", 9),
+			limit: at(moscow, "2026-09-16", 9),
+			want:  []time.Time{at(moscow, "2026-09-07", 9), at(moscow, "2026-09-10", 9), at(moscow, "2026-09-13", 9)},
+		},
+		{
+			name:  "lesson stays at local 09:00 across DST",
+			rule:  "FREQ=WEEKLY;COUNT=3",
+			start: at(berlin, "2026-03-19", 9),
+			limit: at(berlin, "2027-01-01", 0),
+			want:  []time.Time{at(berlin, "2026-03-19", 9), at(berlin, "2026-03-26", 9), at(berlin, "2026-04-02", 9)},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;COUNT=4",
+			start: at(moscow, "2026-01-31", 10),
+			limit: at(moscow, "2028-01-01", 0),
+			want: []time.Time{
+				at(moscow, "2026-01-31", 10), at(moscow, "2026-03-31", 10),
+				at(moscow, "2026-05-31", 10), at(moscow, "2026-07-31", 10),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := parseRule(tt.rule, &timeZones{defaultLoc: tt.start.Location()})
+			if err != nil {
+				t.Fatalf("parseRule(%q): %v", tt.rule, err)
+			}
+			got := rule.expand(tt.start, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expand = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) || got[i].Hour() != tt.want[i].Hour() {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestItemsExdateAndMovedLesson(t *testing.T) {
+	loc := loadLocation(t, "Europe/Moscow")
+	const data = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:math
+SUMMARY:Матанализ
+LOCATION:Ауд. 101
+DTSTART;TZID=Europe/Moscow:20260907T090000
+DTEND;TZID=Europe/Moscow:20260907T103000
+RRULE:FREQ=WEEKLY;COUNT=4
+EXDATE;TZID=Europe/Moscow:20260914T090000
+END:VEVENT
+BEGIN:VEVENT
+UID:math
+RECURRENCE-ID;TZID=Europe/Moscow:20260921T090000
+SUMMARY:Матанализ
+LOCATION:Ауд. 205
+DTSTART;TZID=Europe/Moscow:20260922T120000
+DTEND;TZID=Europe/Moscow:20260922T133000
+END:VEVENT
+END:VCALENDAR
+`
+	cal, err := ParseCalendar(strings.NewReader(data), loc)
+	if err != nil {
+		t.Fatalf("ParseCalendar: %v", err)
+	}
+
+	items := cal.Items(time.Date(2026, 9, 1, 0, 0, 0, 0, loc), time.Date(2026, 10, 1, 0, 0, 0, 0, loc))
+	want := []struct {
+		start    time.Time
+		location string
+	}{
+		{time.Date(2026, 9, 7, 9, 0, 0, 0, loc), "Ауд. 101"},
+		// 14.09 исключено EXDATE, занятие 21.09 перенесено на 22.09
+		{time.Date(2026, 9, 22, 12, 0, 0, 0, loc), "Ауд. 205"},
+		{time.Date(2026, 9, 28, 9, 0, 0, 0, loc), "Ауд. 101"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("items = %+v, want %d lessons", items, len(want))
+	}
+	for i, w := range want {
+		if !items[i].Time.Equal(w.start) || items[i].Location != w.location {
+			t.Errorf("lesson %d = %v in %q, want %v in %q", i, items[i].Time, items[i].Location, w.start, w.location)
+		}
+		if d := items[i].EndTime.Sub(items[i].Time); d != 90*time.Minute {
+			t.Errorf("lesson %d lasts %v, want 1h30m", i, d)
+		}
+	}
+}
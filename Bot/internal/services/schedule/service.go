@@ -2,19 +2,85 @@ package schedule
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// LessonDuration - длительность пары, если время окончания занятия неизвестно
+const LessonDuration = 90 * time.Minute
+
+// ErrOutOfRange - источник расписания не знает занятий на запрошенные даты
+var ErrOutOfRange = errors.New("schedule is not available for requested dates")
+
 type Item struct {
 	Time        time.Time
+	EndTime     time.Time // нулевое - окончание неизвестно, см. End
 	Discipline  string
 	Instructor  string
 	Location    string
 	Description string
 }
 
+// End возвращает время окончания занятия; если оно неизвестно - через LessonDuration после начала
+func (i Item) End() time.Time {
+	if i.EndTime.IsZero() {
+		return i.Time.Add(LessonDuration)
+	}
+	return i.EndTime
+}
+
+// Service возвращает занятия, которые начинаются в [from, to), по времени начала
 type Service interface {
-	GetSchedule(ctx context.Context, userID string) ([]Item, error)
+	GetSchedule(ctx context.Context, userID string, from, to time.Time) ([]Item, error)
+}
+
+// GroupScheduler - необязательное расширение Service: расписание учебной группы целиком.
+// Используется для публикации расписания в групповые чаты
+type GroupScheduler interface {
+	GetGroupSchedule(ctx context.Context, group string, from, to time.Time) ([]Item, error)
+}
+
+// AllScheduler - необязательное расширение Service: занятия всех групп и преподавателей сразу.
+// Используется для занятости аудиторий; общее занятие нескольких групп возвращается один раз
+type AllScheduler interface {
+	GetAllSchedules(ctx context.Context, from, to time.Time) ([]Item, error)
+}
+
+// DayRange возвращает начало дня t и начало следующего дня
+func DayRange(t time.Time) (time.Time, time.Time) {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// WeekRange возвращает понедельник недели, в которую попадает t, и понедельник следующей недели
+func WeekRange(t time.Time) (time.Time, time.Time) {
+	day, _ := DayRange(t)
+	start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	return start, start.AddDate(0, 0, 7)
+}
+
+// mockLesson - занятие недельного расписания мока
+type mockLesson struct {
+	weekday     time.Weekday
+	hour, min   int
+	discipline  string
+	instructor  string
+	location    string
+	description string
+}
+
+// mockWeek - расписание мока, одинаковое каждую неделю
+var mockWeek = []mockLesson{
+	{time.Monday, 9, 0, "Мат. анализ", "доц. Светлана Иванова", "Корпус А, ауд. 302", "Лекция. Возьмите тетрадь и калькулятор."},
+	{time.Monday, 10, 45, "Программирование", "проф. Алексей Петров", "Корпус Б, ауд. 115", "Практика по Go. Подготовьте вопросы по goroutines."},
+	{time.Tuesday, 10, 45, "Физика", "доц. Ольга Смирнова", "Корпус А, ауд. 210", "Лекция. Механика."},
+	{time.Tuesday, 14, 0, "Английский язык", "ст. преп. Мария Кузнецова", "Корпус В, ауд. 405", "Практика. Эссе на свободную тему."},
+	{time.Wednesday, 9, 0, "Мат. анализ", "доц. Светлана Иванова", "Корпус А, ауд. 302", "Семинар. Пределы и непрерывность."},
+	{time.Wednesday, 12, 40, "Программирование", "проф. Алексей Петров", "Корпус Б, ауд. 115", "Лабораторная работа."},
+	{time.Wednesday, 14, 20, "Программирование", "проф. Алексей Петров", "Корпус Б, ауд. 115", "Лабораторная работа, продолжение."},
+	{time.Thursday, 10, 45, "Физика", "доц. Ольга Смирнова", "Корпус А, ауд. 112", "Лабораторная работа."},
+	{time.Friday, 9, 0, "История", "доц. Игорь Васильев", "Корпус В, ауд. 101", "Лекция."},
+	{time.Friday, 10, 45, "Английский язык", "ст. преп. Мария Кузнецова", "Корпус В, ауд. 405", "Практика."},
 }
 
 type mockService struct {
@@ -27,28 +93,41 @@ func NewMock(lag time.Duration) Service {
 	}
 }
 
-func (m *mockService) GetSchedule(ctx context.Context, userID string) ([]Item, error) {
+func (m *mockService) GetSchedule(ctx context.Context, userID string, from, to time.Time) ([]Item, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-time.After(m.lag):
 	}
 
-	now := time.Now()
-	return []Item{
-		{
-			Time:        now.Add(2 * time.Hour),
-			Discipline:  "Мат. анализ",
-			Instructor:  "доц. Светлана Иванова",
-			Location:    "Корпус А, ауд. 302",
-			Description: "Лекция. Возьмите тетрадь и калькулятор.",
-		},
-		{
-			Time:        now.Add(5 * time.Hour),
-			Discipline:  "Программирование",
-			Instructor:  "проф. Алексей Петров",
-			Location:    "Корпус Б, ауд. 115",
-			Description: "Практика по Go. Подготовьте вопросы по goroutines.",
-		},
-	}, nil
+	var items []Item
+	for day, _ := DayRange(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, lesson := range mockWeek {
+			if lesson.weekday != day.Weekday() {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), lesson.hour, lesson.min, 0, 0, day.Location())
+			if start.Before(from) || !start.Before(to) {
+				continue
+			}
+			items = append(items, Item{
+				Time:        start,
+				EndTime:     start.Add(LessonDuration),
+				Discipline:  lesson.discipline,
+				Instructor:  lesson.instructor,
+				Location:    lesson.location,
+				Description: lesson.description,
+			})
+		}
+	}
+	return items, nil
+}
+
+func (m *mockService) GetGroupSchedule(ctx context.Context, group string, from, to time.Time) ([]Item, error) {
+	// В моке у всех групп одинаковое расписание
+	return m.GetSchedule(ctx, group, from, to)
+}
+
+func (m *mockService) GetAllSchedules(ctx context.Context, from, to time.Time) ([]Item, error) {
+	return m.GetSchedule(ctx, "", from, to)
 }
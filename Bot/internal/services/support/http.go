@@ -0,0 +1,298 @@
+package support
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"first-max-bot/internal/uniback"
+)
+
+var (
+	ErrUnavailable   = errors.New("tickets backend unavailable")
+	ErrNotRegistered = errors.New("user is not registered in university system")
+)
+
+// backendUserRef - пользователь в обращении uni-back: при сохранении нужен только внутренний id,
+// при чтении по maxId находим пользователя бота
+type backendUserRef struct {
+	ID    int64 `json:"id"`
+	MaxID int64 `json:"maxId,omitempty"`
+}
+
+// backendTicket - сущность Tickets из uni-back
+type backendTicket struct {
+	ID         string                `json:"id"`
+	User       *backendUserRef       `json:"user"`
+	Department string                `json:"department"`
+	Subject    string                `json:"subject"`
+	Message    string                `json:"message"`
+	Response   string                `json:"response,omitempty"`
+	ResponseBy string                `json:"responseBy,omitempty"`
+	UserReply  string                `json:"userReply,omitempty"`
+	CreatedAt  uniback.LocalDateTime `json:"createdAt"`
+	UpdatedAt  uniback.LocalDateTime `json:"updatedAt"`
+	Status     string                `json:"status"`
+}
+
+func (t backendTicket) ticket() Ticket {
+	ticket := Ticket{
+		ID:         t.ID,
+		Department: t.Department,
+		Subject:    t.Subject,
+		Message:    t.Message,
+		Response:   t.Response,
+		ResponseBy: t.ResponseBy,
+		UserReply:  t.UserReply,
+		CreatedAt:  t.CreatedAt.Time,
+		UpdatedAt:  t.UpdatedAt.Time,
+		Status:     t.Status,
+	}
+	if t.User != nil && t.User.MaxID != 0 {
+		ticket.UserID = strconv.FormatInt(t.User.MaxID, 10)
+	}
+	return ticket
+}
+
+type httpService struct {
+	client *uniback.Client
+	local  Service // копия обращений, прошедших через бота: в uni-back нет списка всех обращений
+}
+
+// NewHTTPService хранит обращения в uni-back (/tickets), чтобы они были видны в системе университета,
+// и копирует каждое прочитанное или измененное обращение в local. Из копии берется список всех
+// обращений для /tickets и обращения пользователя, если uni-back недоступен
+func NewHTTPService(client *uniback.Client, local Service) Service {
+	return &httpService{client: client, local: local}
+}
+
+func (s *httpService) CreateTicket(ctx context.Context, userID, subject, message string) (Ticket, error) {
+	owner, err := s.backendUser(ctx, userID)
+	if err != nil {
+		return Ticket{}, err
+	}
+
+	now := time.Now()
+	id, err := newTicketID(now)
+	if err != nil {
+		return Ticket{}, err
+	}
+	ticket := Ticket{
+		ID:         id,
+		UserID:     userID,
+		Department: "Department of Education",
+		Subject:    subject,
+		Message:    message,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Status:     "received",
+	}
+	if err := s.save(ctx, ticket, owner); err != nil {
+		return Ticket{}, err
+	}
+	return ticket, nil
+}
+
+func (s *httpService) GetTicket(ctx context.Context, ticketID string) (*Ticket, error) {
+	ticket, _, err := s.fetch(ctx, ticketID)
+	if errors.Is(err, ErrUnavailable) {
+		return s.local.GetTicket(ctx, ticketID)
+	}
+	return ticket, err
+}
+
+// GetAllTickets возвращает обращения из копии бота: созданные в боте и прочитанные из uni-back
+func (s *httpService) GetAllTickets(ctx context.Context) ([]Ticket, error) {
+	return s.local.GetAllTickets(ctx)
+}
+
+func (s *httpService) GetUserTickets(ctx context.Context, userID string) ([]Ticket, error) {
+	maxID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("user id %q is not a MAX id", userID)
+	}
+
+	var remote []backendTicket
+	err = s.client.Get(ctx, "/tickets/user/"+strconv.FormatInt(maxID, 10), &remote)
+	if errors.Is(err, uniback.ErrNotFound) {
+		return []Ticket{}, nil
+	}
+	if err != nil {
+		if errors.Is(mapBackendError(err), ErrUnavailable) {
+			return s.local.GetUserTickets(ctx, userID)
+		}
+		return nil, mapBackendError(err)
+	}
+
+	tickets := make([]Ticket, 0, len(remote))
+	for _, t := range remote {
+		ticket := t.ticket()
+		if ticket.UserID == "" {
+			ticket.UserID = userID
+		}
+		s.mirror(ctx, ticket)
+		tickets = append(tickets, ticket)
+	}
+	sort.Slice(tickets, func(i, j int) bool { return tickets[i].CreatedAt.After(tickets[j].CreatedAt) })
+	return tickets, nil
+}
+
+func (s *httpService) UpdateTicketStatus(ctx context.Context, ticketID, status string) error {
+	return s.modify(ctx, ticketID, func(t *Ticket) {
+		t.Status = status
+		t.UpdatedAt = time.Now()
+	})
+}
+
+func (s *httpService) AddResponse(ctx context.Context, ticketID, response, responseBy string) error {
+	return s.modify(ctx, ticketID, func(t *Ticket) {
+		t.Response = response
+		t.ResponseBy = responseBy
+		t.Status = "answered"
+		t.UpdatedAt = time.Now()
+	})
+}
+
+func (s *httpService) AddUserReply(ctx context.Context, ticketID, reply string) error {
+	return s.modify(ctx, ticketID, func(t *Ticket) {
+		if t.UserReply != "" {
+			t.UserReply = t.UserReply + "\n\n---\n\n" + reply
+		} else {
+			t.UserReply = reply
+		}
+		t.Status = "in_progress"
+		t.UpdatedAt = time.Now()
+	})
+}
+
+// ExportTickets, RestoreTicket и DeleteUserTickets работают с копией бота: удалить обращение
+// из системы университета бот не может
+func (s *httpService) ExportTickets(ctx context.Context) ([]Ticket, error) {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return nil, fmt.Errorf("local ticket storage does not support export")
+	}
+	return archiver.ExportTickets(ctx)
+}
+
+func (s *httpService) RestoreTicket(ctx context.Context, ticket Ticket) error {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return fmt.Errorf("local ticket storage does not support import")
+	}
+	return archiver.RestoreTicket(ctx, ticket)
+}
+
+func (s *httpService) DeleteUserTickets(ctx context.Context, userID string) (int, error) {
+	eraser, ok := s.local.(Eraser)
+	if !ok {
+		return 0, fmt.Errorf("local ticket storage does not support deletion")
+	}
+	return eraser.DeleteUserTickets(ctx, userID)
+}
+
+// modify читает обращение из uni-back, применяет fn и сохраняет его целиком: uni-back сохраняет сущность по ID
+func (s *httpService) modify(ctx context.Context, ticketID string, fn func(t *Ticket)) error {
+	ticket, owner, err := s.fetch(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+	if ticket == nil {
+		return fmt.Errorf("ticket not found")
+	}
+	fn(ticket)
+	return s.save(ctx, *ticket, owner)
+}
+
+// fetch возвращает обращение из uni-back и его автора; nil - обращения нет
+func (s *httpService) fetch(ctx context.Context, ticketID string) (*Ticket, *backendUserRef, error) {
+	var remote backendTicket
+	err := s.client.Get(ctx, "/tickets/ticket/"+ticketID, &remote)
+	if errors.Is(err, uniback.ErrNotFound) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, mapBackendError(err)
+	}
+
+	ticket := remote.ticket()
+	if ticket.UserID == "" {
+		// Автора без maxId (создан не через бота) берем из копии, если обращение там есть
+		if local, err := s.local.GetTicket(ctx, ticketID); err == nil && local != nil {
+			ticket.UserID = local.UserID
+		}
+	}
+	s.mirror(ctx, ticket)
+	return &ticket, remote.User, nil
+}
+
+func (s *httpService) save(ctx context.Context, ticket Ticket, owner *backendUserRef) error {
+	if owner == nil {
+		return fmt.Errorf("ticket %s has no owner", ticket.ID)
+	}
+	err := s.client.Post(ctx, "/tickets/save", backendTicket{
+		ID:         ticket.ID,
+		User:       &backendUserRef{ID: owner.ID},
+		Department: ticket.Department,
+		Subject:    ticket.Subject,
+		Message:    ticket.Message,
+		Response:   ticket.Response,
+		ResponseBy: ticket.ResponseBy,
+		UserReply:  ticket.UserReply,
+		CreatedAt:  uniback.LocalDateTime{Time: ticket.CreatedAt},
+		UpdatedAt:  uniback.LocalDateTime{Time: ticket.UpdatedAt},
+		Status:     ticket.Status,
+	}, nil)
+	if err != nil {
+		return mapBackendError(err)
+	}
+	s.mirror(ctx, ticket)
+	return nil
+}
+
+// newTicketID возвращает ID обращения для uni-back, который не генерирует их сам: "DOE-<дата>-<случайный hex>".
+// Суффикс не число, поэтому копия обращения не сдвигает счетчик локальных ID вида "DOE-<n>"
+func newTicketID(now time.Time) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("generate ticket id: %w", err)
+	}
+	return "DOE-" + now.Format("20060102") + "-" + hex.EncodeToString(suffix), nil
+}
+
+// backendUser находит внутренний id пользователя uni-back по ID в MAX
+func (s *httpService) backendUser(ctx context.Context, userID string) (*backendUserRef, error) {
+	maxID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: user id %q is not a MAX id", ErrNotRegistered, userID)
+	}
+
+	var ref backendUserRef
+	err = s.client.Get(ctx, "/users/"+strconv.FormatInt(maxID, 10), &ref)
+	if errors.Is(err, uniback.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRegistered, userID)
+	}
+	if err != nil {
+		return nil, mapBackendError(err)
+	}
+	return &ref, nil
+}
+
+// mirror сохраняет копию обращения. Ошибка копии не мешает работе с uni-back
+func (s *httpService) mirror(ctx context.Context, ticket Ticket) {
+	if archiver, ok := s.local.(Archiver); ok {
+		_ = archiver.RestoreTicket(ctx, ticket)
+	}
+}
+
+func mapBackendError(err error) error {
+	if errors.Is(err, uniback.ErrUnavailable) || errors.Is(err, uniback.ErrServer) {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,330 @@
+package support
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"first-max-bot/internal/uniback"
+)
+
+// fakeBackend повторяет контракт TicketsController и GET /users/{maxId} из uni-back-java:
+// отсутствующий объект - 200 с пустым телом или null, ошибка в данных - 500
+type fakeBackend struct {
+	mu      sync.Mutex
+	users   map[int64]backendUserRef // по maxId
+	tickets map[string]backendTicket
+	status  int // если не 0, все запросы отвечают этим статусом
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		users:   map[int64]backendUserRef{100: {ID: 1, MaxID: 100}},
+		tickets: make(map[string]backendTicket),
+	}
+}
+
+func (b *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.status != 0 {
+		w.WriteHeader(b.status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/users/"):
+		maxID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/users/"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u, ok := b.users[maxID]
+		if !ok {
+			// ResponseEntity.ok(null) - пустое тело
+			return
+		}
+		_ = json.NewEncoder(w).Encode(u)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/tickets/ticket/"):
+		t, ok := b.tickets[strings.TrimPrefix(r.URL.Path, "/tickets/ticket/")]
+		if !ok {
+			_, _ = w.Write([]byte("null"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(t)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/tickets/user/"):
+		maxID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/tickets/user/"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		result := []backendTicket{}
+		for _, t := range b.tickets {
+			if t.User != nil && t.User.MaxID == maxID {
+				result = append(result, t)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(result)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/tickets/save":
+		var t backendTicket
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// Пользователь без id или пустые обязательные поля - ошибка JPA, uni-back отвечает 500
+		owner, ok := b.userByID(t.User)
+		if !ok || t.ID == "" || t.Subject == "" || t.CreatedAt.IsZero() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		t.User = &owner
+		b.tickets[t.ID] = t
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *fakeBackend) userByID(ref *backendUserRef) (backendUserRef, bool) {
+	if ref == nil {
+		return backendUserRef{}, false
+	}
+	for _, u := range b.users {
+		if u.ID == ref.ID {
+			return u, true
+		}
+	}
+	return backendUserRef{}, false
+}
+
+func (b *fakeBackend) setStatus(status int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+}
+
+func newTestService(t *testing.T) (*fakeBackend, Service, Service) {
+	t.Helper()
+	backend := newFakeBackend()
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	local := NewMock()
+	client := uniback.New(server.URL, uniback.WithRetries(0, 0))
+	return backend, NewHTTPService(client, local), local
+}
+
+func TestCreateTicketSavesToBackend(t *testing.T) {
+	ctx := context.Background()
+	backend, service, local := newTestService(t)
+
+	first, err := service.CreateTicket(ctx, "100", "Справка", "Нужна справка об обучении")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	second, err := service.CreateTicket(ctx, "100", "Справка", "Еще одна")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+
+	if first.ID == second.ID {
+		t.Fatalf("ticket ids collide: %s", first.ID)
+	}
+	if !regexp.MustCompile(`^DOE-\d{8}-[0-9a-f]{12}$`).MatchString(first.ID) {
+		t.Errorf("unexpected ticket id %q", first.ID)
+	}
+
+	saved, ok := backend.tickets[first.ID]
+	if !ok {
+		t.Fatalf("ticket %s is not saved in backend", first.ID)
+	}
+	if saved.User.ID != 1 || saved.Status != "received" || saved.Message != "Нужна справка об обучении" {
+		t.Errorf("unexpected backend ticket %+v", saved)
+	}
+
+	mirrored, err := local.GetTicket(ctx, first.ID)
+	if err != nil || mirrored == nil {
+		t.Fatalf("ticket is not mirrored: %v", err)
+	}
+	if mirrored.UserID != "100" {
+		t.Errorf("mirrored user id = %q, want 100", mirrored.UserID)
+	}
+}
+
+func TestCreateTicketNotRegistered(t *testing.T) {
+	_, service, _ := newTestService(t)
+
+	_, err := service.CreateTicket(context.Background(), "200", "Справка", "Текст")
+	if !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("err = %v, want ErrNotRegistered", err)
+	}
+
+	_, err = service.CreateTicket(context.Background(), "not-a-max-id", "Справка", "Текст")
+	if !errors.Is(err, ErrNotRegistered) {
+		t.Fatalf("err = %v, want ErrNotRegistered", err)
+	}
+}
+
+func TestCreateTicketBadDataIsUnavailable(t *testing.T) {
+	// uni-back отвечает 500 и на ошибки в данных: пустая тема нарушает NOT NULL
+	_, service, _ := newTestService(t)
+
+	_, err := service.CreateTicket(context.Background(), "100", "", "Текст")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestGetTicketNullBody(t *testing.T) {
+	_, service, _ := newTestService(t)
+
+	ticket, err := service.GetTicket(context.Background(), "DOE-missing")
+	if err != nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if ticket != nil {
+		t.Fatalf("ticket = %+v, want nil", ticket)
+	}
+}
+
+func TestModifyTicket(t *testing.T) {
+	ctx := context.Background()
+	backend, service, local := newTestService(t)
+
+	ticket, err := service.CreateTicket(ctx, "100", "Справка", "Текст")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+
+	if err := service.AddResponse(ctx, ticket.ID, "Готово", "admin"); err != nil {
+		t.Fatalf("AddResponse: %v", err)
+	}
+	if err := service.AddUserReply(ctx, ticket.ID, "Спасибо"); err != nil {
+		t.Fatalf("AddUserReply: %v", err)
+	}
+	if err := service.AddUserReply(ctx, ticket.ID, "Еще вопрос"); err != nil {
+		t.Fatalf("AddUserReply: %v", err)
+	}
+
+	saved := backend.tickets[ticket.ID]
+	if saved.Response != "Готово" || saved.ResponseBy != "admin" || saved.Status != "in_progress" {
+		t.Errorf("unexpected backend ticket %+v", saved)
+	}
+	if saved.UserReply != "Спасибо\n\n---\n\nЕще вопрос" {
+		t.Errorf("user reply = %q", saved.UserReply)
+	}
+	if saved.User.ID != 1 {
+		t.Errorf("owner changed: %+v", saved.User)
+	}
+
+	mirrored, _ := local.GetTicket(ctx, ticket.ID)
+	if mirrored == nil || mirrored.Status != "in_progress" {
+		t.Errorf("mirror is not updated: %+v", mirrored)
+	}
+
+	if err := service.UpdateTicketStatus(ctx, "DOE-missing", "closed"); err == nil {
+		t.Error("UpdateTicketStatus of missing ticket: want error")
+	}
+}
+
+func TestTicketOwnerFromMirror(t *testing.T) {
+	// Обращение создано не через бота: у автора в uni-back нет maxId
+	ctx := context.Background()
+	backend, service, local := newTestService(t)
+
+	backend.users[0] = backendUserRef{ID: 2}
+	now := time.Now()
+	backend.tickets["EXT-1"] = backendTicket{
+		ID:         "EXT-1",
+		User:       &backendUserRef{ID: 2},
+		Department: "Department of Education",
+		Subject:    "Общежитие",
+		Message:    "Текст",
+		CreatedAt:  uniback.LocalDateTime{Time: now},
+		UpdatedAt:  uniback.LocalDateTime{Time: now},
+		Status:     "received",
+	}
+	if err := local.(Archiver).RestoreTicket(ctx, Ticket{ID: "EXT-1", UserID: "300"}); err != nil {
+		t.Fatalf("RestoreTicket: %v", err)
+	}
+
+	ticket, err := service.GetTicket(ctx, "EXT-1")
+	if err != nil || ticket == nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+	if ticket.UserID != "300" {
+		t.Errorf("user id = %q, want 300 from mirror", ticket.UserID)
+	}
+}
+
+func TestBackendUnavailableFallsBackToMirror(t *testing.T) {
+	ctx := context.Background()
+	backend, service, _ := newTestService(t)
+
+	ticket, err := service.CreateTicket(ctx, "100", "Справка", "Текст")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+
+	backend.setStatus(http.StatusServiceUnavailable)
+
+	tickets, err := service.GetUserTickets(ctx, "100")
+	if err != nil {
+		t.Fatalf("GetUserTickets: %v", err)
+	}
+	if len(tickets) != 1 || tickets[0].ID != ticket.ID {
+		t.Errorf("tickets = %+v, want mirrored ticket", tickets)
+	}
+
+	got, err := service.GetTicket(ctx, ticket.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetTicket: %v", err)
+	}
+
+	if err := service.UpdateTicketStatus(ctx, ticket.ID, "closed"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("UpdateTicketStatus err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestGetUserTicketsFromBackend(t *testing.T) {
+	ctx := context.Background()
+	_, service, _ := newTestService(t)
+
+	older, err := service.CreateTicket(ctx, "100", "Первое", "Текст")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	newer, err := service.CreateTicket(ctx, "100", "Второе", "Текст")
+	if err != nil {
+		t.Fatalf("CreateTicket: %v", err)
+	}
+
+	tickets, err := service.GetUserTickets(ctx, "100")
+	if err != nil {
+		t.Fatalf("GetUserTickets: %v", err)
+	}
+	if len(tickets) != 2 || tickets[0].ID != newer.ID || tickets[1].ID != older.ID {
+		t.Fatalf("tickets = %+v, want newest first", tickets)
+	}
+	if tickets[0].UserID != "100" {
+		t.Errorf("user id = %q, want 100", tickets[0].UserID)
+	}
+
+	tickets, err = service.GetUserTickets(ctx, "200")
+	if err != nil || len(tickets) != 0 {
+		t.Errorf("tickets of unknown user = %+v, %v", tickets, err)
+	}
+}
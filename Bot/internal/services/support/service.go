@@ -7,17 +7,17 @@ import (
 )
 
 type Ticket struct {
-	ID              string
-	UserID          string
-	Department      string
-	Subject         string
-	Message         string
-	Response        string   // Ответ руководителя на обращение
-	ResponseBy      string   // ID администратора, который ответил
-	UserReply       string   // Ответ пользователя на ответ руководителя
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
-	Status          string // "received", "in_progress", "answered", "resolved", "closed"
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Department string    `json:"department"`
+	Subject    string    `json:"subject"`
+	Message    string    `json:"message"`
+	Response   string    `json:"response"`    // Ответ руководителя на обращение
+	ResponseBy string    `json:"response_by"` // ID администратора, который ответил
+	UserReply  string    `json:"user_reply"`  // Ответ пользователя на ответ руководителя
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Status     string    `json:"status"` // "received", "in_progress", "answered", "resolved", "closed"
 }
 
 type Service interface {
@@ -30,6 +30,18 @@ type Service interface {
 	AddUserReply(ctx context.Context, ticketID, reply string) error
 }
 
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех обращений с сохранением ID
+// (резервное копирование и перенос между хранилищами)
+type Archiver interface {
+	ExportTickets(ctx context.Context) ([]Ticket, error)
+	RestoreTicket(ctx context.Context, ticket Ticket) error // создает обращение или заменяет существующее с тем же ID
+}
+
+// Eraser - необязательная возможность сервиса: удаление обращений пользователя по его запросу (/delete_me)
+type Eraser interface {
+	DeleteUserTickets(ctx context.Context, userID string) (int, error) // возвращает число удаленных обращений
+}
+
 type mockService struct {
 	tickets map[string]*Ticket
 }
@@ -165,3 +177,35 @@ func (s *mockService) AddUserReply(ctx context.Context, ticketID, reply string)
 	ticket.UpdatedAt = time.Now()
 	return nil
 }
+
+func (s *mockService) ExportTickets(ctx context.Context) ([]Ticket, error) {
+	return s.GetAllTickets(ctx)
+}
+
+func (s *mockService) RestoreTicket(ctx context.Context, ticket Ticket) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.tickets[ticket.ID] = &ticket
+	return nil
+}
+
+func (s *mockService) DeleteUserTickets(ctx context.Context, userID string) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	deleted := 0
+	for id, ticket := range s.tickets {
+		if ticket.UserID == userID {
+			delete(s.tickets, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
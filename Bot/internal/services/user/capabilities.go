@@ -5,12 +5,15 @@ type Capability string
 
 const (
 	// Общие возможности
-	CapabilitySchedule  Capability = "schedule"   // Расписание
-	CapabilityContact   Capability = "contact"    // Обращение в поддержку
-	CapabilityMyTickets Capability = "my_tickets" // Мои обращения
-	CapabilityHelp      Capability = "help"       // Справка
-	CapabilityReminder  Capability = "reminder"   // Напоминания
-	CapabilityAsk       Capability = "ask"        // Вопросы к AI
+	CapabilitySchedule       Capability = "schedule"        // Расписание
+	CapabilityContact        Capability = "contact"         // Обращение в поддержку
+	CapabilityMyTickets      Capability = "my_tickets"      // Мои обращения
+	CapabilityHelp           Capability = "help"            // Справка
+	CapabilityReminder       Capability = "reminder"        // Напоминания
+	CapabilityAsk            Capability = "ask"             // Вопросы к AI
+	CapabilityRoles          Capability = "roles"           // Переключение активной роли (есть у всех, у кого больше одной роли)
+	CapabilityCalendar       Capability = "calendar"        // Личный календарь (.ics)
+	CapabilityScheduleAlerts Capability = "schedule_alerts" // Уведомления об изменениях расписания
 
 	// Возможности для абитуриентов
 	CapabilityAdmissionInfo Capability = "admission_info" // Информация о поступлении
@@ -26,20 +29,27 @@ const (
 	CapabilityProjects        Capability = "projects"         // Проектная деятельность
 	CapabilityEvents          Capability = "events"           // Внеучебная деятельность
 	CapabilityMoodle          Capability = "moodle"           // Moodle интеграция
+	CapabilityStudyGroup      Capability = "study_group"      // Выбор учебной группы для расписания
 
 	// Возможности для сотрудников
 	CapabilityBusinessTrip  Capability = "business_trip"  // Командировки
 	CapabilityVacation      Capability = "vacation"       // Отпуска
 	CapabilityOffice        Capability = "office"         // Офис (справки, пропуски)
 	CapabilityLibraryManage Capability = "library_manage" // Управление библиотекой
+	CapabilityRooms         Capability = "rooms"          // Свободные аудитории и их занятость
+	CapabilityChatSettings  Capability = "chat_settings"  // Настройки любого группового чата (/chat)
 
 	// Возможности для руководителей
-	CapabilityDashboard Capability = "dashboard" // Дашборд
-	CapabilityAnalytics Capability = "analytics" // Аналитика
-	CapabilityNews      Capability = "news"      // Новости
-	CapabilitySendNews  Capability = "send_news" // Отправка новостей
-	CapabilityTickets   Capability = "tickets"   // Управление обращениями
-	CapabilityDocuments Capability = "documents" // Заявления деканата
+	CapabilityDashboard   Capability = "dashboard"    // Дашборд
+	CapabilityAnalytics   Capability = "analytics"    // Аналитика
+	CapabilityNews        Capability = "news"         // Новости
+	CapabilitySendNews    Capability = "send_news"    // Отправка новостей
+	CapabilityTickets     Capability = "tickets"      // Управление обращениями
+	CapabilityDocuments   Capability = "documents"    // Заявления деканата
+	CapabilityManageRoles Capability = "manage_roles" // Назначение ролей пользователям
+	CapabilityLinks       Capability = "links"        // Отслеживаемые ссылки и QR-коды
+	CapabilityLimits      Capability = "limits"       // Лимиты запросов и блокировки
+	CapabilityTimetable   Capability = "timetable"    // Загрузка расписаний iCalendar
 )
 
 // RoleCapabilities определяет возможности для каждой роли
@@ -63,9 +73,12 @@ var RoleCapabilities = map[Role][]Capability{
 		CapabilityProjects,
 		CapabilityEvents,
 		CapabilityMoodle,
+		CapabilityStudyGroup,
 		CapabilityContact,
 		CapabilityMyTickets,
 		CapabilityReminder,
+		CapabilityCalendar,
+		CapabilityScheduleAlerts,
 		CapabilityAsk,
 	},
 	RoleEmployee: {
@@ -76,7 +89,11 @@ var RoleCapabilities = map[Role][]Capability{
 		CapabilityOffice,
 		CapabilityContact,
 		CapabilityLibraryManage,
+		CapabilityRooms,
+		CapabilityChatSettings,
 		CapabilityReminder,
+		CapabilityCalendar,
+		CapabilityScheduleAlerts,
 		CapabilityAsk,
 	},
 	RoleManager: {
@@ -89,7 +106,16 @@ var RoleCapabilities = map[Role][]Capability{
 		CapabilityTickets,
 		CapabilityContact,
 		CapabilityDocuments,
+		CapabilityLibraryManage,
+		CapabilityManageRoles,
+		CapabilityLinks,
+		CapabilityLimits,
+		CapabilityTimetable,
+		CapabilityRooms,
+		CapabilityChatSettings,
 		CapabilityReminder,
+		CapabilityCalendar,
+		CapabilityScheduleAlerts,
 		CapabilityAsk,
 	},
 }
@@ -103,6 +129,31 @@ func GetCapabilities(role Role) []Capability {
 	return []Capability{CapabilityHelp}
 }
 
+// GetCapabilitiesForRoles возвращает объединение возможностей нескольких ролей без повторов
+func GetCapabilitiesForRoles(roles ...Role) []Capability {
+	if len(roles) == 0 {
+		return GetCapabilities("")
+	}
+
+	var result []Capability
+	for _, role := range roles {
+		for _, c := range GetCapabilities(role) {
+			result = appendCapability(result, c)
+		}
+	}
+	return result
+}
+
+// appendCapability добавляет возможность, если её ещё нет в списке
+func appendCapability(caps []Capability, capability Capability) []Capability {
+	for _, c := range caps {
+		if c == capability {
+			return caps
+		}
+	}
+	return append(caps, capability)
+}
+
 // HasCapability проверяет, есть ли у роли определенная возможность
 func HasCapability(role Role, capability Capability) bool {
 	caps := GetCapabilities(role)
@@ -123,12 +174,23 @@ type CommandInfo struct {
 
 // GetCommandsForRole возвращает список команд для роли
 func GetCommandsForRole(role Role) []CommandInfo {
-	caps := GetCapabilities(role)
+	return GetCommandsForRoles(role)
+}
+
+// GetCommandsForRoles возвращает объединённый список команд для нескольких ролей
+func GetCommandsForRoles(roles ...Role) []CommandInfo {
+	return commandsForCapabilities(GetCapabilitiesForRoles(roles...))
+}
+
+// commandsForCapabilities переводит возможности в команды, пропуская повторяющиеся команды
+func commandsForCapabilities(caps []Capability) []CommandInfo {
 	commands := make([]CommandInfo, 0, len(caps))
+	seen := make(map[string]bool, len(caps))
 
 	for _, cap := range caps {
 		cmd := getCommandForCapability(cap)
-		if cmd.Command != "" {
+		if cmd.Command != "" && !seen[cmd.Command] {
+			seen[cmd.Command] = true
 			commands = append(commands, cmd)
 		}
 	}
@@ -181,8 +243,26 @@ func getCommandForCapability(cap Capability) CommandInfo {
 		return CommandInfo{Command: "/documents", Description: "Заявления деканата", Capability: cap}
 	case CapabilityReminder:
 		return CommandInfo{Command: "/reminder", Description: "Напоминания", Capability: cap}
+	case CapabilityCalendar:
+		return CommandInfo{Command: "/calendar", Description: "Календарь в телефоне", Capability: cap}
+	case CapabilityScheduleAlerts:
+		return CommandInfo{Command: "/notify", Description: "Уведомления об изменениях расписания", Capability: cap}
 	case CapabilityAsk:
 		return CommandInfo{Command: "/ask", Description: "Задать вопрос", Capability: cap}
+	case CapabilityRoles:
+		return CommandInfo{Command: "/role", Description: "Сменить активную роль", Capability: cap}
+	case CapabilityManageRoles:
+		return CommandInfo{Command: "/role", Description: "Роли пользователей", Capability: cap}
+	case CapabilityLinks:
+		return CommandInfo{Command: "/links", Description: "Ссылки и QR-коды", Capability: cap}
+	case CapabilityLimits:
+		return CommandInfo{Command: "/limits", Description: "Лимиты запросов", Capability: cap}
+	case CapabilityStudyGroup:
+		return CommandInfo{Command: "/group", Description: "Учебная группа", Capability: cap}
+	case CapabilityTimetable:
+		return CommandInfo{Command: "/timetable", Description: "Загрузить расписание", Capability: cap}
+	case CapabilityRooms:
+		return CommandInfo{Command: "/rooms", Description: "Свободные аудитории", Capability: cap}
 	default:
 		return CommandInfo{}
 	}
@@ -0,0 +1,373 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"first-max-bot/internal/secret"
+	"first-max-bot/internal/uniback"
+)
+
+// backendUser - сущность Users из uni-back (GET /users/{maxId}, POST /users/register)
+type backendUser struct {
+	ID         int64  `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic,omitempty"`
+	MaxID      int64  `json:"maxId"`
+	Email      string `json:"email"`
+	Age        int    `json:"age,omitempty"`
+	Role       string `json:"role,omitempty"`
+	Verified   bool   `json:"verified"`
+}
+
+// validateRequest - ValidateUserRequest из uni-back (POST /users/validate)
+type validateRequest struct {
+	MaxID int64  `json:"maxId"`
+	Code  string `json:"code"`
+}
+
+// Роли uni-back называет по-своему: преподаватель там "professor"
+var backendRoles = map[string]Role{
+	"applicant": RoleApplicant,
+	"student":   RoleStudent,
+	"professor": RoleEmployee,
+	"employee":  RoleEmployee,
+	"manager":   RoleManager,
+}
+
+func roleFromBackend(role string) Role {
+	if r, ok := backendRoles[role]; ok {
+		return r
+	}
+	return RoleApplicant
+}
+
+type httpOptions struct {
+	cacheTTL time.Duration
+}
+
+type Option func(*httpOptions)
+
+// WithCacheTTL задает, сколько хранить ответ uni-back о пользователе (по умолчанию 30 секунд, 0 - не кэшировать)
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(o *httpOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+type cachedUser struct {
+	user    *User // nil - пользователя нет ни в uni-back, ни в локальном хранилище
+	expires time.Time
+}
+
+type httpService struct {
+	client *uniback.Client
+	local  Service // поля, которых нет в uni-back: пол, роли из /role, активная роль, группа, токен Moodle
+	opts   httpOptions
+
+	mu    sync.Mutex
+	cache map[string]cachedUser
+}
+
+// NewHTTPService берет профиль пользователя (имя, фамилию, email, возраст и роль) из uni-back,
+// а остальные поля хранит в local. Регистрация создает пользователя и там, и там.
+// Если uni-back недоступен, используется последняя копия профиля из local
+func NewHTTPService(client *uniback.Client, local Service, opts ...Option) Service {
+	o := httpOptions{cacheTTL: 30 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &httpService{
+		client: client,
+		local:  local,
+		opts:   o,
+		cache:  make(map[string]cachedUser),
+	}
+}
+
+func (s *httpService) GetUserByID(ctx context.Context, userID string) (*User, error) {
+	if u, ok := s.cached(userID); ok {
+		return u, nil
+	}
+
+	local, err := s.local.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := s.fetch(ctx, userID)
+	if err != nil {
+		if local != nil && errors.Is(err, ErrUnavailable) {
+			return local, nil
+		}
+		return nil, err
+	}
+
+	u := mergeBackendUser(userID, remote, local)
+	s.store(userID, u)
+	return u, nil
+}
+
+func (s *httpService) CreateUser(ctx context.Context, user User) (*User, error) {
+	// Пользователь мог уже зарегистрироваться в uni-back: через RequestCode или в другой системе
+	remote, err := s.fetch(ctx, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if remote == nil {
+		if remote, err = s.register(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// Роль назначает uni-back по списку разрешенных email
+	user.Role = roleFromBackend(remote.Role)
+	created, err := s.local.CreateUser(ctx, user)
+	s.invalidate(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return mergeBackendUser(user.UserID, remote, created), nil
+}
+
+// UpdateUser меняет только локальную копию: в uni-back нет метода изменения профиля,
+// поэтому имя, email и возраст при следующем чтении снова придут из uni-back
+func (s *httpService) UpdateUser(ctx context.Context, userID string, user User) (*User, error) {
+	defer s.invalidate(userID)
+	if _, err := s.local.UpdateUser(ctx, userID, user); err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, userID)
+}
+
+func (s *httpService) GetUserRole(ctx context.Context, userID string) (Role, error) {
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if u == nil {
+		return RoleApplicant, nil // По умолчанию абитуриент
+	}
+	if u.ActiveRole != "" && u.HasRole(u.ActiveRole) {
+		return u.ActiveRole, nil
+	}
+	return u.Role, nil
+}
+
+// GetAllUsers возвращает пользователей бота: в uni-back нет списка пользователей
+func (s *httpService) GetAllUsers(ctx context.Context) ([]User, error) {
+	return s.local.GetAllUsers(ctx)
+}
+
+func (s *httpService) SetMoodleToken(ctx context.Context, userID string, token secret.Sealed) error {
+	defer s.invalidate(userID)
+	return s.local.SetMoodleToken(ctx, userID, token)
+}
+
+func (s *httpService) SetUserRoles(ctx context.Context, userID string, roles []Role) error {
+	defer s.invalidate(userID)
+	return s.local.SetUserRoles(ctx, userID, roles)
+}
+
+func (s *httpService) SetActiveRole(ctx context.Context, userID string, role Role) error {
+	defer s.invalidate(userID)
+	return s.local.SetActiveRole(ctx, userID, role)
+}
+
+func (s *httpService) SetStudyGroup(ctx context.Context, userID string, group string) error {
+	defer s.invalidate(userID)
+	return s.local.SetStudyGroup(ctx, userID, group)
+}
+
+// RequestCode регистрирует пользователя в uni-back, и тот отправляет код подтверждения на email.
+// Если пользователь там уже есть, новое письмо не отправляется: действует код из прошлого письма,
+// а подтвержденному пользователю код не нужен
+func (s *httpService) RequestCode(ctx context.Context, user User) (bool, error) {
+	defer s.invalidate(user.UserID)
+	remote, err := s.fetch(ctx, user.UserID)
+	if err != nil {
+		return false, err
+	}
+	if remote != nil {
+		return !remote.Verified, nil
+	}
+	if _, err := s.register(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VerifyCode проверяет код из письма
+func (s *httpService) VerifyCode(ctx context.Context, userID, code string) error {
+	maxID, err := parseMaxID(userID)
+	if err != nil {
+		return err
+	}
+	defer s.invalidate(userID)
+
+	err = s.client.Post(ctx, "/users/validate", validateRequest{MaxID: maxID, Code: code}, nil)
+	if errors.Is(err, uniback.ErrServer) {
+		// На неверный код uni-back отвечает 500 без подробностей
+		return fmt.Errorf("%w: %v", ErrInvalidCode, err)
+	}
+	return mapBackendError(err)
+}
+
+// ExportUsers и RestoreUser работают с локальной копией, если хранилище их поддерживает
+func (s *httpService) ExportUsers(ctx context.Context) ([]User, error) {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return nil, fmt.Errorf("local user storage does not support export")
+	}
+	return archiver.ExportUsers(ctx)
+}
+
+func (s *httpService) RestoreUser(ctx context.Context, user User) error {
+	archiver, ok := s.local.(Archiver)
+	if !ok {
+		return fmt.Errorf("local user storage does not support import")
+	}
+	defer s.invalidate(user.UserID)
+	return archiver.RestoreUser(ctx, user)
+}
+
+// DeleteUser удаляет локальную копию. В uni-back удаления одного пользователя нет:
+// запись там удаляет администратор университета
+func (s *httpService) DeleteUser(ctx context.Context, userID string) error {
+	eraser, ok := s.local.(Eraser)
+	if !ok {
+		return fmt.Errorf("local user storage does not support deletion")
+	}
+	defer s.invalidate(userID)
+	return eraser.DeleteUser(ctx, userID)
+}
+
+// fetch возвращает пользователя из uni-back или nil, если его там нет
+func (s *httpService) fetch(ctx context.Context, userID string) (*backendUser, error) {
+	maxID, err := parseMaxID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var remote backendUser
+	err = s.client.Get(ctx, "/users/"+strconv.FormatInt(maxID, 10), &remote)
+	if errors.Is(err, uniback.ErrNotFound) {
+		return nil, nil
+	}
+	if errors.Is(err, uniback.ErrServer) {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err != nil {
+		return nil, mapBackendError(err)
+	}
+	return &remote, nil
+}
+
+func (s *httpService) register(ctx context.Context, user User) (*backendUser, error) {
+	maxID, err := parseMaxID(user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var remote backendUser
+	err = s.client.Post(ctx, "/users/register", backendUser{
+		Name:    user.FirstName,
+		Surname: user.LastName,
+		MaxID:   maxID,
+		Email:   user.Email,
+		Age:     user.Age,
+	}, &remote)
+	if errors.Is(err, uniback.ErrServer) {
+		// uni-back отвечает 500 и на занятый email
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	if err != nil {
+		return nil, mapBackendError(err)
+	}
+	return &remote, nil
+}
+
+func (s *httpService) cached(userID string) (*User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[userID]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	if entry.user == nil {
+		return nil, true
+	}
+	u := *entry.user
+	return &u, true
+}
+
+func (s *httpService) store(userID string, u *User) {
+	if s.opts.cacheTTL <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := cachedUser{expires: time.Now().Add(s.opts.cacheTTL)}
+	if u != nil {
+		copied := *u
+		entry.user = &copied
+	}
+	s.cache[userID] = entry
+}
+
+func (s *httpService) invalidate(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, userID)
+}
+
+// mergeBackendUser накладывает профиль из uni-back на локальную копию. Роль из uni-back
+// всегда остается у пользователя и идет первой, роли, выданные через /role, сохраняются
+func mergeBackendUser(userID string, remote *backendUser, local *User) *User {
+	if remote == nil {
+		return local
+	}
+
+	var u User
+	if local != nil {
+		u = *local
+	} else {
+		u = User{ID: userID, UserID: userID}
+	}
+	u.FirstName = remote.Name
+	u.LastName = remote.Surname
+	u.Email = remote.Email
+	if remote.Age > 0 {
+		u.Age = remote.Age
+	}
+	u.Roles = NormalizeRoles(append([]Role{roleFromBackend(remote.Role)}, u.AllRoles()...))
+	u.Role = u.Roles[0]
+	if u.ActiveRole != "" && !u.HasRole(u.ActiveRole) {
+		u.ActiveRole = ""
+	}
+	return &u
+}
+
+func parseMaxID(userID string) (int64, error) {
+	maxID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: user id %q is not a MAX id", ErrRejected, userID)
+	}
+	return maxID, nil
+}
+
+func mapBackendError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, uniback.ErrUnavailable):
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	case errors.Is(err, uniback.ErrRejected), errors.Is(err, uniback.ErrServer), errors.Is(err, uniback.ErrNotFound):
+		return fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+	return err
+}
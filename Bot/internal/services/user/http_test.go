@@ -0,0 +1,289 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"first-max-bot/internal/uniback"
+)
+
+// fakeBackend повторяет контракт UsersController из uni-back-java: отсутствующий пользователь -
+// 200 с пустым телом, занятый email и неверный код - 500
+type fakeBackend struct {
+	mu      sync.Mutex
+	users   map[int64]backendUser // по maxId
+	codes   map[int64]string
+	allowed map[string]string // email -> роль
+	nextID  int64
+	status  int // если не 0, все запросы отвечают этим статусом
+	gets    int // число запросов GET /users/{maxId}
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		users:   make(map[int64]backendUser),
+		codes:   make(map[int64]string),
+		allowed: map[string]string{"student@uni.ru": "student"},
+	}
+}
+
+func (b *fakeBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.status != 0 {
+		w.WriteHeader(b.status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/users/register":
+		var u backendUser
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, existing := range b.users {
+			if existing.Email == u.Email {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		b.nextID++
+		u.ID = b.nextID
+		u.Role = "applicant"
+		if role, ok := b.allowed[u.Email]; ok {
+			u.Role = role
+		}
+		b.users[u.MaxID] = u
+		b.codes[u.MaxID] = "123456"
+		_ = json.NewEncoder(w).Encode(u)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/users/validate":
+		var req validateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u, ok := b.users[req.MaxID]
+		if !ok || b.codes[req.MaxID] != req.Code {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u.Verified = true
+		b.users[req.MaxID] = u
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/users/"):
+		b.gets++
+		maxID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/users/"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u, ok := b.users[maxID]
+		if !ok {
+			return
+		}
+		_ = json.NewEncoder(w).Encode(u)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *fakeBackend) setStatus(status int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status = status
+}
+
+func (b *fakeBackend) getCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.gets
+}
+
+func newTestService(t *testing.T, opts ...Option) (*fakeBackend, Service, Service) {
+	t.Helper()
+	backend := newFakeBackend()
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	local := NewMock()
+	client := uniback.New(server.URL, uniback.WithRetries(0, 0))
+	return backend, NewHTTPService(client, local, opts...), local
+}
+
+func TestCreateUserRegistersInBackend(t *testing.T) {
+	ctx := context.Background()
+	backend, service, local := newTestService(t)
+
+	created, err := service.CreateUser(ctx, User{UserID: "100", FirstName: "Иван", LastName: "Иванов", Email: "student@uni.ru", Gender: "male"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.Role != RoleStudent || created.Gender != "male" {
+		t.Errorf("created = %+v, want student with local fields", created)
+	}
+	if remote := backend.users[100]; remote.Name != "Иван" || remote.Email != "student@uni.ru" {
+		t.Errorf("backend user = %+v", remote)
+	}
+	if copied, _ := local.GetUserByID(ctx, "100"); copied == nil || copied.Role != RoleStudent {
+		t.Errorf("local copy = %+v, want student", copied)
+	}
+}
+
+func TestRequestAndVerifyCode(t *testing.T) {
+	ctx := context.Background()
+	backend, service, _ := newTestService(t)
+	verifier := service.(Verifier)
+
+	u := User{UserID: "100", FirstName: "Иван", LastName: "Иванов", Email: "ivan@uni.ru"}
+	needed, err := verifier.RequestCode(ctx, u)
+	if err != nil || !needed {
+		t.Fatalf("RequestCode = %v, %v, want code sent", needed, err)
+	}
+
+	// На неверный код uni-back отвечает 500
+	if err := verifier.VerifyCode(ctx, "100", "000000"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("VerifyCode with wrong code: err = %v, want ErrInvalidCode", err)
+	}
+	if err := verifier.VerifyCode(ctx, "100", "123456"); err != nil {
+		t.Fatalf("VerifyCode: %v", err)
+	}
+	if !backend.users[100].Verified {
+		t.Error("user is not verified in backend")
+	}
+
+	needed, err = verifier.RequestCode(ctx, u)
+	if err != nil || needed {
+		t.Errorf("RequestCode for verified user = %v, %v, want no code", needed, err)
+	}
+}
+
+func TestRegisterTakenEmailIsRejected(t *testing.T) {
+	// uni-back отвечает 500 и на занятый email
+	ctx := context.Background()
+	backend, service, _ := newTestService(t)
+	backend.users[200] = backendUser{ID: 1, MaxID: 200, Email: "ivan@uni.ru"}
+
+	_, err := service.(Verifier).RequestCode(ctx, User{UserID: "100", Email: "ivan@uni.ru"})
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("err = %v, want ErrRejected", err)
+	}
+}
+
+func TestVerifyCodeUnavailable(t *testing.T) {
+	backend, service, _ := newTestService(t)
+	backend.setStatus(http.StatusServiceUnavailable)
+
+	err := service.(Verifier).VerifyCode(context.Background(), "100", "123456")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestGetUserByIDFallsBackToLocal(t *testing.T) {
+	ctx := context.Background()
+	backend, service, _ := newTestService(t, WithCacheTTL(0))
+
+	if _, err := service.CreateUser(ctx, User{UserID: "100", FirstName: "Иван", Email: "ivan@uni.ru"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusInternalServerError} {
+		backend.setStatus(status)
+
+		u, err := service.GetUserByID(ctx, "100")
+		if err != nil {
+			t.Fatalf("status %d: GetUserByID: %v", status, err)
+		}
+		if u == nil || u.FirstName != "Иван" {
+			t.Errorf("status %d: user = %+v, want local copy", status, u)
+		}
+
+		// Без локальной копии отвечать нечем
+		if _, err := service.GetUserByID(ctx, "200"); !errors.Is(err, ErrUnavailable) {
+			t.Errorf("status %d: err = %v, want ErrUnavailable", status, err)
+		}
+	}
+}
+
+func TestGetUserByIDMergesBackendProfile(t *testing.T) {
+	ctx := context.Background()
+	backend, service, local := newTestService(t, WithCacheTTL(0))
+
+	if _, err := service.CreateUser(ctx, User{UserID: "100", FirstName: "Иван", Email: "ivan@uni.ru"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := service.SetUserRoles(ctx, "100", []Role{RoleApplicant, RoleManager}); err != nil {
+		t.Fatalf("SetUserRoles: %v", err)
+	}
+
+	// Профиль изменили в системе университета
+	remote := backend.users[100]
+	remote.Name = "Петр"
+	remote.Role = "professor"
+	backend.users[100] = remote
+
+	u, err := service.GetUserByID(ctx, "100")
+	if err != nil || u == nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if u.FirstName != "Петр" || u.Role != RoleEmployee {
+		t.Errorf("user = %+v, want backend profile", u)
+	}
+	if !u.HasRole(RoleManager) {
+		t.Errorf("roles = %v, want local roles kept", u.Roles)
+	}
+	if copied, _ := local.GetUserByID(ctx, "100"); copied.FirstName != "Иван" {
+		t.Errorf("local copy changed: %+v", copied)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	backend, service, _ := newTestService(t, WithCacheTTL(time.Minute))
+
+	if _, err := service.CreateUser(ctx, User{UserID: "100", FirstName: "Иван", Email: "ivan@uni.ru"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	start := backend.getCount()
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.GetUserByID(ctx, "100"); err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+	}
+	if got := backend.getCount() - start; got != 1 {
+		t.Fatalf("backend reads = %d, want 1 with cache", got)
+	}
+
+	if err := service.SetStudyGroup(ctx, "100", "ИВТ-21"); err != nil {
+		t.Fatalf("SetStudyGroup: %v", err)
+	}
+	u, err := service.GetUserByID(ctx, "100")
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if u.StudyGroup != "ИВТ-21" {
+		t.Errorf("study group = %q, want value set after invalidation", u.StudyGroup)
+	}
+	if got := backend.getCount() - start; got != 2 {
+		t.Errorf("backend reads = %d, want 2 after invalidation", got)
+	}
+
+	// Изменение кэшированной копии не меняет кэш
+	u.FirstName = "Изменено"
+	if cached, _ := service.GetUserByID(ctx, "100"); cached.FirstName != "Иван" {
+		t.Errorf("cached user changed through returned copy: %+v", cached)
+	}
+}
@@ -2,8 +2,11 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"first-max-bot/internal/secret"
 )
 
 type Role string
@@ -16,17 +19,20 @@ const (
 )
 
 type User struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"` // ID пользователя в мессенджере
-	FirstName   string    `json:"first_name"`
-	LastName    string    `json:"last_name"`
-	Age         int       `json:"age"`
-	Gender      string    `json:"gender"` // "male", "female"
-	Email       string    `json:"email"`
-	Role        Role      `json:"role"`
-	MoodleToken string    `json:"moodle_token,omitempty"` // Токен для Moodle API
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string        `json:"id"`
+	UserID      string        `json:"user_id"` // ID пользователя в мессенджере
+	FirstName   string        `json:"first_name"`
+	LastName    string        `json:"last_name"`
+	Age         int           `json:"age"`
+	Gender      string        `json:"gender"` // "male", "female"
+	Email       string        `json:"email"`
+	Role        Role          `json:"role"`                   // Основная роль (первая из Roles)
+	Roles       []Role        `json:"roles,omitempty"`        // Все роли пользователя
+	ActiveRole  Role          `json:"active_role,omitempty"`  // Выбранная через /role роль; пустая — действуют все роли
+	StudyGroup  string        `json:"study_group,omitempty"`  // Учебная группа, выбранная через /group
+	MoodleToken secret.Sealed `json:"moodle_token,omitempty"` // Токен для Moodle API, зашифрован (см. secret.Keyring)
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
 }
 
 type Service interface {
@@ -34,10 +40,147 @@ type Service interface {
 	CreateUser(ctx context.Context, user User) (*User, error)
 	UpdateUser(ctx context.Context, userID string, user User) (*User, error)
 	GetUserRole(ctx context.Context, userID string) (Role, error)
-	GetAllUsers(ctx context.Context) ([]User, error)                       // Получить всех пользователей
-	SetMoodleToken(ctx context.Context, userID string, token string) error // Установить токен Moodle
+	GetAllUsers(ctx context.Context) ([]User, error)                              // Получить всех пользователей
+	SetMoodleToken(ctx context.Context, userID string, token secret.Sealed) error // Установить зашифрованный токен Moodle
+	SetUserRoles(ctx context.Context, userID string, roles []Role) error          // Заменить набор ролей
+	SetActiveRole(ctx context.Context, userID string, role Role) error            // Выбрать активную роль ("" — все роли)
+	SetStudyGroup(ctx context.Context, userID string, group string) error         // Выбрать учебную группу ("" — не выбрана)
+}
+
+// AllRoles возвращает все роли пользователя. Для старых записей без Roles используется Role
+func (u *User) AllRoles() []Role {
+	if len(u.Roles) > 0 {
+		return u.Roles
+	}
+	if u.Role != "" {
+		return []Role{u.Role}
+	}
+	return nil
+}
+
+// HasRole проверяет, есть ли у пользователя роль (без учёта активной роли)
+func (u *User) HasRole(role Role) bool {
+	for _, r := range u.AllRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveRoles возвращает роли, которые действуют сейчас: активную роль, если она выбрана, иначе все
+func (u *User) EffectiveRoles() []Role {
+	if u.ActiveRole != "" && u.HasRole(u.ActiveRole) {
+		return []Role{u.ActiveRole}
+	}
+	return u.AllRoles()
 }
 
+// Capabilities возвращает объединение возможностей действующих ролей
+func (u *User) Capabilities() []Capability {
+	caps := GetCapabilitiesForRoles(u.EffectiveRoles()...)
+	if len(u.AllRoles()) > 1 {
+		caps = appendCapability(caps, CapabilityRoles)
+	}
+	return caps
+}
+
+// HasCapability проверяет, есть ли возможность у пользователя с учётом активной роли
+func (u *User) HasCapability(capability Capability) bool {
+	for _, c := range u.Capabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Commands возвращает команды, доступные пользователю с учётом активной роли
+func (u *User) Commands() []CommandInfo {
+	return commandsForCapabilities(u.Capabilities())
+}
+
+// Merge применяет частичное обновление: переносит только заполненные поля update
+func (u *User) Merge(update User) {
+	if update.FirstName != "" {
+		u.FirstName = update.FirstName
+	}
+	if update.LastName != "" {
+		u.LastName = update.LastName
+	}
+	if update.Age != 0 {
+		u.Age = update.Age
+	}
+	if update.Gender != "" {
+		u.Gender = update.Gender
+	}
+	if update.Email != "" {
+		u.Email = update.Email
+	}
+	if len(update.Roles) > 0 {
+		u.Roles = NormalizeRoles(update.Roles)
+		u.Role = u.Roles[0]
+	} else if update.Role != "" {
+		u.Role = update.Role
+		u.Roles = NormalizeRoles(append([]Role{update.Role}, u.Roles...))
+	}
+}
+
+// ReplaceRoles заменяет набор ролей. Активная роль, которой больше нет у пользователя, сбрасывается
+func (u *User) ReplaceRoles(roles []Role) error {
+	roles = NormalizeRoles(roles)
+	if len(roles) == 0 {
+		return fmt.Errorf("user must have at least one role")
+	}
+
+	u.Roles = roles
+	u.Role = roles[0]
+	if u.ActiveRole != "" && !u.HasRole(u.ActiveRole) {
+		u.ActiveRole = ""
+	}
+	return nil
+}
+
+// NormalizeRoles убирает пустые и повторяющиеся роли, сохраняя порядок
+func NormalizeRoles(roles []Role) []Role {
+	result := make([]Role, 0, len(roles))
+	seen := make(map[Role]bool, len(roles))
+	for _, r := range roles {
+		if r == "" || seen[r] {
+			continue
+		}
+		seen[r] = true
+		result = append(result, r)
+	}
+	return result
+}
+
+// Archiver - необязательная возможность сервиса: выгрузка и загрузка всех пользователей как есть
+// (с датами, ролями и токенами)
+type Archiver interface {
+	ExportUsers(ctx context.Context) ([]User, error)
+	RestoreUser(ctx context.Context, user User) error // создает пользователя или заменяет существующего
+}
+
+// Eraser - необязательная возможность сервиса: удаление профиля по запросу пользователя (/delete_me)
+type Eraser interface {
+	DeleteUser(ctx context.Context, userID string) error // отсутствующий пользователь - не ошибка
+}
+
+// Verifier - необязательная возможность сервиса: подтверждение email кодом из письма (uni-back).
+// Без нее регистрация принимает код по умолчанию
+type Verifier interface {
+	// RequestCode отправляет код на email пользователя; false - код не нужен, email уже подтвержден
+	RequestCode(ctx context.Context, user User) (bool, error)
+	VerifyCode(ctx context.Context, userID, code string) error // ErrInvalidCode - код неверный
+}
+
+var (
+	ErrUnavailable = errors.New("user backend unavailable")
+	ErrRejected    = errors.New("user backend rejected request")
+	ErrInvalidCode = errors.New("invalid verification code")
+)
+
 type mockService struct {
 	users map[string]*User
 }
@@ -95,9 +238,11 @@ func (s *mockService) CreateUser(ctx context.Context, user User) (*User, error)
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	if user.Role == "" {
-		user.Role = RoleApplicant
+	user.Roles = NormalizeRoles(append([]Role{user.Role}, user.Roles...))
+	if len(user.Roles) == 0 {
+		user.Roles = []Role{RoleApplicant}
 	}
+	user.Role = user.Roles[0]
 
 	s.users[user.UserID] = &user
 	return &user, nil
@@ -115,25 +260,7 @@ func (s *mockService) UpdateUser(ctx context.Context, userID string, user User)
 		return nil, nil
 	}
 
-	// Обновляем поля
-	if user.FirstName != "" {
-		existing.FirstName = user.FirstName
-	}
-	if user.LastName != "" {
-		existing.LastName = user.LastName
-	}
-	if user.Age != 0 {
-		existing.Age = user.Age
-	}
-	if user.Gender != "" {
-		existing.Gender = user.Gender
-	}
-	if user.Email != "" {
-		existing.Email = user.Email
-	}
-	if user.Role != "" {
-		existing.Role = user.Role
-	}
+	existing.Merge(user)
 	existing.UpdatedAt = time.Now()
 
 	return existing, nil
@@ -147,6 +274,9 @@ func (s *mockService) GetUserRole(ctx context.Context, userID string) (Role, err
 	if user == nil {
 		return RoleApplicant, nil // По умолчанию абитуриент
 	}
+	if user.ActiveRole != "" && user.HasRole(user.ActiveRole) {
+		return user.ActiveRole, nil
+	}
 	return user.Role, nil
 }
 
@@ -164,7 +294,7 @@ func (s *mockService) GetAllUsers(ctx context.Context) ([]User, error) {
 	return result, nil
 }
 
-func (s *mockService) SetMoodleToken(ctx context.Context, userID string, token string) error {
+func (s *mockService) SetMoodleToken(ctx context.Context, userID string, token secret.Sealed) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -180,3 +310,87 @@ func (s *mockService) SetMoodleToken(ctx context.Context, userID string, token s
 	user.UpdatedAt = time.Now()
 	return nil
 }
+
+func (s *mockService) SetUserRoles(ctx context.Context, userID string, roles []Role) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	user, exists := s.users[userID]
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := user.ReplaceRoles(roles); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *mockService) SetActiveRole(ctx context.Context, userID string, role Role) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	user, exists := s.users[userID]
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	if role != "" && !user.HasRole(role) {
+		return fmt.Errorf("user has no role %q", role)
+	}
+
+	user.ActiveRole = role
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *mockService) SetStudyGroup(ctx context.Context, userID string, group string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	user, exists := s.users[userID]
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+
+	user.StudyGroup = group
+	user.UpdatedAt = time.Now()
+	return nil
+}
+
+func (s *mockService) ExportUsers(ctx context.Context) ([]User, error) {
+	return s.GetAllUsers(ctx)
+}
+
+func (s *mockService) RestoreUser(ctx context.Context, user User) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	user.ID = user.UserID
+	s.users[user.UserID] = &user
+	return nil
+}
+
+func (s *mockService) DeleteUser(ctx context.Context, userID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	delete(s.users, userID)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	redis2 "github.com/redis/go-redis/v9"
@@ -12,16 +13,18 @@ import (
 )
 
 type Repository struct {
-	client redis2.Cmdable
-	prefix string
-	ttl    time.Duration
+	client     redis2.Cmdable
+	prefix     string
+	chatPrefix string
+	ttl        time.Duration
 }
 
 type Option func(*options)
 
 type options struct {
-	prefix string
-	ttl    time.Duration
+	prefix     string
+	chatPrefix string
+	ttl        time.Duration
 }
 
 func WithPrefix(prefix string) Option {
@@ -30,6 +33,13 @@ func WithPrefix(prefix string) Option {
 	}
 }
 
+// WithChatPrefix задает префикс ключей для настроек групповых чатов
+func WithChatPrefix(prefix string) Option {
+	return func(o *options) {
+		o.chatPrefix = prefix
+	}
+}
+
 func WithTTL(ttl time.Duration) Option {
 	return func(o *options) {
 		o.ttl = ttl
@@ -38,17 +48,19 @@ func WithTTL(ttl time.Duration) Option {
 
 func New(client redis2.Cmdable, opts ...Option) *Repository {
 	cfg := options{
-		prefix: "maxbot:user:",
-		ttl:    24 * time.Hour,
+		prefix:     "maxbot:user:",
+		chatPrefix: "maxbot:chat:",
+		ttl:        24 * time.Hour,
 	}
 	for _, opt := range opts {
 		opt(&cfg)
 	}
 
 	return &Repository{
-		client: client,
-		prefix: cfg.prefix,
-		ttl:    cfg.ttl,
+		client:     client,
+		prefix:     cfg.prefix,
+		chatPrefix: cfg.chatPrefix,
+		ttl:        cfg.ttl,
 	}
 }
 
@@ -80,6 +92,83 @@ func (r *Repository) SaveUserState(ctx context.Context, userID string, st state.
 	return r.client.Set(ctx, r.key(userID), payload, r.ttl).Err()
 }
 
+func (r *Repository) DeleteUserState(ctx context.Context, userID string) error {
+	return r.client.Del(ctx, r.key(userID)).Err()
+}
+
+func (r *Repository) chatKey(chatID int64) string {
+	return fmt.Sprintf("%s%d", r.chatPrefix, chatID)
+}
+
+// chatIndexKey - множество идентификаторов чатов, для которых сохранены настройки
+func (r *Repository) chatIndexKey() string {
+	return r.chatPrefix + "index"
+}
+
+func (r *Repository) GetChatSettings(ctx context.Context, chatID int64) (*state.ChatSettings, error) {
+	raw, err := r.client.Get(ctx, r.chatKey(chatID)).Result()
+	if err != nil {
+		if err == redis2.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var settings state.ChatSettings
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SaveChatSettings сохраняет настройки чата без TTL: они должны жить, пока бот состоит в чате
+func (r *Repository) SaveChatSettings(ctx context.Context, settings state.ChatSettings) error {
+	payload, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.chatKey(settings.ChatID), payload, 0)
+	pipe.SAdd(ctx, r.chatIndexKey(), settings.ChatID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *Repository) DeleteChatSettings(ctx context.Context, chatID int64) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.chatKey(chatID))
+	pipe.SRem(ctx, r.chatIndexKey(), chatID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *Repository) ListChatSettings(ctx context.Context) ([]state.ChatSettings, error) {
+	ids, err := r.client.SMembers(ctx, r.chatIndexKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]state.ChatSettings, 0, len(ids))
+	for _, id := range ids {
+		chatID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			continue
+		}
+		settings, err := r.GetChatSettings(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		if settings == nil {
+			// Ключ настроек пропал, а индекс остался - подчищаем индекс
+			r.client.SRem(ctx, r.chatIndexKey(), id)
+			continue
+		}
+		result = append(result, *settings)
+	}
+	return result, nil
+}
+
 func (r *Repository) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
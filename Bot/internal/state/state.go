@@ -17,6 +17,66 @@ type UserState struct {
 type Repository interface {
 	GetUserState(ctx context.Context, userID string) (*UserState, error)
 	SaveUserState(ctx context.Context, userID string, st UserState) error
+	DeleteUserState(ctx context.Context, userID string) error // удаление по запросу пользователя (/delete_me)
 	Ping(ctx context.Context) error
 	Close() error
 }
+
+// ChatSettings хранит настройки группового чата, в который добавлен бот
+type ChatSettings struct {
+	ChatID     int64  `json:"chat_id"`
+	StudyGroup string `json:"study_group,omitempty"` // учебная группа, к которой привязан чат
+	Department string `json:"department,omitempty"`  // подразделение, к которому привязан чат
+
+	SubscribeSchedule bool      `json:"subscribe_schedule,omitempty"` // ежедневная публикация расписания группы
+	SubscribeNews     bool      `json:"subscribe_news,omitempty"`     // рассылка новостей в чат
+	SchedulePostedAt  time.Time `json:"schedule_posted_at,omitempty"` // когда расписание публиковалось в последний раз
+
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChatRepository хранит настройки групповых чатов
+type ChatRepository interface {
+	GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error)
+	SaveChatSettings(ctx context.Context, settings ChatSettings) error
+	DeleteChatSettings(ctx context.Context, chatID int64) error
+	ListChatSettings(ctx context.Context) ([]ChatSettings, error)
+}
+
+// InFlow сообщает, ждет ли бот от пользователя ввода в многошаговом сценарии (регистрация, ответ на обращение и т.п.)
+func (s *UserState) InFlow() bool {
+	return s != nil && s.UserRegistrationStep != "" && s.UserRegistrationStep != "completed"
+}
+
+// ResetFlow выходит из текущего сценария и удаляет введенные в нем данные
+func (s *UserState) ResetFlow() {
+	s.UserRegistrationStep = ""
+	s.UserRegistrationData = make(map[string]string)
+}
+
+// SensitiveInput сообщает, что следующее сообщение пользователя - секрет (токен Moodle)
+// и его нельзя писать в лог
+func (s *UserState) SensitiveInput() bool {
+	return s.UserRegistrationStep == "moodle_token"
+}
+
+// FlowTitle возвращает название сценария для сообщений пользователю
+func FlowTitle(step string) string {
+	switch step {
+	case "ticket_reply", "ticket_user_reply":
+		return "ответ на обращение"
+	case "doc_response":
+		return "ответ на заявление"
+	case "send_news":
+		return "отправка новости"
+	case "moodle_token":
+		return "ввод токена Moodle"
+	case "reminder_create":
+		return "создание напоминания"
+	case "links_create":
+		return "создание ссылки"
+	default:
+		return "регистрация"
+	}
+}
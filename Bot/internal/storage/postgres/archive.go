@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/news"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/support"
+	"first-max-bot/internal/services/user"
+)
+
+// Выгрузка и восстановление данных для резервного копирования (интерфейсы Archiver сервисов).
+// Записи восстанавливаются с исходными ID, поэтому последовательности сдвигаются за восстановленные номера,
+// чтобы новые записи не получили занятый ID
+
+// advanceSequence поднимает последовательность seq не ниже числового суффикса id вида "<prefix><n>".
+// ID другого вида последовательность не меняют
+func advanceSequence(ctx context.Context, tx pgx.Tx, seq, prefix, id string) error {
+	n, err := strconv.ParseInt(strings.TrimPrefix(id, prefix), 10, 64)
+	if !strings.HasPrefix(id, prefix) || err != nil || n < 1 {
+		return nil
+	}
+	_, err = tx.Exec(ctx, "SELECT setval($1::regclass, GREATEST($2::bigint, nextval($1::regclass)))", seq, n)
+	return err
+}
+
+// restore выполняет upsert записи и сдвиг последовательности в одной транзакции
+func restore(ctx context.Context, pool *pgxpool.Pool, seq, prefix, id, query string, args ...any) error {
+	return withTx(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return err
+		}
+		return advanceSequence(ctx, tx, seq, prefix, id)
+	})
+}
+
+func (s *userService) ExportUsers(ctx context.Context) ([]user.User, error) {
+	return s.GetAllUsers(ctx)
+}
+
+func (s *userService) RestoreUser(ctx context.Context, u user.User) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (user_id) DO UPDATE SET
+			first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, age = EXCLUDED.age,
+			gender = EXCLUDED.gender, email = EXCLUDED.email, roles = EXCLUDED.roles,
+			active_role = EXCLUDED.active_role, study_group = EXCLUDED.study_group, moodle_token = EXCLUDED.moodle_token,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		u.UserID, u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+		roleStrings(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, u.CreatedAt, u.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("restore user %s: %w", u.UserID, err)
+	}
+	return nil
+}
+
+func (s *supportService) ExportTickets(ctx context.Context) ([]support.Ticket, error) {
+	return s.GetAllTickets(ctx)
+}
+
+func (s *supportService) RestoreTicket(ctx context.Context, t support.Ticket) error {
+	err := restore(ctx, s.pool, "tickets_seq", "DOE-", t.ID, `INSERT INTO tickets (`+ticketColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = EXCLUDED.user_id, department = EXCLUDED.department, subject = EXCLUDED.subject,
+			message = EXCLUDED.message, response = EXCLUDED.response, response_by = EXCLUDED.response_by,
+			user_reply = EXCLUDED.user_reply, status = EXCLUDED.status,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		t.ID, t.UserID, t.Department, t.Subject, t.Message, t.Response, t.ResponseBy, t.UserReply, t.Status,
+		t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("restore ticket %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+func (s *deaneryService) ExportDocuments(ctx context.Context) ([]deanery.Document, error) {
+	return s.GetAllDocuments(ctx)
+}
+
+func (s *deaneryService) RestoreDocument(ctx context.Context, d deanery.Document) error {
+	err := restore(ctx, s.pool, "documents_seq", "DOC-", d.ID, `INSERT INTO documents (`+documentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = EXCLUDED.user_id, type = EXCLUDED.type, status = EXCLUDED.status,
+			description = EXCLUDED.description, response = EXCLUDED.response,
+			response_file = EXCLUDED.response_file, response_by = EXCLUDED.response_by,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		d.ID, d.UserID, string(d.Type), d.Status, d.Description, d.Response, d.ResponseFile, d.ResponseBy,
+		d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("restore document %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+func (s *libraryService) ExportBooks(ctx context.Context) ([]library.Book, error) {
+	rows, err := s.pool.Query(ctx, "SELECT id, title, author, isbn, available FROM books ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("export books: %w", err)
+	}
+	books, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (library.Book, error) {
+		var b library.Book
+		err := row.Scan(&b.ID, &b.Title, &b.Author, &b.ISBN, &b.Available)
+		return b, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export books: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) ExportLoans(ctx context.Context) ([]library.UserBook, error) {
+	loans, err := s.queryUserBooks(ctx, "TRUE")
+	if err != nil {
+		return nil, fmt.Errorf("export loans: %w", err)
+	}
+	return loans, nil
+}
+
+func (s *libraryService) RestoreBook(ctx context.Context, b library.Book) error {
+	_, err := s.pool.Exec(ctx, `INSERT INTO books (id, title, author, isbn, available) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET title = EXCLUDED.title, author = EXCLUDED.author,
+			isbn = EXCLUDED.isbn, available = EXCLUDED.available`,
+		b.ID, b.Title, b.Author, b.ISBN, b.Available)
+	if err != nil {
+		return fmt.Errorf("restore book %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+func (s *libraryService) RestoreLoan(ctx context.Context, ub library.UserBook) error {
+	err := restore(ctx, s.pool, "user_books_seq", "UB-", ub.ID, `INSERT INTO user_books (id, user_id, book_id, user_name,
+		user_surname, status, borrowed_at, return_date, returned, issued_at, taken_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = EXCLUDED.user_id, book_id = EXCLUDED.book_id, user_name = EXCLUDED.user_name,
+			user_surname = EXCLUDED.user_surname, status = EXCLUDED.status, borrowed_at = EXCLUDED.borrowed_at,
+			return_date = EXCLUDED.return_date, returned = EXCLUDED.returned,
+			issued_at = EXCLUDED.issued_at, taken_at = EXCLUDED.taken_at`,
+		ub.ID, ub.UserID, ub.BookID, ub.UserName, ub.UserSurname, ub.Status,
+		ub.BorrowedAt, ub.ReturnDate, ub.Returned, ub.IssuedAt, ub.TakenAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("restore loan %s: книга %s уже выдана по другой записи", ub.ID, ub.BookID)
+	}
+	if err != nil {
+		return fmt.Errorf("restore loan %s: %w", ub.ID, err)
+	}
+	return nil
+}
+
+func (s *businessTripService) ExportTrips(ctx context.Context) ([]businesstrip.Trip, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+tripColumns+" FROM trips ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("export trips: %w", err)
+	}
+	trips, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (businesstrip.Trip, error) {
+		return scanTrip(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export trips: %w", err)
+	}
+	return trips, nil
+}
+
+func (s *businessTripService) RestoreTrip(ctx context.Context, t businesstrip.Trip) error {
+	err := restore(ctx, s.pool, "trips_seq", "TRIP-", t.ID, `INSERT INTO trips (`+tripColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = EXCLUDED.user_id, destination = EXCLUDED.destination, purpose = EXCLUDED.purpose,
+			start_date = EXCLUDED.start_date, end_date = EXCLUDED.end_date, status = EXCLUDED.status,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		t.ID, t.UserID, t.Destination, t.Purpose, t.StartDate, t.EndDate, t.Status, t.CreatedAt, t.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("restore trip %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+func (s *newsService) ExportNews(ctx context.Context) ([]news.News, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+newsColumns+" FROM news ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("export news: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (news.News, error) {
+		return scanNews(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export news: %w", err)
+	}
+	return result, nil
+}
+
+func (s *newsService) RestoreNews(ctx context.Context, n news.News) error {
+	err := restore(ctx, s.pool, "news_seq", "news-", n.ID, `INSERT INTO news (`+newsColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET title = EXCLUDED.title, content = EXCLUDED.content,
+			author_id = EXCLUDED.author_id, author = EXCLUDED.author, created_at = EXCLUDED.created_at`,
+		n.ID, n.Title, n.Content, n.AuthorID, n.Author, n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("restore news %s: %w", n.ID, err)
+	}
+	return nil
+}
+
+func (s *reminderService) ExportReminders(ctx context.Context) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders ORDER BY date_time")
+	if err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) RestoreReminder(ctx context.Context, r reminder.Reminder) error {
+	// Захват обработчиком не переносится: в новом хранилище напоминание снова свободно
+	err := restore(ctx, s.pool, "reminders_seq", "REM-", r.ID, `INSERT INTO reminders (id, user_id, text, date_time, created_at, status, kind)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			user_id = EXCLUDED.user_id, text = EXCLUDED.text, date_time = EXCLUDED.date_time,
+			created_at = EXCLUDED.created_at, status = EXCLUDED.status, kind = EXCLUDED.kind, claimed_by = '', claimed_until = NULL`,
+		r.ID, r.UserID, r.Text, r.DateTime, r.CreatedAt, r.Status, r.Kind)
+	if err != nil {
+		return fmt.Errorf("restore reminder %s: %w", r.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/deanery"
+)
+
+const documentColumns = "id, user_id, type, status, description, response, response_file, response_by, created_at, updated_at"
+
+type deaneryService struct {
+	pool *pgxpool.Pool
+}
+
+func NewDeaneryService(pool *pgxpool.Pool) deanery.Service {
+	return &deaneryService{pool: pool}
+}
+
+func scanDocument(row pgx.Row) (deanery.Document, error) {
+	var d deanery.Document
+	err := row.Scan(&d.ID, &d.UserID, &d.Type, &d.Status, &d.Description, &d.Response,
+		&d.ResponseFile, &d.ResponseBy, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+func (s *deaneryService) queryDocuments(ctx context.Context, query string, args ...any) ([]deanery.Document, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (deanery.Document, error) {
+		return scanDocument(row)
+	})
+}
+
+func (s *deaneryService) CreateDocument(ctx context.Context, userID string, docType deanery.DocumentType, description string) (*deanery.Document, error) {
+	now := time.Now()
+	doc, err := scanDocument(s.pool.QueryRow(ctx, `INSERT INTO documents (user_id, type, status, description, created_at, updated_at)
+		VALUES ($1, $2, 'pending', $3, $4, $4)
+		RETURNING `+documentColumns, userID, string(docType), description, now))
+	if err != nil {
+		return nil, fmt.Errorf("create document: %w", err)
+	}
+	return &doc, nil
+}
+
+func (s *deaneryService) GetUserDocuments(ctx context.Context, userID string) ([]deanery.Document, error) {
+	docs, err := s.queryDocuments(ctx, "SELECT "+documentColumns+" FROM documents WHERE user_id = $1 ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *deaneryService) GetDocument(ctx context.Context, documentID string) (*deanery.Document, error) {
+	doc, err := scanDocument(s.pool.QueryRow(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = $1", documentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+	return &doc, nil
+}
+
+func (s *deaneryService) GetAllDocuments(ctx context.Context) ([]deanery.Document, error) {
+	docs, err := s.queryDocuments(ctx, "SELECT "+documentColumns+" FROM documents ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *deaneryService) AddDocumentResponse(ctx context.Context, documentID, response, responseFile, responseBy string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE documents SET response = $2, response_file = $3, response_by = $4,
+			status = 'completed', updated_at = $5
+		WHERE id = $1`, documentID, response, responseFile, responseBy, time.Now())
+	if err != nil {
+		return fmt.Errorf("add document response: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("document not found")
+	}
+	return nil
+}
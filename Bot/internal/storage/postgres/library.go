@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/library"
+)
+
+const userBookColumns = `ub.id, ub.user_id, ub.book_id, ub.user_name, ub.user_surname, ub.status, ub.borrowed_at,
+	ub.return_date, ub.returned, ub.issued_at, ub.taken_at, b.id, b.title, b.author, b.isbn, b.available`
+
+// activeStatuses - статусы, при которых книга занята
+const activeStatuses = "('requested', 'issued', 'taken')"
+
+type libraryService struct {
+	pool *pgxpool.Pool
+}
+
+func NewLibraryService(pool *pgxpool.Pool) library.Service {
+	return &libraryService{pool: pool}
+}
+
+func scanUserBook(row pgx.Row) (library.UserBook, error) {
+	var (
+		ub   library.UserBook
+		book library.Book
+	)
+	err := row.Scan(&ub.ID, &ub.UserID, &ub.BookID, &ub.UserName, &ub.UserSurname, &ub.Status, &ub.BorrowedAt,
+		&ub.ReturnDate, &ub.Returned, &ub.IssuedAt, &ub.TakenAt, &book.ID, &book.Title, &book.Author, &book.ISBN, &book.Available)
+	if err != nil {
+		return library.UserBook{}, err
+	}
+	ub.Book = &book
+	return ub, nil
+}
+
+func (s *libraryService) queryUserBooks(ctx context.Context, where string, args ...any) ([]library.UserBook, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+userBookColumns+" FROM user_books ub JOIN books b ON b.id = ub.book_id WHERE "+where+" ORDER BY ub.borrowed_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (library.UserBook, error) {
+		return scanUserBook(row)
+	})
+}
+
+func (s *libraryService) SearchBooks(ctx context.Context, query string) ([]library.Book, error) {
+	// Книга доступна, если на нее нет активного запроса, выдачи или она не на руках.
+	// Пустой запрос возвращает все доступные книги
+	rows, err := s.pool.Query(ctx, `SELECT b.id, b.title, b.author, b.isbn, b.available FROM books b
+		WHERE NOT EXISTS (SELECT 1 FROM user_books ub WHERE ub.book_id = b.id AND NOT ub.returned AND ub.status IN `+activeStatuses+`)
+			AND ($1 = '' OR b.title ILIKE '%' || $1 || '%' OR b.author ILIKE '%' || $1 || '%')
+		ORDER BY b.title`, query)
+	if err != nil {
+		return nil, fmt.Errorf("search books: %w", err)
+	}
+	books, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (library.Book, error) {
+		var b library.Book
+		err := row.Scan(&b.ID, &b.Title, &b.Author, &b.ISBN, &b.Available)
+		return b, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search books: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) GetBookByID(ctx context.Context, bookID string) (*library.Book, error) {
+	var b library.Book
+	err := s.pool.QueryRow(ctx, "SELECT id, title, author, isbn, available FROM books WHERE id = $1", bookID).
+		Scan(&b.ID, &b.Title, &b.Author, &b.ISBN, &b.Available)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get book: %w", err)
+	}
+	return &b, nil
+}
+
+func (s *libraryService) BorrowBook(ctx context.Context, userID, userName, userSurname, bookID string) (*library.UserBook, error) {
+	var result library.UserBook
+	err := withTx(ctx, s.pool, func(tx pgx.Tx) error {
+		// Блокируем книгу, чтобы два читателя не заказали ее одновременно
+		var book library.Book
+		err := tx.QueryRow(ctx, "SELECT id, title, author, isbn, available FROM books WHERE id = $1 FOR UPDATE", bookID).
+			Scan(&book.ID, &book.Title, &book.Author, &book.ISBN, &book.Available)
+		if errors.Is(err, pgx.ErrNoRows) || (err == nil && !book.Available) {
+			return fmt.Errorf("книга недоступна")
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		returnDate := now.Add(30 * 24 * time.Hour) // 30 дней
+		err = tx.QueryRow(ctx, `INSERT INTO user_books (user_id, book_id, user_name, user_surname, status, borrowed_at, return_date)
+			VALUES ($1, $2, $3, $4, 'requested', $5, $6)
+			RETURNING id`, userID, bookID, userName, userSurname, now, returnDate).Scan(&result.ID)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("книга недоступна")
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE books SET available = FALSE WHERE id = $1", bookID); err != nil {
+			return err
+		}
+
+		book.Available = false
+		result.UserID = userID
+		result.BookID = bookID
+		result.Book = &book
+		result.UserName = userName
+		result.UserSurname = userSurname
+		result.Status = "requested"
+		result.BorrowedAt = now
+		result.ReturnDate = returnDate
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *libraryService) GetUserBooks(ctx context.Context, userID string) ([]library.UserBook, error) {
+	// Возвращенные книги не показываются
+	books, err := s.queryUserBooks(ctx, "ub.user_id = $1 AND NOT ub.returned", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user books: %w", err)
+	}
+	return books, nil
+}
+
+// returnBook закрывает запись о выдаче и снова делает книгу доступной
+func returnBook(ctx context.Context, tx pgx.Tx, userID, bookID, status string) (bool, error) {
+	tag, err := tx.Exec(ctx, `UPDATE user_books SET status = 'returned', returned = TRUE
+		WHERE user_id = $1 AND book_id = $2 AND NOT returned AND ($3 = '' OR status = $3)`, userID, bookID, status)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	_, err = tx.Exec(ctx, "UPDATE books SET available = TRUE WHERE id = $1", bookID)
+	return err == nil, err
+}
+
+func (s *libraryService) ReturnBook(ctx context.Context, userID, bookID string) error {
+	return withTx(ctx, s.pool, func(tx pgx.Tx) error {
+		found, err := returnBook(ctx, tx, userID, bookID, "")
+		if err != nil {
+			return fmt.Errorf("return book: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("книга не найдена")
+		}
+		return nil
+	})
+}
+
+func (s *libraryService) GetAllRequests(ctx context.Context) ([]library.UserBook, error) {
+	books, err := s.queryUserBooks(ctx, "NOT ub.returned AND ub.status IN "+activeStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("get book requests: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) IssueBook(ctx context.Context, userID string, bookID string) (*library.UserBook, error) {
+	var id string
+	err := s.pool.QueryRow(ctx, `UPDATE user_books SET status = 'issued', issued_at = $3
+		WHERE user_id = $1 AND book_id = $2 AND status = 'requested'
+		RETURNING id`, userID, bookID, time.Now()).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("issue book: %w", err)
+	}
+
+	books, err := s.queryUserBooks(ctx, "ub.id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("issue book: %w", err)
+	}
+	if len(books) == 0 {
+		return nil, fmt.Errorf("request not found")
+	}
+	return &books[0], nil
+}
+
+func (s *libraryService) MarkBookTaken(ctx context.Context, userID string, bookID string) error {
+	tag, err := s.pool.Exec(ctx, `UPDATE user_books SET status = 'taken', taken_at = $3
+		WHERE user_id = $1 AND book_id = $2 AND status = 'issued'`, userID, bookID, time.Now())
+	if err != nil {
+		return fmt.Errorf("mark book taken: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("issued book not found")
+	}
+	return nil
+}
+
+func (s *libraryService) MarkBookReturned(ctx context.Context, userID string, bookID string) error {
+	return withTx(ctx, s.pool, func(tx pgx.Tx) error {
+		found, err := returnBook(ctx, tx, userID, bookID, "taken")
+		if err != nil {
+			return fmt.Errorf("mark book returned: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("taken book not found")
+		}
+		return nil
+	})
+}
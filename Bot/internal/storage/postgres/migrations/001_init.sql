@@ -0,0 +1,123 @@
+CREATE TABLE users (
+    user_id      TEXT PRIMARY KEY,
+    first_name   TEXT NOT NULL DEFAULT '',
+    last_name    TEXT NOT NULL DEFAULT '',
+    age          INTEGER NOT NULL DEFAULT 0,
+    gender       TEXT NOT NULL DEFAULT '',
+    email        TEXT NOT NULL DEFAULT '',
+    roles        TEXT[] NOT NULL,
+    active_role  TEXT NOT NULL DEFAULT '',
+    moodle_token TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+-- Номера обращений, заявлений и т.д. берутся из последовательностей, чтобы не было коллизий
+-- при одновременном создании (mock использует секунды, которые совпадают)
+CREATE SEQUENCE tickets_seq;
+
+CREATE TABLE tickets (
+    id          TEXT PRIMARY KEY DEFAULT 'DOE-' || nextval('tickets_seq'),
+    user_id     TEXT NOT NULL,
+    department  TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    message     TEXT NOT NULL,
+    response    TEXT NOT NULL DEFAULT '',
+    response_by TEXT NOT NULL DEFAULT '',
+    user_reply  TEXT NOT NULL DEFAULT '',
+    status      TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX tickets_user_id_idx ON tickets (user_id);
+
+CREATE SEQUENCE documents_seq;
+
+CREATE TABLE documents (
+    id            TEXT PRIMARY KEY DEFAULT 'DOC-' || nextval('documents_seq'),
+    user_id       TEXT NOT NULL,
+    type          TEXT NOT NULL,
+    status        TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    response      TEXT NOT NULL DEFAULT '',
+    response_file TEXT NOT NULL DEFAULT '',
+    response_by   TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX documents_user_id_idx ON documents (user_id);
+
+CREATE TABLE books (
+    id        TEXT PRIMARY KEY,
+    title     TEXT NOT NULL,
+    author    TEXT NOT NULL,
+    isbn      TEXT NOT NULL DEFAULT '',
+    available BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE SEQUENCE user_books_seq;
+
+CREATE TABLE user_books (
+    id           TEXT PRIMARY KEY DEFAULT 'UB-' || nextval('user_books_seq'),
+    user_id      TEXT NOT NULL,
+    book_id      TEXT NOT NULL REFERENCES books (id),
+    user_name    TEXT NOT NULL DEFAULT '',
+    user_surname TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL,
+    borrowed_at  TIMESTAMPTZ NOT NULL,
+    return_date  TIMESTAMPTZ NOT NULL,
+    returned     BOOLEAN NOT NULL DEFAULT FALSE,
+    issued_at    TIMESTAMPTZ,
+    taken_at     TIMESTAMPTZ
+);
+
+-- Один экземпляр книги не может быть на руках у двух читателей
+CREATE UNIQUE INDEX user_books_active_book_idx ON user_books (book_id) WHERE NOT returned;
+CREATE INDEX user_books_user_id_idx ON user_books (user_id);
+
+CREATE SEQUENCE trips_seq;
+
+CREATE TABLE trips (
+    id          TEXT PRIMARY KEY DEFAULT 'TRIP-' || nextval('trips_seq'),
+    user_id     TEXT NOT NULL,
+    destination TEXT NOT NULL,
+    purpose     TEXT NOT NULL,
+    start_date  TIMESTAMPTZ NOT NULL,
+    end_date    TIMESTAMPTZ NOT NULL,
+    status      TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX trips_user_id_idx ON trips (user_id);
+
+CREATE SEQUENCE news_seq;
+
+CREATE TABLE news (
+    id         TEXT PRIMARY KEY DEFAULT 'news-' || nextval('news_seq'),
+    title      TEXT NOT NULL,
+    content    TEXT NOT NULL,
+    author_id  TEXT NOT NULL,
+    author     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX news_created_at_idx ON news (created_at DESC);
+
+CREATE SEQUENCE reminders_seq;
+
+CREATE TABLE reminders (
+    id            TEXT PRIMARY KEY DEFAULT 'REM-' || nextval('reminders_seq'),
+    user_id       TEXT NOT NULL,
+    text          TEXT NOT NULL,
+    date_time     TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    status        TEXT NOT NULL,
+    claimed_by    TEXT NOT NULL DEFAULT '',
+    claimed_until TIMESTAMPTZ
+);
+
+CREATE INDEX reminders_user_id_idx ON reminders (user_id);
+CREATE INDEX reminders_due_idx ON reminders (date_time) WHERE status = 'active';
@@ -0,0 +1,2 @@
+-- Учебная группа пользователя (/group): по ней выбирается расписание из iCalendar
+ALTER TABLE users ADD COLUMN study_group TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Вид напоминания: пустой у созданных пользователем, "lesson" у напоминаний перед парами (/notify)
+ALTER TABLE reminders ADD COLUMN kind TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/news"
+)
+
+const newsColumns = "id, title, content, author_id, author, created_at"
+
+type newsService struct {
+	pool *pgxpool.Pool
+}
+
+func NewNewsService(pool *pgxpool.Pool) news.Service {
+	return &newsService{pool: pool}
+}
+
+func scanNews(row pgx.Row) (news.News, error) {
+	var n news.News
+	err := row.Scan(&n.ID, &n.Title, &n.Content, &n.AuthorID, &n.Author, &n.CreatedAt)
+	return n, err
+}
+
+func (s *newsService) GetLatestNews(ctx context.Context, count int) ([]news.News, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+newsColumns+" FROM news ORDER BY created_at DESC LIMIT $1", count)
+	if err != nil {
+		return nil, fmt.Errorf("get latest news: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (news.News, error) {
+		return scanNews(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get latest news: %w", err)
+	}
+	return result, nil
+}
+
+func (s *newsService) CreateNews(ctx context.Context, title, content, authorID, author string) (*news.News, error) {
+	n, err := scanNews(s.pool.QueryRow(ctx, `INSERT INTO news (title, content, author_id, author, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+newsColumns, title, content, authorID, author, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("create news: %w", err)
+	}
+	return &n, nil
+}
@@ -0,0 +1,176 @@
+// Package postgres хранит данные сервисов (пользователи, обращения, заявления, библиотека,
+// командировки, новости, напоминания) в PostgreSQL
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLockID - ключ advisory lock, чтобы миграции не применялись одновременно несколькими экземплярами
+const migrationLockID = 7420115
+
+type options struct {
+	maxConns        int32
+	minConns        int32
+	maxConnLifetime time.Duration
+	maxConnIdleTime time.Duration
+}
+
+type Option func(*options)
+
+// WithMaxConns ограничивает размер пула соединений
+func WithMaxConns(n int32) Option {
+	return func(o *options) {
+		o.maxConns = n
+	}
+}
+
+// WithMinConns задает число соединений, которые пул держит открытыми
+func WithMinConns(n int32) Option {
+	return func(o *options) {
+		o.minConns = n
+	}
+}
+
+// WithMaxConnLifetime задает, через сколько соединение пересоздается
+func WithMaxConnLifetime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxConnLifetime = d
+	}
+}
+
+// WithMaxConnIdleTime задает, через сколько простоя соединение закрывается
+func WithMaxConnIdleTime(d time.Duration) Option {
+	return func(o *options) {
+		o.maxConnIdleTime = d
+	}
+}
+
+// Open создает пул соединений и проверяет доступность базы.
+// Нулевые значения опций оставляют настройки из DSN (pool_max_conns и т.д.) или значения pgx по умолчанию
+func Open(ctx context.Context, dsn string, opts ...Option) (*pgxpool.Pool, error) {
+	o := options{
+		maxConnLifetime: time.Hour,
+		maxConnIdleTime: 30 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parse postgres dsn: %w", err)
+	}
+	if o.maxConns > 0 {
+		cfg.MaxConns = o.maxConns
+	}
+	if o.minConns > 0 {
+		cfg.MinConns = o.minConns
+	}
+	if o.maxConnLifetime > 0 {
+		cfg.MaxConnLifetime = o.maxConnLifetime
+	}
+	if o.maxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = o.maxConnIdleTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create postgres pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("ping postgres: %w", err)
+	}
+	return pool, nil
+}
+
+// Migrate применяет встроенные миграции, которые еще не применены. Каждая миграция выполняется
+// в отдельной транзакции вместе с записью в schema_migrations
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied := make(map[string]bool)
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("list applied migrations: %w", err)
+	}
+	for _, v := range versions {
+		applied[v] = true
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("apply migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// withTx выполняет fn в транзакции: commit, если fn вернула nil, иначе rollback
+func withTx(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	return pgx.BeginFunc(ctx, pool, fn)
+}
+
+// isUniqueViolation проверяет, что ошибка - нарушение уникального индекса
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/reminder"
+)
+
+const reminderColumns = "id, user_id, text, date_time, created_at, status, kind, claimed_by, claimed_until"
+
+type reminderService struct {
+	pool *pgxpool.Pool
+}
+
+func NewReminderService(pool *pgxpool.Pool) reminder.Service {
+	return &reminderService{pool: pool}
+}
+
+func scanReminder(row pgx.Row) (reminder.Reminder, error) {
+	var (
+		r            reminder.Reminder
+		claimedUntil *time.Time
+	)
+	err := row.Scan(&r.ID, &r.UserID, &r.Text, &r.DateTime, &r.CreatedAt, &r.Status, &r.Kind, &r.ClaimedBy, &claimedUntil)
+	if claimedUntil != nil {
+		r.ClaimedUntil = *claimedUntil
+	}
+	return r, err
+}
+
+func (s *reminderService) queryReminders(ctx context.Context, query string, args ...any) ([]reminder.Reminder, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (reminder.Reminder, error) {
+		return scanReminder(row)
+	})
+}
+
+func (s *reminderService) CreateReminder(ctx context.Context, userID, text string, dateTime time.Time) (*reminder.Reminder, error) {
+	return s.CreateGeneratedReminder(ctx, reminder.KindManual, userID, text, dateTime)
+}
+
+func (s *reminderService) CreateGeneratedReminder(ctx context.Context, kind, userID, text string, dateTime time.Time) (*reminder.Reminder, error) {
+	r, err := scanReminder(s.pool.QueryRow(ctx, `INSERT INTO reminders (user_id, text, date_time, created_at, status, kind)
+		VALUES ($1, $2, $3, $4, 'active', $5)
+		RETURNING `+reminderColumns, userID, text, dateTime, time.Now(), kind))
+	if err != nil {
+		return nil, fmt.Errorf("create reminder: %w", err)
+	}
+	return &r, nil
+}
+
+func (s *reminderService) GetUserReminders(ctx context.Context, userID string) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE user_id = $1 ORDER BY date_time", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) GetActiveReminders(ctx context.Context, userID string) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE user_id = $1 AND status = 'active' ORDER BY date_time", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get active reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) GetAllActiveReminders(ctx context.Context) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE status = 'active' ORDER BY date_time")
+	if err != nil {
+		return nil, fmt.Errorf("get all active reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) DeleteReminder(ctx context.Context, reminderID string) error {
+	if _, err := s.pool.Exec(ctx, "DELETE FROM reminders WHERE id = $1", reminderID); err != nil {
+		return fmt.Errorf("delete reminder: %w", err)
+	}
+	return nil
+}
+
+func (s *reminderService) GetReminderByID(ctx context.Context, reminderID string) (*reminder.Reminder, error) {
+	r, err := scanReminder(s.pool.QueryRow(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE id = $1", reminderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("reminder not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reminder: %w", err)
+	}
+	return &r, nil
+}
+
+// updateReminder выполняет UPDATE и возвращает "reminder not found", если напоминания нет
+func (s *reminderService) updateReminder(ctx context.Context, query string, args ...any) error {
+	tag, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update reminder: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+func (s *reminderService) MarkReminderCompleted(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, "UPDATE reminders SET status = 'completed', claimed_by = '', claimed_until = NULL WHERE id = $1", reminderID)
+}
+
+func (s *reminderService) ClaimDueReminders(ctx context.Context, now time.Time, owner string, lease time.Duration) ([]reminder.Reminder, error) {
+	// SKIP LOCKED: строки, которые прямо сейчас захватывает другой экземпляр, пропускаются, а не ждут
+	claimed, err := s.queryReminders(ctx, `UPDATE reminders SET claimed_by = $2, claimed_until = $3
+		WHERE id IN (
+			SELECT id FROM reminders
+			WHERE status = 'active' AND date_time <= $1 AND (claimed_until IS NULL OR claimed_until <= $1)
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+reminderColumns, now, owner, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("claim due reminders: %w", err)
+	}
+
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].DateTime.Before(claimed[j].DateTime)
+	})
+	return claimed, nil
+}
+
+func (s *reminderService) ReleaseReminder(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, "UPDATE reminders SET claimed_by = '', claimed_until = NULL WHERE id = $1", reminderID)
+}
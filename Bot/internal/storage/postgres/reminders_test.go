@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reminderClaim - напоминание, захваченное экземпляром owner
+type reminderClaim struct {
+	id    string
+	owner string
+}
+
+func TestClaimDueRemindersSkipsLockedRows(t *testing.T) {
+	ctx := context.Background()
+	pool := newMigratedPool(t)
+	service := NewReminderService(pool)
+
+	now := time.Now()
+	first, err := service.CreateReminder(ctx, "100", "Первое", now.Add(-2*time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	second, err := service.CreateReminder(ctx, "100", "Второе", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	if _, err := service.CreateReminder(ctx, "100", "Будущее", now.Add(time.Hour)); err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+
+	// Другой экземпляр прямо сейчас захватывает первое напоминание
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, "SELECT id FROM reminders WHERE id = $1 FOR UPDATE", first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	claimed, err := service.ClaimDueReminders(claimCtx, now, "a", time.Minute)
+	cancel()
+	if err != nil {
+		t.Fatalf("ClaimDueReminders with locked row: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != second.ID || claimed[0].ClaimedBy != "a" {
+		t.Fatalf("claimed = %+v, want only %s", claimed, second.ID)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Второе захвачено до now+1m, первое свободно
+	claimed, err = service.ClaimDueReminders(ctx, now, "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != first.ID {
+		t.Fatalf("claimed = %+v, want only %s", claimed, first.ID)
+	}
+
+	// Захват истек: оба напоминания снова доступны, раньше наступившее первым
+	later := now.Add(2 * time.Minute)
+	claimed, err = service.ClaimDueReminders(ctx, later, "c", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != first.ID || claimed[1].ID != second.ID {
+		t.Fatalf("claimed = %+v, want %s and %s", claimed, first.ID, second.ID)
+	}
+
+	// Выполненное не захватывается, освобожденное захватывается сразу
+	if err := service.MarkReminderCompleted(ctx, first.ID); err != nil {
+		t.Fatalf("MarkReminderCompleted: %v", err)
+	}
+	if err := service.ReleaseReminder(ctx, second.ID); err != nil {
+		t.Fatalf("ReleaseReminder: %v", err)
+	}
+	claimed, err = service.ClaimDueReminders(ctx, later, "d", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != second.ID || claimed[0].ClaimedBy != "d" {
+		t.Fatalf("claimed = %+v, want only %s", claimed, second.ID)
+	}
+}
+
+func TestClaimDueRemindersConcurrently(t *testing.T) {
+	ctx := context.Background()
+	service := NewReminderService(newMigratedPool(t))
+
+	now := time.Now()
+	const total = 50
+	for i := 0; i < total; i++ {
+		if _, err := service.CreateReminder(ctx, "100", fmt.Sprintf("Напоминание %d", i), now.Add(-time.Minute)); err != nil {
+			t.Fatalf("CreateReminder: %v", err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		claims   []reminderClaim
+		wg       sync.WaitGroup
+		claimErr error
+	)
+	for i := 0; i < 5; i++ {
+		owner := fmt.Sprintf("instance-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			claimed, err := service.ClaimDueReminders(ctx, now, owner, time.Minute)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				claimErr = err
+				return
+			}
+			for _, r := range claimed {
+				claims = append(claims, reminderClaim{id: r.ID, owner: owner})
+			}
+		}()
+	}
+	wg.Wait()
+	if claimErr != nil {
+		t.Fatalf("ClaimDueReminders: %v", claimErr)
+	}
+
+	// Каждое напоминание захвачено ровно одним экземпляром
+	seen := make(map[string]string, total)
+	for _, c := range claims {
+		if owner, ok := seen[c.id]; ok {
+			t.Errorf("reminder %s claimed by %s and %s", c.id, owner, c.owner)
+		}
+		seen[c.id] = c.owner
+	}
+	if len(seen) != total {
+		t.Errorf("claimed %d reminders, want %d", len(seen), total)
+	}
+}
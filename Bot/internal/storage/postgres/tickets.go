@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/support"
+)
+
+const ticketColumns = "id, user_id, department, subject, message, response, response_by, user_reply, status, created_at, updated_at"
+
+type supportService struct {
+	pool *pgxpool.Pool
+}
+
+func NewSupportService(pool *pgxpool.Pool) support.Service {
+	return &supportService{pool: pool}
+}
+
+func scanTicket(row pgx.Row) (support.Ticket, error) {
+	var t support.Ticket
+	err := row.Scan(&t.ID, &t.UserID, &t.Department, &t.Subject, &t.Message, &t.Response,
+		&t.ResponseBy, &t.UserReply, &t.Status, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+func (s *supportService) queryTickets(ctx context.Context, query string, args ...any) ([]support.Ticket, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (support.Ticket, error) {
+		return scanTicket(row)
+	})
+}
+
+func (s *supportService) CreateTicket(ctx context.Context, userID, subject, message string) (support.Ticket, error) {
+	now := time.Now()
+	ticket, err := scanTicket(s.pool.QueryRow(ctx, `INSERT INTO tickets (user_id, department, subject, message, status, created_at, updated_at)
+		VALUES ($1, 'Department of Education', $2, $3, 'received', $4, $4)
+		RETURNING `+ticketColumns, userID, subject, message, now))
+	if err != nil {
+		return support.Ticket{}, fmt.Errorf("create ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+func (s *supportService) GetTicket(ctx context.Context, ticketID string) (*support.Ticket, error) {
+	ticket, err := scanTicket(s.pool.QueryRow(ctx, "SELECT "+ticketColumns+" FROM tickets WHERE id = $1", ticketID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ticket: %w", err)
+	}
+	return &ticket, nil
+}
+
+func (s *supportService) GetAllTickets(ctx context.Context) ([]support.Ticket, error) {
+	tickets, err := s.queryTickets(ctx, "SELECT "+ticketColumns+" FROM tickets ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get tickets: %w", err)
+	}
+	return tickets, nil
+}
+
+func (s *supportService) GetUserTickets(ctx context.Context, userID string) ([]support.Ticket, error) {
+	tickets, err := s.queryTickets(ctx, "SELECT "+ticketColumns+" FROM tickets WHERE user_id = $1 ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user tickets: %w", err)
+	}
+	return tickets, nil
+}
+
+// updateTicket выполняет UPDATE и возвращает "ticket not found", если обращения нет
+func (s *supportService) updateTicket(ctx context.Context, query string, args ...any) error {
+	tag, err := s.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update ticket: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("ticket not found")
+	}
+	return nil
+}
+
+func (s *supportService) UpdateTicketStatus(ctx context.Context, ticketID, status string) error {
+	return s.updateTicket(ctx, "UPDATE tickets SET status = $2, updated_at = $3 WHERE id = $1", ticketID, status, time.Now())
+}
+
+func (s *supportService) AddResponse(ctx context.Context, ticketID, response, responseBy string) error {
+	// Статус "answered" - есть ответ, но тикет еще не закрыт
+	return s.updateTicket(ctx, `UPDATE tickets SET response = $2, response_by = $3, status = 'answered', updated_at = $4
+		WHERE id = $1`, ticketID, response, responseBy, time.Now())
+}
+
+func (s *supportService) AddUserReply(ctx context.Context, ticketID, reply string) error {
+	// Новый ответ пользователя дописывается к предыдущим; статус "in_progress" - ждем ответа руководителя
+	return s.updateTicket(ctx, `UPDATE tickets SET
+			user_reply = CASE WHEN user_reply = '' THEN $2 ELSE user_reply || E'\n\n---\n\n' || $2 END,
+			status = 'in_progress', updated_at = $3
+		WHERE id = $1`, ticketID, reply, time.Now())
+}
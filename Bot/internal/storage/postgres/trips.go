@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/services/businesstrip"
+)
+
+const tripColumns = "id, user_id, destination, purpose, start_date, end_date, status, created_at, updated_at"
+
+type businessTripService struct {
+	pool *pgxpool.Pool
+}
+
+func NewBusinessTripService(pool *pgxpool.Pool) businesstrip.Service {
+	return &businessTripService{pool: pool}
+}
+
+func scanTrip(row pgx.Row) (businesstrip.Trip, error) {
+	var t businesstrip.Trip
+	err := row.Scan(&t.ID, &t.UserID, &t.Destination, &t.Purpose, &t.StartDate, &t.EndDate, &t.Status, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+func (s *businessTripService) CreateTrip(ctx context.Context, userID, destination, purpose string, startDate, endDate time.Time) (*businesstrip.Trip, error) {
+	trip, err := scanTrip(s.pool.QueryRow(ctx, `INSERT INTO trips (user_id, destination, purpose, start_date, end_date, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', $6, $6)
+		RETURNING `+tripColumns, userID, destination, purpose, startDate, endDate, time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("create trip: %w", err)
+	}
+	return &trip, nil
+}
+
+func (s *businessTripService) GetUserTrips(ctx context.Context, userID string) ([]businesstrip.Trip, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+tripColumns+" FROM trips WHERE user_id = $1 ORDER BY start_date", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user trips: %w", err)
+	}
+	trips, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (businesstrip.Trip, error) {
+		return scanTrip(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get user trips: %w", err)
+	}
+	return trips, nil
+}
+
+func (s *businessTripService) GetTrip(ctx context.Context, tripID string) (*businesstrip.Trip, error) {
+	trip, err := scanTrip(s.pool.QueryRow(ctx, "SELECT "+tripColumns+" FROM trips WHERE id = $1", tripID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get trip: %w", err)
+	}
+	return &trip, nil
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"first-max-bot/internal/secret"
+	"first-max-bot/internal/services/user"
+)
+
+const userColumns = "user_id, first_name, last_name, age, gender, email, roles, active_role, study_group, moodle_token, created_at, updated_at"
+
+type userService struct {
+	pool *pgxpool.Pool
+}
+
+func NewUserService(pool *pgxpool.Pool) user.Service {
+	return &userService{pool: pool}
+}
+
+func scanUser(row pgx.Row) (*user.User, error) {
+	var (
+		u     user.User
+		roles []string
+	)
+	err := row.Scan(&u.UserID, &u.FirstName, &u.LastName, &u.Age, &u.Gender, &u.Email,
+		&roles, &u.ActiveRole, &u.StudyGroup, &u.MoodleToken, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	u.ID = u.UserID
+	for _, r := range roles {
+		u.Roles = append(u.Roles, user.Role(r))
+	}
+	if len(u.Roles) > 0 {
+		u.Role = u.Roles[0]
+	}
+	return &u, nil
+}
+
+func roleStrings(roles []user.Role) []string {
+	result := make([]string, 0, len(roles))
+	for _, r := range roles {
+		result = append(result, string(r))
+	}
+	return result
+}
+
+func (s *userService) GetUserByID(ctx context.Context, userID string) (*user.User, error) {
+	u, err := scanUser(s.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE user_id = $1", userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil // Пользователь не найден
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *userService) CreateUser(ctx context.Context, u user.User) (*user.User, error) {
+	now := time.Now()
+	u.ID = u.UserID
+	u.CreatedAt = now
+	u.UpdatedAt = now
+
+	u.Roles = user.NormalizeRoles(append([]user.Role{u.Role}, u.Roles...))
+	if len(u.Roles) == 0 {
+		u.Roles = []user.Role{user.RoleApplicant}
+	}
+	u.Role = u.Roles[0]
+
+	// Повторная регистрация перезаписывает профиль, как и в mock
+	_, err := s.pool.Exec(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (user_id) DO UPDATE SET
+			first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, age = EXCLUDED.age,
+			gender = EXCLUDED.gender, email = EXCLUDED.email, roles = EXCLUDED.roles,
+			active_role = EXCLUDED.active_role, study_group = EXCLUDED.study_group, moodle_token = EXCLUDED.moodle_token,
+			created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at`,
+		u.UserID, u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+		roleStrings(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, u.CreatedAt, u.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+	return &u, nil
+}
+
+// modify загружает пользователя под блокировкой строки, применяет fn и сохраняет результат
+func (s *userService) modify(ctx context.Context, userID string, fn func(u *user.User) error) (*user.User, error) {
+	var result *user.User
+	err := withTx(ctx, s.pool, func(tx pgx.Tx) error {
+		u, err := scanUser(tx.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE user_id = $1 FOR UPDATE", userID))
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+
+		u.UpdatedAt = time.Now()
+		_, err = tx.Exec(ctx, `UPDATE users SET first_name = $2, last_name = $3, age = $4, gender = $5, email = $6,
+			roles = $7, active_role = $8, study_group = $9, moodle_token = $10, updated_at = $11 WHERE user_id = $1`,
+			u.UserID, u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+			roleStrings(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, u.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		result = u
+		return nil
+	})
+	return result, err
+}
+
+func (s *userService) UpdateUser(ctx context.Context, userID string, update user.User) (*user.User, error) {
+	u, err := s.modify(ctx, userID, func(u *user.User) error {
+		u.Merge(update)
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *userService) GetUserRole(ctx context.Context, userID string) (user.Role, error) {
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if u == nil {
+		return user.RoleApplicant, nil // По умолчанию абитуриент
+	}
+	if u.ActiveRole != "" && u.HasRole(u.ActiveRole) {
+		return u.ActiveRole, nil
+	}
+	return u.Role, nil
+}
+
+func (s *userService) GetAllUsers(ctx context.Context) ([]user.User, error) {
+	rows, err := s.pool.Query(ctx, "SELECT "+userColumns+" FROM users ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+	defer rows.Close()
+
+	var result []user.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get users: %w", err)
+		}
+		result = append(result, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+	return result, nil
+}
+
+func (s *userService) SetMoodleToken(ctx context.Context, userID string, token secret.Sealed) error {
+	tag, err := s.pool.Exec(ctx, "UPDATE users SET moodle_token = $2, updated_at = $3 WHERE user_id = $1", userID, token, time.Now())
+	if err != nil {
+		return fmt.Errorf("set moodle token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (s *userService) SetUserRoles(ctx context.Context, userID string, roles []user.Role) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		return u.ReplaceRoles(roles)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+func (s *userService) SetActiveRole(ctx context.Context, userID string, role user.Role) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		if role != "" && !u.HasRole(role) {
+			return fmt.Errorf("user has no role %q", role)
+		}
+		u.ActiveRole = role
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+func (s *userService) SetStudyGroup(ctx context.Context, userID string, group string) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		u.StudyGroup = group
+		return nil
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/library"
+	"first-max-bot/internal/services/news"
+	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/support"
+	"first-max-bot/internal/services/user"
+)
+
+// Выгрузка и восстановление данных для резервного копирования (интерфейсы Archiver сервисов).
+// Записи восстанавливаются с исходными ID, поэтому счетчики сдвигаются за восстановленные номера,
+// чтобы новые записи не получили занятый ID
+
+// advanceSequence поднимает счетчик name не ниже числового суффикса id вида "<prefix><n>".
+// ID другого вида счетчик не меняют
+func advanceSequence(ctx context.Context, q execer, name, prefix, id string) error {
+	n, err := strconv.ParseInt(strings.TrimPrefix(id, prefix), 10, 64)
+	if !strings.HasPrefix(id, prefix) || err != nil {
+		return nil
+	}
+	_, err = q.ExecContext(ctx, `INSERT INTO sequences (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = MAX(value, excluded.value)`, name, n)
+	return err
+}
+
+// restore выполняет upsert записи и сдвиг счетчика в одной транзакции
+func restore(ctx context.Context, db *sql.DB, sequence, prefix, id, query string, args ...any) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+		return advanceSequence(ctx, tx, sequence, prefix, id)
+	})
+}
+
+func (s *userService) ExportUsers(ctx context.Context) ([]user.User, error) {
+	return s.GetAllUsers(ctx)
+}
+
+func (s *userService) RestoreUser(ctx context.Context, u user.User) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.UserID, u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+		encodeRoles(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, toMillis(u.CreatedAt), toMillis(u.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("restore user %s: %w", u.UserID, err)
+	}
+	return nil
+}
+
+func (s *supportService) ExportTickets(ctx context.Context) ([]support.Ticket, error) {
+	return s.GetAllTickets(ctx)
+}
+
+func (s *supportService) RestoreTicket(ctx context.Context, t support.Ticket) error {
+	err := restore(ctx, s.db, "tickets", "DOE-", t.ID, `INSERT OR REPLACE INTO tickets (`+ticketColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.UserID, t.Department, t.Subject, t.Message, t.Response, t.ResponseBy, t.UserReply, t.Status,
+		toMillis(t.CreatedAt), toMillis(t.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("restore ticket %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+func (s *deaneryService) ExportDocuments(ctx context.Context) ([]deanery.Document, error) {
+	return s.GetAllDocuments(ctx)
+}
+
+func (s *deaneryService) RestoreDocument(ctx context.Context, d deanery.Document) error {
+	err := restore(ctx, s.db, "documents", "DOC-", d.ID, `INSERT OR REPLACE INTO documents (`+documentColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.UserID, string(d.Type), d.Status, d.Description, d.Response, d.ResponseFile, d.ResponseBy,
+		toMillis(d.CreatedAt), toMillis(d.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("restore document %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+func (s *libraryService) ExportBooks(ctx context.Context) ([]library.Book, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, title, author, isbn, available FROM books ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("export books: %w", err)
+	}
+	defer rows.Close()
+
+	var books []library.Book
+	for rows.Next() {
+		b, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("export books: %w", err)
+		}
+		books = append(books, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export books: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) ExportLoans(ctx context.Context) ([]library.UserBook, error) {
+	loans, err := s.queryUserBooks(ctx, "1 = 1")
+	if err != nil {
+		return nil, fmt.Errorf("export loans: %w", err)
+	}
+	return loans, nil
+}
+
+func (s *libraryService) RestoreBook(ctx context.Context, b library.Book) error {
+	// UPSERT, а не REPLACE: REPLACE удалил бы строку, на которую ссылаются выдачи
+	_, err := s.db.ExecContext(ctx, `INSERT INTO books (id, title, author, isbn, available) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title, author = excluded.author,
+			isbn = excluded.isbn, available = excluded.available`,
+		b.ID, b.Title, b.Author, b.ISBN, b.Available)
+	if err != nil {
+		return fmt.Errorf("restore book %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+func (s *libraryService) RestoreLoan(ctx context.Context, ub library.UserBook) error {
+	var issuedAt, takenAt sql.NullInt64
+	if ub.IssuedAt != nil {
+		issuedAt = sql.NullInt64{Int64: toMillis(*ub.IssuedAt), Valid: true}
+	}
+	if ub.TakenAt != nil {
+		takenAt = sql.NullInt64{Int64: toMillis(*ub.TakenAt), Valid: true}
+	}
+
+	err := restore(ctx, s.db, "user_books", "UB-", ub.ID, `INSERT OR REPLACE INTO user_books (id, user_id, book_id, user_name,
+		user_surname, status, borrowed_at, return_date, returned, issued_at, taken_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		ub.ID, ub.UserID, ub.BookID, ub.UserName, ub.UserSurname, ub.Status,
+		toMillis(ub.BorrowedAt), toMillis(ub.ReturnDate), ub.Returned, issuedAt, takenAt)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("restore loan %s: книга %s уже выдана по другой записи", ub.ID, ub.BookID)
+	}
+	if err != nil {
+		return fmt.Errorf("restore loan %s: %w", ub.ID, err)
+	}
+	return nil
+}
+
+func (s *businessTripService) ExportTrips(ctx context.Context) ([]businesstrip.Trip, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tripColumns+" FROM trips ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("export trips: %w", err)
+	}
+	defer rows.Close()
+
+	var trips []businesstrip.Trip
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, fmt.Errorf("export trips: %w", err)
+		}
+		trips = append(trips, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export trips: %w", err)
+	}
+	return trips, nil
+}
+
+func (s *businessTripService) RestoreTrip(ctx context.Context, t businesstrip.Trip) error {
+	err := restore(ctx, s.db, "trips", "TRIP-", t.ID, `INSERT OR REPLACE INTO trips (`+tripColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.UserID, t.Destination, t.Purpose, toMillis(t.StartDate), toMillis(t.EndDate), t.Status,
+		toMillis(t.CreatedAt), toMillis(t.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("restore trip %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+func (s *newsService) ExportNews(ctx context.Context) ([]news.News, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+newsColumns+" FROM news ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("export news: %w", err)
+	}
+	defer rows.Close()
+
+	var result []news.News
+	for rows.Next() {
+		var (
+			n         news.News
+			createdAt int64
+		)
+		if err := rows.Scan(&n.ID, &n.Title, &n.Content, &n.AuthorID, &n.Author, &createdAt); err != nil {
+			return nil, fmt.Errorf("export news: %w", err)
+		}
+		n.CreatedAt = fromMillis(createdAt)
+		result = append(result, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export news: %w", err)
+	}
+	return result, nil
+}
+
+func (s *newsService) RestoreNews(ctx context.Context, n news.News) error {
+	err := restore(ctx, s.db, "news", "news-", n.ID, "INSERT OR REPLACE INTO news ("+newsColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+		n.ID, n.Title, n.Content, n.AuthorID, n.Author, toMillis(n.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("restore news %s: %w", n.ID, err)
+	}
+	return nil
+}
+
+func (s *reminderService) ExportReminders(ctx context.Context) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders ORDER BY date_time")
+	if err != nil {
+		return nil, fmt.Errorf("export reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) RestoreReminder(ctx context.Context, r reminder.Reminder) error {
+	// Захват обработчиком не переносится: в новом хранилище напоминание снова свободно
+	err := restore(ctx, s.db, "reminders", "REM-", r.ID, `INSERT OR REPLACE INTO reminders (id, user_id, text, date_time, created_at, status, kind)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, r.ID, r.UserID, r.Text, toMillis(r.DateTime), toMillis(r.CreatedAt), r.Status, r.Kind)
+	if err != nil {
+		return fmt.Errorf("restore reminder %s: %w", r.ID, err)
+	}
+	return nil
+}
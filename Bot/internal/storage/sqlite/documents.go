@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"first-max-bot/internal/services/deanery"
+)
+
+const documentColumns = "id, user_id, type, status, description, response, response_file, response_by, created_at, updated_at"
+
+type deaneryService struct {
+	db *sql.DB
+}
+
+func NewDeaneryService(db *sql.DB) deanery.Service {
+	return &deaneryService{db: db}
+}
+
+func scanDocument(row scanner) (deanery.Document, error) {
+	var (
+		d                    deanery.Document
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(&d.ID, &d.UserID, &d.Type, &d.Status, &d.Description, &d.Response,
+		&d.ResponseFile, &d.ResponseBy, &createdAt, &updatedAt)
+	d.CreatedAt = fromMillis(createdAt)
+	d.UpdatedAt = fromMillis(updatedAt)
+	return d, err
+}
+
+func (s *deaneryService) queryDocuments(ctx context.Context, query string, args ...any) ([]deanery.Document, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []deanery.Document
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (s *deaneryService) CreateDocument(ctx context.Context, userID string, docType deanery.DocumentType, description string) (*deanery.Document, error) {
+	now := time.Now()
+	doc := &deanery.Document{
+		UserID:      userID,
+		Type:        docType,
+		Status:      "pending",
+		Description: description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		id, err := nextID(ctx, tx, "documents", "DOC-")
+		if err != nil {
+			return err
+		}
+		doc.ID = id
+		_, err = tx.ExecContext(ctx, `INSERT INTO documents (id, user_id, type, status, description, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, doc.ID, doc.UserID, string(doc.Type), doc.Status, doc.Description, toMillis(now), toMillis(now))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document: %w", err)
+	}
+	return doc, nil
+}
+
+func (s *deaneryService) GetUserDocuments(ctx context.Context, userID string) ([]deanery.Document, error) {
+	docs, err := s.queryDocuments(ctx, "SELECT "+documentColumns+" FROM documents WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *deaneryService) GetDocument(ctx context.Context, documentID string) (*deanery.Document, error) {
+	doc, err := scanDocument(s.db.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ?", documentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+	return &doc, nil
+}
+
+func (s *deaneryService) GetAllDocuments(ctx context.Context) ([]deanery.Document, error) {
+	docs, err := s.queryDocuments(ctx, "SELECT "+documentColumns+" FROM documents ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get documents: %w", err)
+	}
+	return docs, nil
+}
+
+func (s *deaneryService) AddDocumentResponse(ctx context.Context, documentID, response, responseFile, responseBy string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE documents SET response = ?, response_file = ?, response_by = ?,
+			status = 'completed', updated_at = ?
+		WHERE id = ?`, response, responseFile, responseBy, toMillis(time.Now()), documentID)
+	if err != nil {
+		return fmt.Errorf("add document response: %w", err)
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return fmt.Errorf("document not found")
+	}
+	return nil
+}
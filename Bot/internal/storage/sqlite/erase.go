@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// Удаление и обезличивание данных по запросу пользователя (интерфейсы Eraser сервисов)
+
+func (s *userService) DeleteUser(ctx context.Context, userID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM users WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
+
+func (s *supportService) DeleteUserTickets(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM tickets WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("delete user tickets: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *deaneryService) DeleteUserDocuments(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM documents WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("delete user documents: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *businessTripService) DeleteUserTrips(ctx context.Context, userID string) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM trips WHERE user_id = ?", userID)
+	if err != nil {
+		return 0, fmt.Errorf("delete user trips: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *libraryService) AnonymizeUserLoans(ctx context.Context, userID, pseudonym string) (int, error) {
+	result, err := s.db.ExecContext(ctx, "UPDATE user_books SET user_id = ?2, user_name = '', user_surname = '' WHERE user_id = ?1", userID, pseudonym)
+	if err != nil {
+		return 0, fmt.Errorf("anonymize user loans: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *newsService) AnonymizeAuthor(ctx context.Context, authorID, pseudonym, name string) (int, error) {
+	result, err := s.db.ExecContext(ctx, "UPDATE news SET author_id = ?2, author = ?3 WHERE author_id = ?1", authorID, pseudonym, name)
+	if err != nil {
+		return 0, fmt.Errorf("anonymize news author: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
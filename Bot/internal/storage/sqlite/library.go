@@ -0,0 +1,250 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"first-max-bot/internal/services/library"
+)
+
+const userBookColumns = `ub.id, ub.user_id, ub.book_id, ub.user_name, ub.user_surname, ub.status, ub.borrowed_at,
+	ub.return_date, ub.returned, ub.issued_at, ub.taken_at, b.id, b.title, b.author, b.isbn, b.available`
+
+// activeStatuses - статусы, при которых книга занята
+const activeStatuses = "('requested', 'issued', 'taken')"
+
+type libraryService struct {
+	db *sql.DB
+}
+
+func NewLibraryService(db *sql.DB) library.Service {
+	return &libraryService{db: db}
+}
+
+func scanBook(row scanner) (library.Book, error) {
+	var b library.Book
+	err := row.Scan(&b.ID, &b.Title, &b.Author, &b.ISBN, &b.Available)
+	return b, err
+}
+
+func scanUserBook(row scanner) (library.UserBook, error) {
+	var (
+		ub                     library.UserBook
+		book                   library.Book
+		borrowedAt, returnDate int64
+		issuedAt, takenAt      sql.NullInt64
+	)
+	err := row.Scan(&ub.ID, &ub.UserID, &ub.BookID, &ub.UserName, &ub.UserSurname, &ub.Status, &borrowedAt,
+		&returnDate, &ub.Returned, &issuedAt, &takenAt, &book.ID, &book.Title, &book.Author, &book.ISBN, &book.Available)
+	if err != nil {
+		return library.UserBook{}, err
+	}
+	ub.BorrowedAt = fromMillis(borrowedAt)
+	ub.ReturnDate = fromMillis(returnDate)
+	ub.IssuedAt = timePtr(issuedAt)
+	ub.TakenAt = timePtr(takenAt)
+	ub.Book = &book
+	return ub, nil
+}
+
+func (s *libraryService) queryUserBooks(ctx context.Context, where string, args ...any) ([]library.UserBook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userBookColumns+" FROM user_books ub JOIN books b ON b.id = ub.book_id WHERE "+where+" ORDER BY ub.borrowed_at", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []library.UserBook
+	for rows.Next() {
+		ub, err := scanUserBook(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ub)
+	}
+	return result, rows.Err()
+}
+
+func (s *libraryService) SearchBooks(ctx context.Context, query string) ([]library.Book, error) {
+	// Книга доступна, если на нее нет активного запроса, выдачи или она не на руках
+	rows, err := s.db.QueryContext(ctx, `SELECT b.id, b.title, b.author, b.isbn, b.available FROM books b
+		WHERE NOT EXISTS (SELECT 1 FROM user_books ub WHERE ub.book_id = b.id AND ub.returned = 0 AND ub.status IN `+activeStatuses+`)
+		ORDER BY b.title`)
+	if err != nil {
+		return nil, fmt.Errorf("search books: %w", err)
+	}
+	defer rows.Close()
+
+	// lower() в SQLite понимает только латиницу, поэтому запрос сравнивается в Go.
+	// Пустой запрос возвращает все доступные книги
+	query = strings.ToLower(strings.TrimSpace(query))
+	var books []library.Book
+	for rows.Next() {
+		b, err := scanBook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("search books: %w", err)
+		}
+		if query == "" || strings.Contains(strings.ToLower(b.Title), query) || strings.Contains(strings.ToLower(b.Author), query) {
+			books = append(books, b)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search books: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) GetBookByID(ctx context.Context, bookID string) (*library.Book, error) {
+	b, err := scanBook(s.db.QueryRowContext(ctx, "SELECT id, title, author, isbn, available FROM books WHERE id = ?", bookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("book not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get book: %w", err)
+	}
+	return &b, nil
+}
+
+func (s *libraryService) BorrowBook(ctx context.Context, userID, userName, userSurname, bookID string) (*library.UserBook, error) {
+	now := time.Now()
+	userBook := &library.UserBook{
+		UserID:      userID,
+		BookID:      bookID,
+		UserName:    userName,
+		UserSurname: userSurname,
+		Status:      "requested",
+		BorrowedAt:  now,
+		ReturnDate:  now.Add(30 * 24 * time.Hour), // 30 дней
+	}
+
+	// Транзакция начинается с блокировкой на запись, поэтому проверка доступности и заказ атомарны
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		book, err := scanBook(tx.QueryRowContext(ctx, "SELECT id, title, author, isbn, available FROM books WHERE id = ?", bookID))
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !book.Available) {
+			return fmt.Errorf("книга недоступна")
+		}
+		if err != nil {
+			return err
+		}
+
+		userBook.ID, err = nextID(ctx, tx, "user_books", "UB-")
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_books (id, user_id, book_id, user_name, user_surname, status, borrowed_at, return_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, userBook.ID, userID, bookID, userName, userSurname, userBook.Status,
+			toMillis(userBook.BorrowedAt), toMillis(userBook.ReturnDate))
+		if isUniqueViolation(err) {
+			return fmt.Errorf("книга недоступна")
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE books SET available = 0 WHERE id = ?", bookID); err != nil {
+			return err
+		}
+		book.Available = false
+		userBook.Book = &book
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return userBook, nil
+}
+
+func (s *libraryService) GetUserBooks(ctx context.Context, userID string) ([]library.UserBook, error) {
+	// Возвращенные книги не показываются
+	books, err := s.queryUserBooks(ctx, "ub.user_id = ? AND ub.returned = 0", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user books: %w", err)
+	}
+	return books, nil
+}
+
+// returnBook закрывает запись о выдаче и снова делает книгу доступной
+func returnBook(ctx context.Context, tx *sql.Tx, userID, bookID, status string) (bool, error) {
+	result, err := tx.ExecContext(ctx, `UPDATE user_books SET status = 'returned', returned = 1
+		WHERE user_id = ?1 AND book_id = ?2 AND returned = 0 AND (?3 = '' OR status = ?3)`, userID, bookID, status)
+	if err != nil {
+		return false, err
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE books SET available = 1 WHERE id = ?", bookID)
+	return err == nil, err
+}
+
+func (s *libraryService) ReturnBook(ctx context.Context, userID, bookID string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		found, err := returnBook(ctx, tx, userID, bookID, "")
+		if err != nil {
+			return fmt.Errorf("return book: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("книга не найдена")
+		}
+		return nil
+	})
+}
+
+func (s *libraryService) GetAllRequests(ctx context.Context) ([]library.UserBook, error) {
+	books, err := s.queryUserBooks(ctx, "ub.returned = 0 AND ub.status IN "+activeStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("get book requests: %w", err)
+	}
+	return books, nil
+}
+
+func (s *libraryService) IssueBook(ctx context.Context, userID string, bookID string) (*library.UserBook, error) {
+	var id string
+	err := s.db.QueryRowContext(ctx, `UPDATE user_books SET status = 'issued', issued_at = ?
+		WHERE user_id = ? AND book_id = ? AND status = 'requested'
+		RETURNING id`, toMillis(time.Now()), userID, bookID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("request not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("issue book: %w", err)
+	}
+
+	books, err := s.queryUserBooks(ctx, "ub.id = ?", id)
+	if err != nil {
+		return nil, fmt.Errorf("issue book: %w", err)
+	}
+	if len(books) == 0 {
+		return nil, fmt.Errorf("request not found")
+	}
+	return &books[0], nil
+}
+
+func (s *libraryService) MarkBookTaken(ctx context.Context, userID string, bookID string) error {
+	result, err := s.db.ExecContext(ctx, `UPDATE user_books SET status = 'taken', taken_at = ?
+		WHERE user_id = ? AND book_id = ? AND status = 'issued'`, toMillis(time.Now()), userID, bookID)
+	if err != nil {
+		return fmt.Errorf("mark book taken: %w", err)
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return fmt.Errorf("issued book not found")
+	}
+	return nil
+}
+
+func (s *libraryService) MarkBookReturned(ctx context.Context, userID string, bookID string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		found, err := returnBook(ctx, tx, userID, bookID, "taken")
+		if err != nil {
+			return fmt.Errorf("mark book returned: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("taken book not found")
+		}
+		return nil
+	})
+}
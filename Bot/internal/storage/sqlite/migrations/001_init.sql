@@ -0,0 +1,117 @@
+-- Время хранится в миллисекундах Unix: так сравнения в SQL не зависят от формата и часового пояса
+
+-- Счетчики для номеров обращений, заявлений и т.д. (аналог последовательностей PostgreSQL)
+CREATE TABLE sequences (
+    name  TEXT PRIMARY KEY,
+    value INTEGER NOT NULL
+);
+
+CREATE TABLE users (
+    user_id      TEXT PRIMARY KEY,
+    first_name   TEXT NOT NULL DEFAULT '',
+    last_name    TEXT NOT NULL DEFAULT '',
+    age          INTEGER NOT NULL DEFAULT 0,
+    gender       TEXT NOT NULL DEFAULT '',
+    email        TEXT NOT NULL DEFAULT '',
+    roles        TEXT NOT NULL, -- JSON-массив ролей
+    active_role  TEXT NOT NULL DEFAULT '',
+    moodle_token TEXT NOT NULL DEFAULT '',
+    created_at   INTEGER NOT NULL,
+    updated_at   INTEGER NOT NULL
+);
+
+CREATE TABLE tickets (
+    id          TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL,
+    department  TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    message     TEXT NOT NULL,
+    response    TEXT NOT NULL DEFAULT '',
+    response_by TEXT NOT NULL DEFAULT '',
+    user_reply  TEXT NOT NULL DEFAULT '',
+    status      TEXT NOT NULL,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL
+);
+
+CREATE INDEX tickets_user_id_idx ON tickets (user_id);
+
+CREATE TABLE documents (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL,
+    type          TEXT NOT NULL,
+    status        TEXT NOT NULL,
+    description   TEXT NOT NULL,
+    response      TEXT NOT NULL DEFAULT '',
+    response_file TEXT NOT NULL DEFAULT '',
+    response_by   TEXT NOT NULL DEFAULT '',
+    created_at    INTEGER NOT NULL,
+    updated_at    INTEGER NOT NULL
+);
+
+CREATE INDEX documents_user_id_idx ON documents (user_id);
+
+CREATE TABLE books (
+    id        TEXT PRIMARY KEY,
+    title     TEXT NOT NULL,
+    author    TEXT NOT NULL,
+    isbn      TEXT NOT NULL DEFAULT '',
+    available INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE user_books (
+    id           TEXT PRIMARY KEY,
+    user_id      TEXT NOT NULL,
+    book_id      TEXT NOT NULL REFERENCES books (id),
+    user_name    TEXT NOT NULL DEFAULT '',
+    user_surname TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL,
+    borrowed_at  INTEGER NOT NULL,
+    return_date  INTEGER NOT NULL,
+    returned     INTEGER NOT NULL DEFAULT 0,
+    issued_at    INTEGER,
+    taken_at     INTEGER
+);
+
+-- Один экземпляр книги не может быть на руках у двух читателей
+CREATE UNIQUE INDEX user_books_active_book_idx ON user_books (book_id) WHERE returned = 0;
+CREATE INDEX user_books_user_id_idx ON user_books (user_id);
+
+CREATE TABLE trips (
+    id          TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL,
+    destination TEXT NOT NULL,
+    purpose     TEXT NOT NULL,
+    start_date  INTEGER NOT NULL,
+    end_date    INTEGER NOT NULL,
+    status      TEXT NOT NULL,
+    created_at  INTEGER NOT NULL,
+    updated_at  INTEGER NOT NULL
+);
+
+CREATE INDEX trips_user_id_idx ON trips (user_id);
+
+CREATE TABLE news (
+    id         TEXT PRIMARY KEY,
+    title      TEXT NOT NULL,
+    content    TEXT NOT NULL,
+    author_id  TEXT NOT NULL,
+    author     TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX news_created_at_idx ON news (created_at);
+
+CREATE TABLE reminders (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL,
+    text          TEXT NOT NULL,
+    date_time     INTEGER NOT NULL,
+    created_at    INTEGER NOT NULL,
+    status        TEXT NOT NULL,
+    claimed_by    TEXT NOT NULL DEFAULT '',
+    claimed_until INTEGER
+);
+
+CREATE INDEX reminders_user_id_idx ON reminders (user_id);
+CREATE INDEX reminders_due_idx ON reminders (date_time) WHERE status = 'active';
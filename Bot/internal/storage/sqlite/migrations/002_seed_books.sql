@@ -0,0 +1,7 @@
+-- Стартовый каталог библиотеки (тот же, что в mock)
+INSERT INTO books (id, title, author, isbn) VALUES
+    ('1', 'Введение в алгоритмы', 'Томас Кормен', '978-5-8459-0857-4'),
+    ('2', 'Чистый код', 'Роберт Мартин', '978-5-4461-0772-1'),
+    ('3', 'Архитектура компьютера', 'Эндрю Таненбаум', '978-5-4461-1234-3'),
+    ('4', 'Дизайн паттерны', 'Gang of Four', '978-5-459-00401-2')
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"first-max-bot/internal/services/news"
+)
+
+const newsColumns = "id, title, content, author_id, author, created_at"
+
+type newsService struct {
+	db *sql.DB
+}
+
+func NewNewsService(db *sql.DB) news.Service {
+	return &newsService{db: db}
+}
+
+func (s *newsService) GetLatestNews(ctx context.Context, count int) ([]news.News, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+newsColumns+" FROM news ORDER BY created_at DESC LIMIT ?", count)
+	if err != nil {
+		return nil, fmt.Errorf("get latest news: %w", err)
+	}
+	defer rows.Close()
+
+	result := []news.News{}
+	for rows.Next() {
+		var (
+			n         news.News
+			createdAt int64
+		)
+		if err := rows.Scan(&n.ID, &n.Title, &n.Content, &n.AuthorID, &n.Author, &createdAt); err != nil {
+			return nil, fmt.Errorf("get latest news: %w", err)
+		}
+		n.CreatedAt = fromMillis(createdAt)
+		result = append(result, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get latest news: %w", err)
+	}
+	return result, nil
+}
+
+func (s *newsService) CreateNews(ctx context.Context, title, content, authorID, author string) (*news.News, error) {
+	n := &news.News{
+		Title:     title,
+		Content:   content,
+		AuthorID:  authorID,
+		Author:    author,
+		CreatedAt: time.Now(),
+	}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		id, err := nextID(ctx, tx, "news", "news-")
+		if err != nil {
+			return err
+		}
+		n.ID = id
+		_, err = tx.ExecContext(ctx, "INSERT INTO news ("+newsColumns+") VALUES (?, ?, ?, ?, ?, ?)",
+			n.ID, n.Title, n.Content, n.AuthorID, n.Author, toMillis(n.CreatedAt))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create news: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"first-max-bot/internal/services/reminder"
+)
+
+const reminderColumns = "id, user_id, text, date_time, created_at, status, kind, claimed_by, claimed_until"
+
+type reminderService struct {
+	db *sql.DB
+}
+
+func NewReminderService(db *sql.DB) reminder.Service {
+	return &reminderService{db: db}
+}
+
+func scanReminder(row scanner) (reminder.Reminder, error) {
+	var (
+		r                   reminder.Reminder
+		dateTime, createdAt int64
+		claimedUntil        sql.NullInt64
+	)
+	err := row.Scan(&r.ID, &r.UserID, &r.Text, &dateTime, &createdAt, &r.Status, &r.Kind, &r.ClaimedBy, &claimedUntil)
+	r.DateTime = fromMillis(dateTime)
+	r.CreatedAt = fromMillis(createdAt)
+	if claimedUntil.Valid {
+		r.ClaimedUntil = fromMillis(claimedUntil.Int64)
+	}
+	return r, err
+}
+
+func (s *reminderService) queryReminders(ctx context.Context, query string, args ...any) ([]reminder.Reminder, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []reminder.Reminder
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func (s *reminderService) CreateReminder(ctx context.Context, userID, text string, dateTime time.Time) (*reminder.Reminder, error) {
+	return s.CreateGeneratedReminder(ctx, reminder.KindManual, userID, text, dateTime)
+}
+
+func (s *reminderService) CreateGeneratedReminder(ctx context.Context, kind, userID, text string, dateTime time.Time) (*reminder.Reminder, error) {
+	r := &reminder.Reminder{
+		UserID:    userID,
+		Text:      text,
+		DateTime:  dateTime,
+		CreatedAt: time.Now(),
+		Status:    "active",
+		Kind:      kind,
+	}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		id, err := nextID(ctx, tx, "reminders", "REM-")
+		if err != nil {
+			return err
+		}
+		r.ID = id
+		_, err = tx.ExecContext(ctx, `INSERT INTO reminders (id, user_id, text, date_time, created_at, status, kind)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`, r.ID, userID, text, toMillis(dateTime), toMillis(r.CreatedAt), r.Status, r.Kind)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create reminder: %w", err)
+	}
+	return r, nil
+}
+
+func (s *reminderService) GetUserReminders(ctx context.Context, userID string) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE user_id = ? ORDER BY date_time", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) GetActiveReminders(ctx context.Context, userID string) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE user_id = ? AND status = 'active' ORDER BY date_time", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get active reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) GetAllActiveReminders(ctx context.Context) ([]reminder.Reminder, error) {
+	reminders, err := s.queryReminders(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE status = 'active' ORDER BY date_time")
+	if err != nil {
+		return nil, fmt.Errorf("get all active reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s *reminderService) DeleteReminder(ctx context.Context, reminderID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM reminders WHERE id = ?", reminderID); err != nil {
+		return fmt.Errorf("delete reminder: %w", err)
+	}
+	return nil
+}
+
+func (s *reminderService) GetReminderByID(ctx context.Context, reminderID string) (*reminder.Reminder, error) {
+	r, err := scanReminder(s.db.QueryRowContext(ctx, "SELECT "+reminderColumns+" FROM reminders WHERE id = ?", reminderID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("reminder not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reminder: %w", err)
+	}
+	return &r, nil
+}
+
+// updateReminder выполняет UPDATE и возвращает "reminder not found", если напоминания нет
+func (s *reminderService) updateReminder(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update reminder: %w", err)
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return fmt.Errorf("reminder not found")
+	}
+	return nil
+}
+
+func (s *reminderService) MarkReminderCompleted(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, "UPDATE reminders SET status = 'completed', claimed_by = '', claimed_until = NULL WHERE id = ?", reminderID)
+}
+
+func (s *reminderService) ClaimDueReminders(ctx context.Context, now time.Time, owner string, lease time.Duration) ([]reminder.Reminder, error) {
+	// Один UPDATE атомарен: запись в SQLite выполняется одним писателем
+	claimed, err := s.queryReminders(ctx, `UPDATE reminders SET claimed_by = ?2, claimed_until = ?3
+		WHERE status = 'active' AND date_time <= ?1 AND (claimed_until IS NULL OR claimed_until <= ?1)
+		RETURNING `+reminderColumns, toMillis(now), owner, toMillis(now.Add(lease)))
+	if err != nil {
+		return nil, fmt.Errorf("claim due reminders: %w", err)
+	}
+
+	sort.Slice(claimed, func(i, j int) bool {
+		return claimed[i].DateTime.Before(claimed[j].DateTime)
+	})
+	return claimed, nil
+}
+
+func (s *reminderService) ReleaseReminder(ctx context.Context, reminderID string) error {
+	return s.updateReminder(ctx, "UPDATE reminders SET claimed_by = '', claimed_until = NULL WHERE id = ?", reminderID)
+}
@@ -0,0 +1,202 @@
+// Package sqlite хранит данные сервисов в одном файле SQLite - для небольших установок без PostgreSQL.
+// Реализации повторяют пакет postgres
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type options struct {
+	busyTimeout  time.Duration
+	maxOpenConns int
+}
+
+type Option func(*options)
+
+// WithBusyTimeout задает, сколько запись ждет освобождения блокировки базы
+func WithBusyTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.busyTimeout = d
+	}
+}
+
+// WithMaxOpenConns ограничивает число открытых соединений
+func WithMaxOpenConns(n int) Option {
+	return func(o *options) {
+		o.maxOpenConns = n
+	}
+}
+
+// Open открывает (или создает) файл базы в режиме WAL: чтение не блокируется записью,
+// а копию можно снять, не останавливая бота.
+// Транзакции начинаются с блокировкой на запись (BEGIN IMMEDIATE), чтобы конкурирующие записи ждали,
+// а не падали с SQLITE_BUSY при повышении блокировки
+func Open(ctx context.Context, path string, opts ...Option) (*sql.DB, error) {
+	o := options{
+		busyTimeout:  5 * time.Second,
+		maxOpenConns: 4,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	query := url.Values{}
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", o.busyTimeout.Milliseconds()))
+	query.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(o.maxOpenConns)
+	db.SetMaxIdleConns(o.maxOpenConns)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Migrate применяет встроенные миграции, которые еще не применены. Каждая миграция выполняется
+// в отдельной транзакции вместе с записью в schema_migrations
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		script, err := migrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		// Проверка выполняется внутри транзакции: BEGIN IMMEDIATE не пустит второй процесс,
+		// пока первый не применит миграцию
+		err = withTx(ctx, db, func(tx *sql.Tx) error {
+			var applied int
+			if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&applied); err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)", version, toMillis(time.Now()))
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("apply migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// Backup делает согласованную копию базы в файл dest (VACUUM INTO). Бот при этом может продолжать работу:
+// копия соответствует моменту начала операции. Файл dest не должен существовать
+func Backup(ctx context.Context, db *sql.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup file %s already exists", dest)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dest); err != nil {
+		return fmt.Errorf("backup sqlite to %s: %w", dest, err)
+	}
+	return nil
+}
+
+// withTx выполняет fn в транзакции: commit, если fn вернула nil, иначе rollback
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// execer - общее у *sql.DB и *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner - общее у *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// nextID выдает следующий номер вида "<prefix><n>" из счетчика name
+func nextID(ctx context.Context, q execer, name, prefix string) (string, error) {
+	var value int64
+	err := q.QueryRowContext(ctx, `INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = value + 1
+		RETURNING value`, name).Scan(&value)
+	if err != nil {
+		return "", fmt.Errorf("next %s id: %w", name, err)
+	}
+	return fmt.Sprintf("%s%d", prefix, value), nil
+}
+
+// affected возвращает false, если запрос не изменил ни одной строки
+func affected(result sql.Result) (bool, error) {
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// isUniqueViolation проверяет, что ошибка - нарушение уникального индекса
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.UnixMilli(ms)
+}
+
+// timePtr переводит nullable колонку в необязательное время
+func timePtr(ms sql.NullInt64) *time.Time {
+	if !ms.Valid {
+		return nil
+	}
+	t := fromMillis(ms.Int64)
+	return &t
+}
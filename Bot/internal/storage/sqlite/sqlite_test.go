@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"first-max-bot/internal/services/support"
+)
+
+// newTestDB открывает новую базу во временном каталоге теста
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newMigratedDB открывает новую базу с примененными миграциями
+func newMigratedDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := newTestDB(t)
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func appliedMigrations(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&n); err != nil {
+		t.Fatalf("count migrations: %v", err)
+	}
+	return n
+}
+
+func TestMigrateTwice(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDB(t)
+
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := appliedMigrations(t, db); got != len(files) {
+		t.Fatalf("applied migrations = %d, want %d", got, len(files))
+	}
+
+	// Повторный запуск ничего не применяет и данные не трогает
+	var books int
+	if err := db.QueryRow("SELECT COUNT(*) FROM books").Scan(&books); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+	if got := appliedMigrations(t, db); got != len(files) {
+		t.Errorf("applied migrations after rerun = %d, want %d", got, len(files))
+	}
+	var again int
+	if err := db.QueryRow("SELECT COUNT(*) FROM books").Scan(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again != books {
+		t.Errorf("books after rerun = %d, want %d", again, books)
+	}
+}
+
+func TestTicketIDSequence(t *testing.T) {
+	ctx := context.Background()
+	service := NewSupportService(newMigratedDB(t))
+
+	create := func() string {
+		t.Helper()
+		ticket, err := service.CreateTicket(ctx, "100", "Тема", "Текст")
+		if err != nil {
+			t.Fatalf("CreateTicket: %v", err)
+		}
+		return ticket.ID
+	}
+	if first, second := create(), create(); first != "DOE-1" || second != "DOE-2" {
+		t.Fatalf("ids = %s, %s, want DOE-1, DOE-2", first, second)
+	}
+
+	// Восстановленный номер сдвигает счетчик, меньший - не возвращает назад
+	now := time.Now()
+	for _, id := range []string{"DOE-41", "DOE-5", "DOE-20261019-0123456789ab"} {
+		err := service.(support.Archiver).RestoreTicket(ctx, support.Ticket{
+			ID: id, UserID: "100", Department: "Department of Education", Subject: "Восстановленное",
+			Message: "Текст", Status: "received", CreatedAt: now, UpdatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("RestoreTicket %s: %v", id, err)
+		}
+	}
+	if id := create(); id != "DOE-42" {
+		t.Errorf("id after restore = %s, want DOE-42", id)
+	}
+
+	// Параллельные записи получают разные номера
+	var (
+		mu  sync.Mutex
+		ids = make(map[string]bool)
+		wg  sync.WaitGroup
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticket, err := service.CreateTicket(ctx, "100", "Тема", "Текст")
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				t.Errorf("CreateTicket: %v", err)
+				return
+			}
+			if ids[ticket.ID] {
+				t.Errorf("id %s issued twice", ticket.ID)
+			}
+			ids[ticket.ID] = true
+		}()
+	}
+	wg.Wait()
+	if len(ids) != 20 {
+		t.Errorf("unique ids = %d, want 20", len(ids))
+	}
+}
+
+func TestClaimDueReminders(t *testing.T) {
+	ctx := context.Background()
+	service := NewReminderService(newMigratedDB(t))
+
+	now := time.Now()
+	first, err := service.CreateReminder(ctx, "100", "Первое", now.Add(-2*time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	second, err := service.CreateReminder(ctx, "100", "Второе", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+	if _, err := service.CreateReminder(ctx, "100", "Будущее", now.Add(time.Hour)); err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+
+	claimed, err := service.ClaimDueReminders(ctx, now, "a", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != first.ID || claimed[1].ID != second.ID || claimed[0].ClaimedBy != "a" {
+		t.Fatalf("claimed = %+v, want %s and %s by a", claimed, first.ID, second.ID)
+	}
+
+	// Пока захват действует, другой экземпляр ничего не получает
+	claimed, err = service.ClaimDueReminders(ctx, now.Add(30*time.Second), "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("claimed under lease = %+v, want none", claimed)
+	}
+
+	// Освобожденное захватывается сразу, выполненное - никогда
+	if err := service.ReleaseReminder(ctx, second.ID); err != nil {
+		t.Fatalf("ReleaseReminder: %v", err)
+	}
+	if err := service.MarkReminderCompleted(ctx, first.ID); err != nil {
+		t.Fatalf("MarkReminderCompleted: %v", err)
+	}
+	claimed, err = service.ClaimDueReminders(ctx, now.Add(30*time.Second), "b", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != second.ID || claimed[0].ClaimedBy != "b" {
+		t.Fatalf("claimed after release = %+v, want only %s by b", claimed, second.ID)
+	}
+
+	// Захват истек: напоминание снова доступно
+	claimed, err = service.ClaimDueReminders(ctx, now.Add(2*time.Minute), "c", time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueReminders: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != second.ID || claimed[0].ClaimedBy != "c" {
+		t.Errorf("claimed after lease = %+v, want only %s by c", claimed, second.ID)
+	}
+
+	if err := service.ReleaseReminder(ctx, "REM-999"); err == nil {
+		t.Error("ReleaseReminder of unknown reminder: want error")
+	}
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDB(t)
+	if _, err := NewReminderService(db).CreateReminder(ctx, "100", "Напоминание", time.Now()); err != nil {
+		t.Fatalf("CreateReminder: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := Backup(ctx, db, dest); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	copied, err := Open(ctx, dest)
+	if err != nil {
+		t.Fatalf("open backup: %v", err)
+	}
+	defer copied.Close()
+	if got, err := NewReminderService(copied).GetActiveReminders(ctx, "100"); err != nil || len(got) != 1 {
+		t.Errorf("reminders in backup = %+v, %v, want 1", got, err)
+	}
+
+	// Существующий файл не перезаписывается
+	existing := filepath.Join(t.TempDir(), "existing.db")
+	if err := os.WriteFile(existing, []byte("старая копия"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Backup(ctx, db, existing); err == nil {
+		t.Fatal("Backup to existing file: want error")
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "старая копия" {
+		t.Errorf("existing file changed: %q", data)
+	}
+}
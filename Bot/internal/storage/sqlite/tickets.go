@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"first-max-bot/internal/services/support"
+)
+
+const ticketColumns = "id, user_id, department, subject, message, response, response_by, user_reply, status, created_at, updated_at"
+
+type supportService struct {
+	db *sql.DB
+}
+
+func NewSupportService(db *sql.DB) support.Service {
+	return &supportService{db: db}
+}
+
+func scanTicket(row scanner) (support.Ticket, error) {
+	var (
+		t                    support.Ticket
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(&t.ID, &t.UserID, &t.Department, &t.Subject, &t.Message, &t.Response,
+		&t.ResponseBy, &t.UserReply, &t.Status, &createdAt, &updatedAt)
+	t.CreatedAt = fromMillis(createdAt)
+	t.UpdatedAt = fromMillis(updatedAt)
+	return t, err
+}
+
+func (s *supportService) queryTickets(ctx context.Context, query string, args ...any) ([]support.Ticket, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []support.Ticket
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+func (s *supportService) CreateTicket(ctx context.Context, userID, subject, message string) (support.Ticket, error) {
+	now := time.Now()
+	ticket := support.Ticket{
+		UserID:     userID,
+		Department: "Department of Education",
+		Subject:    subject,
+		Message:    message,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Status:     "received",
+	}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		id, err := nextID(ctx, tx, "tickets", "DOE-")
+		if err != nil {
+			return err
+		}
+		ticket.ID = id
+		_, err = tx.ExecContext(ctx, `INSERT INTO tickets (id, user_id, department, subject, message, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, ticket.ID, ticket.UserID, ticket.Department, ticket.Subject, ticket.Message,
+			ticket.Status, toMillis(now), toMillis(now))
+		return err
+	})
+	if err != nil {
+		return support.Ticket{}, fmt.Errorf("create ticket: %w", err)
+	}
+	return ticket, nil
+}
+
+func (s *supportService) GetTicket(ctx context.Context, ticketID string) (*support.Ticket, error) {
+	ticket, err := scanTicket(s.db.QueryRowContext(ctx, "SELECT "+ticketColumns+" FROM tickets WHERE id = ?", ticketID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get ticket: %w", err)
+	}
+	return &ticket, nil
+}
+
+func (s *supportService) GetAllTickets(ctx context.Context) ([]support.Ticket, error) {
+	tickets, err := s.queryTickets(ctx, "SELECT "+ticketColumns+" FROM tickets ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get tickets: %w", err)
+	}
+	return tickets, nil
+}
+
+func (s *supportService) GetUserTickets(ctx context.Context, userID string) ([]support.Ticket, error) {
+	tickets, err := s.queryTickets(ctx, "SELECT "+ticketColumns+" FROM tickets WHERE user_id = ? ORDER BY created_at", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user tickets: %w", err)
+	}
+	return tickets, nil
+}
+
+// updateTicket выполняет UPDATE и возвращает "ticket not found", если обращения нет
+func (s *supportService) updateTicket(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("update ticket: %w", err)
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return fmt.Errorf("ticket not found")
+	}
+	return nil
+}
+
+func (s *supportService) UpdateTicketStatus(ctx context.Context, ticketID, status string) error {
+	return s.updateTicket(ctx, "UPDATE tickets SET status = ?, updated_at = ? WHERE id = ?", status, toMillis(time.Now()), ticketID)
+}
+
+func (s *supportService) AddResponse(ctx context.Context, ticketID, response, responseBy string) error {
+	// Статус "answered" - есть ответ, но тикет еще не закрыт
+	return s.updateTicket(ctx, `UPDATE tickets SET response = ?, response_by = ?, status = 'answered', updated_at = ?
+		WHERE id = ?`, response, responseBy, toMillis(time.Now()), ticketID)
+}
+
+func (s *supportService) AddUserReply(ctx context.Context, ticketID, reply string) error {
+	// Новый ответ пользователя дописывается к предыдущим; статус "in_progress" - ждем ответа руководителя
+	return s.updateTicket(ctx, `UPDATE tickets SET
+			user_reply = CASE WHEN user_reply = '' THEN ?1 ELSE user_reply || char(10, 10) || '---' || char(10, 10) || ?1 END,
+			status = 'in_progress', updated_at = ?2
+		WHERE id = ?3`, reply, toMillis(time.Now()), ticketID)
+}
@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"first-max-bot/internal/services/businesstrip"
+)
+
+const tripColumns = "id, user_id, destination, purpose, start_date, end_date, status, created_at, updated_at"
+
+type businessTripService struct {
+	db *sql.DB
+}
+
+func NewBusinessTripService(db *sql.DB) businesstrip.Service {
+	return &businessTripService{db: db}
+}
+
+func scanTrip(row scanner) (businesstrip.Trip, error) {
+	var (
+		t                                      businesstrip.Trip
+		startDate, endDate, createdAt, updated int64
+	)
+	err := row.Scan(&t.ID, &t.UserID, &t.Destination, &t.Purpose, &startDate, &endDate, &t.Status, &createdAt, &updated)
+	t.StartDate = fromMillis(startDate)
+	t.EndDate = fromMillis(endDate)
+	t.CreatedAt = fromMillis(createdAt)
+	t.UpdatedAt = fromMillis(updated)
+	return t, err
+}
+
+func (s *businessTripService) CreateTrip(ctx context.Context, userID, destination, purpose string, startDate, endDate time.Time) (*businesstrip.Trip, error) {
+	now := time.Now()
+	trip := &businesstrip.Trip{
+		UserID:      userID,
+		Destination: destination,
+		Purpose:     purpose,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Status:      "pending",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		id, err := nextID(ctx, tx, "trips", "TRIP-")
+		if err != nil {
+			return err
+		}
+		trip.ID = id
+		_, err = tx.ExecContext(ctx, `INSERT INTO trips (id, user_id, destination, purpose, start_date, end_date, status, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, trip.ID, userID, destination, purpose,
+			toMillis(startDate), toMillis(endDate), trip.Status, toMillis(now), toMillis(now))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create trip: %w", err)
+	}
+	return trip, nil
+}
+
+func (s *businessTripService) GetUserTrips(ctx context.Context, userID string) ([]businesstrip.Trip, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE user_id = ? ORDER BY start_date", userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user trips: %w", err)
+	}
+	defer rows.Close()
+
+	var trips []businesstrip.Trip
+	for rows.Next() {
+		t, err := scanTrip(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get user trips: %w", err)
+		}
+		trips = append(trips, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get user trips: %w", err)
+	}
+	return trips, nil
+}
+
+func (s *businessTripService) GetTrip(ctx context.Context, tripID string) (*businesstrip.Trip, error) {
+	trip, err := scanTrip(s.db.QueryRowContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE id = ?", tripID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get trip: %w", err)
+	}
+	return &trip, nil
+}
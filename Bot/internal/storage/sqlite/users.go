@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"first-max-bot/internal/secret"
+	"first-max-bot/internal/services/user"
+)
+
+const userColumns = "user_id, first_name, last_name, age, gender, email, roles, active_role, study_group, moodle_token, created_at, updated_at"
+
+type userService struct {
+	db *sql.DB
+}
+
+func NewUserService(db *sql.DB) user.Service {
+	return &userService{db: db}
+}
+
+func scanUser(row scanner) (*user.User, error) {
+	var (
+		u                    user.User
+		roles                string
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(&u.UserID, &u.FirstName, &u.LastName, &u.Age, &u.Gender, &u.Email,
+		&roles, &u.ActiveRole, &u.StudyGroup, &u.MoodleToken, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(roles), &u.Roles); err != nil {
+		return nil, fmt.Errorf("decode roles of user %s: %w", u.UserID, err)
+	}
+
+	u.ID = u.UserID
+	if len(u.Roles) > 0 {
+		u.Role = u.Roles[0]
+	}
+	u.CreatedAt = fromMillis(createdAt)
+	u.UpdatedAt = fromMillis(updatedAt)
+	return &u, nil
+}
+
+func encodeRoles(roles []user.Role) string {
+	if roles == nil {
+		roles = []user.Role{}
+	}
+	data, _ := json.Marshal(roles)
+	return string(data)
+}
+
+func (s *userService) GetUserByID(ctx context.Context, userID string) (*user.User, error) {
+	u, err := scanUser(s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE user_id = ?", userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Пользователь не найден
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *userService) CreateUser(ctx context.Context, u user.User) (*user.User, error) {
+	now := time.Now()
+	u.ID = u.UserID
+	u.CreatedAt = now
+	u.UpdatedAt = now
+
+	u.Roles = user.NormalizeRoles(append([]user.Role{u.Role}, u.Roles...))
+	if len(u.Roles) == 0 {
+		u.Roles = []user.Role{user.RoleApplicant}
+	}
+	u.Role = u.Roles[0]
+
+	// Повторная регистрация перезаписывает профиль, как и в mock
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.UserID, u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+		encodeRoles(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, toMillis(u.CreatedAt), toMillis(u.UpdatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+	return &u, nil
+}
+
+// modify загружает пользователя в транзакции на запись, применяет fn и сохраняет результат
+func (s *userService) modify(ctx context.Context, userID string, fn func(u *user.User) error) (*user.User, error) {
+	var result *user.User
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		u, err := scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE user_id = ?", userID))
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+
+		u.UpdatedAt = time.Now()
+		_, err = tx.ExecContext(ctx, `UPDATE users SET first_name = ?, last_name = ?, age = ?, gender = ?, email = ?,
+			roles = ?, active_role = ?, study_group = ?, moodle_token = ?, updated_at = ? WHERE user_id = ?`,
+			u.FirstName, u.LastName, u.Age, u.Gender, u.Email,
+			encodeRoles(u.Roles), string(u.ActiveRole), u.StudyGroup, u.MoodleToken, toMillis(u.UpdatedAt), u.UserID)
+		if err != nil {
+			return err
+		}
+		result = u
+		return nil
+	})
+	return result, err
+}
+
+func (s *userService) UpdateUser(ctx context.Context, userID string, update user.User) (*user.User, error) {
+	u, err := s.modify(ctx, userID, func(u *user.User) error {
+		u.Merge(update)
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update user: %w", err)
+	}
+	return u, nil
+}
+
+func (s *userService) GetUserRole(ctx context.Context, userID string) (user.Role, error) {
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if u == nil {
+		return user.RoleApplicant, nil // По умолчанию абитуриент
+	}
+	if u.ActiveRole != "" && u.HasRole(u.ActiveRole) {
+		return u.ActiveRole, nil
+	}
+	return u.Role, nil
+}
+
+func (s *userService) GetAllUsers(ctx context.Context) ([]user.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+	defer rows.Close()
+
+	var result []user.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("get users: %w", err)
+		}
+		result = append(result, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get users: %w", err)
+	}
+	return result, nil
+}
+
+func (s *userService) SetMoodleToken(ctx context.Context, userID string, token secret.Sealed) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE users SET moodle_token = ?, updated_at = ? WHERE user_id = ?", token, toMillis(time.Now()), userID)
+	if err != nil {
+		return fmt.Errorf("set moodle token: %w", err)
+	}
+	if ok, err := affected(result); err != nil || !ok {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (s *userService) SetUserRoles(ctx context.Context, userID string, roles []user.Role) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		return u.ReplaceRoles(roles)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+func (s *userService) SetActiveRole(ctx context.Context, userID string, role user.Role) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		if role != "" && !u.HasRole(role) {
+			return fmt.Errorf("user has no role %q", role)
+		}
+		u.ActiveRole = role
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+func (s *userService) SetStudyGroup(ctx context.Context, userID string, group string) error {
+	_, err := s.modify(ctx, userID, func(u *user.User) error {
+		u.StudyGroup = group
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+// Package uniback - HTTP клиент бэкенда университета (uni-back-java): таймауты, повтор запросов
+// на чтение и перевод ответов в ошибки-сигналы, которые сервисы бота переводят в свои
+package uniback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("uni-back: not found")    // 404 или пустой ответ там, где ожидается объект
+	ErrRejected    = errors.New("uni-back: rejected")     // остальные 4xx: запрос неверный
+	ErrServer      = errors.New("uni-back: server error") // 500: uni-back отвечает так и на ошибки в данных
+	ErrUnavailable = errors.New("uni-back: unavailable")  // сеть, таймаут, 502-504
+)
+
+// maxErrorBody - сколько байт тела ответа с ошибкой попадает в текст ошибки
+const maxErrorBody = 512
+
+type options struct {
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	backoff    time.Duration
+}
+
+type Option func(*options)
+
+// WithTimeout ограничивает время одной попытки запроса (по умолчанию 5 секунд)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetries задает число повторов запросов на чтение и паузу перед первым повтором,
+// которая удваивается с каждой попыткой (по умолчанию 2 повтора, 200 мс)
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.retries = retries
+		o.backoff = backoff
+	}
+}
+
+// WithHTTPClient задает свой http.Client, например с прокси или TLS-настройками
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+type Client struct {
+	baseURL string
+	opts    options
+}
+
+// New создает клиент для uni-back по адресу вида "http://uni-back:8080"
+func New(baseURL string, opts ...Option) *Client {
+	o := options{
+		httpClient: http.DefaultClient,
+		timeout:    5 * time.Second,
+		retries:    2,
+		backoff:    200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), opts: o}
+}
+
+// Get читает JSON в out. Пустой ответ (uni-back так возвращает null) - ErrNotFound
+func (c *Client) Get(ctx context.Context, path string, out any) error {
+	return c.Do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post отправляет body в JSON; out может быть nil, если ответ не нужен
+func (c *Client) Post(ctx context.Context, path string, body, out any) error {
+	return c.Do(ctx, http.MethodPost, path, body, out)
+}
+
+// Do выполняет запрос. Запросы на чтение (GET) повторяются при недоступности и ошибках сервера,
+// остальные - только если соединение не удалось установить и запрос точно не дошел
+func (c *Client) Do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case string:
+		// uni-back принимает часть полей как @RequestBody String - сырой текст
+		payload, contentType = []byte(b), "text/plain; charset=utf-8"
+	default:
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encode %s %s: %w", method, path, err)
+		}
+		contentType = "application/json"
+	}
+
+	backoff := c.opts.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.do(ctx, method, path, contentType, payload, out)
+		if err == nil || attempt >= c.opts.retries || !c.retryable(method, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) retryable(method string, err error) bool {
+	var dialErr *dialError
+	if errors.As(err, &dialErr) {
+		return true
+	}
+	return method == http.MethodGet && (errors.Is(err, ErrUnavailable) || errors.Is(err, ErrServer))
+}
+
+// dialError - запрос не дошел до сервера, его можно повторить при любом методе
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, payload []byte, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.timeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("build %s %s: %w", method, path, err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.opts.httpClient.Do(req)
+	if err != nil {
+		if isDialError(err) {
+			return &dialError{err: fmt.Errorf("%w: %s %s: %v", ErrUnavailable, method, path, err)}
+		}
+		return fmt.Errorf("%w: %s %s: %v", ErrUnavailable, method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return fmt.Errorf("%w: %s %s: status %d: %s", statusError(resp.StatusCode), method, path, resp.StatusCode, strings.TrimSpace(string(text)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%w: read %s %s: %v", ErrUnavailable, method, path, err)
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return fmt.Errorf("%w: %s %s: empty response", ErrNotFound, method, path)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func statusError(status int) error {
+	switch {
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return ErrUnavailable
+	case status >= 500:
+		return ErrServer
+	default:
+		return ErrRejected
+	}
+}
+
+// isDialError сообщает, что соединение не установлено и сервер запрос не получил
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,190 @@
+package uniback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingServer отвечает status первые failures запросов, затем 200 с body
+func failingServer(t *testing.T, status int, failures int32, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestGetRetriesServerErrors(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		server, calls := failingServer(t, status, 2, `{"id": 7}`)
+		client := New(server.URL, WithRetries(2, time.Millisecond))
+
+		var out struct {
+			ID int `json:"id"`
+		}
+		if err := client.Get(context.Background(), "/users/1", &out); err != nil {
+			t.Fatalf("status %d: Get: %v", status, err)
+		}
+		if out.ID != 7 || calls.Load() != 3 {
+			t.Errorf("status %d: id = %d, calls = %d, want 7 after 3 calls", status, out.ID, calls.Load())
+		}
+	}
+}
+
+func TestGetGivesUpAfterRetries(t *testing.T) {
+	server, calls := failingServer(t, http.StatusBadGateway, 10, "")
+	client := New(server.URL, WithRetries(2, time.Millisecond))
+
+	err := client.Get(context.Background(), "/news", &struct{}{})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestPostIsNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusServiceUnavailable} {
+		server, calls := failingServer(t, status, 1, "")
+		client := New(server.URL, WithRetries(2, time.Millisecond))
+
+		err := client.Post(context.Background(), "/tickets/save", map[string]string{"id": "1"}, nil)
+		if err == nil {
+			t.Fatalf("status %d: Post: want error", status)
+		}
+		if calls.Load() != 1 {
+			t.Errorf("status %d: calls = %d, want 1", status, calls.Load())
+		}
+	}
+}
+
+func TestNotRetriedOnClientErrors(t *testing.T) {
+	server, calls := failingServer(t, http.StatusNotFound, 10, "")
+	client := New(server.URL, WithRetries(2, time.Millisecond))
+
+	if err := client.Get(context.Background(), "/users/1", &struct{}{}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
+
+// dialFailingTransport не может установить соединение failures раз, затем передает запросы дальше
+type dialFailingTransport struct {
+	failures atomic.Int32
+	next     http.RoundTripper
+}
+
+func (t *dialFailingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.failures.Add(-1) >= 0 {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return t.next.RoundTrip(r)
+}
+
+func TestPostRetriesDialErrors(t *testing.T) {
+	server, calls := failingServer(t, 0, 0, "")
+	transport := &dialFailingTransport{next: http.DefaultTransport}
+	transport.failures.Store(2)
+	client := New(server.URL, WithRetries(2, time.Millisecond), WithHTTPClient(&http.Client{Transport: transport}))
+
+	if err := client.Post(context.Background(), "/news/save", map[string]string{"text": "a"}, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("server calls = %d, want 1", calls.Load())
+	}
+}
+
+func TestDialErrorIsUnavailable(t *testing.T) {
+	transport := &dialFailingTransport{next: http.DefaultTransport}
+	transport.failures.Store(10)
+	client := New("http://uni-back", WithRetries(1, time.Millisecond), WithHTTPClient(&http.Client{Transport: transport}))
+
+	err := client.Post(context.Background(), "/news/save", map[string]string{"text": "a"}, nil)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if left := transport.failures.Load(); left != 8 {
+		t.Errorf("attempts = %d, want 2", 10-left)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusBadRequest, ErrRejected},
+		{http.StatusConflict, ErrRejected},
+		{http.StatusInternalServerError, ErrServer},
+		{http.StatusBadGateway, ErrUnavailable},
+		{http.StatusServiceUnavailable, ErrUnavailable},
+		{http.StatusGatewayTimeout, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		server, _ := failingServer(t, tt.status, 1, "")
+		client := New(server.URL, WithRetries(0, 0))
+
+		err := client.Post(context.Background(), "/users/register", map[string]string{}, nil)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: err = %v, want %v", tt.status, err, tt.want)
+		}
+	}
+}
+
+func TestEmptyResponse(t *testing.T) {
+	for _, body := range []string{"", "null", " null\n"} {
+		server, _ := failingServer(t, 0, 0, body)
+		client := New(server.URL, WithRetries(0, 0))
+
+		err := client.Get(context.Background(), "/users/1", &struct{}{})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("body %q: err = %v, want ErrNotFound", body, err)
+		}
+		// Ответ не нужен - пустое тело не ошибка
+		if err := client.Post(context.Background(), "/news/save", map[string]string{}, nil); err != nil {
+			t.Errorf("body %q: Post: %v", body, err)
+		}
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		contentType, body = r.Header.Get("Content-Type"), string(raw)
+	}))
+	defer server.Close()
+	client := New(server.URL + "/")
+
+	if err := client.Do(context.Background(), http.MethodPatch, "/news/1", "новый текст", nil); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if contentType != "text/plain; charset=utf-8" || body != "новый текст" {
+		t.Errorf("string body sent as %q: %q", contentType, body)
+	}
+
+	if err := client.Post(context.Background(), "/news/save", map[string]int{"id": 1}, nil); err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	if contentType != "application/json" || body != `{"id":1}` {
+		t.Errorf("json body sent as %q: %q", contentType, body)
+	}
+}
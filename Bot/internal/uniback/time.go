@@ -0,0 +1,41 @@
+package uniback
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// localDateTimeLayout - java.time.LocalDateTime в JSON Spring Boot: ISO без часового пояса
+const localDateTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// LocalDateTime - время без часового пояса, как LocalDateTime в uni-back.
+// Читается и пишется в часовом поясе бота (time.Local), так же как его пишет uni-back
+type LocalDateTime struct {
+	time.Time
+}
+
+func (t LocalDateTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.In(time.Local).Format(localDateTimeLayout))
+}
+
+func (t *LocalDateTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("local date time: %w", err)
+	}
+	parsed, err := time.ParseInLocation(localDateTimeLayout, s, time.Local)
+	if err != nil {
+		return fmt.Errorf("local date time %q: %w", s, err)
+	}
+	t.Time = parsed
+	return nil
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,20 +20,35 @@ import (
 	maxbot "github.com/max-messenger/max-bot-api-client-go"
 	"github.com/max-messenger/max-bot-api-client-go/schemes"
 
+	"first-max-bot/internal/backup"
 	botpkg "first-max-bot/internal/bot"
 	"first-max-bot/internal/bot/handlers"
+	"first-max-bot/internal/calendar"
+	"first-max-bot/internal/cluster"
 	"first-max-bot/internal/config"
+	"first-max-bot/internal/idempotency"
+	"first-max-bot/internal/privacy"
+	"first-max-bot/internal/ratelimit"
+	"first-max-bot/internal/schedulewatch"
+	"first-max-bot/internal/secret"
 	"first-max-bot/internal/services/ai"
 	"first-max-bot/internal/services/businesstrip"
+	"first-max-bot/internal/services/campaign"
 	"first-max-bot/internal/services/deanery"
+	"first-max-bot/internal/services/intent"
 	"first-max-bot/internal/services/library"
 	"first-max-bot/internal/services/moodle"
 	"first-max-bot/internal/services/news"
 	"first-max-bot/internal/services/reminder"
+	"first-max-bot/internal/services/rooms"
 	"first-max-bot/internal/services/schedule"
 	"first-max-bot/internal/services/support"
 	"first-max-bot/internal/services/user"
+	"first-max-bot/internal/state"
 	redisstate "first-max-bot/internal/state/redis"
+	"first-max-bot/internal/storage/postgres"
+	"first-max-bot/internal/storage/sqlite"
+	"first-max-bot/internal/uniback"
 )
 
 func main() {
@@ -49,9 +67,12 @@ func main() {
 	zerolog.SetGlobalLevel(level)
 	logger := log.With().Str("component", "max_helper").Logger()
 
-	api, err := maxbot.New(cfg.BotToken)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to create max api client")
+	// Онлайн-копия SQLite базы: `bot backup <файл>`. Работающий бот останавливать не нужно
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		if err := runSQLiteBackup(ctx, cfg, os.Args[2:], logger); err != nil {
+			logger.Fatal().Err(err).Msg("backup failed")
+		}
+		return
 	}
 
 	redisClient := redisclient.NewClient(&redisclient.Options{
@@ -70,15 +91,99 @@ func main() {
 		logger.Fatal().Err(err).Msg("redis ping failed")
 	}
 
+	// Координация реплик: дедупликация обновлений и выбор лидера для фоновых задач
+	instanceID := cfg.InstanceID
+	if instanceID == "" {
+		instanceID = cluster.DefaultInstanceID()
+	}
+	coordinator := cluster.NewRedis(redisClient, instanceID, logger.With().Str("component", "cluster").Logger())
+
+	// Хранилище данных сервисов выбирается через STORAGE_DRIVER
+	storage, closeStorage, err := openStorage(ctx, cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Str("driver", cfg.StorageDriver).Msg("failed to open storage")
+	}
+	defer closeStorage()
+
+	// Напоминания можно хранить в Redis независимо от основного хранилища:
+	// проверка читает только наступившие напоминания из sorted set
+	switch cfg.ReminderStorage {
+	case "":
+	case "redis":
+		storage.reminders = reminder.NewRedisService(redisClient)
+		logger.Info().Msg("using redis reminder storage")
+	default:
+		logger.Fatal().Str("storage", cfg.ReminderStorage).Msg("unknown reminder storage")
+	}
+
+	// Выгрузка и загрузка всех данных для резервного копирования и переноса между хранилищами:
+	// `bot export <файл>`, `bot import <файл> [--on-conflict=fail|skip|overwrite]`
+	if len(os.Args) > 1 && (os.Args[1] == "export" || os.Args[1] == "import") {
+		if err := runArchive(ctx, storage, os.Args[1], os.Args[2:], logger); err != nil {
+			logger.Fatal().Err(err).Msg(os.Args[1] + " failed")
+		}
+		return
+	}
+
+	// Ключи шифрования токенов Moodle
+	secrets, err := openSecrets(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load encryption keys")
+	}
+
+	// Перешифровка токенов активным ключом после смены ключа или включения шифрования: `bot rekey`
+	if len(os.Args) > 1 && os.Args[1] == "rekey" {
+		if err := runRekey(ctx, storage.users, secrets, logger); err != nil {
+			logger.Fatal().Err(err).Msg("rekey failed")
+		}
+		return
+	}
+
+	api, err := maxbot.New(cfg.BotToken)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create max api client")
+	}
+
 	scheduleService := schedule.NewMock(cfg.MockScheduleLag)
-	supportService := support.NewMock()
-	userService := user.NewMock()
-	deaneryService := deanery.NewMock()
-	libraryService := library.NewMock()
-	businessTripService := businesstrip.NewMock()
-	newsService := news.NewMockService()
+	supportService := storage.support
+	userService := storage.users
+	deaneryService := storage.deanery
+	libraryService := storage.library
+	businessTripService := storage.trips
+	newsService := storage.news
 	moodleService := moodle.NewService()
-	reminderService := reminder.NewMockService()
+	reminderService := storage.reminders
+	campaignService := campaign.NewRedisService(redisClient, "")
+
+	// Бэкенд университета (uni-back): профиль пользователя, подтверждение email, расписание, обращения и новости
+	if cfg.UniBackURL != "" {
+		uniBack := newUniBackClient(cfg)
+		userService = user.NewHTTPService(uniBack, userService)
+		scheduleService = schedule.NewHTTPService(uniBack, userService)
+		supportService = support.NewHTTPService(uniBack, supportService)
+		newsService = news.NewHTTPService(uniBack, newsService)
+		logger.Info().Str("url", cfg.UniBackURL).Msg("using uni-back for users, schedule, tickets and news")
+	}
+
+	// Расписание из файлов iCalendar: работает без uni-back и важнее его, если задано
+	if cfg.ScheduleICalDir != "" {
+		scheduleService, err = schedule.NewICalService(cfg.ScheduleICalDir, userService)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to init ical schedule")
+		}
+		if err := scheduleService.(schedule.CalendarStore).Reload(); err != nil {
+			logger.Warn().Err(err).Msg("some timetables were not loaded")
+		}
+		logger.Info().Str("dir", cfg.ScheduleICalDir).Msg("using iCalendar files for schedule")
+	}
+
+	// Имя бота нужно для deep-link ссылок (https://max.ru/<бот>?start=...)
+	botUsername := ""
+	if info, err := api.Bots.GetBot(ctx); err != nil {
+		logger.Warn().Err(err).Msg("failed to get bot info")
+	} else {
+		botUsername = info.Username
+	}
 
 	// Инициализируем AI сервис (YandexGPT)
 	var aiService ai.Service
@@ -92,11 +197,14 @@ func main() {
 	}
 
 	router := botpkg.NewRouter()
-	router.Register("/start", handlers.NewStartHandler(userService, logger.With().Str("handler", "start").Logger()))
+	startHandler := handlers.NewStartHandler(userService, campaignService, logger.With().Str("handler", "start").Logger())
+	router.Register("/start", startHandler)
 	menuHandler := handlers.NewMenuHandler(userService)
 	router.Register("/menu", menuHandler)
 	router.Register("/help", menuHandler) // Используем тот же handler что и для /menu
-	router.Register("/schedule", handlers.NewScheduleHandler(scheduleService, logger.With().Str("handler", "schedule").Logger()))
+	scheduleHandler := handlers.NewScheduleHandler(scheduleService, logger.With().Str("handler", "schedule").Logger())
+	router.Register("/schedule", scheduleHandler)
+	router.RegisterCallback("schedule:*", scheduleHandler) // Регистрируем callback handler для навигации по расписанию
 	router.Register("/contact", handlers.NewSupportHandler(supportService, logger.With().Str("handler", "support").Logger()))
 
 	myTicketsHandler := handlers.NewMyTicketsHandler(supportService, logger.With().Str("handler", "mytickets").Logger())
@@ -106,12 +214,17 @@ func main() {
 	// Команды для абитуриентов
 	router.Register("/admission", handlers.NewAdmissionHandler())
 	router.Register("/programs", handlers.NewProgramsHandler())
-	router.Register("/openday", handlers.NewOpenDayHandler())
+	openDayHandler := handlers.NewOpenDayHandler()
+	router.Register("/openday", openDayHandler)
 
 	// Команды для студентов
 	studentScheduleHandler := handlers.NewScheduleHandler(scheduleService, logger.With().Str("handler", "student_schedule").Logger())
 	router.Register("/myschedule", studentScheduleHandler)
 
+	groupHandler := handlers.NewGroupHandler(userService, scheduleService, logger.With().Str("handler", "group").Logger())
+	router.Register("/group", groupHandler)
+	router.RegisterCallback("group:*", groupHandler) // Регистрируем callback handler для выбора группы
+
 	deaneryHandler := handlers.NewDeaneryHandler(deaneryService, logger.With().Str("handler", "deanery").Logger())
 	router.Register("/deanery", deaneryHandler)
 	router.RegisterCallback("doc:*", deaneryHandler) // Регистрируем callback handler для документов
@@ -124,9 +237,10 @@ func main() {
 	router.Register("/library_manage", libraryManageHandler)
 	router.RegisterCallback("lib_manage:*", libraryManageHandler) // Регистрируем callback handler для управления библиотекой
 
-	router.Register("/dormitory", handlers.NewDormitoryHandler())
+	dormitoryHandler := handlers.NewDormitoryHandler()
+	router.Register("/dormitory", dormitoryHandler)
 
-	moodleHandler := handlers.NewMoodleHandler(moodleService, userService, logger.With().Str("handler", "moodle").Logger())
+	moodleHandler := handlers.NewMoodleHandler(moodleService, userService, secrets, logger.With().Str("handler", "moodle").Logger())
 	router.Register("/moodle", moodleHandler)
 	router.RegisterCallback("moodle:*", moodleHandler) // Регистрируем callback handler для Moodle
 
@@ -136,7 +250,7 @@ func main() {
 
 	// AI помощник (только если сервис инициализирован)
 	if aiService != nil {
-		askHandler := handlers.NewAskHandler(aiService, scheduleService, moodleService, userService, logger.With().Str("handler", "ask").Logger())
+		askHandler := handlers.NewAskHandler(aiService, scheduleService, moodleService, userService, secrets, logger.With().Str("handler", "ask").Logger())
 		router.Register("/ask", askHandler)
 	}
 
@@ -153,7 +267,7 @@ func main() {
 	newsHandler := handlers.NewNewsHandler(newsService, logger.With().Str("handler", "news").Logger())
 	router.Register("/news", newsHandler)
 
-	sendNewsHandler := handlers.NewSendNewsHandler(newsService, userService, logger.With().Str("handler", "send_news").Logger())
+	sendNewsHandler := handlers.NewSendNewsHandler(newsService, userService, stateRepo, logger.With().Str("handler", "send_news").Logger())
 	router.Register("/send_news", sendNewsHandler)
 
 	ticketsHandler := handlers.NewTicketsHandler(supportService, userService, logger.With().Str("handler", "tickets").Logger())
@@ -164,19 +278,171 @@ func main() {
 	router.Register("/documents", documentsHandler)
 	router.RegisterCallback("doc_admin:*", documentsHandler) // Регистрируем callback handler для заявлений деканата
 
+	router.Register("/timetable", handlers.NewTimetableHandler(userService, scheduleService, logger.With().Str("handler", "timetable").Logger()))
+
 	// User registration handler
-	userRegHandler := handlers.NewUserRegistrationHandler(userService, logger.With().Str("handler", "user_registration").Logger())
+	userRegHandler := handlers.NewUserRegistrationHandler(userService, campaignService, logger.With().Str("handler", "user_registration").Logger())
 	router.Register("/register", userRegHandler)
 	router.RegisterCallback("user_reg:*", userRegHandler)
 
-	router.SetFallback(handlers.NewFallbackHandler())
+	// Отслеживаемые ссылки и QR-коды
+	linksHandler := handlers.NewLinksHandler(campaignService, userService, botUsername, logger.With().Str("handler", "links").Logger())
+	router.Register("/links", linksHandler)
+	router.RegisterCallback("links:*", linksHandler)
+
+	// Сценарии для payload'ов deep-link: start=openday_2026_12, start=library_desk, start=dorm_reception.
+	// start=invite_<код> обрабатывается самим /start
+	startHandler.RegisterPayload("openday", openDayHandler)
+	startHandler.RegisterPayload("library", libraryHandler)
+	startHandler.RegisterPayload("dorm", dormitoryHandler)
+
+	// Переключение активной роли и назначение ролей
+	roleHandler := handlers.NewRoleHandler(userService, logger.With().Str("handler", "role").Logger())
+	router.Register("/role", roleHandler)
+	router.RegisterCallback("role:*", roleHandler)
+
+	// Настройки групповых чатов
+	chatHandler := handlers.NewChatHandler(stateRepo, userService, logger.With().Str("handler", "chat").Logger())
+	router.Register("/chat", chatHandler)
+	router.RegisterCallback("chat:*", chatHandler)
+
+	// События MAX, не являющиеся сообщениями или callback'ами
+	router.OnBotStarted(startHandler.HandleBotStarted) // нажатие "Начать", в т.ч. по deep-link
+	chatEventsHandler := handlers.NewChatEventsHandler(stateRepo, logger.With().Str("handler", "chat_events").Logger())
+	router.OnBotAdded(chatEventsHandler.HandleBotAdded)
+	router.OnBotRemoved(chatEventsHandler.HandleBotRemoved)
+	router.OnUserAdded(chatEventsHandler.HandleUserAdded)
+	router.OnUserRemoved(chatEventsHandler.HandleUserRemoved)
+	messageEventsHandler := handlers.NewMessageEventsHandler(logger.With().Str("handler", "message_events").Logger())
+	router.OnMessageEdited(messageEventsHandler.HandleMessageEdited)
+	router.OnMessageRemoved(messageEventsHandler.HandleMessageRemoved)
+
+	// Кнопки, изменяющие данные, выполняются один раз: повторное нажатие в том же сообщении
+	// или одновременное нажатие несколькими руководителями получает уведомление вместо повторного действия
+	router.UseIdempotencyStore(idempotency.NewRedisStore(redisClient, ""))
+	// Кнопки убираются только из сообщений с одним действием: в списках книг и запросов /library_manage
+	// остальные кнопки должны остаться, а повтор той же кнопки отклоняется по ключу
+	router.MarkIdempotent("book:borrow:", botpkg.ScopeMessage, 10*time.Minute)
+	router.MarkIdempotent("doc:request:", botpkg.ScopeMessage, 10*time.Minute, botpkg.WithKeyboardRemoval())
+	router.MarkIdempotent("ticket:close:", botpkg.ScopeGlobal, 24*time.Hour, botpkg.WithKeyboardRemoval())
+	router.MarkIdempotent("lib_manage:issue:", botpkg.ScopeGlobal, time.Hour)
+	router.MarkIdempotent("lib_manage:taken:", botpkg.ScopeGlobal, time.Hour)
+	router.MarkIdempotent("lib_manage:returned:", botpkg.ScopeGlobal, time.Hour)
+
+	// Ограничение частоты запросов: общий лимит, лимит пользователя и лимиты дорогих команд (/ask, /contact)
+	limiter := ratelimit.NewRedisLimiter(redisClient, ratelimit.DefaultConfig(), "")
+	router.UseRateLimiter(limiter, logger.With().Str("component", "ratelimit").Logger())
+	router.Register("/limits", handlers.NewLimitsHandler(limiter, userService, logger.With().Str("handler", "limits").Logger()))
+
+	// Личный календарь: файл .ics в боте и подписка по секретной ссылке, если задан CALENDAR_ADDR.
+	// Сервер календарей работает на каждом экземпляре
+	calendarFeed := calendar.NewFeed(calendar.Sources{
+		Schedule:  scheduleService,
+		Reminders: reminderService,
+		Trips:     businessTripService,
+		Library:   libraryService,
+	})
+	calendarTokens := calendar.NewRedisTokenStore(redisClient, "")
+	var feedTokens calendar.TokenStore
+	if cfg.CalendarAddr != "" {
+		if cfg.CalendarURL == "" {
+			logger.Warn().Msg("CALENDAR_URL is not set, calendar links are disabled")
+		} else {
+			feedTokens = calendarTokens
+		}
+		go serveCalendars(ctx, cfg.CalendarAddr, calendar.NewHandler(calendarTokens, calendarFeed, logger.With().Str("component", "calendar").Logger()), logger)
+	}
+	calendarHandler := handlers.NewCalendarHandler(calendarFeed, feedTokens, cfg.CalendarURL, userService, logger.With().Str("handler", "calendar").Logger())
+	router.Register("/calendar", calendarHandler)
+	router.RegisterCallback("calendar:*", calendarHandler)
+
+	// Свободные аудитории: реестр и занятость по расписанию всех групп
+	roomRegistry := rooms.NewMockRegistry()
+	if cfg.RoomsFile != "" {
+		roomRegistry, err = rooms.NewFileRegistry(cfg.RoomsFile)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to load rooms")
+		}
+		logger.Info().Str("file", cfg.RoomsFile).Msg("using rooms file")
+	}
+	roomsHandler := handlers.NewRoomsHandler(rooms.NewService(roomRegistry, scheduleService), userService, logger.With().Str("handler", "rooms").Logger())
+	router.Register("/rooms", roomsHandler)
+	router.RegisterCallback("rooms:*", roomsHandler)
+
+	// Уведомления об изменениях расписания
+	scheduleWatchStore := schedulewatch.NewRedisStore(redisClient, "")
+	notifyHandler := handlers.NewNotifyHandler(scheduleWatchStore, userService, logger.With().Str("handler", "notify").Logger())
+	router.Register("/notify", notifyHandler)
+	router.RegisterCallback("notify:*", notifyHandler)
+
+	// Выгрузка и удаление персональных данных по запросу пользователя
+	privacyService := privacy.New(privacy.Services{
+		Users:         userService,
+		Support:       supportService,
+		Deanery:       deaneryService,
+		Library:       libraryService,
+		Trips:         businessTripService,
+		News:          newsService,
+		Reminders:     reminderService,
+		Campaigns:     campaignService,
+		State:         stateRepo,
+		Chats:         stateRepo,
+		Limiter:       limiter,
+		Calendars:     calendarTokens,
+		ScheduleWatch: scheduleWatchStore,
+		Audit:         privacy.NewRedisAuditLog(redisClient, ""),
+	}, logger.With().Str("component", "privacy").Logger(), privacy.WithPseudonymKey(cfg.PseudonymKey))
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, logger.With().Str("handler", "privacy").Logger())
+	router.Register("/my_data", privacyHandler)
+	router.Register("/delete_me", privacyHandler)
+	router.RegisterCallback("privacy:*", privacyHandler)
+	router.MarkIdempotent("privacy:delete:", botpkg.ScopeMessage, 10*time.Minute, botpkg.WithKeyboardRemoval())
+
+	// Выход из любого сценария ввода
+	cancelHandler := handlers.NewCancelHandler()
+	router.Register("/cancel", cancelHandler)
+	router.RegisterCallback("flow:*", cancelHandler)
+
+	// В групповых чатах доступны только команды, не требующие диалога с ботом
+	router.AllowInGroups("/chat", "/schedule", "/news", "/menu", "/help", "/ask")
+
+	// Кнопки-подсказки "Возможно, ты имел в виду" выполняют команду напрямую
+	router.RegisterCallback("cmd:*", router.CommandCallback())
+	fallbackHandler := handlers.NewFallbackHandler(userService, logger.With().Str("handler", "fallback").Logger())
+
+	// Свободный текст сначала разбирается классификатором намерений: локальные правила,
+	// а если они не уверены - AI (когда он доступен)
+	classifiers := []intent.Classifier{intent.NewRuleEngine(intent.DefaultRules()...)}
+	if completer, ok := aiService.(ai.Completer); ok {
+		classifiers = append(classifiers, intent.NewAIClassifier(completer))
+	}
+	intentHandler := handlers.NewIntentHandler(intent.Chain(classifiers...), userService, router, fallbackHandler, logger.With().Str("handler", "intent").Logger())
+	router.SetFallback(intentHandler)
 
-	helperBot := botpkg.New(api, router, stateRepo, logger)
+	helperBot := botpkg.New(api, router, stateRepo, logger, botpkg.WithDeduplicator(coordinator), botpkg.WithToken(cfg.BotToken))
 
+	// Фоновые задачи выполняются только на экземпляре-лидере
 	// Запускаем фоновый процесс для проверки напоминаний
-	go startReminderChecker(ctx, reminderService, api, logger.With().Str("component", "reminder_checker").Logger())
+	go coordinator.RunAsLeader(ctx, "reminder_checker", func(ctx context.Context) {
+		startReminderChecker(ctx, reminderService, api, instanceID, logger.With().Str("component", "reminder_checker").Logger())
+	})
 
-	logger.Info().Msg("max helper bot started")
+	// Запускаем фоновую публикацию расписания в подписанные групповые чаты
+	if groupScheduler, ok := scheduleService.(schedule.GroupScheduler); ok {
+		go coordinator.RunAsLeader(ctx, "chat_schedule_poster", func(ctx context.Context) {
+			startChatSchedulePoster(ctx, stateRepo, groupScheduler, api, logger.With().Str("component", "chat_schedule_poster").Logger())
+		})
+	}
+
+	// Проверка изменений расписания, уведомления пользователей и напоминания о парах
+	scheduleWatcher := schedulewatch.NewWatcher(scheduleService, userService, reminderService, scheduleWatchStore, func(ctx context.Context, userID, text string) error {
+		return sendTextToUser(ctx, api, userID, text)
+	}, logger.With().Str("component", "schedule_watcher").Logger())
+	go coordinator.RunAsLeader(ctx, "schedule_watcher", func(ctx context.Context) {
+		startScheduleWatcher(ctx, scheduleWatcher, logger.With().Str("component", "schedule_watcher").Logger())
+	})
+
+	logger.Info().Str("instance_id", instanceID).Msg("max helper bot started")
 	if err := helperBot.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		logger.Error().Err(err).Msg("bot stopped with error")
 	} else {
@@ -184,15 +450,318 @@ func main() {
 	}
 }
 
+// storageServices - сервисы, данные которых хранятся в выбранном хранилище
+type storageServices struct {
+	users     user.Service
+	support   support.Service
+	deanery   deanery.Service
+	library   library.Service
+	trips     businesstrip.Service
+	news      news.Service
+	reminders reminder.Service
+}
+
+// openStorage создает сервисы поверх хранилища из конфигурации: "memory" (по умолчанию) - mock-сервисы
+// в памяти, данные теряются при перезапуске; "postgres" - PostgreSQL, "sqlite" - файл SQLite для установок
+// на одном сервере. Миграции применяются автоматически
+func openStorage(ctx context.Context, cfg *config.Config, logger zerolog.Logger) (*storageServices, func(), error) {
+	switch cfg.StorageDriver {
+	case "", "memory":
+		logger.Warn().Msg("using in-memory storage, data will be lost on restart")
+		return &storageServices{
+			users:     user.NewMock(),
+			support:   support.NewMock(),
+			deanery:   deanery.NewMock(),
+			library:   library.NewMock(),
+			trips:     businesstrip.NewMock(),
+			news:      news.NewMockService(),
+			reminders: reminder.NewMockService(),
+		}, func() {}, nil
+
+	case "postgres":
+		if cfg.PostgresDSN == "" {
+			return nil, nil, fmt.Errorf("POSTGRES_DSN is required for postgres storage")
+		}
+		pool, err := postgres.Open(ctx, cfg.PostgresDSN, postgres.WithMaxConns(cfg.PostgresMaxConns))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := postgres.Migrate(ctx, pool); err != nil {
+			pool.Close()
+			return nil, nil, err
+		}
+		logger.Info().Msg("using postgres storage")
+		return &storageServices{
+			users:     postgres.NewUserService(pool),
+			support:   postgres.NewSupportService(pool),
+			deanery:   postgres.NewDeaneryService(pool),
+			library:   postgres.NewLibraryService(pool),
+			trips:     postgres.NewBusinessTripService(pool),
+			news:      postgres.NewNewsService(pool),
+			reminders: postgres.NewReminderService(pool),
+		}, pool.Close, nil
+
+	case "sqlite":
+		db, err := sqlite.Open(ctx, sqlitePath(cfg))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := sqlite.Migrate(ctx, db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		logger.Info().Str("path", sqlitePath(cfg)).Msg("using sqlite storage")
+		return &storageServices{
+			users:     sqlite.NewUserService(db),
+			support:   sqlite.NewSupportService(db),
+			deanery:   sqlite.NewDeaneryService(db),
+			library:   sqlite.NewLibraryService(db),
+			trips:     sqlite.NewBusinessTripService(db),
+			news:      sqlite.NewNewsService(db),
+			reminders: sqlite.NewReminderService(db),
+		}, func() {
+			if err := db.Close(); err != nil {
+				logger.Error().Err(err).Msg("failed to close sqlite")
+			}
+		}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+}
+
+// defaultSQLitePath - файл базы, если SQLITE_PATH не задан
+const defaultSQLitePath = "maxbot.db"
+
+func sqlitePath(cfg *config.Config) string {
+	if cfg.SQLitePath != "" {
+		return cfg.SQLitePath
+	}
+	return defaultSQLitePath
+}
+
+// runSQLiteBackup копирует базу из SQLITE_PATH в файл из аргументов
+func runSQLiteBackup(ctx context.Context, cfg *config.Config, args []string, logger zerolog.Logger) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s backup <file>", os.Args[0])
+	}
+
+	// Открытие несуществующего пути создало бы пустую базу и пустую копию
+	source := sqlitePath(cfg)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("sqlite database %s: %w", source, err)
+	}
+
+	db, err := sqlite.Open(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlite.Backup(ctx, db, args[0]); err != nil {
+		return err
+	}
+	logger.Info().Str("source", source).Str("backup", args[0]).Msg("sqlite backup created")
+	return nil
+}
+
+// runArchive выполняет команды export и import над хранилищем из конфигурации
+func runArchive(ctx context.Context, storage *storageServices, command string, args []string, logger zerolog.Logger) error {
+	services := backup.Services{
+		Users:     storage.users,
+		Support:   storage.support,
+		Deanery:   storage.deanery,
+		Library:   storage.library,
+		Trips:     storage.trips,
+		News:      storage.news,
+		Reminders: storage.reminders,
+	}
+
+	var (
+		path       string
+		onConflict string
+	)
+	for _, arg := range args {
+		switch {
+		case command == "import" && strings.HasPrefix(arg, "--on-conflict="):
+			onConflict = strings.TrimPrefix(arg, "--on-conflict=")
+		case path == "" && !strings.HasPrefix(arg, "-"):
+			path = arg
+		default:
+			return fmt.Errorf("unexpected argument %q", arg)
+		}
+	}
+	if path == "" {
+		if command == "import" {
+			return fmt.Errorf("usage: %s import <file> [--on-conflict=fail|skip|overwrite]", os.Args[0])
+		}
+		return fmt.Errorf("usage: %s export <file>", os.Args[0])
+	}
+
+	var (
+		report backup.Report
+		err    error
+	)
+	if command == "export" {
+		report, err = exportArchive(ctx, path, services)
+	} else {
+		report, err = importArchive(ctx, path, services, onConflict)
+	}
+	for _, warning := range report.Warnings {
+		logger.Warn().Str("file", path).Msg(warning)
+	}
+	if err != nil {
+		return err
+	}
+
+	event := logger.Info().Str("file", path).Int("records", report.Total())
+	for kind, n := range report.Records {
+		event = event.Int(kind, n)
+	}
+	for kind, n := range report.Skipped {
+		event = event.Int("skipped_"+kind, n)
+	}
+	event.Msg(command + " completed")
+	return nil
+}
+
+// newUniBackClient создает клиент uni-back из UNI_BACK_URL и UNI_BACK_TIMEOUT
+func newUniBackClient(cfg *config.Config) *uniback.Client {
+	var opts []uniback.Option
+	if cfg.UniBackTimeout > 0 {
+		opts = append(opts, uniback.WithTimeout(cfg.UniBackTimeout))
+	}
+	return uniback.New(cfg.UniBackURL, opts...)
+}
+
+// openSecrets загружает ключи из SECRET_KEYS. Без ключей для хранения в памяти создается временный ключ,
+// как в uni-back; для постоянного хранилища ключи обязательны, иначе токены не расшифровать после перезапуска
+func openSecrets(cfg *config.Config, logger zerolog.Logger) (*secret.Keyring, error) {
+	if cfg.SecretKeys == "" {
+		if cfg.StorageDriver != "" && cfg.StorageDriver != "memory" {
+			return nil, fmt.Errorf("SECRET_KEYS is required for %s storage", cfg.StorageDriver)
+		}
+		logger.Warn().Msg("SECRET_KEYS not set, using ephemeral encryption key")
+		return secret.NewEphemeralKeyring()
+	}
+
+	secrets, err := secret.ParseKeys(cfg.SecretKeys, cfg.SecretActiveKey)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Str("active_key", secrets.ActiveKeyID()).Strs("keys", secrets.KeyIDs()).Msg("encryption keys loaded")
+	return secrets, nil
+}
+
+// runRekey перешифровывает активным ключом токены, зашифрованные старыми ключами или сохраненные открытым текстом.
+// После этого старый ключ можно убрать из SECRET_KEYS
+func runRekey(ctx context.Context, users user.Service, secrets *secret.Keyring, logger zerolog.Logger) error {
+	all, err := users.GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+
+	rekeyed, failed := 0, 0
+	for _, u := range all {
+		if !secrets.NeedsRekey(u.MoodleToken) {
+			continue
+		}
+		sealed, err := secrets.Rekey(u.MoodleToken)
+		if err == nil {
+			err = users.SetMoodleToken(ctx, u.UserID, sealed)
+		}
+		if err != nil {
+			failed++
+			logger.Error().Err(err).Str("user_id", u.UserID).Msg("failed to rekey moodle token")
+			continue
+		}
+		rekeyed++
+	}
+
+	logger.Info().Str("active_key", secrets.ActiveKeyID()).Int("users", len(all)).Int("rekeyed", rekeyed).Int("failed", failed).Msg("rekey completed")
+	if failed > 0 {
+		return fmt.Errorf("%d tokens were not rekeyed", failed)
+	}
+	return nil
+}
+
+// exportArchive записывает архив в новый файл. Существующий файл не перезаписывается,
+// а при ошибке недописанный файл удаляется
+func exportArchive(ctx context.Context, path string, services backup.Services) (backup.Report, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return backup.Report{}, fmt.Errorf("create archive: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	report, err := backup.Export(ctx, w, services)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return report, err
+	}
+	return report, nil
+}
+
+func importArchive(ctx context.Context, path string, services backup.Services, onConflict string) (backup.Report, error) {
+	mode, err := backup.ParseConflictMode(onConflict)
+	if err != nil {
+		return backup.Report{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return backup.Report{}, fmt.Errorf("open archive: %w", err)
+	}
+	defer file.Close()
+
+	return backup.Import(ctx, file, services, mode)
+}
+
+// serveCalendars запускает HTTP-сервер календарей и останавливает его при отмене ctx
+func serveCalendars(ctx context.Context, addr string, handler http.Handler, logger zerolog.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle(calendar.FeedPath, handler)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("failed to stop calendar server")
+		}
+	}()
+
+	logger.Info().Str("addr", addr).Msg("calendar server started")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error().Err(err).Msg("calendar server stopped with error")
+	}
+}
+
+// reminderClaimLease - на сколько напоминание захватывается для отправки.
+// Если экземпляр упал после захвата, напоминание будет отправлено повторно по истечении этого срока
+const reminderClaimLease = 2 * time.Minute
+
 // startReminderChecker запускает фоновый процесс для проверки и отправки напоминаний
-func startReminderChecker(ctx context.Context, reminderService reminder.Service, api *maxbot.Api, logger zerolog.Logger) {
+func startReminderChecker(ctx context.Context, reminderService reminder.Service, api *maxbot.Api, instanceID string, logger zerolog.Logger) {
 	ticker := time.NewTicker(1 * time.Minute) // Проверяем каждую минуту
 	defer ticker.Stop()
 
 	logger.Info().Msg("reminder checker started")
 
 	// Первая проверка сразу при запуске
-	checkAndSendReminders(ctx, reminderService, api, logger)
+	checkAndSendReminders(ctx, reminderService, api, instanceID, logger)
 
 	for {
 		select {
@@ -200,18 +769,17 @@ func startReminderChecker(ctx context.Context, reminderService reminder.Service,
 			logger.Info().Msg("reminder checker stopped")
 			return
 		case <-ticker.C:
-			checkAndSendReminders(ctx, reminderService, api, logger)
+			checkAndSendReminders(ctx, reminderService, api, instanceID, logger)
 		}
 	}
 }
 
-// checkAndSendReminders проверяет активные напоминания и отправляет те, которые должны быть отправлены
-func checkAndSendReminders(ctx context.Context, reminderService reminder.Service, api *maxbot.Api, logger zerolog.Logger) {
-	now := time.Now()
-
-	reminders, err := reminderService.GetAllActiveReminders(ctx)
+// checkAndSendReminders захватывает наступившие напоминания, отправляет их и помечает выполненными.
+// Неотправленные напоминания освобождаются и будут отправлены при следующей проверке
+func checkAndSendReminders(ctx context.Context, reminderService reminder.Service, api *maxbot.Api, instanceID string, logger zerolog.Logger) {
+	reminders, err := reminderService.ClaimDueReminders(ctx, time.Now(), instanceID, reminderClaimLease)
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to get active reminders")
+		logger.Error().Err(err).Msg("failed to claim due reminders")
 		return
 	}
 
@@ -219,20 +787,21 @@ func checkAndSendReminders(ctx context.Context, reminderService reminder.Service
 		return
 	}
 
-	logger.Debug().Int("count", len(reminders)).Msg("checking reminders")
+	logger.Debug().Int("count", len(reminders)).Msg("sending reminders")
 
 	for _, r := range reminders {
-		if !r.DateTime.After(now) {
-			if err := sendReminderToUser(ctx, api, r, logger); err != nil {
-				logger.Error().Err(err).Str("reminder_id", r.ID).Str("user_id", r.UserID).Msg("failed to send reminder")
-				continue
+		if err := sendReminderToUser(ctx, api, r, logger); err != nil {
+			logger.Error().Err(err).Str("reminder_id", r.ID).Str("user_id", r.UserID).Msg("failed to send reminder")
+			if err := reminderService.ReleaseReminder(ctx, r.ID); err != nil {
+				logger.Error().Err(err).Str("reminder_id", r.ID).Msg("failed to release reminder")
 			}
+			continue
+		}
 
-			if err := reminderService.MarkReminderCompleted(ctx, r.ID); err != nil {
-				logger.Error().Err(err).Str("reminder_id", r.ID).Msg("failed to mark reminder as completed")
-			} else {
-				logger.Info().Str("reminder_id", r.ID).Str("user_id", r.UserID).Str("text", r.Text).Msg("reminder sent and marked as completed")
-			}
+		if err := reminderService.MarkReminderCompleted(ctx, r.ID); err != nil {
+			logger.Error().Err(err).Str("reminder_id", r.ID).Msg("failed to mark reminder as completed")
+		} else {
+			logger.Info().Str("reminder_id", r.ID).Str("user_id", r.UserID).Str("text", r.Text).Msg("reminder sent and marked as completed")
 		}
 	}
 }
@@ -266,3 +835,127 @@ func sendReminderToUser(ctx context.Context, api *maxbot.Api, r reminder.Reminde
 
 	return nil
 }
+
+// scheduleWatchInterval - как часто расписание пользователей сравнивается с предыдущим снимком
+const scheduleWatchInterval = 15 * time.Minute
+
+// startScheduleWatcher периодически ищет изменения расписания и уведомляет пользователей
+func startScheduleWatcher(ctx context.Context, watcher *schedulewatch.Watcher, logger zerolog.Logger) {
+	ticker := time.NewTicker(scheduleWatchInterval)
+	defer ticker.Stop()
+
+	logger.Info().Msg("schedule watcher started")
+
+	// Первая проверка сразу при запуске: снимки создаются или сравниваются с сохраненными до перезапуска
+	watcher.Check(ctx, time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("schedule watcher stopped")
+			return
+		case <-ticker.C:
+			watcher.Check(ctx, time.Now())
+		}
+	}
+}
+
+// sendTextToUser отправляет пользователю текстовое сообщение
+func sendTextToUser(ctx context.Context, api *maxbot.Api, userID, text string) error {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse user ID: %w", err)
+	}
+
+	msg := maxbot.NewMessage()
+	msg.SetUser(id)
+	msg.SetText(text)
+	if _, err := api.Messages.Send(ctx, msg); err != nil {
+		if a, _ := err.(schemes.Error); a.Code != "" {
+			return fmt.Errorf("failed to send message: %w", err)
+		}
+	}
+	return nil
+}
+
+// chatSchedulePostHour - час, начиная с которого расписание публикуется в групповые чаты
+const chatSchedulePostHour = 7
+
+// startChatSchedulePoster раз в день публикует расписание учебной группы в подписанные чаты
+func startChatSchedulePoster(ctx context.Context, chats state.ChatRepository, scheduler schedule.GroupScheduler, api *maxbot.Api, logger zerolog.Logger) {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	logger.Info().Msg("chat schedule poster started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("chat schedule poster stopped")
+			return
+		case <-ticker.C:
+			postChatSchedules(ctx, chats, scheduler, api, logger)
+		}
+	}
+}
+
+// postChatSchedules публикует расписание в чаты, где оно сегодня еще не публиковалось
+func postChatSchedules(ctx context.Context, chats state.ChatRepository, scheduler schedule.GroupScheduler, api *maxbot.Api, logger zerolog.Logger) {
+	now := time.Now()
+	if now.Hour() < chatSchedulePostHour {
+		return
+	}
+
+	list, err := chats.ListChatSettings(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to list chats")
+		return
+	}
+
+	for _, chat := range list {
+		if !chat.SubscribeSchedule || chat.StudyGroup == "" {
+			continue
+		}
+		if !chat.SchedulePostedAt.IsZero() && sameDay(chat.SchedulePostedAt, now) {
+			continue
+		}
+
+		dayStart, dayEnd := schedule.DayRange(now)
+		items, err := scheduler.GetGroupSchedule(ctx, chat.StudyGroup, dayStart, dayEnd)
+		if err != nil {
+			logger.Error().Err(err).Int64("chat_id", chat.ChatID).Str("group", chat.StudyGroup).Msg("failed to get group schedule")
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("📅 Расписание группы %s на сегодня:\n\n", chat.StudyGroup))
+		if len(items) == 0 {
+			b.WriteString("Занятий нет.")
+		}
+		for _, item := range items {
+			b.WriteString(fmt.Sprintf("• %s — %s\n  %s, %s\n\n", item.Time.Format("15:04"), item.Discipline, item.Instructor, item.Location))
+		}
+
+		msg := maxbot.NewMessage()
+		msg.SetChat(chat.ChatID)
+		msg.SetText(b.String())
+		if _, err := api.Messages.Send(ctx, msg); err != nil {
+			if a, _ := err.(schemes.Error); a.Code != "" {
+				logger.Error().Err(err).Int64("chat_id", chat.ChatID).Msg("failed to post schedule to chat")
+				continue
+			}
+		}
+
+		chat.SchedulePostedAt = now
+		if err := chats.SaveChatSettings(ctx, chat); err != nil {
+			logger.Error().Err(err).Int64("chat_id", chat.ChatID).Msg("failed to save chat settings")
+		}
+		logger.Info().Int64("chat_id", chat.ChatID).Str("group", chat.StudyGroup).Msg("schedule posted to chat")
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -12,7 +12,7 @@ import techaas.max_uni.uni_back.service.TicketsService;
 @RequiredArgsConstructor
 public class TicketsController {
 
-    private TicketsService ticketsService;
+    private final TicketsService ticketsService;
 
     @GetMapping("/ticket/{ticketId}")
     public ResponseEntity<?> getTicket(@PathVariable String ticketId) {
@@ -1,5 +1,6 @@
 package techaas.max_uni.uni_back.dao.entity;
 
+import com.fasterxml.jackson.annotation.JsonIgnoreProperties;
 import lombok.Data;
 
 import jakarta.persistence.*;
@@ -15,6 +16,7 @@ public class Tickets {
     private String id;
 
     @ManyToOne(fetch = FetchType.LAZY)
+    @JsonIgnoreProperties({"hibernateLazyInitializer", "handler", "generatedCode"})
     @JoinColumn(name = "user_id", nullable = false)
     private Users user;
 
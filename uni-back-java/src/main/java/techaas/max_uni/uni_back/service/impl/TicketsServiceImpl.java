@@ -3,6 +3,7 @@ package techaas.max_uni.uni_back.service.impl;
 import lombok.RequiredArgsConstructor;
 import org.springframework.stereotype.Service;
 import techaas.max_uni.uni_back.dao.entity.Tickets;
+import techaas.max_uni.uni_back.dao.entity.Users;
 import techaas.max_uni.uni_back.dao.repository.TicketsRepository;
 import techaas.max_uni.uni_back.dao.repository.UsersRepository;
 import techaas.max_uni.uni_back.service.TicketsService;
@@ -28,6 +29,10 @@ public class TicketsServiceImpl implements TicketsService {
 
     @Override
     public List<Tickets> getUserTickets(Long maxId) {
-        return ticketsRepository.findTicketsByUser(usersRepository.findUsersById((maxId)).get(0));
+        Users user = usersRepository.findByMaxId(maxId);
+        if (user == null) {
+            return List.of();
+        }
+        return ticketsRepository.findTicketsByUser(user);
     }
 }